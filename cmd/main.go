@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
 	"path"
 	"syscall"
+	_ "time/tzdata" // Station timezones have to resolve in minimal container images
 
 	"github.com/cheatsnake/airstation/internal/config"
 	"github.com/cheatsnake/airstation/internal/http"
@@ -21,10 +23,12 @@ func main() {
 	fs.DeleteDirIfExists(conf.TmpDir)
 	fs.MustDir(conf.TmpDir)
 	fs.MustDir(conf.TracksDir)
+	fs.MustDir(conf.VoiceDir)
+	fs.MustDir(conf.JinglesDir)
 	fs.MustDir(conf.DBDir)
 
-	stopSignal := make(chan os.Signal, 1)
-	signal.Notify(stopSignal, os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	log := logger.New()
 	store, err := sqlite.New(path.Join(conf.DBDir, conf.DBFile), log.WithGroup("storage"))
@@ -34,20 +38,33 @@ func main() {
 	}
 
 	httpServer := http.NewServer(store, conf, log)
-	go httpServer.Run()
+	go func() {
+		<-ctx.Done()
+		log.Info("Shutting down the app...")
+	}()
 
-	<-stopSignal
-	shutdown(log, store)
-}
+	err = httpServer.Run(ctx)
+	if err != nil {
+		log.Error("Server stopped: " + err.Error())
+	}
 
-func shutdown(log *slog.Logger, store storage.Storage) {
-	println()
-	log.Info("Shutting down the app...")
+	shutdown(log, store, conf)
+	if err != nil {
+		os.Exit(1)
+	}
+}
 
+func shutdown(log *slog.Logger, store storage.Storage, conf *config.Config) {
 	err := store.Close()
 	if err != nil {
 		log.Error("Failed to close database connection: " + err.Error())
 	}
 
+	// Segments are regenerated on start, the ones left behind may be half-written
+	err = fs.DeleteDirIfExists(conf.TmpDir)
+	if err != nil {
+		log.Warn("Failed to delete temporary files: " + err.Error())
+	}
+
 	log.Info("App gracefully stopped")
 }
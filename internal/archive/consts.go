@@ -0,0 +1,18 @@
+package archive
+
+import "time"
+
+const (
+	feedInterval  = 500 * time.Millisecond // How often the playing segment is checked
+	gapTolerance  = 10 * time.Second       // A longer break in the broadcast starts a new file
+	pruneInterval = time.Hour              // How often the archives older than the retention period are deleted
+)
+
+// MaxClipDuration limits the length of the clips cut from the archives.
+const MaxClipDuration = 4 * time.Hour
+
+const (
+	fileExt       = ".aac"
+	nameLayout    = "20060102T150405.000Z" // The layout of the start and end times in the file names
+	nameSeparator = "_"                    // Separates the start and the end time in the names of finished files
+)
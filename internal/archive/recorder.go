@@ -0,0 +1,506 @@
+// Package archive records the broadcast of a channel into hourly files, as it went out to the listeners,
+// and keeps them for the retention period. The recordings can be listed by time, downloaded and cut into clips.
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+)
+
+// Recorder writes the segments of a channel into an ADTS file as they start playing, the AAC audio is
+// copied as is. A new file is started every hour and after every break in the broadcast.
+type Recorder struct {
+	source    Source
+	ffmpegCLI *ffmpeg.CLI
+	dir       string
+	retention time.Duration // How long the recordings are kept, 0 keeps them forever
+	log       *slog.Logger
+
+	current     *Archive // The recording in progress, nil if nothing is recorded
+	lastSegment string   // The path of the last recorded segment, used by Run only
+	mutex       sync.Mutex
+}
+
+// recording is the file the recorder writes to and the encoder that feeds it.
+type recording struct {
+	file    *os.File
+	start   time.Time
+	written float64 // Duration (in seconds) of the recorded audio
+	encoder *encoder
+}
+
+// encoder copies the audio of the segments written to its input into the file.
+type encoder struct {
+	cmd   *exec.Cmd
+	input io.WriteCloser
+	init  string        // The init segment the encoder was given
+	done  chan struct{} // Closed once the output is copied
+}
+
+// NewRecorder creates a recorder of the channel broadcast.
+//
+// Parameters:
+//   - source: The playback timeline of the channel.
+//   - cli: The FFmpeg CLI used to copy the audio of the segments.
+//   - dir: The directory of the channel recordings.
+//   - retention: How long the recordings are kept, 0 keeps them forever.
+//   - log: The logger.
+//
+// Returns:
+//   - A pointer to a new Recorder instance.
+func NewRecorder(source Source, cli *ffmpeg.CLI, dir string, retention time.Duration, log *slog.Logger) *Recorder {
+	return &Recorder{
+		source:    source,
+		ffmpegCLI: cli,
+		dir:       dir,
+		retention: retention,
+		log:       log,
+	}
+}
+
+// Run records the broadcast until it's stopped. The recordings an unexpected shutdown left unfinished
+// are finished first, and the ones older than the retention period are deleted every hour.
+//
+// Parameters:
+//   - stop: Closed to stop the recorder, the recording in progress is finished then.
+func (r *Recorder) Run(stop <-chan struct{}) {
+	r.recover()
+	r.prune(time.Now())
+	lastPrune := time.Now()
+
+	ticker := time.NewTicker(feedInterval)
+	defer ticker.Stop()
+
+	var rec *recording
+	for {
+		select {
+		case <-stop:
+			r.finish(rec)
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		rec = r.record(rec, now)
+
+		if now.Sub(lastPrune) >= pruneInterval {
+			r.prune(now)
+			lastPrune = now
+		}
+	}
+}
+
+// record writes the playing segment into the recording once it starts playing. The recording is finished
+// when the hour is over or the broadcast breaks, and the segment goes into a new one.
+//
+// Returns:
+//   - The recording in progress, nil if nothing is recorded.
+func (r *Recorder) record(rec *recording, now time.Time) *recording {
+	seg, _ := r.source.PlayingSegment()
+	if seg == nil {
+		if rec != nil && now.Sub(rec.end()) > gapTolerance {
+			r.finish(rec)
+			return nil
+		}
+		return rec
+	}
+
+	if seg.Path == r.lastSegment {
+		return rec
+	}
+	r.lastSegment = seg.Path
+
+	if rec != nil && needsNewFile(rec.start, rec.end(), now) {
+		r.finish(rec)
+		rec = nil
+	}
+
+	if rec == nil {
+		var err error
+		rec, err = r.open(now)
+		if err != nil {
+			r.log.Error("Recording failed to start: " + err.Error())
+			return nil
+		}
+	}
+
+	// Segments with another init segment are a separate MP4 file for FFmpeg
+	if rec.encoder != nil && rec.encoder.init != seg.Init {
+		r.stopEncoder(rec)
+	}
+
+	if rec.encoder == nil {
+		if err := r.startEncoder(rec, seg.Init); err != nil {
+			r.log.Error("Recording encoder failed: " + err.Error())
+			return rec
+		}
+	}
+
+	if err := writeFile(rec.encoder.input, seg.Path); err != nil {
+		r.log.Warn("Recording segment is skipped: " + err.Error())
+		r.stopEncoder(rec)
+		return rec
+	}
+
+	rec.written += seg.Duration
+	r.mutex.Lock()
+	r.current.end = rec.end()
+	r.mutex.Unlock()
+
+	return rec
+}
+
+// open creates the file of a new recording that starts at the given time.
+func (r *Recorder) open(start time.Time) (*recording, error) {
+	path := filepath.Join(r.dir, formatTime(start)+fileExt)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.current = newArchive(path, start, start)
+	r.mutex.Unlock()
+
+	return &recording{file: file, start: start}, nil
+}
+
+// finish stops the encoder of the recording and names the file after the time range it covers.
+// An empty recording is removed.
+func (r *Recorder) finish(rec *recording) {
+	if rec == nil {
+		return
+	}
+
+	r.stopEncoder(rec)
+	if err := rec.file.Close(); err != nil {
+		r.log.Warn("Recording file failed to close: " + err.Error())
+	}
+
+	r.mutex.Lock()
+	r.current = nil
+	r.mutex.Unlock()
+
+	path := rec.file.Name()
+	if rec.written == 0 {
+		_ = os.Remove(path)
+		return
+	}
+
+	if err := os.Rename(path, finishedPath(path, rec.start, rec.end())); err != nil {
+		r.log.Warn("Recording file failed to rename: " + err.Error())
+	}
+}
+
+// startEncoder starts copying the audio of the segments into the file of the recording.
+// Fragmented MP4 segments need their init segment to be written first.
+func (r *Recorder) startEncoder(rec *recording, init string) error {
+	cmd, input, output, err := r.ffmpegCLI.StartStreamEncoder(ffmpeg.StreamADTS, 0)
+	if err != nil {
+		return err
+	}
+
+	enc := &encoder{cmd: cmd, input: input, init: init, done: make(chan struct{})}
+	go func() {
+		defer close(enc.done)
+		if _, err := io.Copy(rec.file, output); err != nil {
+			r.log.Warn("Recording output is lost: " + err.Error())
+		}
+	}()
+	rec.encoder = enc
+
+	if init != "" {
+		if err := writeFile(input, init); err != nil {
+			r.stopEncoder(rec)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stopEncoder waits until the encoder of the recording writes out everything it was given.
+func (r *Recorder) stopEncoder(rec *recording) {
+	enc := rec.encoder
+	if enc == nil {
+		return
+	}
+
+	enc.input.Close()
+	<-enc.done
+	if err := enc.cmd.Wait(); err != nil {
+		r.log.Warn("Recording encoder stopped: " + err.Error())
+	}
+	rec.encoder = nil
+}
+
+// recover finishes the recordings an unexpected shutdown left unfinished. Their duration is probed,
+// or taken from the time the file was last written if it can't be.
+func (r *Recorder) recover() {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		r.log.Warn("Recordings failed to read: " + err.Error())
+		return
+	}
+
+	for _, entry := range entries {
+		a, ok := parseArchive(r.dir, entry.Name())
+		if !ok || !a.start.Equal(a.end) {
+			continue
+		}
+
+		end := a.start
+		if meta, err := r.ffmpegCLI.AudioMetadata(context.Background(), a.path); err == nil {
+			end = a.start.Add(seconds(meta.Duration))
+		} else if info, err := entry.Info(); err == nil {
+			end = info.ModTime()
+		}
+
+		if !end.After(a.start) {
+			_ = os.Remove(a.path)
+			continue
+		}
+
+		if err := os.Rename(a.path, finishedPath(a.path, a.start, end)); err != nil {
+			r.log.Warn("Recording file failed to rename: " + err.Error())
+		}
+	}
+}
+
+// prune deletes the recordings that ended before the retention period.
+func (r *Recorder) prune(now time.Time) {
+	if r.retention <= 0 {
+		return
+	}
+
+	archives, err := r.archives()
+	if err != nil {
+		r.log.Warn("Recordings failed to read: " + err.Error())
+		return
+	}
+
+	for _, a := range archives {
+		if a.Recording || !a.end.Before(now.Add(-r.retention)) {
+			continue
+		}
+
+		if err := os.Remove(a.path); err != nil {
+			r.log.Warn("Expired recording failed to delete: " + err.Error())
+		}
+	}
+}
+
+// List returns the recordings that overlap the given time range, from the oldest to the newest.
+//
+// Parameters:
+//   - from: The start of the time range.
+//   - to: The end of the time range.
+//
+// Returns:
+//   - The recordings, or an error if the directory can't be read.
+func (r *Recorder) List(from, to time.Time) ([]*Archive, error) {
+	archives, err := r.archives()
+	if err != nil {
+		return nil, err
+	}
+
+	overlapping := make([]*Archive, 0, len(archives))
+	for _, a := range archives {
+		if a.end.After(from) && a.start.Before(to) {
+			overlapping = append(overlapping, a)
+		}
+	}
+
+	return overlapping, nil
+}
+
+// Archive returns a recording by its ID.
+//
+// Parameters:
+//   - id: The ID of the recording.
+//
+// Returns:
+//   - The recording, or an error if there is no such recording.
+func (r *Recorder) Archive(id string) (*Archive, error) {
+	archives, err := r.archives()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range archives {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+
+	return nil, errors.New("recording not found")
+}
+
+// ExportClip cuts the broadcast of the given time range out of the recordings into a single ADTS file.
+// The breaks in the broadcast are left out of the clip.
+//
+// Parameters:
+//   - ctx: Stops the export when canceled.
+//   - from: The start of the clip.
+//   - to: The end of the clip.
+//   - outputPath: Destination path for the clip (should end with .aac).
+//
+// Returns:
+//   - An error if the time range is invalid, nothing was recorded in it, or the export fails.
+func (r *Recorder) ExportClip(ctx context.Context, from, to time.Time, outputPath string) error {
+	if !to.After(from) {
+		return errors.New("clip must end after it starts")
+	}
+
+	if to.Sub(from) > MaxClipDuration {
+		return errors.New("clip must not be longer than " + MaxClipDuration.String())
+	}
+
+	archives, err := r.List(from, to)
+	if err != nil {
+		return err
+	}
+
+	parts := clipParts(archives, from, to)
+	if len(parts) == 0 {
+		return errors.New("nothing was recorded in this time range")
+	}
+
+	return r.ffmpegCLI.ExportClip(ctx, parts, outputPath)
+}
+
+// archives reads all recordings from the directory, the one in progress included, from the oldest to the newest.
+func (r *Recorder) archives() ([]*Archive, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	var current Archive
+	if r.current != nil {
+		current = *r.current
+	}
+	r.mutex.Unlock()
+
+	archives := make([]*Archive, 0, len(entries))
+	for _, entry := range entries {
+		a, ok := parseArchive(r.dir, entry.Name())
+		if !ok {
+			continue
+		}
+
+		if a.path == current.path {
+			a = newArchive(current.path, current.start, current.end)
+			a.Recording = true
+		}
+
+		if info, err := entry.Info(); err == nil {
+			a.Size = info.Size()
+		}
+
+		archives = append(archives, a)
+	}
+
+	slices.SortFunc(archives, func(a, b *Archive) int {
+		return a.start.Compare(b.start)
+	})
+
+	return archives, nil
+}
+
+func (rec *recording) end() time.Time {
+	return rec.start.Add(seconds(rec.written))
+}
+
+// needsNewFile reports whether a segment that starts playing at the given time goes into a new file:
+// when the hour of the recording is over, or when nothing was aired for a while.
+func needsNewFile(start, end, now time.Time) bool {
+	return now.Sub(end) > gapTolerance || !now.UTC().Truncate(time.Hour).Equal(start.UTC().Truncate(time.Hour))
+}
+
+// clipParts returns the parts of the recordings that fall into the given time range.
+func clipParts(archives []*Archive, from, to time.Time) []ffmpeg.ClipPart {
+	parts := make([]ffmpeg.ClipPart, 0, len(archives))
+	for _, a := range archives {
+		start := max(from.Sub(a.start).Seconds(), 0)
+		end := min(to.Sub(a.start).Seconds(), a.Duration)
+		if end > start {
+			parts = append(parts, ffmpeg.ClipPart{Path: a.path, Start: start, End: end})
+		}
+	}
+
+	return parts
+}
+
+func newArchive(path string, start, end time.Time) *Archive {
+	return &Archive{
+		ID:       formatTime(start),
+		Start:    start.Unix(),
+		End:      end.Unix(),
+		Duration: end.Sub(start).Seconds(),
+		path:     path,
+		start:    start,
+		end:      end,
+	}
+}
+
+// parseArchive reads the time range of a recording from the name of its file. The file of a recording
+// in progress is named after its start time, a finished one after its start and end times.
+func parseArchive(dir, name string) (*Archive, bool) {
+	stem, ok := strings.CutSuffix(name, fileExt)
+	if !ok {
+		return nil, false
+	}
+
+	startName, endName, finished := strings.Cut(stem, nameSeparator)
+	start, err := time.Parse(nameLayout, startName)
+	if err != nil {
+		return nil, false
+	}
+
+	end := start
+	if finished {
+		end, err = time.Parse(nameLayout, endName)
+		if err != nil || end.Before(start) {
+			return nil, false
+		}
+	}
+
+	return newArchive(filepath.Join(dir, name), start, end), true
+}
+
+// finishedPath returns the path a recording is moved to once it's finished.
+func finishedPath(path string, start, end time.Time) string {
+	return filepath.Join(filepath.Dir(path), formatTime(start)+nameSeparator+formatTime(end)+fileExt)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(nameLayout)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func writeFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,198 @@
+package archive
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+)
+
+var hour = time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)
+
+// newTestRecorder returns a recorder of a temporary directory with the given files.
+func newTestRecorder(t *testing.T, retention time.Duration, names ...string) *Recorder {
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewRecorder(nil, ffmpeg.NewCLI(hls.ContainerTS), dir, retention, slog.New(slog.DiscardHandler))
+}
+
+func finishedName(start, end time.Time) string {
+	return filepath.Base(finishedPath(formatTime(start)+fileExt, start, end))
+}
+
+func TestParseArchive(t *testing.T) {
+	t.Run("finished recording", func(t *testing.T) {
+		end := hour.Add(time.Hour - 1500*time.Millisecond)
+		a, ok := parseArchive("dir", finishedName(hour, end))
+		if !ok {
+			t.Fatal("expected the name to be parsed")
+		}
+		if a.ID != "20261018T030000.000Z" || !a.start.Equal(hour) || !a.end.Equal(end) {
+			t.Errorf("unexpected archive %+v", a)
+		}
+		if a.Duration != 3598.5 || a.Start != hour.Unix() {
+			t.Errorf("expected 3598.5 seconds from %d, got %.1f from %d", hour.Unix(), a.Duration, a.Start)
+		}
+	})
+
+	t.Run("recording in progress", func(t *testing.T) {
+		a, ok := parseArchive("dir", "20261018T030000.250Z.aac")
+		if !ok || !a.start.Equal(a.end) || a.start.Nanosecond() != 250*int(time.Millisecond) {
+			t.Errorf("expected an empty recording, got %+v", a)
+		}
+	})
+
+	t.Run("other files", func(t *testing.T) {
+		for _, name := range []string{"notes.txt", "clip.aac", "20261018T040000.000Z_20261018T030000.000Z.aac"} {
+			if _, ok := parseArchive("dir", name); ok {
+				t.Errorf("expected %q to be ignored", name)
+			}
+		}
+	})
+}
+
+func TestNeedsNewFile(t *testing.T) {
+	cases := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		now      time.Time
+		expected bool
+	}{
+		{"continuous", hour, hour.Add(10 * time.Minute), hour.Add(10*time.Minute + time.Second), false},
+		{"ahead of the clock", hour, hour.Add(10 * time.Minute), hour.Add(9 * time.Minute), false},
+		{"break", hour, hour.Add(10 * time.Minute), hour.Add(11 * time.Minute), true},
+		{"next hour", hour, hour.Add(time.Hour - time.Second), hour.Add(time.Hour), true},
+	}
+
+	for _, c := range cases {
+		if got := needsNewFile(c.start, c.end, c.now); got != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestRecorder_List(t *testing.T) {
+	r := newTestRecorder(t, 0,
+		finishedName(hour.Add(time.Hour), hour.Add(2*time.Hour)),
+		finishedName(hour, hour.Add(time.Hour)),
+		"notes.txt",
+	)
+
+	archives, err := r.List(hour.Add(30*time.Minute), hour.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(archives) != 2 || !archives[0].start.Equal(hour) || archives[0].Size != 5 {
+		t.Fatalf("expected both recordings from the oldest, got %+v", archives)
+	}
+
+	archives, _ = r.List(hour.Add(time.Hour), hour.Add(3*time.Hour))
+	if len(archives) != 1 || !archives[0].start.Equal(hour.Add(time.Hour)) {
+		t.Errorf("expected only the second recording, got %+v", archives)
+	}
+
+	if _, err := r.Archive(archives[0].ID); err != nil {
+		t.Errorf("expected the recording to be found by its ID: %v", err)
+	}
+	if _, err := r.Archive("../storage"); err == nil {
+		t.Error("expected an error for an unknown ID")
+	}
+}
+
+func TestRecorder_ListInProgress(t *testing.T) {
+	start := hour.Add(2 * time.Hour)
+	r := newTestRecorder(t, 0, formatTime(start)+fileExt)
+	r.current = newArchive(filepath.Join(r.dir, formatTime(start)+fileExt), start, start.Add(5*time.Minute))
+
+	archives, err := r.List(start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(archives) != 1 || !archives[0].Recording || archives[0].Duration != 300 {
+		t.Errorf("expected the recording in progress with 5 minutes recorded, got %+v", archives)
+	}
+}
+
+func TestRecorder_Prune(t *testing.T) {
+	now := hour.Add(48 * time.Hour)
+	r := newTestRecorder(t, 24*time.Hour,
+		finishedName(hour, hour.Add(time.Hour)),
+		finishedName(now.Add(-2*time.Hour), now.Add(-time.Hour)),
+	)
+
+	r.prune(now)
+
+	archives, _ := r.List(time.Time{}, now)
+	if len(archives) != 1 || !archives[0].start.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("expected only the recent recording to be kept, got %+v", archives)
+	}
+
+	r.retention = 0
+	r.prune(now.Add(100 * time.Hour))
+	if archives, _ := r.List(time.Time{}, now); len(archives) != 1 {
+		t.Error("expected recordings to be kept forever without retention")
+	}
+}
+
+func TestRecorder_Recover(t *testing.T) {
+	r := newTestRecorder(t, 0, formatTime(hour)+fileExt)
+	modified := hour.Add(20 * time.Minute)
+	if err := os.Chtimes(filepath.Join(r.dir, formatTime(hour)+fileExt), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	r.recover()
+
+	archives, _ := r.List(hour, hour.Add(time.Hour))
+	if len(archives) != 1 || archives[0].Recording || archives[0].Duration <= 0 {
+		t.Errorf("expected the recording to be finished, got %+v", archives)
+	}
+}
+
+func TestClipParts(t *testing.T) {
+	first, _ := parseArchive("dir", finishedName(hour, hour.Add(time.Hour)))
+	second, _ := parseArchive("dir", finishedName(hour.Add(70*time.Minute), hour.Add(2*time.Hour)))
+
+	parts := clipParts([]*Archive{first, second}, hour.Add(50*time.Minute), hour.Add(80*time.Minute))
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %+v", parts)
+	}
+	if parts[0].Start != 3000 || parts[0].End != 3600 {
+		t.Errorf("expected the last 10 minutes of the first recording, got %+v", parts[0])
+	}
+	if parts[1].Start != 0 || parts[1].End != 600 {
+		t.Errorf("expected the first 10 minutes of the second recording, got %+v", parts[1])
+	}
+}
+
+func TestRecorder_ExportClipValidation(t *testing.T) {
+	r := newTestRecorder(t, 0, finishedName(hour, hour.Add(time.Hour)))
+	ctx := context.Background()
+	output := filepath.Join(t.TempDir(), "clip.aac")
+
+	cases := []struct {
+		name     string
+		from, to time.Time
+	}{
+		{"ends before it starts", hour.Add(time.Minute), hour},
+		{"too long", hour, hour.Add(MaxClipDuration + time.Second)},
+		{"nothing recorded", hour.Add(2 * time.Hour), hour.Add(3 * time.Hour)},
+	}
+
+	for _, c := range cases {
+		if err := r.ExportClip(ctx, c.from, c.to, output); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package archive
+
+import (
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+)
+
+// Source provides the segments of the playback timeline.
+type Source interface {
+	// PlayingSegment returns the segment that plays at the moment and the name of its track,
+	// or a nil segment if nothing plays.
+	PlayingSegment() (*hls.Segment, string)
+}
+
+// Archive is a recording of the broadcast, usually an hour long. A recording is shorter
+// when the broadcast started or stopped within the hour.
+type Archive struct {
+	ID        string  `json:"id"`        // The start time of the recording, which identifies it
+	Start     int64   `json:"start"`     // Unix timestamp of the start of the recording
+	End       int64   `json:"end"`       // Unix timestamp of the end of the recording
+	Duration  float64 `json:"duration"`  // Duration of the recording in seconds
+	Size      int64   `json:"size"`      // Size of the file in bytes
+	Recording bool    `json:"recording"` // Whether the recording is still in progress
+
+	path  string
+	start time.Time
+	end   time.Time
+}
+
+// Path returns the path of the recording file.
+func (a *Archive) Path() string {
+	return a.path
+}
@@ -0,0 +1,11 @@
+package autodj
+
+const (
+	DefaultThreshold        = 3
+	DefaultAvoidRecentHours = 2
+
+	minThreshold        = 1
+	maxThreshold        = 50
+	maxAvoidRecentHours = 24 * 7
+	maxLibraryTracks    = 10000 // Upper bound of library tracks considered for a refill
+)
@@ -0,0 +1,237 @@
+package autodj
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/playlist"
+	"github.com/cheatsnake/airstation/internal/queue"
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+type Service struct {
+	store           Store
+	queueService    *queue.Service
+	trackService    *track.Service
+	playlistService *playlist.Service
+	channelID       string
+	log             *slog.Logger
+	mutex           sync.Mutex // Refills are triggered both by playback and by queue changes
+}
+
+// NewService creates an auto-DJ service that keeps the queue of a single channel filled.
+func NewService(store Store, qs *queue.Service, ts *track.Service, pls *playlist.Service, channelID string, log *slog.Logger) *Service {
+	return &Service{
+		store:           store,
+		queueService:    qs,
+		trackService:    ts,
+		playlistService: pls,
+		channelID:       channelID,
+		log:             log,
+	}
+}
+
+// Settings retrieves the auto-DJ settings of the channel, falling back to the defaults if none are saved.
+//
+// Returns:
+//   - A pointer to the settings, or an error.
+func (s *Service) Settings() (*Settings, error) {
+	settings, err := s.store.AutoDJSettings(s.channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		settings = &Settings{
+			Threshold:        DefaultThreshold,
+			AvoidRecentHours: DefaultAvoidRecentHours,
+		}
+	}
+
+	return settings, nil
+}
+
+// SaveSettings validates and saves the auto-DJ settings of the channel.
+//
+// Parameters:
+//   - settings: The new settings.
+//
+// Returns:
+//   - An error if the settings are invalid or cannot be saved.
+func (s *Service) SaveSettings(settings *Settings) error {
+	err := validateSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	if settings.PlaylistID != "" {
+		pl, err := s.playlistService.Playlist(settings.PlaylistID)
+		if err != nil || pl == nil {
+			return errors.New("fallback playlist not found")
+		}
+	}
+
+	err = s.store.SaveAutoDJSettings(s.channelID, settings)
+	return err
+}
+
+// Refill adds tracks to the queue when it has fewer tracks than the threshold. Tracks are drawn from
+// the fallback playlist or the whole library, skipping the queued ones and, if possible, the recently played ones.
+// It does nothing if the auto-DJ is disabled or the queue is meant to stop at the end.
+//
+// Returns:
+//   - The number of added tracks, or an error.
+func (s *Service) Refill() (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	settings, err := s.Settings()
+	if err != nil || !settings.Enabled {
+		return 0, err
+	}
+
+	mode, err := s.queueService.Mode()
+	if err != nil || mode == queue.ModeStopAtEnd {
+		return 0, err
+	}
+
+	queued, err := s.queueService.Queue()
+	if err != nil {
+		return 0, err
+	}
+
+	missing := settings.Threshold - len(queued)
+	if missing <= 0 {
+		return 0, nil
+	}
+
+	pool, err := s.pool(settings)
+	if err != nil {
+		return 0, err
+	}
+
+	var recent []string
+	if settings.AvoidRecentHours > 0 {
+		since := time.Now().Add(-time.Duration(settings.AvoidRecentHours) * time.Hour).Unix()
+		recent, err = s.store.RecentlyPlayedTrackIDs(s.channelID, since)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	candidates := filterTracks(pool, trackIDs(queued), recent)
+	if len(candidates) == 0 { // Repeating a track is better than going off air
+		candidates = filterTracks(pool, trackIDs(queued), nil)
+	}
+
+	var counts map[string]int
+	if settings.WeightByPlayCount {
+		counts, err = s.store.TrackPlayCounts(s.channelID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	picked := pickTracks(candidates, counts, missing)
+	if len(picked) == 0 {
+		return 0, nil
+	}
+
+	err = s.queueService.AddToQueue(picked)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(picked), nil
+}
+
+// pool returns the tracks the auto-DJ may pick from.
+func (s *Service) pool(settings *Settings) ([]*track.Track, error) {
+	if settings.PlaylistID != "" {
+		pl, err := s.playlistService.Playlist(settings.PlaylistID)
+		if err == nil && pl != nil && len(pl.Tracks) > 0 {
+			return pl.Tracks, nil
+		}
+
+		s.log.Warn("Auto-DJ fallback playlist is missing or empty, using the whole library")
+	}
+
+	page, err := s.trackService.Tracks(1, maxLibraryTracks, "", "id", "asc")
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Tracks, nil
+}
+
+// filterTracks returns the tracks whose IDs are not listed in any of the exclusion lists.
+func filterTracks(tracks []*track.Track, excluded ...[]string) []*track.Track {
+	filtered := make([]*track.Track, 0, len(tracks))
+	for _, t := range tracks {
+		skip := false
+		for _, ids := range excluded {
+			if slices.Contains(ids, t.ID) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			filtered = append(filtered, t)
+		}
+	}
+
+	return filtered
+}
+
+// pickTracks randomly picks up to amount distinct tracks. If play counts are given,
+// the chance of a track to be picked is inversely proportional to the number of its plays.
+func pickTracks(candidates []*track.Track, counts map[string]int, amount int) []*track.Track {
+	pool := slices.Clone(candidates)
+	picked := make([]*track.Track, 0, amount)
+
+	for len(picked) < amount && len(pool) > 0 {
+		i := rand.IntN(len(pool))
+		if counts != nil {
+			i = weightedIndex(pool, counts)
+		}
+
+		picked = append(picked, pool[i])
+		pool = slices.Delete(pool, i, i+1)
+	}
+
+	return picked
+}
+
+func weightedIndex(pool []*track.Track, counts map[string]int) int {
+	total := 0.0
+	for _, t := range pool {
+		total += playWeight(counts[t.ID])
+	}
+
+	r := rand.Float64() * total
+	for i, t := range pool {
+		r -= playWeight(counts[t.ID])
+		if r < 0 {
+			return i
+		}
+	}
+
+	return len(pool) - 1
+}
+
+func playWeight(playCount int) float64 {
+	return 1 / float64(1+playCount)
+}
+
+func trackIDs(tracks []*track.Track) []string {
+	ids := make([]string, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.ID
+	}
+
+	return ids
+}
@@ -0,0 +1,111 @@
+package autodj
+
+import (
+	"testing"
+
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+type mockStore struct {
+	settings *Settings
+}
+
+func (m *mockStore) AutoDJSettings(channelID string) (*Settings, error) {
+	return m.settings, nil
+}
+
+func (m *mockStore) SaveAutoDJSettings(channelID string, settings *Settings) error {
+	m.settings = settings
+	return nil
+}
+
+func (m *mockStore) TrackPlayCounts(channelID string) (map[string]int, error) {
+	return nil, nil
+}
+
+func (m *mockStore) RecentlyPlayedTrackIDs(channelID string, since int64) ([]string, error) {
+	return nil, nil
+}
+
+func TestService_Settings(t *testing.T) {
+	t.Run("defaults when nothing is saved", func(t *testing.T) {
+		svc := NewService(&mockStore{}, nil, nil, nil, "main", nil)
+		settings, err := svc.Settings()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if settings.Enabled || settings.Threshold != DefaultThreshold || settings.AvoidRecentHours != DefaultAvoidRecentHours {
+			t.Errorf("unexpected default settings: %+v", settings)
+		}
+	})
+
+	t.Run("saves valid settings", func(t *testing.T) {
+		mock := &mockStore{}
+		svc := NewService(mock, nil, nil, nil, "main", nil)
+		err := svc.SaveSettings(&Settings{Enabled: true, Threshold: 5})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mock.settings == nil || mock.settings.Threshold != 5 {
+			t.Errorf("settings were not saved: %+v", mock.settings)
+		}
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		svc := NewService(&mockStore{}, nil, nil, nil, "main", nil)
+		invalid := []*Settings{
+			{Threshold: 0},
+			{Threshold: maxThreshold + 1},
+			{Threshold: 1, AvoidRecentHours: -1},
+			{Threshold: 1, AvoidRecentHours: maxAvoidRecentHours + 1},
+		}
+		for _, settings := range invalid {
+			if err := svc.SaveSettings(settings); err == nil {
+				t.Errorf("expected error for %+v, got nil", settings)
+			}
+		}
+	})
+}
+
+func TestFilterTracks(t *testing.T) {
+	tracks := []*track.Track{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}}
+	filtered := filterTracks(tracks, []string{"1"}, []string{"3"})
+	if len(filtered) != 2 || filtered[0].ID != "2" || filtered[1].ID != "4" {
+		t.Errorf("unexpected filtered tracks: %v", trackIDs(filtered))
+	}
+}
+
+func TestPickTracks(t *testing.T) {
+	candidates := []*track.Track{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+
+	t.Run("picks distinct tracks", func(t *testing.T) {
+		picked := pickTracks(candidates, nil, 3)
+		seen := make(map[string]bool)
+		for _, p := range picked {
+			seen[p.ID] = true
+		}
+		if len(picked) != 3 || len(seen) != 3 {
+			t.Errorf("expected 3 distinct tracks, got %v", trackIDs(picked))
+		}
+	})
+
+	t.Run("limited by candidates", func(t *testing.T) {
+		picked := pickTracks(candidates, nil, 5)
+		if len(picked) != 3 {
+			t.Errorf("expected 3 tracks, got %d", len(picked))
+		}
+	})
+
+	t.Run("prefers rarely played tracks", func(t *testing.T) {
+		counts := map[string]int{"1": 1000, "2": 1000}
+		rare := 0
+		for range 100 {
+			if pickTracks(candidates, counts, 1)[0].ID == "3" {
+				rare++
+			}
+		}
+		if rare < 90 {
+			t.Errorf("expected rarely played track to be picked most of the time, got %d/100", rare)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package autodj
+
+// Settings defines how the auto-DJ keeps the queue of a channel filled.
+type Settings struct {
+	Enabled           bool   `json:"enabled"`           // Whether the queue is refilled automatically.
+	Threshold         int    `json:"threshold"`         // The queue is refilled when it has fewer tracks than this.
+	PlaylistID        string `json:"playlistId"`        // The fallback playlist to draw tracks from, the whole library is used if empty.
+	AvoidRecentHours  int    `json:"avoidRecentHours"`  // Tracks played within this many hours are not picked again.
+	WeightByPlayCount bool   `json:"weightByPlayCount"` // Whether rarely played tracks are picked more often.
+}
+
+type Store interface {
+	AutoDJSettings(channelID string) (*Settings, error)
+	SaveAutoDJSettings(channelID string, settings *Settings) error
+	TrackPlayCounts(channelID string) (map[string]int, error)
+	RecentlyPlayedTrackIDs(channelID string, since int64) ([]string, error)
+}
@@ -0,0 +1,13 @@
+package autodj
+
+import "fmt"
+
+func validateSettings(settings *Settings) error {
+	if settings.Threshold < minThreshold || settings.Threshold > maxThreshold {
+		return fmt.Errorf("threshold must be between %d and %d", minThreshold, maxThreshold)
+	}
+	if settings.AvoidRecentHours < 0 || settings.AvoidRecentHours > maxAvoidRecentHours {
+		return fmt.Errorf("avoid recent hours must be between 0 and %d", maxAvoidRecentHours)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package channel
+
+// DefaultID is the identifier of the channel that always exists and is served at /stream.
+const DefaultID = "main"
+
+const (
+	minIDLen    = 2
+	maxIDLen    = 32
+	minNameLen  = 3
+	maxNameLen  = 128
+	maxDescrLen = 4096
+)
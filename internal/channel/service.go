@@ -0,0 +1,143 @@
+package channel
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Service manages the channels of the station, each streaming its own playback.
+type Service struct {
+	store Store
+}
+
+// NewService creates a channel service.
+//
+// Parameters:
+//   - store: The storage of the channels.
+//
+// Returns:
+//   - A pointer to a new Service instance.
+func NewService(store Store) *Service {
+	return &Service{
+		store: store,
+	}
+}
+
+// AddChannel validates and creates a new channel.
+//
+// Parameters:
+//   - id: The identifier of the channel, used in its URLs.
+//   - name: The display name of the channel.
+//   - description: The description of the channel.
+//
+// Returns:
+//   - The created channel, or an error if the values are invalid or the ID is taken.
+func (s *Service) AddChannel(id, name, description string) (*Channel, error) {
+	err := validateID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateDescr(description)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.store.Channel(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("channel with this id already exists")
+	}
+
+	ch, err := s.store.AddChannel(id, name, description)
+	return ch, err
+}
+
+// Channels retrieves all channels of the station.
+//
+// Returns:
+//   - A slice of Channel pointers or an error.
+func (s *Service) Channels() ([]*Channel, error) {
+	chs, err := s.store.Channels()
+	return chs, err
+}
+
+// Channel retrieves a channel by its ID.
+//
+// Parameters:
+//   - id: The identifier of the channel.
+//
+// Returns:
+//   - The channel, or an error if it doesn't exist or can't be read.
+func (s *Service) Channel(id string) (*Channel, error) {
+	ch, err := s.store.Channel(id)
+	if err != nil {
+		return nil, err
+	}
+	if ch == nil {
+		return nil, fmt.Errorf("channel %s not found", id)
+	}
+
+	return ch, nil
+}
+
+// EditChannel validates and updates the name and the description of a channel.
+//
+// Parameters:
+//   - id: The identifier of the channel.
+//   - name: The new display name.
+//   - description: The new description.
+//
+// Returns:
+//   - The updated channel, or an error if the values are invalid or the channel doesn't exist.
+func (s *Service) EditChannel(id, name, description string) (*Channel, error) {
+	err := validateName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateDescr(description)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.Channel(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.EditChannel(id, name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Channel(id)
+}
+
+// DeleteChannel removes a channel. The default channel can't be deleted.
+//
+// Parameters:
+//   - id: The identifier of the channel.
+//
+// Returns:
+//   - An error if the channel is the default one, doesn't exist, or can't be deleted.
+func (s *Service) DeleteChannel(id string) error {
+	if id == DefaultID {
+		return errors.New("default channel cannot be deleted")
+	}
+
+	_, err := s.Channel(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.DeleteChannel(id)
+	return err
+}
@@ -0,0 +1,164 @@
+package channel
+
+import (
+	"testing"
+)
+
+type mockStore struct {
+	channelsFn      func() ([]*Channel, error)
+	channelFn       func(id string) (*Channel, error)
+	addChannelFn    func(id, name, description string) (*Channel, error)
+	editChannelFn   func(id, name, description string) error
+	deleteChannelFn func(id string) error
+}
+
+func (m *mockStore) Channels() ([]*Channel, error) {
+	if m.channelsFn != nil {
+		return m.channelsFn()
+	}
+	return nil, nil
+}
+
+func (m *mockStore) Channel(id string) (*Channel, error) {
+	if m.channelFn != nil {
+		return m.channelFn(id)
+	}
+	return nil, nil
+}
+
+func (m *mockStore) AddChannel(id, name, description string) (*Channel, error) {
+	if m.addChannelFn != nil {
+		return m.addChannelFn(id, name, description)
+	}
+	return &Channel{ID: id, Name: name, Description: description}, nil
+}
+
+func (m *mockStore) EditChannel(id, name, description string) error {
+	if m.editChannelFn != nil {
+		return m.editChannelFn(id, name, description)
+	}
+	return nil
+}
+
+func (m *mockStore) DeleteChannel(id string) error {
+	if m.deleteChannelFn != nil {
+		return m.deleteChannelFn(id)
+	}
+	return nil
+}
+
+func TestService_AddChannel(t *testing.T) {
+	t.Run("successful creation", func(t *testing.T) {
+		svc := NewService(&mockStore{})
+		ch, err := svc.AddChannel("jazz", "Jazz", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ch.ID != "jazz" {
+			t.Errorf("expected id %q, got %q", "jazz", ch.ID)
+		}
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		svc := NewService(&mockStore{})
+		for _, id := range []string{"", "a", "Jazz", "jazz club", "-jazz", "jazz/1"} {
+			_, err := svc.AddChannel(id, "Jazz", "")
+			if err == nil {
+				t.Errorf("expected error for id %q, got nil", id)
+			}
+		}
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		svc := NewService(&mockStore{})
+		_, err := svc.AddChannel("jazz", "J", "")
+		if err == nil {
+			t.Error("expected error for short name, got nil")
+		}
+	})
+
+	t.Run("duplicate id", func(t *testing.T) {
+		mock := &mockStore{
+			channelFn: func(id string) (*Channel, error) {
+				return &Channel{ID: id}, nil
+			},
+		}
+		svc := NewService(mock)
+		_, err := svc.AddChannel("jazz", "Jazz", "")
+		if err == nil {
+			t.Error("expected error for duplicate id, got nil")
+		}
+	})
+}
+
+func TestService_EditChannel(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		svc := NewService(&mockStore{})
+		_, err := svc.EditChannel("jazz", "Jazz", "")
+		if err == nil {
+			t.Error("expected error for missing channel, got nil")
+		}
+	})
+
+	t.Run("returns updated channel", func(t *testing.T) {
+		stored := &Channel{ID: "jazz", Name: "Jazz"}
+		mock := &mockStore{
+			channelFn: func(id string) (*Channel, error) {
+				return stored, nil
+			},
+			editChannelFn: func(id, name, description string) error {
+				stored = &Channel{ID: id, Name: name, Description: description}
+				return nil
+			},
+		}
+		svc := NewService(mock)
+		ch, err := svc.EditChannel("jazz", "Jazz Club", "Late night")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ch.Name != "Jazz Club" {
+			t.Errorf("expected name %q, got %q", "Jazz Club", ch.Name)
+		}
+	})
+}
+
+func TestService_DeleteChannel(t *testing.T) {
+	t.Run("default channel is protected", func(t *testing.T) {
+		called := false
+		mock := &mockStore{
+			deleteChannelFn: func(id string) error {
+				called = true
+				return nil
+			},
+		}
+		svc := NewService(mock)
+		err := svc.DeleteChannel(DefaultID)
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+		if called {
+			t.Error("store should not be called for the default channel")
+		}
+	})
+
+	t.Run("deletes existing channel", func(t *testing.T) {
+		var deleted string
+		mock := &mockStore{
+			channelFn: func(id string) (*Channel, error) {
+				return &Channel{ID: id}, nil
+			},
+			deleteChannelFn: func(id string) error {
+				deleted = id
+				return nil
+			},
+		}
+		svc := NewService(mock)
+		err := svc.DeleteChannel("jazz")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if deleted != "jazz" {
+			t.Errorf("expected jazz to be deleted, got %q", deleted)
+		}
+	})
+}
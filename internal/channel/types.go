@@ -0,0 +1,16 @@
+package channel
+
+// Channel represents an independent stream with its own queue and playback state.
+type Channel struct {
+	ID          string `json:"id"`          // A URL-friendly identifier used in the /stream/{channel} path.
+	Name        string `json:"name"`        // The human readable name of the channel.
+	Description string `json:"description"` // An optional description of the channel.
+}
+
+type Store interface {
+	Channels() ([]*Channel, error)
+	Channel(id string) (*Channel, error)
+	AddChannel(id, name, description string) (*Channel, error)
+	EditChannel(id, name, description string) error
+	DeleteChannel(id string) error
+}
@@ -0,0 +1,39 @@
+package channel
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+func validateID(id string) error {
+	if len(id) < minIDLen {
+		return fmt.Errorf("id must be at least %d characters", minIDLen)
+	}
+	if len(id) > maxIDLen {
+		return fmt.Errorf("id must be at most %d characters", maxIDLen)
+	}
+	if !idPattern.MatchString(id) {
+		return errors.New("id may contain only lowercase letters, digits and dashes")
+	}
+	return nil
+}
+
+func validateName(name string) error {
+	if len(name) < minNameLen {
+		return fmt.Errorf("name must be at least %d characters", minNameLen)
+	}
+	if len(name) > maxNameLen {
+		return fmt.Errorf("name must be at most %d characters", maxNameLen)
+	}
+	return nil
+}
+
+func validateDescr(descr string) error {
+	if len(descr) > maxDescrLen {
+		return fmt.Errorf("description must be at most %d characters", maxDescrLen)
+	}
+	return nil
+}
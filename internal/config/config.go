@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -15,13 +16,39 @@ type Config struct {
 	DBDir        string
 	DBFile       string
 	TracksDir    string
+	VoiceDir     string
+	JinglesDir   string
 	TmpDir       string
+	CacheDir     string
+	ArchiveDir   string
 	PlayerDir    string
 	StudioDir    string
 	HTTPPort     string
 	JWTSign      string
 	SecretKey    string
 	SecureCookie bool
+
+	CrossfadeDuration float64
+	CrossfadeCurve    string
+
+	ResumeOfflineTime bool
+
+	LoudnessTarget float64
+	TruePeakLimit  float64
+
+	LiveSourcePassword string
+
+	SegmentCacheSize int // The size limit of the segment cache in MB, 0 disables the cache
+
+	HLSContainer    string  // Format of the HLS segment files, ts or fmp4
+	HLSRenditions   string  // Extra renditions of the HLS stream, e.g. "64k-he,128k,256k"
+	HLSPartDuration float64 // Duration of Low-Latency HLS parts in seconds, 0 disables Low-Latency HLS
+	HLSDVRWindow    int     // Minutes of the played broadcast listeners can seek back in, 0 disables the DVR window
+
+	HLSSegmentDuration int // Duration of HLS segments in seconds, the value saved through the API takes precedence
+	HLSLiveSegments    int // Number of segments in the HLS live window, the value saved through the API takes precedence
+
+	ArchiveRetention int // Number of days the broadcast recordings are kept, 0 disables recording
 }
 
 func Load() *Config {
@@ -31,13 +58,39 @@ func Load() *Config {
 		DBDir:        getEnv("AIRSTATION_DB_DIR", filepath.Join("storage")),
 		DBFile:       getEnv("AIRSTATION_DB_FILE", "storage.db"),
 		TracksDir:    getEnv("AIRSTATION_TRACKS_DIR", filepath.Join("static", "tracks")),
+		VoiceDir:     getEnv("AIRSTATION_VOICE_DIR", filepath.Join("static", "voice")),
+		JinglesDir:   getEnv("AIRSTATION_JINGLES_DIR", filepath.Join("static", "jingles")),
 		TmpDir:       getEnv("AIRSTATION_TMP_DIR", filepath.Join("static", "tmp")),
+		CacheDir:     getEnv("AIRSTATION_CACHE_DIR", filepath.Join("static", "cache")),
+		ArchiveDir:   getEnv("AIRSTATION_ARCHIVE_DIR", filepath.Join("static", "archive")),
 		PlayerDir:    getEnv("AIRSTATION_PLAYER_DIR", filepath.Join("web", "player", "dist")),
 		StudioDir:    getEnv("AIRSTATION_STUDIO_DIR", filepath.Join("web", "studio", "dist")),
 		HTTPPort:     getEnv("AIRSTATION_HTTP_PORT", "7331"),
 		JWTSign:      getSecret("AIRSTATION_JWT_SIGN"),
 		SecretKey:    getSecret("AIRSTATION_SECRET_KEY"),
 		SecureCookie: getEnvBool("AIRSTATION_SECURE_COOKIE", false),
+
+		CrossfadeDuration: getEnvFloat("AIRSTATION_CROSSFADE_DURATION", 0),
+		CrossfadeCurve:    getEnv("AIRSTATION_CROSSFADE_CURVE", "tri"),
+
+		ResumeOfflineTime: getEnvBool("AIRSTATION_RESUME_OFFLINE_TIME", false),
+
+		LoudnessTarget: getEnvFloat("AIRSTATION_LOUDNESS_TARGET", -14),
+		TruePeakLimit:  getEnvFloat("AIRSTATION_TRUE_PEAK_LIMIT", -1),
+
+		LiveSourcePassword: os.Getenv("AIRSTATION_LIVE_SOURCE_PASSWORD"),
+
+		SegmentCacheSize: getEnvInt("AIRSTATION_SEGMENT_CACHE_SIZE", 1024),
+
+		HLSContainer:    getEnv("AIRSTATION_HLS_CONTAINER", "ts"),
+		HLSRenditions:   os.Getenv("AIRSTATION_HLS_RENDITIONS"),
+		HLSPartDuration: getEnvFloat("AIRSTATION_HLS_PART_DURATION", 0),
+		HLSDVRWindow:    getEnvInt("AIRSTATION_HLS_DVR_WINDOW", 0),
+
+		HLSSegmentDuration: getEnvInt("AIRSTATION_HLS_SEGMENT_DURATION", 5),
+		HLSLiveSegments:    getEnvInt("AIRSTATION_HLS_LIVE_SEGMENTS", 3),
+
+		ArchiveRetention: getEnvInt("AIRSTATION_ARCHIVE_RETENTION", 0),
 	}
 }
 
@@ -58,6 +111,34 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return val == "1" || val == "true" || val == "yes" || val == "on"
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		log.Fatal(key + " must be a number")
+	}
+
+	return parsed
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(val)
+	if err != nil || parsed < 0 {
+		log.Fatal(key + " must be a non-negative integer")
+	}
+
+	return parsed
+}
+
 func getSecret(key string) string {
 	secretKey := os.Getenv(key)
 
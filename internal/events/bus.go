@@ -0,0 +1,94 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuffer is the number of events a subscriber may fall behind before new events are dropped for it.
+const DefaultBuffer = 64
+
+// Bus delivers published events to every subscriber. Publishing never blocks: if a subscriber
+// doesn't keep up and its buffer is full, the event is dropped for that subscriber only.
+type Bus struct {
+	mutex       sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events published on the bus after it was created.
+type Subscription struct {
+	bus     *Bus
+	events  chan Event
+	dropped atomic.Int64
+}
+
+// NewBus creates and returns a new Bus instance without subscribers.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish delivers an event to all current subscribers. Publishing on a nil bus does nothing.
+//
+// Parameters:
+//   - event: The event to deliver.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber.
+//
+// Parameters:
+//   - buffer: The number of events that are kept for the subscriber until it reads them.
+//
+// Returns:
+//   - A subscription that must be closed once the events are no longer needed.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	sub := &Subscription{
+		bus:    b,
+		events: make(chan Event, max(buffer, 0)),
+	}
+
+	b.mutex.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mutex.Unlock()
+
+	return sub
+}
+
+// Events returns the channel the events are delivered to. It is closed when the subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events that were dropped because the subscriber didn't keep up.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes from the bus and closes the events channel. It is safe to call it more than once.
+func (s *Subscription) Close() {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+
+	if _, ok := s.bus.subscribers[s]; !ok {
+		return
+	}
+
+	delete(s.bus.subscribers, s)
+	close(s.events)
+}
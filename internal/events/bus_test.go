@@ -0,0 +1,127 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBus_MultipleSubscribers(t *testing.T) {
+	bus := NewBus()
+	a := bus.Subscribe(4)
+	b := bus.Subscribe(4)
+	defer a.Close()
+	defer b.Close()
+
+	bus.Publish(TrackStarted{ChannelID: "main", TrackID: "1", TrackName: "Song"})
+
+	for _, sub := range []*Subscription{a, b} {
+		select {
+		case e := <-sub.Events():
+			started, ok := e.(TrackStarted)
+			if !ok {
+				t.Fatalf("expected TrackStarted, got %T", e)
+			}
+			if started.TrackName != "Song" {
+				t.Errorf("expected track name %q, got %q", "Song", started.TrackName)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("event was not delivered")
+		}
+	}
+}
+
+func TestBus_PublishDoesNotBlock(t *testing.T) {
+	bus := NewBus()
+	slow := bus.Subscribe(1)
+	fast := bus.Subscribe(10)
+	defer slow.Close()
+	defer fast.Close()
+
+	done := make(chan struct{})
+	go func() {
+		for range 5 {
+			bus.Publish(QueueChanged{ChannelID: "main"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publishing blocked on a slow subscriber")
+	}
+
+	if got := slow.Dropped(); got != 4 {
+		t.Errorf("expected 4 dropped events, got %d", got)
+	}
+	if got := fast.Dropped(); got != 0 {
+		t.Errorf("expected no dropped events, got %d", got)
+	}
+	if got := len(fast.Events()); got != 5 {
+		t.Errorf("expected 5 buffered events, got %d", got)
+	}
+}
+
+func TestBus_PreservesOrder(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(3)
+	defer sub.Close()
+
+	bus.Publish(PlaybackResumed{ChannelID: "main"})
+	bus.Publish(TrackStarted{ChannelID: "main"})
+	bus.Publish(PlaybackPaused{ChannelID: "main"})
+
+	if _, ok := (<-sub.Events()).(PlaybackResumed); !ok {
+		t.Error("expected PlaybackResumed first")
+	}
+	if _, ok := (<-sub.Events()).(TrackStarted); !ok {
+		t.Error("expected TrackStarted second")
+	}
+	if _, ok := (<-sub.Events()).(PlaybackPaused); !ok {
+		t.Error("expected PlaybackPaused third")
+	}
+}
+
+func TestSubscription_Close(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+
+	sub.Close()
+	sub.Close() // Closing twice is fine
+
+	if _, ok := <-sub.Events(); ok {
+		t.Error("expected events channel to be closed")
+	}
+
+	bus.Publish(LibraryChanged{Added: []string{"1"}}) // Doesn't panic on a closed subscription
+	if got := sub.Dropped(); got != 0 {
+		t.Errorf("expected closed subscription to receive nothing, got %d dropped", got)
+	}
+}
+
+func TestBus_ConcurrentUse(t *testing.T) {
+	bus := NewBus()
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sub := bus.Subscribe(1)
+			bus.Publish(IngestProgress{File: "a.mp3", Processed: 1, Total: 1})
+			sub.Close()
+		}()
+		go func() {
+			defer wg.Done()
+			bus.Publish(PlaybackPaused{ChannelID: "main"})
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestBus_NilPublish(t *testing.T) {
+	var bus *Bus
+	bus.Publish(PlaybackPaused{ChannelID: "main"})
+}
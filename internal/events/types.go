@@ -0,0 +1,55 @@
+// Package events provides the internal publish/subscribe bus the station components use
+// to notify each other about playback, queue and library changes.
+package events
+
+// Event is implemented by every event published on the bus.
+type Event interface {
+	event()
+}
+
+// TrackStarted is published when a track, a clip or a live source starts airing on a channel.
+type TrackStarted struct {
+	ChannelID string
+	TrackID   string // Empty for clips and live sources.
+	TrackName string
+	Overlay   bool // Whether the clip airs over the current track instead of replacing it.
+}
+
+// PlaybackPaused is published when the playback of a channel stops.
+type PlaybackPaused struct {
+	ChannelID string
+}
+
+// PlaybackResumed is published when the playback of a channel starts after a pause.
+type PlaybackResumed struct {
+	ChannelID string
+	TrackID   string
+	TrackName string
+}
+
+// QueueChanged is published when the tracks or the play-order mode of a channel queue change.
+type QueueChanged struct {
+	ChannelID string
+}
+
+// LibraryChanged is published when tracks are added to, updated in or deleted from the library.
+type LibraryChanged struct {
+	Added   []string // IDs of the added tracks.
+	Updated []string // IDs of the updated tracks.
+	Deleted []string // IDs of the deleted tracks.
+}
+
+// IngestProgress is published after each uploaded file is processed.
+type IngestProgress struct {
+	File      string // The name of the processed file.
+	Processed int    // The number of files processed so far, including this one.
+	Total     int    // The number of files being processed.
+	Error     string // The reason the file was not added to the library, if any.
+}
+
+func (TrackStarted) event()    {}
+func (PlaybackPaused) event()  {}
+func (PlaybackResumed) event() {}
+func (QueueChanged) event()    {}
+func (LibraryChanged) event()  {}
+func (IngestProgress) event()  {}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/archive"
+)
+
+// handleArchives lists the recordings of a channel that overlap the time range given by the "from" and "to"
+// query parameters, the last day by default.
+func (s *Server) handleArchives(w http.ResponseWriter, r *http.Request) {
+	recorder, ok := s.requestRecorder(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseTimeRange(w, r, 24*time.Hour)
+	if !ok {
+		return
+	}
+
+	archives, err := recorder.List(from, to)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonInternalError(w, "Recordings retrieving failed")
+		return
+	}
+
+	jsonResponse(w, archives)
+}
+
+// handleArchiveDownload serves the file of a recording as an attachment.
+func (s *Server) handleArchiveDownload(w http.ResponseWriter, r *http.Request) {
+	recorder, ok := s.requestRecorder(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := recorder.Archive(r.PathValue("id"))
+	if err != nil {
+		jsonNotFound(w, "Recording not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", archiveContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+a.ID+`.aac"`)
+	http.ServeFile(w, r, a.Path())
+}
+
+// handleArchiveClip cuts the broadcast of the time range given by the "from" and "to" query parameters
+// out of the recordings and serves it as an attachment.
+func (s *Server) handleArchiveClip(w http.ResponseWriter, r *http.Request) {
+	recorder, ok := s.requestRecorder(w, r)
+	if !ok {
+		return
+	}
+
+	queries := r.URL.Query()
+	if queries.Get("from") == "" || queries.Get("to") == "" {
+		jsonBadRequest(w, "Both from and to are required")
+		return
+	}
+
+	from, to, ok := parseTimeRange(w, r, 0)
+	if !ok {
+		return
+	}
+
+	clip, err := os.CreateTemp("", "airstation-clip-*.aac")
+	if err != nil {
+		jsonInternalError(w, "Clip export failed: "+err.Error())
+		return
+	}
+	clip.Close()
+	defer os.Remove(clip.Name())
+
+	err = recorder.ExportClip(r.Context(), from, to, clip.Name())
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Clip export failed: "+err.Error())
+		return
+	}
+
+	name := from.UTC().Format(clipNameLayout) + "-" + to.UTC().Format(clipNameLayout) + ".aac"
+	w.Header().Set("Content-Type", archiveContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeFile(w, r, clip.Name())
+}
+
+// requestRecorder resolves the recorder of the requested channel. If the channel doesn't exist
+// or its broadcast isn't recorded, it writes a not found response and returns false.
+func (s *Server) requestRecorder(w http.ResponseWriter, r *http.Request) (*archive.Recorder, bool) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if ch.recorder == nil {
+		jsonNotFound(w, "Recording is disabled")
+		return nil, false
+	}
+
+	return ch.recorder, true
+}
+
+// parseTimeRange reads the "from" and "to" query parameters. The range ends now
+// and lasts for the given duration by default.
+func parseTimeRange(w http.ResponseWriter, r *http.Request, defaultDuration time.Duration) (time.Time, time.Time, bool) {
+	queries := r.URL.Query()
+
+	to, err := parseUnixQuery(queries, "to", time.Now())
+	if err != nil {
+		jsonBadRequest(w, err.Error())
+		return time.Time{}, time.Time{}, false
+	}
+
+	from, err := parseUnixQuery(queries, "from", to.Add(-defaultDuration))
+	if err != nil {
+		jsonBadRequest(w, err.Error())
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to, true
+}
@@ -0,0 +1,252 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/archive"
+	"github.com/cheatsnake/airstation/internal/autodj"
+	"github.com/cheatsnake/airstation/internal/channel"
+	"github.com/cheatsnake/airstation/internal/events"
+	"github.com/cheatsnake/airstation/internal/jingle"
+	"github.com/cheatsnake/airstation/internal/live"
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/fs"
+	"github.com/cheatsnake/airstation/internal/pkg/sse"
+	"github.com/cheatsnake/airstation/internal/playback"
+	"github.com/cheatsnake/airstation/internal/queue"
+	"github.com/cheatsnake/airstation/internal/relay"
+	"github.com/cheatsnake/airstation/internal/schedule"
+	"github.com/cheatsnake/airstation/internal/voice"
+)
+
+// channelRuntime holds everything a single channel needs to stream independently of the others.
+type channelRuntime struct {
+	id              string
+	playbackState   *playback.State
+	eventsEmitter   *sse.Emitter
+	queueService    *queue.Service
+	playbackService *playback.Service
+	autoDJService   *autodj.Service
+	scheduleService *schedule.Service
+	liveService     *live.Service
+	voiceService    *voice.Service
+	mp3Stream       *relay.Stream
+	aacStream       *relay.Stream
+	recorder        *archive.Recorder // Records the broadcast, nil if recording is disabled
+	tmpDir          string
+	voiceDir        string
+	subscriptions   []*events.Subscription
+	stop            chan struct{}
+}
+
+// startChannel creates the runtime of a channel, registers it and starts its playback.
+func (s *Server) startChannel(info *channel.Channel) *channelRuntime {
+	tmpDir := filepath.Join(s.config.TmpDir, info.ID)
+	fs.MustDir(tmpDir)
+	voiceDir := filepath.Join(s.config.VoiceDir, info.ID)
+	fs.MustDir(voiceDir)
+
+	log := s.rootLogger.WithGroup("playback").With("channel", info.ID)
+	qs := queue.NewService(s.store, s.eventBus, info.ID)
+	ps := playback.NewService(s.store, info.ID, log)
+	ads := autodj.NewService(s.store, qs, s.trackService, s.playlistService, info.ID, log)
+	state := playback.NewState(s.trackService, qs, ps, tmpDir, log)
+	state.SetEvents(s.eventBus, info.ID)
+	state.SetAutoDJ(ads)
+	state.SetJingles(jingle.NewRotator(s.jingleService, info.ID))
+	state.SetSegmentCache(s.segmentCache)
+	state.SetRenditions(s.renditionNames())
+	state.SetPartDuration(s.partDuration)
+	state.SetDVRWindow(s.dvrWindow)
+	if info, err := s.stationService.Info(); err == nil {
+		state.SetArtworkURL(info.LogoURL)
+	}
+	ss := schedule.NewService(s.store, s.playlistService, s.stationService, info.ID)
+
+	// Playlists of other channels are served one level deeper than /stream
+	if info.ID != channel.DefaultID {
+		state.SetURIPrefix("../")
+	}
+
+	err := state.SetCrossfade(playback.Crossfade{Duration: s.config.CrossfadeDuration, Curve: s.config.CrossfadeCurve})
+	if err != nil {
+		log.Warn("Crossfade is disabled: " + err.Error())
+	}
+
+	ch := &channelRuntime{
+		id:              info.ID,
+		playbackState:   state,
+		eventsEmitter:   sse.NewEmitter(),
+		queueService:    qs,
+		playbackService: ps,
+		autoDJService:   ads,
+		scheduleService: ss,
+		liveService:     live.NewService(state, s.ffmpegCLI, s.timing, tmpDir, log.WithGroup("live")),
+		voiceService:    voice.NewService(s.store, state, s.ffmpegCLI, voiceDir, info.ID, log.WithGroup("voice")),
+		mp3Stream:       relay.NewStream(state, s.ffmpegCLI, ffmpeg.StreamMP3, log.WithGroup("relay")),
+		aacStream:       relay.NewStream(state, s.ffmpegCLI, ffmpeg.StreamADTS, log.WithGroup("relay")),
+		tmpDir:          tmpDir,
+		voiceDir:        voiceDir,
+		stop:            make(chan struct{}),
+	}
+
+	s.channelsMutex.Lock()
+	s.channels[info.ID] = ch
+	s.channelsMutex.Unlock()
+
+	s.listenChannelEvents(ch)
+
+	err = state.Resume(s.config.ResumeOfflineTime)
+	if err != nil {
+		log.Warn("Auto start playing failed: " + err.Error())
+	}
+
+	scheduler := schedule.NewScheduler(ss, qs, s.playlistService, state, log.WithGroup("schedule"))
+	s.background.Go(func() { state.Run(s.ctx) })
+	s.background.Go(func() { scheduler.Run(ch.stop) })
+	s.background.Go(func() { ch.voiceService.Run(ch.stop) })
+
+	if s.config.ArchiveRetention > 0 {
+		archiveDir := filepath.Join(s.config.ArchiveDir, info.ID)
+		fs.MustDir(archiveDir)
+		retention := time.Duration(s.config.ArchiveRetention) * 24 * time.Hour
+		ch.recorder = archive.NewRecorder(state, s.ffmpegCLI, archiveDir, retention, log.WithGroup("archive"))
+		s.background.Go(func() { ch.recorder.Run(ch.stop) })
+	}
+
+	return ch
+}
+
+// stopChannel halts the playback of a channel, stops its background routines and removes its segments.
+func (s *Server) stopChannel(id string) {
+	s.channelsMutex.Lock()
+	ch, ok := s.channels[id]
+	delete(s.channels, id)
+	s.channelsMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	ch.playbackState.Stop()
+	ch.mp3Stream.Close()
+	ch.aacStream.Close()
+	close(ch.stop)
+	for _, sub := range ch.subscriptions {
+		sub.Close()
+	}
+	ch.eventsEmitter.RegisterEvent(eventPause, " ")
+
+	err := fs.DeleteDirIfExists(ch.tmpDir)
+	if err != nil {
+		s.logger.Warn("Failed to delete channel segments: " + err.Error())
+	}
+}
+
+// channel returns the runtime of a running channel by its ID.
+func (s *Server) channel(id string) (*channelRuntime, bool) {
+	s.channelsMutex.RLock()
+	defer s.channelsMutex.RUnlock()
+
+	ch, ok := s.channels[id]
+	return ch, ok
+}
+
+// allChannels returns runtimes of all running channels.
+func (s *Server) allChannels() []*channelRuntime {
+	s.channelsMutex.RLock()
+	defer s.channelsMutex.RUnlock()
+
+	chs := make([]*channelRuntime, 0, len(s.channels))
+	for _, ch := range s.channels {
+		chs = append(chs, ch)
+	}
+
+	return chs
+}
+
+// requestChannel resolves the channel from the "channel" query parameter, falling back to the default one.
+// If the channel doesn't exist, it writes a not found response and returns false.
+func (s *Server) requestChannel(w http.ResponseWriter, r *http.Request) (*channelRuntime, bool) {
+	id := r.URL.Query().Get("channel")
+	if id == "" {
+		id = channel.DefaultID
+	}
+
+	ch, ok := s.channel(id)
+	if !ok {
+		jsonNotFound(w, "Channel not found")
+	}
+
+	return ch, ok
+}
+
+// broadcastEvent sends an event to the listeners of all channels.
+func (s *Server) broadcastEvent(name, data string) {
+	for _, ch := range s.allChannels() {
+		ch.eventsEmitter.RegisterEvent(name, data)
+	}
+}
+
+// reloadChannels refreshes playlists of all channels, used after changes in the shared library.
+func (s *Server) reloadChannels() {
+	for _, ch := range s.allChannels() {
+		err := ch.playbackState.Reload()
+		if err != nil {
+			s.logger.Debug("Playback reload failed: " + err.Error())
+		}
+	}
+}
+
+// startChannels starts all channels stored in the database.
+func (s *Server) startChannels() error {
+	chs, err := s.channelService.Channels()
+	if err != nil {
+		return err
+	}
+
+	if len(chs) == 0 {
+		return errors.New("no channels found")
+	}
+
+	for _, info := range chs {
+		s.startChannel(info)
+	}
+
+	return nil
+}
+
+func (s *Server) countListeners(ch *channelRuntime) *sse.Event {
+	count := ch.eventsEmitter.CountSubscribers() + ch.mp3Stream.Listeners() + ch.aacStream.Listeners()
+	return sse.NewEvent(eventCountListeners, strconv.Itoa(count))
+}
+
+// listenChannelEvents forwards the playback events of a channel to its SSE listeners
+// until the channel is stopped.
+func (s *Server) listenChannelEvents(ch *channelRuntime) {
+	sub := s.eventBus.Subscribe(events.DefaultBuffer)
+	ch.subscriptions = append(ch.subscriptions, sub)
+
+	s.background.Go(func() {
+		for e := range sub.Events() {
+			switch e := e.(type) {
+			case events.PlaybackResumed:
+				if e.ChannelID == ch.id {
+					ch.eventsEmitter.RegisterEvent(eventPlay, e.TrackName)
+				}
+			case events.PlaybackPaused:
+				if e.ChannelID == ch.id {
+					ch.eventsEmitter.RegisterEvent(eventPause, " ")
+				}
+			case events.TrackStarted:
+				if e.ChannelID == ch.id && !e.Overlay {
+					ch.eventsEmitter.RegisterEvent(eventNewTrack, e.TrackName)
+				}
+			}
+		}
+	})
+}
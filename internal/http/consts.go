@@ -1,5 +1,7 @@
 package http
 
+import "time"
+
 const (
 	eventPlay           = "play"
 	eventPause          = "pause"
@@ -7,4 +9,35 @@ const (
 	eventLoadedTracks   = "loaded_tracks"
 	eventCountListeners = "count_listeners"
 	eventChangeTheme    = "change_theme"
+	eventQueueMode      = "queue_mode"
+	eventLiveStart      = "live_start"
+	eventLiveEnd        = "live_end"
+)
+
+// loudnessRange is the target loudness range in LU for normalized tracks, suitable for most music.
+const loudnessRange = 11
+
+const bytesInMB = 1 << 20
+
+// sourceRendition names the main rendition of the HLS stream in the master playlist,
+// its bitrate is the one the tracks are converted to.
+const (
+	sourceRendition = "source"
+	sourceBitRate   = 192
 )
+
+// partBlockSegments limits how long a Low-Latency HLS playlist request waits for the part it asks for,
+// in durations of a segment.
+const partBlockSegments = 3
+
+// Recordings and clips of the broadcast are ADTS files named after the time they start.
+const (
+	archiveContentType = "audio/aac"
+	clipNameLayout     = "20060102T150405Z"
+)
+
+// maxDVRWindow limits the DVR window in minutes, since its segments are kept on disk and listed in every playlist.
+const maxDVRWindow = 120
+
+// shutdownTimeout limits how long the server waits for in-flight requests to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
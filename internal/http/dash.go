@@ -0,0 +1,49 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cheatsnake/airstation/internal/pkg/dash"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+)
+
+// handleDASHManifest serves the playback of a channel as a live MPEG-DASH manifest.
+// It describes the same segments as the HLS playlists, so it needs fragmented MP4 segments.
+func (s *Server) handleDASHManifest(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	if s.ffmpegCLI.Container() != hls.ContainerFMP4 {
+		jsonNotFound(w, "DASH requires fragmented MP4 segments")
+		return
+	}
+
+	timeline := ch.playbackState.Timeline()
+	if len(timeline) == 0 {
+		jsonNotFound(w, "Nothing is playing")
+		return
+	}
+
+	variants := s.variants()
+	representations := make([]dash.Representation, 0, len(variants))
+	for i, v := range variants {
+		rep := dash.Representation{ID: v.Name, BitRate: v.BitRate, Codecs: v.Codecs}
+		if i > 0 { // Segments of the main rendition aren't in a subdirectory
+			rep.Dir = v.Name
+		}
+		representations = append(representations, rep)
+	}
+
+	manifest, err := dash.Manifest(timeline, representations, s.timing.SegmentDuration())
+	if err != nil {
+		jsonInternalError(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/dash+xml")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, manifest)
+}
@@ -10,11 +10,22 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"time"
 
+	"github.com/cheatsnake/airstation/internal/autodj"
+	"github.com/cheatsnake/airstation/internal/channel"
+	"github.com/cheatsnake/airstation/internal/jingle"
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/fs"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
 	"github.com/cheatsnake/airstation/internal/pkg/sse"
+	"github.com/cheatsnake/airstation/internal/pkg/ulid"
+	"github.com/cheatsnake/airstation/internal/queue"
+	"github.com/cheatsnake/airstation/internal/schedule"
 	"github.com/cheatsnake/airstation/internal/station"
 	"github.com/cheatsnake/airstation/internal/track"
+	"github.com/cheatsnake/airstation/internal/voice"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -22,30 +33,174 @@ const multipartChunkLimit = 64 * 1024 * 1024 // 64 MB
 const copyBufferSize = 256 * 1024            // 256 KB
 
 func (s *Server) handleHLSPlaylist(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.channel(channel.DefaultID)
+	if !ok {
+		jsonNotFound(w, "Channel not found")
+		return
+	}
+
+	s.writeHLSPlaylist(w, r, ch)
+}
+
+func (s *Server) handleChannelHLSPlaylist(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("channel")
+	if id == channel.DefaultID { // Segment URIs of the default channel are relative to /stream
+		http.Redirect(w, r, "../stream", http.StatusMovedPermanently)
+		return
+	}
+
+	ch, ok := s.channel(id)
+	if !ok {
+		jsonNotFound(w, "Channel not found")
+		return
+	}
+
+	s.writeHLSPlaylist(w, r, ch)
+}
+
+// writeHLSPlaylist writes the media playlist of the rendition passed in the rendition query parameter.
+// Without the parameter, the master playlist is written if there are extra renditions,
+// otherwise the media playlist of the main rendition is. Low-Latency HLS blocking requests wait
+// for the part they ask for before the media playlist is written.
+func (s *Server) writeHLSPlaylist(w http.ResponseWriter, r *http.Request, ch *channelRuntime) {
+	rendition := r.URL.Query().Get("rendition")
+	renditions := s.ffmpegCLI.Renditions()
+
+	if rendition == "" && len(renditions) > 0 {
+		w.Header().Set("Content-Type", "audio/mpegurl")
+		fmt.Fprint(w, hls.MasterPlaylist(s.variants()))
+		return
+	}
+
+	if !s.awaitHLSPart(w, r, ch) {
+		return
+	}
+
+	if rendition != "" && rendition != sourceRendition {
+		playlist, ok := ch.playbackState.RenditionPlaylist(rendition)
+		if !ok {
+			jsonNotFound(w, "Rendition not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "audio/mpegurl")
+		fmt.Fprint(w, playlist)
+		return
+	}
+
 	w.Header().Set("Content-Type", "audio/mpegurl")
 
-	if s.playbackState.IsPlaying {
-		fmt.Fprint(w, s.playbackState.PlaylistStr)
+	if ch.playbackState.IsPlaying {
+		fmt.Fprint(w, ch.playbackState.PlaylistStr)
+	}
+}
+
+// awaitHLSPart holds a Low-Latency HLS blocking playlist request until the playlist lists the segment
+// passed in the _HLS_msn query parameter and the part passed in the _HLS_part one.
+// It returns false if the response has already been written or the request should end.
+func (s *Server) awaitHLSPart(w http.ResponseWriter, r *http.Request, ch *channelRuntime) bool {
+	query := r.URL.Query()
+	msnParam, partParam := query.Get("_HLS_msn"), query.Get("_HLS_part")
+	if s.partDuration == 0 || (msnParam == "" && partParam == "") {
+		return true
+	}
+
+	if msnParam == "" {
+		jsonBadRequest(w, "_HLS_part requires _HLS_msn")
+		return false
+	}
+
+	msn, err := strconv.ParseInt(msnParam, 10, 64)
+	if err != nil || msn < 0 {
+		jsonBadRequest(w, "Invalid _HLS_msn")
+		return false
+	}
+
+	part := -1
+	if partParam != "" {
+		part, err = strconv.Atoi(partParam)
+		if err != nil || part < 0 {
+			jsonBadRequest(w, "Invalid _HLS_part")
+			return false
+		}
+	}
+
+	// Clients must not ask for segments more than two segments ahead of the playlist
+	if msn > ch.playbackState.MediaSequence()+2 {
+		jsonBadRequest(w, "_HLS_msn is too far ahead")
+		return false
+	}
+
+	timeout := time.NewTimer(partBlockSegments * s.timing.SegmentInterval())
+	defer timeout.Stop()
+
+	for {
+		listed, updated := ch.playbackState.HasPart(msn, part)
+		if listed {
+			return true
+		}
+
+		select {
+		case <-updated:
+		case <-timeout.C:
+			jsonMessage(w, http.StatusServiceUnavailable, "Requested part is not available")
+			return false
+		case <-r.Context().Done():
+			return false
+		case <-s.ctx.Done():
+			return false
+		}
 	}
 }
 
+// variants returns the renditions of the HLS stream, starting with the main one.
+func (s *Server) variants() []hls.Variant {
+	renditions := s.ffmpegCLI.Renditions()
+	variants := make([]hls.Variant, 0, len(renditions)+1)
+	variants = append(variants, hls.Variant{Name: sourceRendition, BitRate: sourceBitRate, Codecs: ffmpeg.Rendition{}.Codecs()})
+	for _, r := range renditions {
+		variants = append(variants, hls.Variant{Name: r.Name, BitRate: r.BitRate, Codecs: r.Codecs()})
+	}
+
+	return variants
+}
+
+// renditionNames returns the names of the extra renditions of the HLS stream.
+func (s *Server) renditionNames() []string {
+	renditions := s.ffmpegCLI.Renditions()
+	names := make([]string, 0, len(renditions))
+	for _, r := range renditions {
+		names = append(names, r.Name)
+	}
+
+	return names
+}
+
 func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
 	eventChan := make(chan *sse.Event)
-	s.eventsEmitter.Subscribe(eventChan)
+	ch.eventsEmitter.Subscribe(eventChan)
 
-	closeNotify := r.Context().Done()
+	// Event streams never end on their own, so they are closed on shutdown instead of being drained
 	go func() {
-		<-closeNotify
-		s.eventsEmitter.Unsubscribe(eventChan)
+		select {
+		case <-r.Context().Done():
+		case <-s.ctx.Done():
+		}
+		ch.eventsEmitter.Unsubscribe(eventChan)
 		close(eventChan)
 	}()
 
 	// Send current number of listeners immediately
-	countEvent := s.countListeners()
+	countEvent := s.countListeners(ch)
 	fmt.Fprint(w, countEvent.Stringify())
 	w.(http.Flusher).Flush()
 
@@ -135,14 +290,14 @@ func (s *Server) handleTracksUpload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, fileHeader := range files {
-		_, err := s.saveFile(fileHeader)
+		_, err := s.saveFile(fileHeader, filepath.Join(s.config.TracksDir, filepath.Base(fileHeader.Filename)))
 		if err != nil {
 			jsonBadRequest(w, err.Error())
 			return
 		}
 	}
 
-	go s.trackService.LoadTracksFromDisk(s.config.TracksDir)
+	s.loadTracks()
 
 	msg := fmt.Sprintf("%d track(s) uploaded successfully. They will be available in your library once processed.", len(files))
 	jsonOK(w, msg)
@@ -165,8 +320,70 @@ func (s *Server) handleDeleteTracks(w http.ResponseWriter, r *http.Request) {
 	jsonOK(w, "Tracks deleted")
 }
 
-func (s *Server) handleQueue(w http.ResponseWriter, _ *http.Request) {
-	queue, err := s.queueService.Queue()
+func (s *Server) handleTracksCrossfade(w http.ResponseWriter, r *http.Request) {
+	body, err := parseJSONBody[struct {
+		IDs      []string `json:"ids"`
+		Disabled bool     `json:"disabled"`
+	}](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	err = s.trackService.SetCrossfadeDisabled(body.IDs, body.Disabled)
+	if err != nil {
+		jsonBadRequest(w, "Changing crossfade settings failed: "+err.Error())
+		return
+	}
+
+	s.reloadChannels()
+
+	jsonOK(w, "Crossfade settings updated")
+}
+
+func (s *Server) handleEditTrackCues(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	body, err := parseJSONBody[track.Cues](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	t, err := s.trackService.EditCues(r.Context(), id, *body)
+	if err != nil {
+		jsonBadRequest(w, "Editing cue points failed: "+err.Error())
+		return
+	}
+
+	s.reloadChannels()
+
+	jsonResponse(w, t)
+}
+
+func (s *Server) handleNormalizeTracks(w http.ResponseWriter, r *http.Request) {
+	body, err := parseJSONBody[track.BodyWithIDs](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	err = s.trackService.NormalizeTracks(s.ctx, body.IDs)
+	if err != nil {
+		jsonBadRequest(w, "Tracks normalization failed: "+err.Error())
+		return
+	}
+
+	jsonOK(w, "Tracks normalization started. It may take a while for large libraries.")
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	queue, err := ch.queueService.Queue()
 	if err != nil {
 		s.logger.Debug(err.Error())
 		jsonBadRequest(w, "Queue retrieving failed: "+err.Error())
@@ -177,6 +394,11 @@ func (s *Server) handleQueue(w http.ResponseWriter, _ *http.Request) {
 }
 
 func (s *Server) handleAddToQueue(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
 	body, err := parseJSONBody[track.BodyWithIDs](r)
 	if err != nil {
 		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
@@ -189,13 +411,13 @@ func (s *Server) handleAddToQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.queueService.AddToQueue(tracks)
+	err = ch.queueService.AddToQueue(tracks)
 	if err != nil {
 		jsonBadRequest(w, "Adding tracks to queue failed: "+err.Error())
 		return
 	}
 
-	err = s.playbackState.Reload()
+	err = ch.playbackState.Reload()
 	if err != nil {
 		s.logger.Debug("Playback reload failed: " + err.Error())
 	}
@@ -204,19 +426,24 @@ func (s *Server) handleAddToQueue(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleReorderQueue(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
 	body, err := parseJSONBody[track.BodyWithIDs](r)
 	if err != nil {
 		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
 		return
 	}
 
-	err = s.queueService.ReorderQueue(body.IDs)
+	err = ch.queueService.ReorderQueue(body.IDs)
 	if err != nil {
 		jsonBadRequest(w, "Queue reordering failed: "+err.Error())
 		return
 	}
 
-	err = s.playbackState.Reload()
+	err = ch.playbackState.Reload()
 	if err != nil {
 		s.logger.Debug("Playback reload failed: " + err.Error())
 	}
@@ -225,26 +452,31 @@ func (s *Server) handleReorderQueue(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleRemoveFromQueue(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
 	body, err := parseJSONBody[track.BodyWithIDs](r)
 	if err != nil {
 		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
 		return
 	}
 
-	if s.playbackState.CurrentTrack != nil {
-		hasCurrent := slices.Contains(body.IDs, s.playbackState.CurrentTrack.ID)
+	if ch.playbackState.CurrentTrack != nil {
+		hasCurrent := slices.Contains(body.IDs, ch.playbackState.CurrentTrack.ID)
 		if hasCurrent {
-			s.playbackState.Pause()
+			ch.playbackState.Pause()
 		}
 	}
 
-	err = s.queueService.RemoveFromQueue(body.IDs)
+	err = ch.queueService.RemoveFromQueue(body.IDs)
 	if err != nil {
 		jsonBadRequest(w, "Removing from queue failed: "+err.Error())
 		return
 	}
 
-	err = s.playbackState.Reload()
+	err = ch.playbackState.Reload()
 	if err != nil {
 		s.logger.Debug("Playback reload failed: " + err.Error())
 	}
@@ -252,29 +484,145 @@ func (s *Server) handleRemoveFromQueue(w http.ResponseWriter, r *http.Request) {
 	jsonOK(w, "Tracks removed")
 }
 
-func (s *Server) handlePlaybackState(w http.ResponseWriter, _ *http.Request) {
-	jsonResponse(w, s.playbackState)
+func (s *Server) handleQueueMode(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	mode, err := ch.queueService.Mode()
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Queue mode retrieving failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, queue.BodyWithMode{Mode: mode})
 }
 
-func (s *Server) handlePausePlayback(w http.ResponseWriter, _ *http.Request) {
-	s.playbackState.Pause()
-	jsonResponse(w, s.playbackState)
+func (s *Server) handleSetQueueMode(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := parseJSONBody[queue.BodyWithMode](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	err = ch.queueService.SetMode(body.Mode)
+	if err != nil {
+		jsonBadRequest(w, "Queue mode changing failed: "+err.Error())
+		return
+	}
+
+	err = ch.playbackState.Reload()
+	if err != nil {
+		s.logger.Debug("Playback reload failed: " + err.Error())
+	}
+
+	ch.eventsEmitter.RegisterEvent(eventQueueMode, string(body.Mode))
+	jsonResponse(w, body)
+}
+
+func (s *Server) handlePlaybackState(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	jsonResponse(w, ch.playbackState)
 }
 
-func (s *Server) handlePlayPlayback(w http.ResponseWriter, _ *http.Request) {
-	err := s.playbackState.Play()
+func (s *Server) handlePausePlayback(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	ch.playbackState.Pause()
+	jsonResponse(w, ch.playbackState)
+}
+
+func (s *Server) handlePlayPlayback(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	err := ch.playbackState.Play()
 	if err != nil {
 		jsonBadRequest(w, "Playback failed to start: "+err.Error())
 		return
 	}
 
-	jsonResponse(w, s.playbackState)
+	jsonResponse(w, ch.playbackState)
+}
+
+func (s *Server) handleSkipPlayback(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := ch.playbackState.Skip()
+	if err != nil {
+		jsonBadRequest(w, "Skipping track failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, ch.playbackState)
+}
+
+func (s *Server) handlePreviousPlayback(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := ch.playbackState.Previous()
+	if err != nil {
+		jsonBadRequest(w, "Returning to previous track failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, ch.playbackState)
+}
+
+func (s *Server) handleJumpPlayback(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := parseJSONBody[struct {
+		Position int `json:"position"`
+	}](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	_, err = ch.playbackState.Jump(body.Position)
+	if err != nil {
+		jsonBadRequest(w, "Jumping to track failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, ch.playbackState)
 }
 
 func (s *Server) handlePlaybackHistory(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
 	queries := r.URL.Query()
 	limit := parseIntQuery(queries, "limit", 50)
-	history, err := s.playbackService.RecentPlaybackHistory(limit)
+	history, err := ch.playbackService.RecentPlaybackHistory(limit)
 	if err != nil {
 		s.logger.Debug(err.Error())
 		jsonBadRequest(w, "Playback history retrieving failed")
@@ -284,6 +632,333 @@ func (s *Server) handlePlaybackHistory(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, history)
 }
 
+func (s *Server) handleAutoDJSettings(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := ch.autoDJService.Settings()
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Auto-DJ settings retrieving failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, settings)
+}
+
+func (s *Server) handleEditAutoDJSettings(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := parseJSONBody[autodj.Settings](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	err = ch.autoDJService.SaveSettings(body)
+	if err != nil {
+		jsonBadRequest(w, "Auto-DJ settings editing failed: "+err.Error())
+		return
+	}
+
+	_, err = ch.autoDJService.Refill()
+	if err != nil {
+		s.logger.Debug("Auto-DJ refill failed: " + err.Error())
+	}
+
+	err = ch.playbackState.Reload()
+	if err != nil {
+		s.logger.Debug("Playback reload failed: " + err.Error())
+	}
+
+	jsonResponse(w, body)
+}
+
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := ch.scheduleService.Entries()
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Schedule retrieving failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, entries)
+}
+
+func (s *Server) handleAddScheduleEntry(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := parseJSONBody[schedule.Entry](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	entry, err := ch.scheduleService.AddEntry(body)
+	if err != nil {
+		jsonBadRequest(w, "Schedule entry creation failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, entry)
+}
+
+func (s *Server) handleEditScheduleEntry(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := parseJSONBody[schedule.Entry](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	body.ID = r.PathValue("id")
+	err = ch.scheduleService.EditEntry(body)
+	if err != nil {
+		jsonBadRequest(w, "Schedule entry editing failed: "+err.Error())
+		return
+	}
+
+	jsonOK(w, "Schedule entry updated")
+}
+
+func (s *Server) handleDeleteScheduleEntry(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	err := ch.scheduleService.DeleteEntry(r.PathValue("id"))
+	if err != nil {
+		jsonBadRequest(w, "Schedule entry deletion failed: "+err.Error())
+		return
+	}
+
+	jsonOK(w, "Schedule entry deleted")
+}
+
+func (s *Server) handleJingles(w http.ResponseWriter, _ *http.Request) {
+	jingles, err := s.jingleService.Jingles()
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Jingles retrieving failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, jingles)
+}
+
+func (s *Server) handleJinglesUpload(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseMultipartForm(multipartChunkLimit)
+	if err != nil {
+		jsonBadRequest(w, "Failed to parse multipart form: "+err.Error())
+		return
+	}
+
+	files := r.MultipartForm.File["jingles"]
+	if len(files) == 0 {
+		jsonBadRequest(w, "No files uploaded")
+		return
+	}
+
+	jingles := make([]*jingle.Jingle, 0, len(files))
+	for _, fileHeader := range files {
+		uploadedPath, err := s.saveFile(fileHeader, filepath.Join(s.config.JinglesDir, filepath.Base(fileHeader.Filename)))
+		if err != nil {
+			jsonBadRequest(w, err.Error())
+			return
+		}
+
+		j, err := s.jingleService.AddJingle(r.Context(), "", uploadedPath)
+		if err != nil {
+			jsonBadRequest(w, "Jingle upload failed: "+err.Error())
+			return
+		}
+
+		jingles = append(jingles, j)
+	}
+
+	jsonResponse(w, jingles)
+}
+
+func (s *Server) handleDeleteJingles(w http.ResponseWriter, r *http.Request) {
+	body, err := parseJSONBody[struct {
+		IDs []string `json:"ids"`
+	}](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	err = s.jingleService.DeleteJingles(body.IDs)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Deleting jingles failed")
+		return
+	}
+
+	jsonOK(w, "Jingles deleted")
+}
+
+func (s *Server) handleJingleRotation(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	rotation, err := s.jingleService.Rotation(ch.id)
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Jingle rotation retrieving failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, rotation)
+}
+
+func (s *Server) handleEditJingleRotation(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := parseJSONBody[jingle.Rotation](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	err = s.jingleService.SaveRotation(ch.id, body)
+	if err != nil {
+		jsonBadRequest(w, "Jingle rotation saving failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, body)
+}
+
+func (s *Server) handleVoiceMessages(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	messages, err := ch.voiceService.Messages()
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Voice messages retrieving failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, messages)
+}
+
+// handleAddVoiceMessage stores a recorded voice message and puts it on air in the "mode" form value. In the "now"
+// mode the message starts over the current track after the live window and one more segment, so listeners hear it
+// that much later; it airs after the track if it doesn't fit. The response tells the mode used and the delay.
+func (s *Server) handleAddVoiceMessage(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseMultipartForm(multipartChunkLimit)
+	if err != nil {
+		jsonBadRequest(w, "Failed to parse multipart form: "+err.Error())
+		return
+	}
+
+	files := r.MultipartForm.File["voice"]
+	if len(files) == 0 {
+		jsonBadRequest(w, "No voice message uploaded")
+		return
+	}
+
+	mode := voice.Mode(r.FormValue("mode"))
+	if mode == "" {
+		mode = voice.ModeNext
+	}
+
+	ttl := voice.DefaultTTL
+	if expiresIn := r.FormValue("expiresIn"); expiresIn != "" {
+		hours, err := strconv.Atoi(expiresIn)
+		if err != nil {
+			jsonBadRequest(w, "Expiry must be a number of hours")
+			return
+		}
+		ttl = time.Duration(hours) * time.Hour
+	}
+
+	// Browsers name recorded blobs arbitrarily, so only the extension is kept to help FFmpeg detect the format
+	recordedPath := filepath.Join(ch.voiceDir, "recorded"+ulid.New()+filepath.Ext(files[0].Filename))
+	_, err = s.saveFile(files[0], recordedPath)
+	if err != nil {
+		jsonBadRequest(w, err.Error())
+		return
+	}
+
+	airing, err := ch.voiceService.AddMessage(r.Context(), r.FormValue("name"), recordedPath, mode, ttl)
+	if err != nil {
+		jsonBadRequest(w, "Voice message failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, airing)
+}
+
+// handleAirVoiceMessage puts a stored voice message on air again, with the same timing as handleAddVoiceMessage.
+func (s *Server) handleAirVoiceMessage(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := parseJSONBody[voice.BodyWithMode](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	airing, err := ch.voiceService.AirMessage(r.PathValue("id"), body.Mode)
+	if err != nil {
+		jsonBadRequest(w, "Voice message airing failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, airing)
+}
+
+func (s *Server) handleDeleteVoiceMessage(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	err := ch.voiceService.DeleteMessage(r.PathValue("id"))
+	if err != nil {
+		jsonBadRequest(w, "Voice message deletion failed: "+err.Error())
+		return
+	}
+
+	jsonOK(w, "Voice message deleted")
+}
+
 func (s *Server) handleAddPlaylist(w http.ResponseWriter, r *http.Request) {
 	body, err := parseJSONBody[struct {
 		Name        string   `json:"name"`
@@ -357,6 +1032,70 @@ func (s *Server) handleDeletePlaylist(w http.ResponseWriter, r *http.Request) {
 	jsonOK(w, "Playlist deleted")
 }
 
+func (s *Server) handleChannels(w http.ResponseWriter, _ *http.Request) {
+	chs, err := s.channelService.Channels()
+	if err != nil {
+		s.logger.Debug(err.Error())
+		jsonBadRequest(w, "Channels retrieving failed")
+		return
+	}
+
+	jsonResponse(w, chs)
+}
+
+func (s *Server) handleAddChannel(w http.ResponseWriter, r *http.Request) {
+	body, err := parseJSONBody[channel.Channel](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	ch, err := s.channelService.AddChannel(body.ID, body.Name, body.Description)
+	if err != nil {
+		jsonBadRequest(w, "Channel creation failed: "+err.Error())
+		return
+	}
+
+	s.startChannel(ch)
+
+	jsonResponse(w, ch)
+}
+
+func (s *Server) handleEditChannel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	body, err := parseJSONBody[channel.Channel](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	ch, err := s.channelService.EditChannel(id, body.Name, body.Description)
+	if err != nil {
+		jsonBadRequest(w, "Channel editing failed: "+err.Error())
+		return
+	}
+
+	jsonResponse(w, ch)
+}
+
+func (s *Server) handleDeleteChannel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := s.channelService.DeleteChannel(id)
+	if err != nil {
+		jsonBadRequest(w, "Channel deletion failed: "+err.Error())
+		return
+	}
+
+	s.stopChannel(id)
+
+	err = fs.DeleteDirIfExists(filepath.Join(s.config.VoiceDir, id))
+	if err != nil {
+		s.logger.Warn("Failed to delete channel voice messages: " + err.Error())
+	}
+
+	jsonOK(w, "Channel deleted")
+}
+
 func (s *Server) handleStaticDir(prefix string, path string) http.Handler {
 	return http.StripPrefix(prefix, http.FileServer(http.Dir(path)))
 }
@@ -393,12 +1132,52 @@ func (s *Server) handleEditStationInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.eventsEmitter.RegisterEvent(eventChangeTheme, " ")
+	for _, ch := range s.allChannels() {
+		ch.playbackState.SetArtworkURL(info.LogoURL)
+	}
+
+	s.broadcastEvent(eventChangeTheme, " ")
 
 	jsonResponse(w, info)
 }
 
-func (s *Server) saveFile(fileHeader *multipart.FileHeader) (string, error) {
+func (s *Server) handleStreamingSettings(w http.ResponseWriter, _ *http.Request) {
+	jsonResponse(w, &station.Streaming{
+		SegmentDuration: s.timing.SegmentDuration(),
+		LiveSegments:    s.timing.LiveSegments(),
+	})
+}
+
+func (s *Server) handleEditStreamingSettings(w http.ResponseWriter, r *http.Request) {
+	body, err := parseJSONBody[station.Streaming](r)
+	if err != nil {
+		jsonBadRequest(w, "Parsing request body failed: "+err.Error())
+		return
+	}
+
+	if err := hls.ValidateTiming(body.SegmentDuration, body.LiveSegments); err != nil {
+		jsonBadRequest(w, "Invalid streaming settings: "+err.Error())
+		return
+	}
+
+	if s.partDuration > 0 && float64(body.SegmentDuration) <= s.partDuration {
+		jsonBadRequest(w, "Invalid streaming settings: segment duration must be longer than the Low-Latency HLS part duration")
+		return
+	}
+
+	settings, err := s.stationService.EditStreaming(body)
+	if err != nil {
+		jsonInternalError(w, "Streaming settings editing failed: "+err.Error())
+		return
+	}
+
+	// Already validated, new segments are made with the new values from now on
+	_ = s.timing.Set(settings.SegmentDuration, settings.LiveSegments)
+
+	jsonResponse(w, settings)
+}
+
+func (s *Server) saveFile(fileHeader *multipart.FileHeader, filePath string) (string, error) {
 	file, err := fileHeader.Open()
 	if err != nil {
 		msg := "Failed to open file: " + err.Error()
@@ -406,8 +1185,6 @@ func (s *Server) saveFile(fileHeader *multipart.FileHeader) (string, error) {
 		return "", errors.New(msg)
 	}
 
-	fileName := filepath.Base(fileHeader.Filename)
-	filePath := filepath.Join(s.config.TracksDir, fileName)
 	dst, err := os.Create(filePath)
 	if err != nil {
 		msg := "Failed to create file on disk: " + err.Error()
@@ -43,3 +43,7 @@ func jsonForbidden(w http.ResponseWriter, body string) {
 func jsonInternalError(w http.ResponseWriter, body string) {
 	jsonMessage(w, http.StatusInternalServerError, body)
 }
+
+func jsonNotFound(w http.ResponseWriter, body string) {
+	jsonMessage(w, http.StatusNotFound, body)
+}
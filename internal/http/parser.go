@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 func parseIntQuery(queries url.Values, key string, defaultValue int) int {
@@ -19,6 +20,21 @@ func parseIntQuery(queries url.Values, key string, defaultValue int) int {
 	return parsed
 }
 
+// parseUnixQuery reads a time given as a Unix timestamp, falling back to the default value if it's missing.
+func parseUnixQuery(queries url.Values, key string, defaultValue time.Time) (time.Time, error) {
+	queryValue := queries.Get(key)
+	if queryValue == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseInt(queryValue, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a Unix timestamp", key)
+	}
+
+	return time.Unix(parsed, 0), nil
+}
+
 func parseJSONBody[T any](r *http.Request) (*T, error) {
 	rawBytes, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/cheatsnake/airstation/internal/relay"
+)
+
+// handleLiveMP3 serves the playback of a channel as a continuous MP3 stream.
+func (s *Server) handleLiveMP3(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	s.serveRelay(w, r, ch.mp3Stream, "audio/mpeg")
+}
+
+// handleLiveAAC serves the playback of a channel as a continuous AAC (ADTS) stream.
+func (s *Server) handleLiveAAC(w http.ResponseWriter, r *http.Request) {
+	ch, ok := s.requestChannel(w, r)
+	if !ok {
+		return
+	}
+
+	s.serveRelay(w, r, ch.aacStream, "audio/aac")
+}
+
+// serveRelay writes the stream to the listener until it disconnects. Players that send
+// the Icy-MetaData header get the title of the playing track within the audio.
+func (s *Server) serveRelay(w http.ResponseWriter, r *http.Request, stream *relay.Stream, contentType string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonBadRequest(w, "Streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	if info, err := s.stationService.Info(); err == nil && info.Name != "" {
+		w.Header().Set("icy-name", info.Name)
+	}
+
+	var out io.Writer = w
+	if r.Header.Get("Icy-MetaData") == "1" {
+		w.Header().Set("icy-metaint", strconv.Itoa(relay.MetaInterval))
+		out = relay.NewICYWriter(w, relay.MetaInterval, stream.Title)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	listener := stream.Subscribe()
+	defer stream.Unsubscribe(listener)
+
+	for {
+		select {
+		case chunk, ok := <-listener.Chunks():
+			if !ok {
+				return
+			}
+
+			if _, err := out.Write(chunk); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
@@ -1,19 +1,23 @@
 package http
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"mime"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/cheatsnake/airstation/internal/channel"
 	"github.com/cheatsnake/airstation/internal/config"
+	"github.com/cheatsnake/airstation/internal/events"
+	"github.com/cheatsnake/airstation/internal/jingle"
 	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
 	"github.com/cheatsnake/airstation/internal/pkg/hls"
-	"github.com/cheatsnake/airstation/internal/pkg/sse"
-	"github.com/cheatsnake/airstation/internal/playback"
 	"github.com/cheatsnake/airstation/internal/playlist"
-	"github.com/cheatsnake/airstation/internal/queue"
 	"github.com/cheatsnake/airstation/internal/station"
 	"github.com/cheatsnake/airstation/internal/storage"
 	"github.com/cheatsnake/airstation/internal/track"
@@ -21,49 +25,153 @@ import (
 )
 
 type Server struct {
-	playbackState   *playback.State
-	eventsEmitter   *sse.Emitter
+	ctx             context.Context // Canceled when the server shuts down
+	background      sync.WaitGroup  // Tracks the goroutines that have to finish before the store is closed
+	channels        map[string]*channelRuntime
+	channelsMutex   sync.RWMutex
+	eventBus        *events.Bus
+	store           storage.Storage
 	trackService    *track.Service
-	queueService    *queue.Service
-	playbackService *playback.Service
 	playlistService *playlist.Service
 	stationService  *station.Service
+	channelService  *channel.Service
+	jingleService   *jingle.Service
+	segmentCache    *hls.Cache
+	timing          *hls.Timing // Segment duration and live window, can be changed through the API
+	partDuration    float64     // Duration of Low-Latency HLS parts in seconds, 0 if the mode is off
+	dvrWindow       float64     // Duration of the DVR window in seconds, 0 if the mode is off
+	ffmpegCLI       *ffmpeg.CLI
 	config          *config.Config
+	rootLogger      *slog.Logger
 	logger          *slog.Logger
 	router          *http.ServeMux
 }
 
 func NewServer(store storage.Storage, conf *config.Config, logger *slog.Logger) *Server {
-	ffmpegCLI := ffmpeg.NewCLI()
-	ts := track.NewService(store, ffmpegCLI, logger.WithGroup("trackservice"))
-	qs := queue.NewService(store)
-	ps := playback.NewService(store)
-	pls := playlist.NewService(store)
+	renditions, err := ffmpeg.ParseRenditions(conf.HLSRenditions)
+	if err != nil {
+		logger.Warn("Adaptive bitrate is disabled: " + err.Error())
+		renditions = nil
+	}
+
+	container, err := hls.ParseContainer(conf.HLSContainer)
+	if err != nil {
+		logger.Warn("MPEG-TS segments are used: " + err.Error())
+		container = hls.ContainerTS
+	}
+
+	ffmpegCLI := ffmpeg.NewCLI(container, renditions...)
+	loudness := ffmpeg.LoudnessTarget{Integrated: conf.LoudnessTarget, TruePeak: conf.TruePeakLimit, Range: loudnessRange}
+	bus := events.NewBus()
 	ss := station.NewService(store)
-	state := playback.NewState(ts, qs, ps, conf.TmpDir, logger.WithGroup("playback"))
+	timing := newTiming(ss, conf, logger)
+	pls := playlist.NewService(store)
+	cs := channel.NewService(store)
+	js := jingle.NewService(store, ffmpegCLI, conf.JinglesDir, logger.WithGroup("jingles"))
+
+	partDuration := conf.HLSPartDuration
+	if partDuration < 0 || partDuration >= float64(timing.SegmentDuration()) {
+		logger.Warn("Low-Latency HLS is disabled: part duration must be less than " + strconv.Itoa(timing.SegmentDuration()) + " seconds")
+		partDuration = 0
+	}
+	// Parts are byte ranges of MPEG-TS segments cut where audio PES packets start
+	if partDuration > 0 && container != hls.ContainerTS {
+		logger.Warn("Low-Latency HLS is disabled: partial segments require MPEG-TS segments")
+		partDuration = 0
+	}
+
+	dvrWindow := conf.HLSDVRWindow
+	if dvrWindow > maxDVRWindow {
+		logger.Warn("DVR window is disabled: it must not be longer than " + strconv.Itoa(maxDVRWindow) + " minutes")
+		dvrWindow = 0
+	}
+
+	var segmentCache *hls.Cache
+	var invalidator track.Invalidator
+	if conf.SegmentCacheSize > 0 {
+		segmentCache, err = hls.NewCache(conf.CacheDir, int64(conf.SegmentCacheSize)*bytesInMB)
+		if err != nil {
+			logger.Warn("Segment cache is disabled: " + err.Error())
+		} else {
+			invalidator = segmentCache
+		}
+	}
+
+	// The track service drops stale segments itself, so they're never reused after a change
+	ts := track.NewService(store, ffmpegCLI, loudness, bus, invalidator, timing, logger.WithGroup("trackservice"))
 
 	return &Server{
-		playbackState:   state,
-		eventsEmitter:   sse.NewEmitter(),
+		channels:        make(map[string]*channelRuntime),
+		eventBus:        bus,
+		store:           store,
 		trackService:    ts,
-		queueService:    qs,
-		playbackService: ps,
 		playlistService: pls,
 		stationService:  ss,
+		channelService:  cs,
+		jingleService:   js,
+		segmentCache:    segmentCache,
+		timing:          timing,
+		partDuration:    partDuration,
+		dvrWindow:       float64(dvrWindow * 60),
+		ffmpegCLI:       ffmpegCLI,
 		config:          conf,
+		rootLogger:      logger,
 		logger:          logger.WithGroup("http"),
 		router:          http.NewServeMux(),
 	}
 }
 
-func (s *Server) Run() {
-	s.registerMP2TMimeType()
+// newTiming creates the segment timing of the station. The settings saved through the API
+// take precedence over the configured ones, invalid values fall back to the defaults.
+func newTiming(ss *station.Service, conf *config.Config, logger *slog.Logger) *hls.Timing {
+	segmentDuration, liveSegments := conf.HLSSegmentDuration, conf.HLSLiveSegments
+
+	saved, err := ss.Streaming()
+	if err != nil {
+		logger.Warn("Failed to read streaming settings: " + err.Error())
+	} else {
+		if saved.SegmentDuration > 0 {
+			segmentDuration = saved.SegmentDuration
+		}
+		if saved.LiveSegments > 0 {
+			liveSegments = saved.LiveSegments
+		}
+	}
+
+	timing, err := hls.NewTiming(segmentDuration, liveSegments)
+	if err != nil {
+		logger.Warn("Default segment timing is used: " + err.Error())
+		timing, _ = hls.NewTiming(hls.DefaultMaxSegmentDuration, hls.DefaultLiveSegmentsAmount)
+	}
+
+	return timing
+}
+
+// Run starts the channels and serves HTTP requests until the context is canceled.
+// Then it waits for the in-flight requests to finish, saves the playback of every channel
+// and stops the background work, so the store can be closed once it returns.
+//
+// Returns:
+//   - An error if the server cannot listen, in which case the channels are stopped all the same.
+func (s *Server) Run(ctx context.Context) error {
+	// The background work stops with this context, so it's canceled on a failure to listen as well
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.ctx = ctx
+	s.registerSegmentMimeTypes()
 
 	// Public handlers
 	s.router.HandleFunc("GET /stream", s.handleHLSPlaylist)
+	s.router.HandleFunc("GET /stream/{channel}", s.handleChannelHLSPlaylist)
+	s.router.HandleFunc("GET /stream.mpd", s.handleDASHManifest)
+	s.router.HandleFunc("GET /live.mp3", s.handleLiveMP3)
+	s.router.HandleFunc("GET /live.aac", s.handleLiveAAC)
+	s.router.HandleFunc("GET /api/v1/channels", s.handleChannels)
 	s.router.HandleFunc("GET /api/v1/events", s.handleEvents)
 	s.router.HandleFunc("GET /api/v1/station/info", s.handleStationInfo)
 	s.router.HandleFunc("POST /api/v1/login", s.handleLogin)
+	s.router.HandleFunc("SOURCE /source/{channel}", s.handleLiveSource)
+	s.router.HandleFunc("PUT /source/{channel}", s.handleLiveSource)
 	s.router.Handle("GET /static/tmp/", s.handleStaticDirWithoutCache("/static/tmp", s.config.TmpDir))
 	s.router.Handle("GET /api/v1/playback", http.HandlerFunc(s.handlePlaybackState))
 	s.router.Handle("GET /api/v1/playback/history", http.HandlerFunc(s.handlePlaybackHistory))
@@ -72,12 +180,35 @@ func (s *Server) Run() {
 	s.router.Handle("POST /api/v1/tracks", s.jwtAuth(http.HandlerFunc(s.handleTracksUpload)))
 	s.router.Handle("GET /api/v1/tracks", s.jwtAuth(http.HandlerFunc(s.handleTracks)))
 	s.router.Handle("DELETE /api/v1/tracks", s.jwtAuth(http.HandlerFunc(s.handleDeleteTracks)))
+	s.router.Handle("PUT /api/v1/tracks/crossfade", s.jwtAuth(http.HandlerFunc(s.handleTracksCrossfade)))
+	s.router.Handle("PUT /api/v1/tracks/{id}/cues", s.jwtAuth(http.HandlerFunc(s.handleEditTrackCues)))
+	s.router.Handle("POST /api/v1/tracks/normalize", s.jwtAuth(http.HandlerFunc(s.handleNormalizeTracks)))
 	s.router.Handle("GET /api/v1/queue", s.jwtAuth(http.HandlerFunc(s.handleQueue)))
 	s.router.Handle("POST /api/v1/queue", s.jwtAuth(http.HandlerFunc(s.handleAddToQueue)))
 	s.router.Handle("PUT /api/v1/queue", s.jwtAuth(http.HandlerFunc(s.handleReorderQueue)))
 	s.router.Handle("DELETE /api/v1/queue", s.jwtAuth(http.HandlerFunc(s.handleRemoveFromQueue)))
+	s.router.Handle("GET /api/v1/queue/mode", s.jwtAuth(http.HandlerFunc(s.handleQueueMode)))
+	s.router.Handle("PUT /api/v1/queue/mode", s.jwtAuth(http.HandlerFunc(s.handleSetQueueMode)))
 	s.router.Handle("POST /api/v1/playback/pause", s.jwtAuth(http.HandlerFunc(s.handlePausePlayback)))
 	s.router.Handle("POST /api/v1/playback/play", s.jwtAuth(http.HandlerFunc(s.handlePlayPlayback)))
+	s.router.Handle("POST /api/v1/playback/skip", s.jwtAuth(http.HandlerFunc(s.handleSkipPlayback)))
+	s.router.Handle("POST /api/v1/playback/previous", s.jwtAuth(http.HandlerFunc(s.handlePreviousPlayback)))
+	s.router.Handle("POST /api/v1/playback/jump", s.jwtAuth(http.HandlerFunc(s.handleJumpPlayback)))
+	s.router.Handle("GET /api/v1/autodj", s.jwtAuth(http.HandlerFunc(s.handleAutoDJSettings)))
+	s.router.Handle("PUT /api/v1/autodj", s.jwtAuth(http.HandlerFunc(s.handleEditAutoDJSettings)))
+	s.router.Handle("GET /api/v1/schedule", s.jwtAuth(http.HandlerFunc(s.handleSchedule)))
+	s.router.Handle("POST /api/v1/schedule", s.jwtAuth(http.HandlerFunc(s.handleAddScheduleEntry)))
+	s.router.Handle("PUT /api/v1/schedule/{id}/", s.jwtAuth(http.HandlerFunc(s.handleEditScheduleEntry)))
+	s.router.Handle("DELETE /api/v1/schedule/{id}/", s.jwtAuth(http.HandlerFunc(s.handleDeleteScheduleEntry)))
+	s.router.Handle("GET /api/v1/jingles", s.jwtAuth(http.HandlerFunc(s.handleJingles)))
+	s.router.Handle("POST /api/v1/jingles", s.jwtAuth(http.HandlerFunc(s.handleJinglesUpload)))
+	s.router.Handle("DELETE /api/v1/jingles", s.jwtAuth(http.HandlerFunc(s.handleDeleteJingles)))
+	s.router.Handle("GET /api/v1/jingles/rotation", s.jwtAuth(http.HandlerFunc(s.handleJingleRotation)))
+	s.router.Handle("PUT /api/v1/jingles/rotation", s.jwtAuth(http.HandlerFunc(s.handleEditJingleRotation)))
+	s.router.Handle("GET /api/v1/voice", s.jwtAuth(http.HandlerFunc(s.handleVoiceMessages)))
+	s.router.Handle("POST /api/v1/voice", s.jwtAuth(http.HandlerFunc(s.handleAddVoiceMessage)))
+	s.router.Handle("POST /api/v1/voice/{id}/air", s.jwtAuth(http.HandlerFunc(s.handleAirVoiceMessage)))
+	s.router.Handle("DELETE /api/v1/voice/{id}/", s.jwtAuth(http.HandlerFunc(s.handleDeleteVoiceMessage)))
 	s.router.Handle("POST /api/v1/playlist", s.jwtAuth(http.HandlerFunc(s.handleAddPlaylist)))
 	s.router.Handle("GET /api/v1/playlists", s.jwtAuth(http.HandlerFunc(s.handlePlaylists)))
 	s.router.Handle("GET /api/v1/playlist/{id}/", s.jwtAuth(http.HandlerFunc(s.handlePlaylist)))
@@ -85,63 +216,133 @@ func (s *Server) Run() {
 	s.router.Handle("DELETE /api/v1/playlist/{id}/", s.jwtAuth(http.HandlerFunc(s.handleDeletePlaylist)))
 	s.router.Handle("GET /static/tracks/", s.jwtAuth(s.handleStaticDir("/static/tracks", s.config.TracksDir)))
 	s.router.Handle("PUT /api/v1/station/info", s.jwtAuth(http.HandlerFunc(s.handleEditStationInfo)))
+	s.router.Handle("GET /api/v1/station/streaming", s.jwtAuth(http.HandlerFunc(s.handleStreamingSettings)))
+	s.router.Handle("PUT /api/v1/station/streaming", s.jwtAuth(http.HandlerFunc(s.handleEditStreamingSettings)))
+	s.router.Handle("GET /api/v1/archives", s.jwtAuth(http.HandlerFunc(s.handleArchives)))
+	s.router.Handle("GET /api/v1/archives/clip", s.jwtAuth(http.HandlerFunc(s.handleArchiveClip)))
+	s.router.Handle("GET /api/v1/archives/{id}/", s.jwtAuth(http.HandlerFunc(s.handleArchiveDownload)))
+	s.router.Handle("POST /api/v1/channel", s.jwtAuth(http.HandlerFunc(s.handleAddChannel)))
+	s.router.Handle("PUT /api/v1/channel/{id}/", s.jwtAuth(http.HandlerFunc(s.handleEditChannel)))
+	s.router.Handle("DELETE /api/v1/channel/{id}/", s.jwtAuth(http.HandlerFunc(s.handleDeleteChannel)))
 
 	s.router.Handle("GET /studio/", s.handleStaticDir("/studio/", s.config.StudioDir))
 	s.router.Handle("GET /", s.handleStaticDir("/", s.config.PlayerDir))
 
+	err := s.startChannels()
+	if err != nil {
+		s.logger.Error("Channels start failed: " + err.Error())
+	}
+
 	s.listenEvents()
 
-	err := s.playbackState.Play()
-	if err != nil {
-		s.logger.Warn("Auto start playing failed: " + err.Error())
+	s.loadTracks()
+	if defaultCh, ok := s.channel(channel.DefaultID); ok {
+		defaultCh.playbackService.DeleteOldPlaybackHistory()
+	}
+
+	server := &http.Server{
+		Addr:    ":" + s.config.HTTPPort,
+		Handler: cors.Default().Handler(s.router),
 	}
 
-	go s.playbackState.Run()
-	go s.trackService.LoadTracksFromDisk(s.config.TracksDir)
-	s.playbackService.DeleteOldPlaybackHistory()
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			s.logger.Warn("Waiting for requests to finish failed: " + err.Error())
+		}
+	}()
 
 	s.logger.Info("Server starts on http://localhost:" + s.config.HTTPPort)
-	err = http.ListenAndServe(":"+s.config.HTTPPort, cors.Default().Handler(s.router))
-	if err != nil {
-		s.logger.Error("Listen and serve failed", slog.String("info", err.Error()))
+	err = server.ListenAndServe()
+	cancel()
+	<-shutdownDone
+	s.shutdown()
+
+	if !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("listen and serve failed: %w", err)
 	}
+
+	return nil
 }
 
-func (s *Server) registerMP2TMimeType() {
-	err := mime.AddExtensionType(hls.SegmentExtension, "video/mp2t")
-	if err != nil {
-		s.logger.Error("MP2T mime type registration failed", slog.String("info", err.Error()))
+// shutdown stops the background routines of every channel and waits for the playback
+// positions to be saved and the track processing to stop.
+func (s *Server) shutdown() {
+	for _, ch := range s.allChannels() {
+		ch.mp3Stream.Close()
+		ch.aacStream.Close()
+		close(ch.stop)
+		for _, sub := range ch.subscriptions {
+			sub.Close()
+		}
 	}
+
+	s.background.Wait()
+	s.trackService.Wait()
 }
 
-func (s *Server) countListeners() *sse.Event {
-	count := s.eventsEmitter.CountSubscribers()
-	return sse.NewEvent(eventCountListeners, strconv.Itoa(count))
+// loadTracks imports the audio files from the tracks directory in the background.
+func (s *Server) loadTracks() {
+	s.background.Go(func() {
+		s.trackService.LoadTracksFromDisk(s.ctx, s.config.TracksDir)
+	})
 }
 
-func (s *Server) listenEvents() {
-	countConnectionTicker := time.Tick(5 * time.Second)
+func (s *Server) registerSegmentMimeTypes() {
+	container := s.ffmpegCLI.Container()
+	err := mime.AddExtensionType(container.Extension(), container.MimeType())
+	if err != nil {
+		s.logger.Error("Segment mime type registration failed", slog.String("info", err.Error()))
+	}
 
-	// TODO: add context for gracefull shutdown
+	// Init segments of fragmented MP4 segments
+	err = mime.AddExtensionType(".mp4", "audio/mp4")
+	if err != nil {
+		s.logger.Error("MP4 mime type registration failed", slog.String("info", err.Error()))
+	}
+}
+
+func (s *Server) listenEvents() {
+	countConnectionTicker := time.NewTicker(5 * time.Second)
 
 	go func() {
-		for range countConnectionTicker {
-			event := s.countListeners()
-			s.eventsEmitter.RegisterEvent(event.Name, event.Data)
+		defer countConnectionTicker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-countConnectionTicker.C:
+			}
+
+			for _, ch := range s.allChannels() {
+				event := s.countListeners(ch)
+				ch.eventsEmitter.RegisterEvent(event.Name, event.Data)
+			}
 		}
 	}()
 
+	sub := s.eventBus.Subscribe(events.DefaultBuffer)
 	go func() {
-		for {
-			select {
-			case <-s.playbackState.PlayNotify:
-				s.eventsEmitter.RegisterEvent(eventPlay, s.playbackState.CurrentTrack.Name)
-			case <-s.playbackState.PauseNotify:
-				s.eventsEmitter.RegisterEvent(eventPause, " ")
-			case trackName := <-s.playbackState.NewTrackNotify:
-				s.eventsEmitter.RegisterEvent(eventNewTrack, trackName)
-			case loadedTracks := <-s.trackService.LoadedTracksNotify:
-				s.eventsEmitter.RegisterEvent(eventLoadedTracks, strconv.Itoa(loadedTracks))
+		<-s.ctx.Done()
+		sub.Close()
+	}()
+
+	go func() {
+		for e := range sub.Events() {
+			changed, ok := e.(events.LibraryChanged)
+			if !ok {
+				continue
+			}
+
+			if len(changed.Added) > 0 {
+				s.broadcastEvent(eventLoadedTracks, strconv.Itoa(len(changed.Added)))
 			}
 		}
 	}()
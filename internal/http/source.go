@@ -0,0 +1,90 @@
+package http
+
+import (
+	"crypto/subtle"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+)
+
+const (
+	liveSourceUser        = "source" // The user name Icecast compatible encoders send along with the password
+	defaultLiveSourceName = "Live"   // Shown as the current track if the encoder doesn't send a stream name
+)
+
+// handleLiveSource accepts an Icecast source connection (SOURCE or PUT) and puts its audio on air
+// on the requested channel until the encoder disconnects.
+func (s *Server) handleLiveSource(w http.ResponseWriter, r *http.Request) {
+	if s.config.LiveSourcePassword == "" {
+		jsonNotFound(w, "Live sources are disabled")
+		return
+	}
+
+	user, password, ok := r.BasicAuth()
+	isValidUser := subtle.ConstantTimeCompare([]byte(user), []byte(liveSourceUser)) == 1
+	isValidPassword := subtle.ConstantTimeCompare([]byte(password), []byte(s.config.LiveSourcePassword)) == 1
+	if !ok || !isValidUser || !isValidPassword {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Airstation"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ch, ok := s.channel(r.PathValue("channel"))
+	if !ok {
+		jsonNotFound(w, "Channel not found")
+		return
+	}
+
+	if ch.liveService.Source() != "" {
+		jsonForbidden(w, "Another live source is connected")
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		jsonBadRequest(w, "Streaming is not supported")
+		return
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		s.logger.Error("Live source connection failed: " + err.Error())
+		return
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Time{})
+
+	// Icecast replies before reading the audio; newer encoders wait for 100-continue
+	reply := "HTTP/1.0 200 OK\r\n\r\n"
+	if strings.EqualFold(r.Header.Get("Expect"), "100-continue") {
+		reply = "HTTP/1.1 100 Continue\r\n\r\n"
+	}
+
+	_, err = conn.Write([]byte(reply))
+	if err != nil {
+		return
+	}
+
+	var body io.Reader = rw.Reader
+	if len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked" {
+		body = httputil.NewChunkedReader(rw.Reader)
+	}
+
+	name := r.Header.Get("Ice-Name")
+	if name == "" {
+		name = defaultLiveSourceName
+	}
+
+	ch.eventsEmitter.RegisterEvent(eventLiveStart, name)
+
+	err = ch.liveService.Broadcast(name, body)
+	if err != nil {
+		s.logger.Warn("Live source broadcast failed", slog.String("info", err.Error()))
+	}
+
+	ch.eventsEmitter.RegisterEvent(eventLiveEnd, " ")
+}
@@ -0,0 +1,12 @@
+package jingle
+
+const (
+	// HistoryPrefix marks jingles in the playback history.
+	HistoryPrefix = "Jingle: "
+
+	maxDuration     = 120 // Anything longer is a track rather than a jingle
+	maxEverySongs   = 100
+	maxEveryMinutes = 24 * 60
+	jingleBitRate   = 192 // kbps, the same as uploaded tracks are converted to
+	m4aExtension    = ".m4a"
+)
@@ -0,0 +1,131 @@
+package jingle
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Rotator tracks the playback of a single channel and picks the jingles its rotation rules ask for.
+// The progress of the rules is kept in memory, so the rotation starts over after a restart.
+type Rotator struct {
+	service   *Service
+	channelID string
+
+	songs       int       // Tracks started since the last jingle
+	lastAiredAt time.Time // When the last jingle aired, or when the rotation started
+	lastID      string    // The last aired jingle, which is not picked twice in a row
+	mutex       sync.Mutex
+}
+
+// NewRotator creates a jingle rotator for a channel.
+//
+// Parameters:
+//   - s: The jingle service.
+//   - channelID: The ID of the channel.
+//
+// Returns:
+//   - A pointer to a new Rotator instance.
+func NewRotator(s *Service, channelID string) *Rotator {
+	return &Rotator{
+		service:     s,
+		channelID:   channelID,
+		lastAiredAt: time.Now(),
+	}
+}
+
+// TrackStarted counts a queue track that started playing.
+func (r *Rotator) TrackStarted() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.songs++
+}
+
+// Next decides whether a jingle airs after the track that plays within the given time range, and picks it.
+//
+// Parameters:
+//   - start: When the track started playing.
+//   - end: When the track ends.
+//
+// Returns:
+//   - The jingle to air after the track, or nil if none is due or the library is empty.
+func (r *Rotator) Next(start, end time.Time) (*Jingle, error) {
+	rotation, err := r.service.Rotation(r.channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	isDue := rotation.due(r.songs, r.lastAiredAt, start, end)
+	lastID := r.lastID
+	r.mutex.Unlock()
+
+	if !isDue {
+		return nil, nil
+	}
+
+	jingles, err := r.service.Jingles()
+	if err != nil {
+		return nil, err
+	}
+
+	return pickJingle(jingles, lastID), nil
+}
+
+// Aired records that a jingle started playing, which restarts the rotation rules.
+//
+// Parameters:
+//   - id: The ID of the jingle.
+//   - at: When the jingle started playing.
+func (r *Rotator) Aired(id string, at time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.songs = 0
+	r.lastAiredAt = at
+	r.lastID = id
+}
+
+// due reports whether the rules ask for a jingle after the track that plays within the given time range.
+func (rotation *Rotation) due(songs int, lastAiredAt, start, end time.Time) bool {
+	if !rotation.Enabled {
+		return false
+	}
+
+	if rotation.EverySongs > 0 && songs >= rotation.EverySongs {
+		return true
+	}
+
+	interval := time.Duration(rotation.EveryMinutes) * time.Minute
+	if rotation.EveryMinutes > 0 && !end.Before(lastAiredAt.Add(interval)) {
+		return true
+	}
+
+	topOfHour := time.Date(end.Year(), end.Month(), end.Day(), end.Hour(), 0, 0, 0, end.Location())
+	if rotation.TopOfHour && topOfHour.After(start) {
+		return true
+	}
+
+	return false
+}
+
+// pickJingle picks a random jingle, avoiding the last aired one when there is a choice.
+func pickJingle(jingles []*Jingle, lastID string) *Jingle {
+	candidates := make([]*Jingle, 0, len(jingles))
+	for _, jingle := range jingles {
+		if jingle.ID != lastID {
+			candidates = append(candidates, jingle)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = jingles
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return candidates[rand.IntN(len(candidates))]
+}
@@ -0,0 +1,61 @@
+package jingle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotation_Due(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	cases := []struct {
+		name       string
+		rotation   Rotation
+		songs      int
+		lastAired  string
+		start, end string
+		want       bool
+	}{
+		{"disabled", Rotation{EverySongs: 1}, 5, "10:00", "10:10", "10:14", false},
+		{"not enough songs", Rotation{Enabled: true, EverySongs: 3}, 2, "10:00", "10:10", "10:14", false},
+		{"enough songs", Rotation{Enabled: true, EverySongs: 3}, 3, "10:00", "10:10", "10:14", true},
+		{"interval not passed", Rotation{Enabled: true, EveryMinutes: 15}, 0, "10:00", "10:10", "10:14", false},
+		{"interval passes during the track", Rotation{Enabled: true, EveryMinutes: 15}, 0, "10:00", "10:12", "10:16", true},
+		{"hour doesn't change", Rotation{Enabled: true, TopOfHour: true}, 0, "10:00", "10:10", "10:14", false},
+		{"hour changes during the track", Rotation{Enabled: true, TopOfHour: true}, 0, "10:00", "10:58", "11:02", true},
+		{"any rule is enough", Rotation{Enabled: true, EverySongs: 10, TopOfHour: true}, 1, "10:00", "10:58", "11:02", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.rotation.due(c.songs, at(c.lastAired), at(c.start), at(c.end))
+			if got != c.want {
+				t.Errorf("due() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPickJingle(t *testing.T) {
+	if pickJingle([]*Jingle{}, "") != nil {
+		t.Error("expected no jingle from an empty library")
+	}
+
+	only := []*Jingle{{ID: "a"}}
+	if got := pickJingle(only, "a"); got == nil || got.ID != "a" {
+		t.Errorf("expected the only jingle to be picked again, got %+v", got)
+	}
+
+	jingles := []*Jingle{{ID: "a"}, {ID: "b"}}
+	for range 20 {
+		if got := pickJingle(jingles, "a"); got.ID != "b" {
+			t.Fatalf("expected the last aired jingle to be avoided, got %s", got.ID)
+		}
+	}
+}
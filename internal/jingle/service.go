@@ -0,0 +1,159 @@
+// Package jingle manages the library of station IDs and sweepers, and decides when they air
+// between the tracks of a channel.
+package jingle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/fs"
+	"github.com/cheatsnake/airstation/internal/pkg/ulid"
+)
+
+// Service manages the jingle library shared by all channels and their rotation rules.
+type Service struct {
+	store     Store
+	ffmpegCLI *ffmpeg.CLI
+	dir       string
+	log       *slog.Logger
+}
+
+// NewService creates a new jingle Service.
+//
+// Parameters:
+//   - store: The storage of jingles and rotation rules.
+//   - cli: The FFmpeg CLI used to convert the uploaded jingles.
+//   - dir: The directory where the jingles are stored.
+//   - log: The logger.
+//
+// Returns:
+//   - A pointer to a new Service instance.
+func NewService(store Store, cli *ffmpeg.CLI, dir string, log *slog.Logger) *Service {
+	return &Service{
+		store:     store,
+		ffmpegCLI: cli,
+		dir:       dir,
+		log:       log,
+	}
+}
+
+// Jingles returns all jingles of the library.
+func (s *Service) Jingles() ([]*Jingle, error) {
+	return s.store.Jingles()
+}
+
+// AddJingle converts an uploaded audio file to AAC and adds it to the library. The uploaded file is removed in any case.
+//
+// Parameters:
+//   - ctx: Stops the conversion when canceled.
+//   - name: The name of the jingle, the file name is used if it's empty.
+//   - uploadedPath: The path to the uploaded audio file.
+//
+// Returns:
+//   - The added jingle, or an error if the file cannot be converted or is too long.
+func (s *Service) AddJingle(ctx context.Context, name, uploadedPath string) (*Jingle, error) {
+	defer func() {
+		if err := fs.DeleteFile(uploadedPath); err != nil {
+			s.log.Warn("Failed to delete uploaded jingle: " + err.Error())
+		}
+	}()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(uploadedPath), filepath.Ext(uploadedPath))
+	}
+
+	path := filepath.Join(s.dir, ulid.New()+m4aExtension)
+	err := s.ffmpegCLI.ConvertAudioToAAC(ctx, uploadedPath, path, jingleBitRate)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.ffmpegCLI.AudioMetadata(ctx, path)
+	if err != nil {
+		_ = fs.DeleteFile(path)
+		return nil, err
+	}
+
+	if metadata.Duration <= 0 || metadata.Duration > maxDuration {
+		_ = fs.DeleteFile(path)
+		return nil, fmt.Errorf("%s must be shorter than %d seconds to be a jingle", name, maxDuration)
+	}
+
+	jingle, err := s.store.AddJingle(name, path, metadata.Duration)
+	if err != nil {
+		_ = fs.DeleteFile(path)
+		return nil, err
+	}
+
+	return jingle, nil
+}
+
+// DeleteJingles removes jingles from the library along with their files.
+//
+// Parameters:
+//   - ids: The IDs of the jingles.
+//
+// Returns:
+//   - An error if the jingles cannot be deleted.
+func (s *Service) DeleteJingles(ids []string) error {
+	jingles, err := s.store.JinglesByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.DeleteJingles(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, jingle := range jingles {
+		err := fs.DeleteFile(jingle.Path)
+		if err != nil {
+			s.log.Warn("Failed to delete jingle file: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Rotation retrieves the rotation rules of a channel. Jingles are disabled if no rules are saved.
+//
+// Parameters:
+//   - channelID: The ID of the channel.
+//
+// Returns:
+//   - A pointer to the rules, or an error.
+func (s *Service) Rotation(channelID string) (*Rotation, error) {
+	rotation, err := s.store.JingleRotation(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotation == nil {
+		rotation = &Rotation{}
+	}
+
+	return rotation, nil
+}
+
+// SaveRotation validates and saves the rotation rules of a channel.
+//
+// Parameters:
+//   - channelID: The ID of the channel.
+//   - rotation: The new rules.
+//
+// Returns:
+//   - An error if the rules are invalid or cannot be saved.
+func (s *Service) SaveRotation(channelID string, rotation *Rotation) error {
+	err := validateRotation(rotation)
+	if err != nil {
+		return err
+	}
+
+	return s.store.SaveJingleRotation(channelID, rotation)
+}
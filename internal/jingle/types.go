@@ -0,0 +1,28 @@
+package jingle
+
+// Jingle is a short station ID or sweeper. Jingles are kept apart from the music library
+// and spliced between the tracks of a channel according to its rotation rules.
+type Jingle struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Path     string  `json:"-"`
+	Duration float64 `json:"duration"` // Duration in seconds
+}
+
+// Rotation defines when jingles air on a channel. A jingle airs after the current track
+// as soon as any of the enabled rules is met, and it restarts all rules.
+type Rotation struct {
+	Enabled      bool `json:"enabled"`      // Whether jingles air at all.
+	EverySongs   int  `json:"everySongs"`   // A jingle airs after this many tracks, 0 disables the rule.
+	EveryMinutes int  `json:"everyMinutes"` // A jingle airs once this many minutes passed since the last one, 0 disables the rule.
+	TopOfHour    bool `json:"topOfHour"`    // Whether a jingle airs after the track during which a new hour begins.
+}
+
+type Store interface {
+	Jingles() ([]*Jingle, error)
+	AddJingle(name, path string, duration float64) (*Jingle, error)
+	JinglesByIDs(ids []string) ([]*Jingle, error)
+	DeleteJingles(ids []string) error
+	JingleRotation(channelID string) (*Rotation, error)
+	SaveJingleRotation(channelID string, rotation *Rotation) error
+}
@@ -0,0 +1,13 @@
+package jingle
+
+import "fmt"
+
+func validateRotation(rotation *Rotation) error {
+	if rotation.EverySongs < 0 || rotation.EverySongs > maxEverySongs {
+		return fmt.Errorf("every songs must be between 0 and %d", maxEverySongs)
+	}
+	if rotation.EveryMinutes < 0 || rotation.EveryMinutes > maxEveryMinutes {
+		return fmt.Errorf("every minutes must be between 0 and %d", maxEveryMinutes)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package live
+
+import "time"
+
+const (
+	liveBitRate    = 192 // kbps, the same as uploaded tracks are converted to
+	segmentsPrefix = "live"
+	pollInterval   = 500 * time.Millisecond
+)
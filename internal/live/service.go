@@ -0,0 +1,187 @@
+// Package live takes over a channel with an external audio source, such as a DJ streaming through
+// an Icecast compatible encoder. The incoming audio is segmented into HLS on the fly and pre-empts the queue
+// until the source disconnects.
+package live
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+	"github.com/cheatsnake/airstation/internal/pkg/ulid"
+	"github.com/cheatsnake/airstation/internal/playback"
+)
+
+// ErrSourceConnected is returned when a channel already has a live source connected.
+var ErrSourceConnected = errors.New("live source is already connected")
+
+// Service broadcasts a live source on a single channel.
+type Service struct {
+	state     *playback.State
+	ffmpegCLI *ffmpeg.CLI
+	timing    *hls.Timing // The segment duration of the station, read when a source connects
+	dir       string
+	log       *slog.Logger
+
+	source string // Name of the connected source, empty if there is none
+	mutex  sync.Mutex
+}
+
+// NewService creates a new live Service.
+//
+// Parameters:
+//   - state: The playback state of the channel the source takes over.
+//   - cli: The FFmpeg CLI used to segment the incoming audio.
+//   - timing: The segment duration and the live window of the station.
+//   - dir: The directory of the channel HLS segments.
+//   - log: The logger.
+//
+// Returns:
+//   - A pointer to a new Service instance.
+func NewService(state *playback.State, cli *ffmpeg.CLI, timing *hls.Timing, dir string, log *slog.Logger) *Service {
+	return &Service{
+		state:     state,
+		ffmpegCLI: cli,
+		timing:    timing,
+		dir:       dir,
+		log:       log,
+	}
+}
+
+// Source returns the name of the connected live source, or an empty string if there is none.
+func (s *Service) Source() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.source
+}
+
+// Broadcast puts the audio read from the input on air. The current track keeps playing until its already
+// published segments end, then the live source follows. It blocks until the input is closed or the source
+// is taken off air, e.g. playback is paused, and then hands the stream back to the queue.
+//
+// Parameters:
+//   - name: The name of the source shown as the current track.
+//   - input: The audio stream in any format FFmpeg can detect.
+//
+// Returns:
+//   - ErrSourceConnected if another source is connected, or an error if the audio cannot be segmented.
+func (s *Service) Broadcast(name string, input io.Reader) error {
+	s.mutex.Lock()
+	if s.source != "" {
+		s.mutex.Unlock()
+		return ErrSourceConnected
+	}
+	s.source = name
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		s.source = ""
+		s.mutex.Unlock()
+	}()
+
+	id := segmentsPrefix + ulid.New()
+	cmd, err := s.ffmpegCLI.StartLiveHLS(input, s.dir, id, s.timing.SegmentDuration(), liveBitRate)
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	feed := &segmentFeed{playlistPath: filepath.Join(s.dir, id+".m3u8"), dir: s.dir, segName: id}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	isOnAir := false
+	publish := func() error {
+		segments := feed.poll()
+		if len(segments) == 0 {
+			return nil
+		}
+
+		if !isOnAir {
+			isOnAir = true
+			s.log.Info("Live source is on air", slog.String("source", name))
+			return s.state.GoLive(id, name, segments)
+		}
+
+		return s.state.AddLiveSegments(id, segments)
+	}
+
+	for {
+		select {
+		case err := <-exited:
+			if pubErr := publish(); pubErr != nil {
+				s.log.Warn("Live segments are lost: " + pubErr.Error())
+			}
+
+			if isOnAir {
+				s.state.EndLive(id)
+				s.log.Info("Live source is off air", slog.String("source", name))
+			}
+
+			os.Remove(feed.playlistPath)
+
+			if err != nil && !isOnAir {
+				return errors.New("live source is not decodable: " + err.Error())
+			}
+
+			return nil
+		case <-ticker.C:
+			err := publish()
+			if err != nil {
+				s.log.Warn("Live source is taken off air: " + err.Error())
+				_ = cmd.Process.Kill()
+			}
+		}
+	}
+}
+
+// segmentFeed reads the playlist FFmpeg writes for a live input and returns the segments that are new since the
+// previous poll. A segment is complete only once it is listed in the playlist.
+type segmentFeed struct {
+	playlistPath string
+	dir          string
+	segName      string
+	next         int // Index of the first segment not returned yet
+}
+
+func (f *segmentFeed) poll() []*hls.Segment {
+	content, err := os.ReadFile(f.playlistPath)
+	if err != nil {
+		return nil
+	}
+
+	fresh := make([]*hls.Segment, 0)
+	for _, seg := range hls.ParseSegments(string(content), f.dir) {
+		index, ok := f.segmentIndex(seg.Path)
+		if !ok || index < f.next {
+			continue
+		}
+
+		fresh = append(fresh, seg)
+		f.next = index + 1
+	}
+
+	return fresh
+}
+
+// segmentIndex extracts the sequence number FFmpeg appends to the segment file names.
+func (f *segmentFeed) segmentIndex(path string) (int, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	index, err := strconv.Atoi(strings.TrimPrefix(name, f.segName))
+
+	return index, err == nil
+}
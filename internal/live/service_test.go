@@ -0,0 +1,52 @@
+package live
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSegmentFeed_Poll(t *testing.T) {
+	dir := t.TempDir()
+	segName := "live01jq3z5xw8k2r7"
+	feed := &segmentFeed{playlistPath: filepath.Join(dir, segName+".m3u8"), dir: dir, segName: segName}
+
+	if segments := feed.poll(); len(segments) != 0 {
+		t.Fatalf("Expected no segments before the playlist is written, got %d", len(segments))
+	}
+
+	writePlaylist := func(content string) {
+		t.Helper()
+		err := os.WriteFile(feed.playlistPath, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writePlaylist("#EXTM3U\n#EXT-X-TARGETDURATION:5\n#EXTINF:5.0,\n" + segName + "0.ts\n#EXTINF:5.0,\n" + segName + "1.ts\n")
+
+	segments := feed.poll()
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 segments, got %d", len(segments))
+	}
+
+	if segments[1].Path != filepath.Join(dir, segName+"1.ts") {
+		t.Errorf("Unexpected segment path: %s", segments[1].Path)
+	}
+
+	// Old segments leave the playlist window while new ones are added
+	writePlaylist("#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:5.0,\n" + segName + "1.ts\n#EXTINF:4.2,\n" + segName + "2.ts\n")
+
+	segments = feed.poll()
+	if len(segments) != 1 {
+		t.Fatalf("Expected 1 new segment, got %d", len(segments))
+	}
+
+	if segments[0].Duration != 4.2 {
+		t.Errorf("Expected segment duration 4.2, got %f", segments[0].Duration)
+	}
+
+	if segments := feed.poll(); len(segments) != 0 {
+		t.Errorf("Expected no new segments, got %d", len(segments))
+	}
+}
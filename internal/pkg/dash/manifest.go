@@ -0,0 +1,164 @@
+// Package dash provides functionality for describing the HLS playback timeline as an MPEG-DASH manifest.
+package dash
+
+import (
+	"errors"
+	"math"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+)
+
+// timescale is the number of timeline units per second, segment times are listed in milliseconds.
+const timescale = 1000
+
+// availabilityStartTime anchors the timeline, period starts are the times their segments play at.
+const availabilityStartTime = "1970-01-01T00:00:00Z"
+
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// Representation describes a rendition of the stream listed in the manifest.
+type Representation struct {
+	ID      string // The name of the rendition.
+	Dir     string // The subdirectory next to the segments that holds the segments of the rendition, empty for the main one.
+	BitRate int    // The audio bitrate in kbps.
+	Codecs  string // The RFC 6381 codec string, e.g. mp4a.40.2.
+}
+
+// Manifest generates a live (dynamic) MPD that describes the given timeline. Every group of segments cut
+// from the same file becomes a period, which starts when its first segment started playing, so the periods
+// change at track boundaries. The segments are addressed with a template of their file names.
+//
+// Parameters:
+//   - timeline: The segments that played and the one that plays now, oldest first.
+//   - representations: The renditions of the stream.
+//   - segDuration: The duration (in seconds) of new segments, players reload the manifest as often.
+//
+// Returns:
+//   - A string representing the manifest, or an error if the segments aren't fragmented MP4 segments.
+func Manifest(timeline []hls.TimedSegment, representations []Representation, segDuration int) (string, error) {
+	if len(timeline) == 0 {
+		return "", errors.New("timeline is empty")
+	}
+
+	depth := 0.0
+	for _, seg := range timeline {
+		depth += seg.Duration
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" type="dynamic"` +
+		` availabilityStartTime="` + availabilityStartTime + `"` +
+		` publishTime="` + time.Now().UTC().Format(timeFormat) + `"` +
+		` minimumUpdatePeriod="` + duration(float64(segDuration)) + `"` +
+		` minBufferTime="` + duration(float64(segDuration)) + `"` +
+		` suggestedPresentationDelay="` + duration(float64(segDuration)) + `"` +
+		` timeShiftBufferDepth="` + duration(depth) + `">` + "\n")
+
+	for start := 0; start < len(timeline); {
+		end := start + 1
+		for end < len(timeline) && sameGroup(timeline[end-1], timeline[end]) {
+			end++
+		}
+
+		period, err := periodXML(timeline[start:end], representations)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(period)
+		start = end
+	}
+
+	b.WriteString(`  <UTCTiming schemeIdUri="urn:mpeg:dash:utc:direct:2014" value="` + time.Now().UTC().Format(timeFormat) + `"/>` + "\n")
+	b.WriteString("</MPD>\n")
+
+	return b.String(), nil
+}
+
+// periodXML describes a group of segments as a period. The presentation time offset maps the times of the
+// segments within their file to the start of the period, which may be earlier than the first listed segment.
+func periodXML(group []hls.TimedSegment, representations []Representation) (string, error) {
+	first := group[0]
+	prefix, number, ok := first.Number()
+	if !ok {
+		return "", errors.New("segments must be fragmented MP4 segments")
+	}
+
+	mediaTime := int64(math.Round(first.Offset * timescale))
+	offset := mediaTime - first.Start.Sub(first.GroupStart).Milliseconds()
+	periodStart := float64(first.GroupStart.UnixMilli()) / timescale
+
+	var b strings.Builder
+	b.WriteString(`  <Period id="` + strconv.FormatInt(first.GroupStart.UnixMilli(), 10) + `" start="` + duration(periodStart) + `">` + "\n")
+	b.WriteString(`    <AdaptationSet contentType="audio" mimeType="audio/mp4" segmentAlignment="true" startWithSAP="1">` + "\n")
+
+	for _, r := range representations {
+		dir := filepath.ToSlash(filepath.Dir(first.Path))
+		if r.Dir != "" {
+			dir = path.Join(dir, r.Dir)
+		}
+
+		b.WriteString(`      <Representation id="` + r.ID + `" bandwidth="` + strconv.Itoa(r.BitRate*1000) + `" codecs="` + r.Codecs + `">` + "\n")
+		b.WriteString(`        <SegmentTemplate timescale="` + strconv.Itoa(timescale) + `"` +
+			` presentationTimeOffset="` + strconv.FormatInt(offset, 10) + `"` +
+			` startNumber="` + strconv.Itoa(number) + `"` +
+			` initialization="` + path.Join(dir, filepath.Base(first.Init)) + `"` +
+			` media="` + path.Join(dir, prefix+"$Number$"+filepath.Ext(first.Path)) + `">` + "\n")
+		b.WriteString(`          <SegmentTimeline>` + "\n")
+		b.WriteString(segmentTimeline(group, mediaTime))
+		b.WriteString(`          </SegmentTimeline>` + "\n")
+		b.WriteString(`        </SegmentTemplate>` + "\n")
+		b.WriteString(`      </Representation>` + "\n")
+	}
+
+	b.WriteString(`    </AdaptationSet>` + "\n")
+	b.WriteString(`  </Period>` + "\n")
+
+	return b.String(), nil
+}
+
+// segmentTimeline lists the durations of the segments, merging the repeated ones.
+func segmentTimeline(group []hls.TimedSegment, mediaTime int64) string {
+	timeline := ""
+	for i := 0; i < len(group); {
+		d := units(group[i].Duration)
+		repeat := 0
+		for i+repeat+1 < len(group) && units(group[i+repeat+1].Duration) == d {
+			repeat++
+		}
+
+		entry := `            <S`
+		if i == 0 {
+			entry += ` t="` + strconv.FormatInt(mediaTime, 10) + `"`
+		}
+		entry += ` d="` + strconv.FormatInt(d, 10) + `"`
+		if repeat > 0 {
+			entry += ` r="` + strconv.Itoa(repeat) + `"`
+		}
+		timeline += entry + "/>\n"
+
+		i += repeat + 1
+	}
+
+	return timeline
+}
+
+// sameGroup reports whether two consecutive segments are cut from the same file.
+func sameGroup(prev, next hls.TimedSegment) bool {
+	return !next.IsFirst && next.Init == prev.Init && next.GroupStart.Equal(prev.GroupStart)
+}
+
+// units converts seconds to timeline units.
+func units(seconds float64) int64 {
+	return int64(math.Round(seconds * timescale))
+}
+
+// duration formats seconds as an xs:duration.
+func duration(seconds float64) string {
+	return "PT" + strconv.FormatFloat(seconds, 'f', 3, 64) + "S"
+}
@@ -0,0 +1,83 @@
+package dash
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+)
+
+func timedSegment(path, init string, duration float64, isFirst bool, start, groupStart time.Time) hls.TimedSegment {
+	seg := hls.NewSegment(duration, path, isFirst)
+	seg.Init = init
+	if _, number, ok := seg.Number(); ok {
+		seg.Offset = float64(number) * 5
+	}
+	return hls.TimedSegment{Segment: seg, Start: start, GroupStart: groupStart}
+}
+
+func TestManifest(t *testing.T) {
+	groupA := time.UnixMilli(1_700_000_000_000)
+	groupB := groupA.Add(12 * time.Second)
+	timeline := []hls.TimedSegment{
+		// The first segments of the track have already left the window
+		timedSegment("tmp/a1.m4s", "tmp/a_init.mp4", 5, false, groupA.Add(5*time.Second), groupA),
+		timedSegment("tmp/a2.m4s", "tmp/a_init.mp4", 2, false, groupA.Add(10*time.Second), groupA),
+		timedSegment("tmp/b0.m4s", "tmp/b_init.mp4", 5, true, groupB, groupB),
+	}
+	reps := []Representation{
+		{ID: "source", BitRate: 192, Codecs: "mp4a.40.2"},
+		{ID: "64k", Dir: "64k", BitRate: 64, Codecs: "mp4a.40.5"},
+	}
+
+	got, err := Manifest(timeline, reps, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`type="dynamic" availabilityStartTime="1970-01-01T00:00:00Z"`,
+		`timeShiftBufferDepth="PT12.000S"`,
+		`<Period id="1700000000000" start="PT1700000000.000S">`,
+		`<Period id="1700000012000" start="PT1700000012.000S">`,
+		`presentationTimeOffset="0" startNumber="1" initialization="tmp/a_init.mp4" media="tmp/a$Number$.m4s"`,
+		`initialization="tmp/64k/a_init.mp4" media="tmp/64k/a$Number$.m4s"`,
+		`<S t="5000" d="5000"/>` + "\n" + `            <S d="2000"/>`,
+		`startNumber="0" initialization="tmp/b_init.mp4"`,
+		`<Representation id="64k" bandwidth="64000" codecs="mp4a.40.5">`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in manifest:\n%s", want, got)
+		}
+	}
+
+	if strings.Count(got, "<Period ") != 2 {
+		t.Errorf("expected 2 periods:\n%s", got)
+	}
+}
+
+func TestManifestErrors(t *testing.T) {
+	if _, err := Manifest(nil, nil, 5); err == nil {
+		t.Error("expected an error for an empty timeline")
+	}
+
+	now := time.Now()
+	ts := []hls.TimedSegment{timedSegment("tmp/a0.ts", "", 5, true, now, now)}
+	if _, err := Manifest(ts, nil, 5); err == nil {
+		t.Error("expected an error for MPEG-TS segments")
+	}
+}
+
+func TestSegmentTimeline(t *testing.T) {
+	now := time.Now()
+	group := make([]hls.TimedSegment, 0, 4)
+	for _, d := range []float64{5, 5, 5, 1.25} {
+		group = append(group, timedSegment("a.m4s", "a_init.mp4", d, false, now, now))
+	}
+
+	want := `            <S t="10000" d="5000" r="2"/>` + "\n" + `            <S d="1250"/>` + "\n"
+	if got := segmentTimeline(group, 10000); got != want {
+		t.Errorf("segmentTimeline() = %q, want %q", got, want)
+	}
+}
@@ -5,3 +5,19 @@ const (
 	ffmpegBin  = "ffmpeg"
 	ffprobeBin = "ffprobe"
 )
+
+// Live HLS segments that left the FFmpeg playlist stay on disk for a while,
+// since listeners play them with a delay.
+// Voice messages are normalized to the loudness common for speech on streaming platforms.
+const voiceLoudnorm = "loudnorm=I=-16:TP=-1.5:LRA=11"
+
+// Formats of the continuous streams made by StartStreamEncoder.
+const (
+	StreamMP3  = "mp3"
+	StreamADTS = "adts"
+)
+
+const (
+	liveHLSListSize        = 10
+	liveHLSDeleteThreshold = 12
+)
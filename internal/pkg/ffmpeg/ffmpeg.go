@@ -4,59 +4,90 @@ package ffmpeg
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/cheatsnake/airstation/internal/pkg/fs"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
 	"github.com/cheatsnake/airstation/internal/pkg/ulid"
 )
 
 // CLI represents a command-line interface for interacting with FFmpeg and FFprobe.
-type CLI struct{}
+type CLI struct {
+	container  hls.Container // The format of the HLS segment files
+	renditions []Rendition   // Extra renditions every HLS playlist is made in
+}
 
 // NewCLI creates and returns a new instance of CLI.
-func NewCLI() *CLI {
-	return &CLI{}
+//
+// Parameters:
+//   - container: The format of the HLS segment files.
+//   - renditions: Extra renditions the HLS segments are encoded in besides the main one. The segments
+//     of a rendition are stored in a subdirectory named after it and have the same names as the main ones.
+func NewCLI(container hls.Container, renditions ...Rendition) *CLI {
+	return &CLI{container: container, renditions: renditions}
+}
+
+// Container returns the format of the HLS segment files.
+func (cli *CLI) Container() hls.Container {
+	return cli.container
+}
+
+// Renditions returns the extra renditions the HLS segments are encoded in.
+func (cli *CLI) Renditions() []Rendition {
+	return cli.renditions
 }
 
 // MakeHLSPlaylist converts an audio track into an HLS (HTTP Live Streaming) playlist with segmented files.
-// It generates a playlist (.m3u8) and segment files (.ts) in the specified output directory.
+// It generates a playlist (.m3u8) and segment files (.ts or .m4s with an init segment) in the specified output directory.
 //
 // Parameters:
 //   - trackPath: The path to the source audio file to be converted into HLS format.
+//   - start: The position (in seconds) of the audio where the playlist starts.
+//   - duration: The duration (in seconds) of the audio to convert, or 0 to convert it until the end.
 //   - outDir: The directory where the HLS playlist and segments will be stored.
 //   - segName: The base name for the segment files, which will be suffixed with an index.
 //   - segDuration: The duration (in seconds) of each segment.
 //
 // Returns:
 //   - An error if the input file does not exist, or if the HLS generation process fails.
-func (cli *CLI) MakeHLSPlaylist(trackPath, outDir, segName string, segDuration int) error {
+func (cli *CLI) MakeHLSPlaylist(trackPath string, start, duration float64, outDir, segName string, segDuration int) error {
 	if err := fs.FileExists(trackPath); err != nil {
 		return err
 	}
 
-	hlsTime := strconv.Itoa(segDuration)
-	hlsSegName := fmt.Sprintf("%s/%s", outDir, segName) + "%d.ts"
-	hlsPlName := fmt.Sprintf("%s/%s", outDir, segName) + ".m3u8"
+	hlsOpts := hlsEventOptions(segDuration)
 
-	cmd := exec.Command(
-		ffmpegBin,
-		"-i", trackPath,
-		"-codec:", "copy",
-		"-start_number", "0",
-		"-hls_time", hlsTime,
-		"-hls_playlist_type", "event",
-		"-hls_segment_filename", hlsSegName,
-		hlsPlName,
-	)
+	args := make([]string, 0, 16)
+	if start > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(start, 'f', 3, 64))
+	}
+	if duration > 0 {
+		args = append(args, "-t", strconv.FormatFloat(duration, 'f', 3, 64))
+	}
+	args = append(args, "-i", trackPath, "-map", "0:a", "-codec:", "copy")
+	args = append(args, cli.hlsOutput(outDir, segName, hlsOpts)...)
+
+	renditions, err := cli.renditionOutputs(nil, outDir, segName, hlsOpts)
+	if err != nil {
+		return err
+	}
+	args = append(args, renditions...)
+
+	cmd := exec.Command(ffmpegBin, args...)
 
 	var errBuf bytes.Buffer
 	cmd.Stderr = &errBuf
 
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
 		return fmt.Errorf("hls playlist generation failed: %v\n%s", err, errBuf.String())
 	}
@@ -64,23 +95,316 @@ func (cli *CLI) MakeHLSPlaylist(trackPath, outDir, segName string, segDuration i
 	return nil
 }
 
+// MakeHLSCrossfade mixes the tail of one audio track with the head of another one using the acrossfade filter
+// and splits the result into HLS segments, the same way MakeHLSPlaylist does for a single track.
+//
+// Parameters:
+//   - fromPath: The path to the outgoing audio track.
+//   - toPath: The path to the incoming audio track.
+//   - fromStart: The position (in seconds) of the outgoing track where the transition starts.
+//   - fromEnd: The position (in seconds) of the outgoing track where it ends.
+//   - toStart: The position (in seconds) of the incoming track where it starts.
+//   - toEnd: The position (in seconds) of the incoming track where the transition ends.
+//   - fade: The overlap duration and curve of the crossfade.
+//   - outDir: The directory where the HLS playlist and segments will be stored.
+//   - segName: The base name for the segment files, which will be suffixed with an index.
+//   - segDuration: The duration (in seconds) of each segment.
+//   - bitRate: Audio bitrate in kbps of the mixed segments.
+//
+// Returns:
+//   - An error if one of the input files does not exist, or if the mixing fails.
+func (cli *CLI) MakeHLSCrossfade(fromPath, toPath string, fromStart, fromEnd, toStart, toEnd float64, fade Fade, outDir, segName string, segDuration, bitRate int) error {
+	if err := fs.FileExists(fromPath); err != nil {
+		return err
+	}
+
+	if err := fs.FileExists(toPath); err != nil {
+		return err
+	}
+
+	filter, labels := cli.splitFilter(fmt.Sprintf(
+		"[0:a][1:a]acrossfade=d=%s:c1=%s:c2=%s[out]",
+		strconv.FormatFloat(fade.Duration, 'f', 3, 64), fade.Curve, fade.Curve,
+	))
+	hlsOpts := hlsEventOptions(segDuration)
+
+	args := []string{
+		"-ss", strconv.FormatFloat(fromStart, 'f', 3, 64),
+		"-t", strconv.FormatFloat(fromEnd-fromStart, 'f', 3, 64),
+		"-i", fromPath,
+		"-ss", strconv.FormatFloat(toStart, 'f', 3, 64),
+		"-t", strconv.FormatFloat(toEnd-toStart, 'f', 3, 64),
+		"-i", toPath,
+		"-filter_complex", filter,
+		"-map", "[out]",
+		"-c:a", "aac",
+		"-b:a", strconv.Itoa(bitRate) + "k",
+	}
+	args = append(args, cli.hlsOutput(outDir, segName, hlsOpts)...)
+	args = append(args, "-y")
+
+	renditions, err := cli.renditionOutputs(labels, outDir, segName, hlsOpts)
+	if err != nil {
+		return err
+	}
+	args = append(args, renditions...)
+
+	cmd := exec.Command(ffmpegBin, args...)
+
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("hls crossfade generation failed: %v\n%s", err, errBuf.String())
+	}
+
+	return nil
+}
+
+// MakeHLSVoiceOver mixes a voice with a part of an audio track, lowering the track volume while the voice plays,
+// and splits the result into HLS segments, the same way MakeHLSPlaylist does for a single track.
+// The voice starts once the track is lowered, and the track volume is restored after the voice ends.
+//
+// Parameters:
+//   - musicPath: The path to the audio track.
+//   - voicePath: The path to the voice audio file.
+//   - musicStart: The position (in seconds) of the track where the mix starts.
+//   - duration: The duration (in seconds) of the mix, it must cover the voice and both volume fades.
+//   - duck: The volume of the lowered track and the timing of the voice.
+//   - outDir: The directory where the HLS playlist and segments will be stored.
+//   - segName: The base name for the segment files, which will be suffixed with an index.
+//   - segDuration: The duration (in seconds) of each segment.
+//   - bitRate: Audio bitrate in kbps of the mixed segments.
+//
+// Returns:
+//   - An error if one of the input files does not exist, or if the mixing fails.
+func (cli *CLI) MakeHLSVoiceOver(musicPath, voicePath string, musicStart, duration float64, duck Duck, outDir, segName string, segDuration, bitRate int) error {
+	if err := fs.FileExists(musicPath); err != nil {
+		return err
+	}
+
+	if err := fs.FileExists(voicePath); err != nil {
+		return err
+	}
+
+	volume := fmt.Sprintf(
+		"if(lt(t,%[1]f),1-(1-%[3]f)*t/%[1]f,if(lt(t,%[1]f+%[2]f),%[3]f,if(lt(t,2*%[1]f+%[2]f),%[3]f+(1-%[3]f)*(t-%[1]f-%[2]f)/%[1]f,1)))",
+		duck.Fade, duck.Voice, duck.Volume,
+	)
+	filter, labels := cli.splitFilter(fmt.Sprintf(
+		"[0:a]volume='%s':eval=frame[music];[1:a]adelay=delays=%d:all=1,apad[voice];[music][voice]amix=inputs=2:duration=first:normalize=0[out]",
+		volume, int(duck.Fade*1000),
+	))
+	hlsOpts := hlsEventOptions(segDuration)
+
+	args := []string{
+		"-ss", strconv.FormatFloat(musicStart, 'f', 3, 64),
+		"-t", strconv.FormatFloat(duration, 'f', 3, 64),
+		"-i", musicPath,
+		"-i", voicePath,
+		"-filter_complex", filter,
+		"-map", "[out]",
+		"-c:a", "aac",
+		"-b:a", strconv.Itoa(bitRate) + "k",
+	}
+	args = append(args, cli.hlsOutput(outDir, segName, hlsOpts)...)
+	args = append(args, "-y")
+
+	renditions, err := cli.renditionOutputs(labels, outDir, segName, hlsOpts)
+	if err != nil {
+		return err
+	}
+	args = append(args, renditions...)
+
+	cmd := exec.Command(ffmpegBin, args...)
+
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("hls voice-over generation failed: %v\n%s", err, errBuf.String())
+	}
+
+	return nil
+}
+
+// StartLiveHLS starts transcoding an endless audio stream into HLS segments. Unlike MakeHLSPlaylist, it doesn't
+// wait for the input to end: the segments and the playlist (.m3u8) in the output directory are updated as the audio
+// arrives, and only the recent segments are kept on disk. The input is read until it is closed.
+//
+// Parameters:
+//   - input: The source of the audio stream in any format FFmpeg can detect.
+//   - outDir: The directory where the HLS playlist and segments will be stored.
+//   - segName: The base name for the segment files, which will be suffixed with an index.
+//   - segDuration: The duration (in seconds) of each segment.
+//   - bitRate: Audio bitrate in kbps of the segments.
+//
+// Returns:
+//   - The started command, which the caller has to wait for, or an error if FFmpeg cannot be started.
+func (cli *CLI) StartLiveHLS(input io.Reader, outDir, segName string, segDuration, bitRate int) (*exec.Cmd, error) {
+	hlsOpts := []string{
+		"-start_number", "0",
+		"-hls_time", strconv.Itoa(segDuration),
+		"-hls_list_size", strconv.Itoa(liveHLSListSize),
+		"-hls_flags", "delete_segments",
+		"-hls_delete_threshold", strconv.Itoa(liveHLSDeleteThreshold),
+	}
+
+	args := []string{
+		"-loglevel", "error",
+		"-i", "pipe:0",
+		"-map", "0:a",
+		"-c:a", "aac",
+		"-b:a", strconv.Itoa(bitRate) + "k",
+	}
+	args = append(args, cli.hlsOutput(outDir, segName, hlsOpts)...)
+	args = append(args, "-y")
+
+	renditions, err := cli.renditionOutputs(nil, outDir, segName, hlsOpts)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, renditions...)
+
+	cmd := exec.Command(ffmpegBin, args...)
+	cmd.Stdin = input
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("live hls transcoding failed to start: %v", err)
+	}
+
+	return cmd, nil
+}
+
+// StartStreamEncoder starts converting HLS segments written to its input one after another into a continuous
+// audio stream, such as the one served by Icecast. MP3 streams are re-encoded, while ADTS streams carry
+// the AAC audio of the segments as is. The timestamp gaps between the segments of different tracks are smoothed out.
+// Fragmented MP4 segments are read as a single MP4 file, so their init segment has to be written first,
+// and segments with another init segment need a new encoder.
+//
+// Parameters:
+//   - format: The format of the output stream, StreamMP3 or StreamADTS.
+//   - bitRate: Audio bitrate in kbps of MP3 streams.
+//
+// Returns:
+//   - The started command, its input and its output, or an error if FFmpeg cannot be started.
+//     The caller closes the input to stop the encoder and has to wait for the command.
+func (cli *CLI) StartStreamEncoder(format string, bitRate int) (*exec.Cmd, io.WriteCloser, io.ReadCloser, error) {
+	input := "mpegts"
+	if cli.container == hls.ContainerFMP4 {
+		input = "mov"
+	}
+
+	args := []string{
+		"-loglevel", "error",
+		"-f", input,
+		"-i", "pipe:0",
+		"-vn",
+	}
+
+	switch format {
+	case StreamMP3:
+		args = append(args, "-af", "aresample=async=1", "-c:a", "libmp3lame", "-b:a", strconv.Itoa(bitRate)+"k")
+	case StreamADTS:
+		args = append(args, "-c:a", "copy")
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported stream format %q", format)
+	}
+	args = append(args, "-f", format, "pipe:1")
+
+	cmd := exec.Command(ffmpegBin, args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("stream encoder failed to start: %v", err)
+	}
+
+	return cmd, stdin, stdout, nil
+}
+
+// ExportClip joins parts of ADTS audio files, such as the hourly recordings of the broadcast, into a single
+// ADTS file. The audio is copied as is, so parts are cut at the nearest AAC frames.
+//
+// Parameters:
+//   - ctx: Stops the export when canceled.
+//   - parts: The parts of the files in the order they are joined.
+//   - outputPath: Destination path for the clip (should end with .aac).
+//
+// Returns:
+//   - An error if the export fails, including FFmpeg's output. The partially written output is removed.
+func (cli *CLI) ExportClip(ctx context.Context, parts []ClipPart, outputPath string) error {
+	list, err := os.CreateTemp(filepath.Dir(outputPath), "clip-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(list.Name())
+
+	content, err := concatList(parts)
+	if err == nil {
+		_, err = list.WriteString(content)
+	}
+	if closeErr := list.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(
+		ctx,
+		ffmpegBin,
+		"-loglevel", "error",
+		"-f", "concat",
+		"-safe", "0", // The list holds absolute paths
+		"-i", list.Name(),
+		"-vn",
+		"-c:a", "copy",
+		"-f", StreamADTS,
+		outputPath,
+		"-y",
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		removeOutput(outputPath)
+		return fmt.Errorf("clip export failed: %v\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
 // AudioMetadata extracts and returns metadata information from the specified audio file.
 // It uses ffprobe to retrieve details such as duration, bit rate, codec name, sample rate, and channel count.
 //
 // Parameters:
+//   - ctx: Stops the ffprobe process when canceled.
 //   - filePath: The path to the audio file whose metadata is to be retrieved.
 //
 // Returns:
 //   - AudioMetadata: A struct containing the extracted metadata (duration, bit rate, codec, sample rate, and channels).
 //   - An error if the file does not exist, ffprobe execution fails, or metadata parsing encounters an issue.
-func (cli *CLI) AudioMetadata(filePath string) (AudioMetadata, error) {
+func (cli *CLI) AudioMetadata(ctx context.Context, filePath string) (AudioMetadata, error) {
 	metadata := AudioMetadata{}
 
 	if err := fs.FileExists(filePath); err != nil {
 		return metadata, err
 	}
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		ffprobeBin,
 		"-i", filePath,
 		"-v", "error",
@@ -226,15 +550,17 @@ func (cli *CLI) TrimAudio(filePath string, totalDuration float64) error {
 // ConvertAudioToAAC converts an audio file to AAC format.
 //
 // Parameters:
+//   - ctx:        Stops the conversion when canceled.
 //   - inputPath:  Path to the input audio file (supports various formats like MP3, WAV, etc.)
 //   - outputPath: Destination path for the converted AAC file (should end with .aac or .m4a)
 //   - bitRate:    Audio bitrate in kbps (e.g., 128 for 128kbps)
 //
 // Returns:
 //   - error: Returns nil on success, or an error if conversion fails. The error includes
-//     FFmpeg's output when available for debugging purposes.
-func (cli *CLI) ConvertAudioToAAC(inputPath, outputPath string, bitRate int) error {
-	cmd := exec.Command(
+//     FFmpeg's output when available for debugging purposes. The partially written output is removed.
+func (cli *CLI) ConvertAudioToAAC(ctx context.Context, inputPath, outputPath string, bitRate int) error {
+	cmd := exec.CommandContext(
+		ctx,
 		ffmpegBin,
 		"-i", inputPath,
 		"-vn", // Ignore video streams
@@ -246,12 +572,336 @@ func (cli *CLI) ConvertAudioToAAC(inputPath, outputPath string, bitRate int) err
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("triming audio failed: %v\nOutput: %s", err, string(output))
+		removeOutput(outputPath)
+		return fmt.Errorf("converting audio failed: %v\nOutput: %s", err, string(output))
 	}
 
 	return nil
 }
 
+// NormalizeVoice converts a voice recording, such as a clip recorded in a browser (webm, ogg), to AAC format
+// and normalizes its loudness, so speech sounds as loud as the music around it.
+//
+// Parameters:
+//   - ctx: Stops the conversion when canceled.
+//   - inputPath: Path to the recorded audio file.
+//   - outputPath: Destination path for the converted file (should end with .aac or .m4a).
+//   - bitRate: Audio bitrate in kbps.
+//
+// Returns:
+//   - An error if the conversion fails, including FFmpeg's output. The partially written output is removed.
+func (cli *CLI) NormalizeVoice(ctx context.Context, inputPath, outputPath string, bitRate int) error {
+	cmd := exec.CommandContext(
+		ctx,
+		ffmpegBin,
+		"-i", inputPath,
+		"-vn",
+		"-af", voiceLoudnorm,
+		"-ar", "44100",
+		"-ac", "2",
+		"-c:a", "aac",
+		"-b:a", strconv.Itoa(bitRate)+"k",
+		outputPath,
+		"-y",
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		removeOutput(outputPath)
+		return fmt.Errorf("voice normalization failed: %v\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// AnalyzeLoudness runs the first pass of the loudnorm filter and measures the loudness of an audio file.
+//
+// Parameters:
+//   - ctx: Stops the analysis when canceled.
+//   - filePath: The path to the audio file.
+//   - target: The loudness the file is going to be normalized to.
+//
+// Returns:
+//   - The measured loudness stats, or an error if the analysis fails or the audio is silent.
+func (cli *CLI) AnalyzeLoudness(ctx context.Context, filePath string, target LoudnessTarget) (LoudnessStats, error) {
+	if err := fs.FileExists(filePath); err != nil {
+		return LoudnessStats{}, err
+	}
+
+	cmd := exec.CommandContext(
+		ctx,
+		ffmpegBin,
+		"-hide_banner",
+		"-i", filePath,
+		"-vn",
+		"-af", loudnormFilter(target)+":print_format=json",
+		"-f", "null",
+		"-",
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return LoudnessStats{}, fmt.Errorf("loudness analysis failed: %v\nOutput: %s", err, string(output))
+	}
+
+	return parseLoudnessStats(output)
+}
+
+// ConvertAudioToAACNormalized converts an audio file to AAC format and corrects its loudness
+// with the values measured by AnalyzeLoudness (the second pass of the loudnorm filter).
+//
+// Parameters:
+//   - ctx: Stops the conversion when canceled.
+//   - inputPath: Path to the input audio file.
+//   - outputPath: Destination path for the converted file (should end with .aac or .m4a).
+//   - bitRate: Audio bitrate in kbps.
+//   - target: The loudness the file is normalized to.
+//   - measured: The stats returned by AnalyzeLoudness for the input file.
+//
+// Returns:
+//   - The loudness stats of the second pass, or an error if the conversion fails.
+//     The partially written output is removed.
+func (cli *CLI) ConvertAudioToAACNormalized(ctx context.Context, inputPath, outputPath string, bitRate int, target LoudnessTarget, measured LoudnessStats) (LoudnessStats, error) {
+	filter := fmt.Sprintf(
+		"%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true:print_format=json",
+		loudnormFilter(target),
+		formatDB(measured.InputIntegrated),
+		formatDB(measured.InputTruePeak),
+		formatDB(measured.InputRange),
+		formatDB(measured.InputThreshold),
+		formatDB(measured.TargetOffset),
+	)
+
+	cmd := exec.CommandContext(
+		ctx,
+		ffmpegBin,
+		"-hide_banner",
+		"-i", inputPath,
+		"-vn",
+		"-af", filter,
+		"-ar", "44100", // loudnorm upsamples to 192 kHz internally
+		"-c:a", "aac",
+		"-b:a", strconv.Itoa(bitRate)+"k",
+		outputPath,
+		"-y",
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		removeOutput(outputPath)
+		return LoudnessStats{}, fmt.Errorf("loudness normalization failed: %v\nOutput: %s", err, string(output))
+	}
+
+	return parseLoudnessStats(output)
+}
+
+// DetectSilence finds the periods of silence in an audio file using the silencedetect filter.
+//
+// Parameters:
+//   - ctx: Stops the detection when canceled.
+//   - filePath: The path to the audio file.
+//   - duration: The duration (in seconds) of the audio file, it ends the silence that lasts until the end of the audio.
+//   - noise: The level in dB below which the audio is considered silent.
+//   - minSilence: The minimum duration (in seconds) of a detected silence.
+//
+// Returns:
+//   - The detected periods of silence in order, or an error if the detection fails.
+func (cli *CLI) DetectSilence(ctx context.Context, filePath string, duration, noise, minSilence float64) ([]Silence, error) {
+	if err := fs.FileExists(filePath); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(
+		ctx,
+		ffmpegBin,
+		"-hide_banner",
+		"-i", filePath,
+		"-vn",
+		"-af", fmt.Sprintf("silencedetect=noise=%sdB:d=%s", formatDB(noise), strconv.FormatFloat(minSilence, 'f', 3, 64)),
+		"-f", "null",
+		"-",
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("silence detection failed: %v\nOutput: %s", err, string(output))
+	}
+
+	return parseSilences(output, duration), nil
+}
+
+// hlsOutput returns the options of an HLS output that writes the playlist and the segments to the given directory
+// in the container of the CLI. Fragmented MP4 segments share an init segment written next to them.
+func (cli *CLI) hlsOutput(dir, segName string, hlsOpts []string) []string {
+	args := make([]string, 0, len(hlsOpts)+8)
+	args = append(args, hlsOpts...)
+
+	if init := cli.container.InitName(segName); init != "" {
+		// The init segment name is relative to the playlist
+		args = append(args, "-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", init)
+	}
+
+	return append(args,
+		"-hls_segment_filename", fmt.Sprintf("%s/%s", dir, segName)+"%d"+cli.container.Extension(),
+		fmt.Sprintf("%s/%s", dir, segName)+".m3u8",
+	)
+}
+
+// hlsEventOptions returns the options of the HLS muxer for playlists that are made at once.
+func hlsEventOptions(segDuration int) []string {
+	return []string{
+		"-start_number", "0",
+		"-hls_time", strconv.Itoa(segDuration),
+		"-hls_playlist_type", "event",
+	}
+}
+
+// splitFilter copies the [out] label of a filter graph for every rendition.
+// It returns the new graph and the labels the renditions are mapped from.
+func (cli *CLI) splitFilter(filter string) (string, []string) {
+	if len(cli.renditions) == 0 {
+		return filter, nil
+	}
+
+	labels := make([]string, len(cli.renditions))
+	outputs := "[out]"
+	for i := range cli.renditions {
+		labels[i] = "[r" + strconv.Itoa(i) + "]"
+		outputs += labels[i]
+	}
+
+	filter = strings.TrimSuffix(filter, "[out]") + "[mix];[mix]asplit=" + strconv.Itoa(len(labels)+1) + outputs
+
+	return filter, labels
+}
+
+// renditionOutputs returns the output options that encode the audio in every rendition, creating
+// the rendition directories. Each rendition is mapped from the given label, or from the audio
+// of the first input if there are no labels.
+func (cli *CLI) renditionOutputs(labels []string, outDir, segName string, hlsOpts []string) ([]string, error) {
+	args := make([]string, 0, len(cli.renditions)*16)
+	for i, r := range cli.renditions {
+		dir := filepath.Join(outDir, r.Name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+
+		source := "0:a"
+		if i < len(labels) {
+			source = labels[i]
+		}
+
+		args = append(args, "-map", source)
+		args = append(args, r.codecArgs()...)
+		args = append(args, cli.hlsOutput(dir, segName, hlsOpts)...)
+	}
+
+	return args, nil
+}
+
+// concatList builds the input of the concat demuxer that reads the given parts of files one after another.
+func concatList(parts []ClipPart) (string, error) {
+	var list strings.Builder
+	for _, part := range parts {
+		path, err := filepath.Abs(part.Path)
+		if err != nil {
+			return "", err
+		}
+
+		list.WriteString("file '" + strings.ReplaceAll(path, "'", `'\''`) + "'\n")
+		list.WriteString("inpoint " + strconv.FormatFloat(part.Start, 'f', 3, 64) + "\n")
+		list.WriteString("outpoint " + strconv.FormatFloat(part.End, 'f', 3, 64) + "\n")
+	}
+
+	return list.String(), nil
+}
+
+// removeOutput deletes the file an interrupted or failed conversion may have left behind.
+func removeOutput(path string) {
+	_ = os.Remove(path)
+}
+
+// parseSilences reads the silence_start and silence_end lines printed by the silencedetect filter.
+func parseSilences(output []byte, duration float64) []Silence {
+	silences := make([]Silence, 0)
+	start := -1.0
+
+	for line := range strings.Lines(string(output)) {
+		if _, value, ok := strings.Cut(line, "silence_start: "); ok {
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil {
+				start = max(v, 0)
+			}
+			continue
+		}
+
+		if _, value, ok := strings.Cut(line, "silence_end: "); ok && start >= 0 {
+			value, _, _ = strings.Cut(value, " ")
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil {
+				silences = append(silences, Silence{Start: start, End: min(v, duration)})
+			}
+			start = -1
+		}
+	}
+
+	if start >= 0 && start < duration { // Older FFmpeg versions don't end the silence at the end of the audio
+		silences = append(silences, Silence{Start: start, End: duration})
+	}
+
+	return silences
+}
+
+// parseLoudnessStats extracts the JSON block printed by the loudnorm filter at the end of the FFmpeg output.
+func parseLoudnessStats(output []byte) (LoudnessStats, error) {
+	stats := LoudnessStats{}
+
+	start := bytes.LastIndexByte(output, '{')
+	end := bytes.LastIndexByte(output, '}')
+	if start == -1 || end < start {
+		return stats, fmt.Errorf("loudness stats not found in output")
+	}
+
+	var raw rawLoudnessStats
+	if err := json.Unmarshal(output[start:end+1], &raw); err != nil {
+		return stats, fmt.Errorf("parsing loudness stats failed: %v", err)
+	}
+
+	fields := []struct {
+		value string
+		dest  *float64
+	}{
+		{raw.InputI, &stats.InputIntegrated},
+		{raw.InputTP, &stats.InputTruePeak},
+		{raw.InputLRA, &stats.InputRange},
+		{raw.InputThresh, &stats.InputThreshold},
+		{raw.OutputI, &stats.OutputIntegrated},
+		{raw.TargetOffset, &stats.TargetOffset},
+	}
+
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return stats, fmt.Errorf("parsing loudness stats failed: %v", err)
+		}
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return stats, fmt.Errorf("audio is silent, loudness can't be measured")
+		}
+		*f.dest = v
+	}
+
+	return stats, nil
+}
+
+// loudnormFilter returns the loudnorm filter with the target values set.
+func loudnormFilter(target LoudnessTarget) string {
+	return fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s", formatDB(target.Integrated), formatDB(target.TruePeak), formatDB(target.Range))
+}
+
+func formatDB(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
 // generateSilence generates a silent audio file with the specified duration, bitrate, sample rate,
 // and number of channels. The resulting audio file is saved to the provided file path.
 func (cli *CLI) generateSilence(duration float64, bitRate, sampleRate, channelCount int, filePath string) error {
@@ -279,3 +929,54 @@ func (cli *CLI) generateSilence(duration float64, bitRate, sampleRate, channelCo
 
 	return nil
 }
+
+// ParseRenditions parses a comma-separated list of renditions, such as "64k-he,128k,256k".
+// Every rendition is a bitrate in kbps followed by "k", with the "-he" suffix for HE-AAC.
+//
+// Parameters:
+//   - spec: The list of renditions, an empty list means there are no extra renditions.
+//
+// Returns:
+//   - The renditions in the listed order, or an error if the list is malformed.
+func ParseRenditions(spec string) ([]Rendition, error) {
+	renditions := make([]Rendition, 0)
+	seen := make(map[string]bool)
+
+	for item := range strings.SplitSeq(spec, ",") {
+		name := strings.ToLower(strings.TrimSpace(item))
+		if name == "" {
+			continue
+		}
+
+		rate, he := strings.CutSuffix(name, "-he")
+		rate, ok := strings.CutSuffix(rate, "k")
+		bitRate, err := strconv.Atoi(rate)
+		if !ok || err != nil || bitRate <= 0 {
+			return nil, fmt.Errorf("invalid rendition %q, expected a bitrate like 128k or 64k-he", item)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("rendition %q is listed twice", item)
+		}
+		seen[name] = true
+
+		renditions = append(renditions, Rendition{Name: name, BitRate: bitRate, HighEfficiency: he})
+	}
+
+	return renditions, nil
+}
+
+// Codecs returns the RFC 6381 codec string of the rendition used in HLS master playlists.
+func (r Rendition) Codecs() string {
+	if r.HighEfficiency {
+		return "mp4a.40.5"
+	}
+	return "mp4a.40.2"
+}
+
+// codecArgs returns the encoder options of the rendition.
+func (r Rendition) codecArgs() []string {
+	if r.HighEfficiency {
+		return []string{"-c:a", "libfdk_aac", "-profile:a", "aac_he", "-b:a", strconv.Itoa(r.BitRate) + "k"}
+	}
+	return []string{"-c:a", "aac", "-b:a", strconv.Itoa(r.BitRate) + "k"}
+}
@@ -0,0 +1,198 @@
+package ffmpeg
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+)
+
+func TestParseLoudnessStats(t *testing.T) {
+	t.Run("reads the last json block", func(t *testing.T) {
+		output := []byte(`size=N/A time=00:03:12.00 bitrate=N/A speed= 120x
+[Parsed_loudnorm_0 @ 0x5581] 
+{
+	"input_i" : "-9.61",
+	"input_tp" : "0.42",
+	"input_lra" : "5.30",
+	"input_thresh" : "-19.75",
+	"output_i" : "-14.02",
+	"output_tp" : "-1.01",
+	"output_lra" : "4.90",
+	"output_thresh" : "-24.13",
+	"normalization_type" : "linear",
+	"target_offset" : "0.02"
+}
+`)
+
+		stats, err := parseLoudnessStats(output)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := LoudnessStats{
+			InputIntegrated:  -9.61,
+			InputTruePeak:    0.42,
+			InputRange:       5.3,
+			InputThreshold:   -19.75,
+			OutputIntegrated: -14.02,
+			TargetOffset:     0.02,
+		}
+		if stats != want {
+			t.Errorf("parseLoudnessStats() = %+v, want %+v", stats, want)
+		}
+	})
+
+	t.Run("silent audio is rejected", func(t *testing.T) {
+		output := []byte(`{"input_i":"-inf","input_tp":"-inf","input_lra":"0.00","input_thresh":"-70.00","output_i":"-inf","target_offset":"inf"}`)
+		if _, err := parseLoudnessStats(output); err == nil {
+			t.Error("expected an error for silent audio")
+		}
+	})
+
+	t.Run("missing stats", func(t *testing.T) {
+		if _, err := parseLoudnessStats([]byte("Conversion failed!")); err == nil {
+			t.Error("expected an error when there is no json block")
+		}
+	})
+}
+
+func TestLoudnormFilter(t *testing.T) {
+	got := loudnormFilter(LoudnessTarget{Integrated: -14, TruePeak: -1, Range: 11})
+	want := "loudnorm=I=-14.00:TP=-1.00:LRA=11.00"
+	if got != want {
+		t.Errorf("loudnormFilter() = %q, want %q", got, want)
+	}
+}
+
+func TestParseSilences(t *testing.T) {
+	t.Run("leading and trailing silence", func(t *testing.T) {
+		output := []byte(`[silencedetect @ 0x55d1] silence_start: -0.00133333
+[silencedetect @ 0x55d1] silence_end: 3.21 | silence_duration: 3.21133
+size=N/A time=00:01:40.00 bitrate=N/A speed= 500x
+[silencedetect @ 0x55d1] silence_start: 97.5
+[silencedetect @ 0x55d1] silence_end: 100.02 | silence_duration: 2.52
+`)
+
+		got := parseSilences(output, 100)
+		want := []Silence{{Start: 0, End: 3.21}, {Start: 97.5, End: 100}}
+		if len(got) != len(want) {
+			t.Fatalf("parseSilences() = %+v, want %+v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("silence %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("silence lasting until the end is closed", func(t *testing.T) {
+		output := []byte("[silencedetect @ 0x55d1] silence_start: 180.4\n")
+
+		got := parseSilences(output, 182)
+		if len(got) != 1 || got[0] != (Silence{Start: 180.4, End: 182}) {
+			t.Errorf("parseSilences() = %+v, want one silence from 180.4 to 182", got)
+		}
+	})
+
+	t.Run("no silence", func(t *testing.T) {
+		if got := parseSilences([]byte("size=N/A time=00:03:00.00\n"), 180); len(got) != 0 {
+			t.Errorf("parseSilences() = %+v, want none", got)
+		}
+	})
+}
+
+func TestParseRenditions(t *testing.T) {
+	t.Run("valid list", func(t *testing.T) {
+		got, err := ParseRenditions(" 64k-he, 128k,256K ")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []Rendition{
+			{Name: "64k-he", BitRate: 64, HighEfficiency: true},
+			{Name: "128k", BitRate: 128},
+			{Name: "256k", BitRate: 256},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("ParseRenditions() = %+v, want %+v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("rendition %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("empty list", func(t *testing.T) {
+		got, err := ParseRenditions("")
+		if err != nil || len(got) != 0 {
+			t.Errorf("expected no renditions, got %+v (%v)", got, err)
+		}
+	})
+
+	t.Run("malformed entries", func(t *testing.T) {
+		for _, spec := range []string{"128", "abc", "0k", "-64k", "128k,128k"} {
+			if _, err := ParseRenditions(spec); err == nil {
+				t.Errorf("expected an error for %q", spec)
+			}
+		}
+	})
+}
+
+func TestSplitFilter(t *testing.T) {
+	cli := NewCLI(hls.ContainerTS, Rendition{Name: "64k"}, Rendition{Name: "128k"})
+
+	filter, labels := cli.splitFilter("[0:a][1:a]acrossfade=d=3[out]")
+	want := "[0:a][1:a]acrossfade=d=3[mix];[mix]asplit=3[out][r0][r1]"
+	if filter != want {
+		t.Errorf("splitFilter() = %q, want %q", filter, want)
+	}
+	if len(labels) != 2 || labels[0] != "[r0]" || labels[1] != "[r1]" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+
+	if filter, labels := NewCLI(hls.ContainerTS).splitFilter("[0:a]anull[out]"); filter != "[0:a]anull[out]" || labels != nil {
+		t.Errorf("expected the filter to be unchanged without renditions")
+	}
+}
+
+func TestHLSOutput(t *testing.T) {
+	opts := []string{"-hls_time", "5"}
+
+	t.Run("mpeg-ts", func(t *testing.T) {
+		got := NewCLI(hls.ContainerTS).hlsOutput("out", "abc", opts)
+		want := []string{"-hls_time", "5", "-hls_segment_filename", "out/abc%d.ts", "out/abc.m3u8"}
+		if !slices.Equal(got, want) {
+			t.Errorf("hlsOutput() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("fragmented mp4", func(t *testing.T) {
+		got := NewCLI(hls.ContainerFMP4).hlsOutput("out", "abc", opts)
+		want := []string{
+			"-hls_time", "5",
+			"-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", "abc_init.mp4",
+			"-hls_segment_filename", "out/abc%d.m4s", "out/abc.m3u8",
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("hlsOutput() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestConcatList(t *testing.T) {
+	list, err := concatList([]ClipPart{
+		{Path: "/archive/a.aac", Start: 12.5, End: 3600},
+		{Path: "/archive/it's.aac", Start: 0, End: 30.25},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "file '/archive/a.aac'\ninpoint 12.500\noutpoint 3600.000\n" +
+		"file '/archive/it'\\''s.aac'\ninpoint 0.000\noutpoint 30.250\n"
+	if list != want {
+		t.Errorf("concatList() = %q, want %q", list, want)
+	}
+}
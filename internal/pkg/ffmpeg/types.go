@@ -24,3 +24,62 @@ type rawAudioMetadata struct {
 		Channels   int    `json:"channels"`
 	} `json:"streams"`
 }
+
+// Fade describes an overlap between two audio streams.
+type Fade struct {
+	Duration float64 // The length of the overlap in seconds.
+	Curve    string  // The name of the fade curve supported by the acrossfade filter (tri, qsin, exp, etc).
+}
+
+// Duck describes how the music is lowered under a voice-over.
+type Duck struct {
+	Volume float64 // The music volume while the voice plays, from 0 to 1.
+	Fade   float64 // The time in seconds it takes to lower and to restore the music volume.
+	Voice  float64 // The duration of the voice in seconds.
+}
+
+// LoudnessTarget describes the EBU R128 loudness the audio is normalized to.
+type LoudnessTarget struct {
+	Integrated float64 // The integrated loudness in LUFS.
+	TruePeak   float64 // The maximum true peak in dBTP.
+	Range      float64 // The loudness range in LU.
+}
+
+// LoudnessStats holds the values reported by the loudnorm filter.
+type LoudnessStats struct {
+	InputIntegrated  float64 // The integrated loudness of the input in LUFS.
+	InputTruePeak    float64 // The true peak of the input in dBTP.
+	InputRange       float64 // The loudness range of the input in LU.
+	InputThreshold   float64 // The gating threshold of the input in LUFS.
+	OutputIntegrated float64 // The integrated loudness of the output in LUFS.
+	TargetOffset     float64 // The offset gain in dB for the second pass.
+}
+
+type rawLoudnessStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	OutputI      string `json:"output_i"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// Rendition describes an extra quality level of the HLS stream.
+type Rendition struct {
+	Name           string // The name of the rendition, used for its directory, e.g. 64k-he.
+	BitRate        int    // The audio bitrate in kbps.
+	HighEfficiency bool   // Whether the audio is encoded with HE-AAC, which needs FFmpeg built with libfdk_aac.
+}
+
+// Silence describes a period of silence in an audio file.
+type Silence struct {
+	Start float64 // The position (in seconds) where the silence starts.
+	End   float64 // The position (in seconds) where the silence ends.
+}
+
+// ClipPart describes a part of an audio file that goes into a clip.
+type ClipPart struct {
+	Path  string  // The path to the audio file.
+	Start float64 // The position (in seconds) where the part starts.
+	End   float64 // The position (in seconds) where the part ends.
+}
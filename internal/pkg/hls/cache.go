@@ -0,0 +1,388 @@
+package hls
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheTmpPrefix marks the entry directories that are still being generated.
+const cacheTmpPrefix = ".tmp-"
+
+// Cache keeps the segments of tracks on disk, so a track isn't segmented again every time it airs.
+// Entries are keyed by the track ID and the profile the segments were made with and survive restarts.
+// Once the cache grows over its size limit, the least recently used entries are evicted.
+type Cache struct {
+	dir     string
+	maxSize int64 // The size limit in bytes
+	size    int64 // The total size of the entries in bytes
+
+	entries map[string]*list.Element // Keyed by the entry directory
+	order   *list.List               // From the most to the least recently used entry
+	mutex   sync.Mutex
+}
+
+type cacheEntry struct {
+	dir     string
+	trackID string
+	size    int64
+}
+
+// NewCache creates a cache in the directory, picking up the entries left there by a previous run.
+//
+// Parameters:
+//   - dir: The directory the cached segments are stored in.
+//   - maxSize: The size limit of the cache in bytes.
+//
+// Returns:
+//   - The cache, or an error if the directory can't be created or read.
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.evict("")
+	c.mutex.Unlock()
+
+	return c, nil
+}
+
+// Link makes the segments of a track available in outDir. The segments are taken from the cache,
+// or produced with generate and cached first. The files are hard-linked when possible, so evicting
+// an entry doesn't break the playlists that still use its segments.
+//
+// Parameters:
+//   - trackID: The ID of the track.
+//   - profile: Describes how the segments are made, e.g. the segment duration and the cue points.
+//   - outDir: The directory the segments are linked to.
+//   - generate: Writes the segments of the track to the given directory.
+//
+// Returns:
+//   - An error if the segments can't be generated or linked.
+func (c *Cache) Link(trackID, profile, outDir string, generate func(dir string) error) error {
+	entryDir := filepath.Join(c.dir, trackID, profile)
+
+	if c.touch(entryDir) {
+		err := linkFiles(entryDir, outDir)
+		if err == nil {
+			return nil
+		}
+
+		// The entry was evicted or damaged in the meantime, so it's generated again
+		c.remove(entryDir)
+	}
+
+	err := c.add(entryDir, trackID, generate)
+	if err != nil {
+		return err
+	}
+
+	return linkFiles(entryDir, outDir)
+}
+
+// Invalidate removes the cached segments of the tracks, e.g. after they're deleted or re-encoded.
+//
+// Parameters:
+//   - trackIDs: The IDs of the tracks.
+func (c *Cache) Invalidate(trackIDs ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*cacheEntry)
+		if slices.Contains(trackIDs, entry.trackID) {
+			c.drop(el)
+		}
+		el = next
+	}
+
+	for _, id := range trackIDs {
+		_ = os.RemoveAll(filepath.Join(c.dir, id))
+	}
+}
+
+// Size returns the total size of the cached segments in bytes.
+func (c *Cache) Size() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.size
+}
+
+// touch marks the entry as the most recently used one and reports whether it exists.
+func (c *Cache) touch(entryDir string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	el, ok := c.entries[entryDir]
+	if !ok {
+		return false
+	}
+
+	c.order.MoveToFront(el)
+
+	// The modification time keeps the order of the entries across restarts
+	now := time.Now()
+	_ = os.Chtimes(entryDir, now, now)
+
+	return true
+}
+
+// add generates the entry in a temporary directory and moves it in place once it's complete.
+func (c *Cache) add(entryDir, trackID string, generate func(dir string) error) error {
+	trackDir := filepath.Dir(entryDir)
+	if err := os.MkdirAll(trackDir, 0o755); err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(trackDir, cacheTmpPrefix)
+	if err != nil {
+		return err
+	}
+
+	if err := generate(tmpDir); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return err
+	}
+
+	size, err := dirSize(tmpDir)
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.entries[entryDir]; ok {
+		// Another channel cached the same segments first
+		_ = os.RemoveAll(tmpDir)
+		return nil
+	}
+
+	_ = os.RemoveAll(entryDir)
+	if err := os.Rename(tmpDir, entryDir); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return err
+	}
+
+	c.push(&cacheEntry{dir: entryDir, trackID: trackID, size: size}, true)
+	c.evict(entryDir)
+
+	return nil
+}
+
+// remove deletes a single entry.
+func (c *Cache) remove(entryDir string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if el, ok := c.entries[entryDir]; ok {
+		c.drop(el)
+	}
+}
+
+// evict removes the least recently used entries until the cache fits its size limit.
+// The entry in the keep directory is never evicted. The caller must hold the mutex.
+func (c *Cache) evict(keep string) {
+	for c.size > c.maxSize {
+		el := c.order.Back()
+		if el == nil || el.Value.(*cacheEntry).dir == keep {
+			return
+		}
+
+		c.drop(el)
+	}
+}
+
+// push registers an entry as the most or the least recently used one. The caller must hold the mutex.
+func (c *Cache) push(entry *cacheEntry, recent bool) {
+	var el *list.Element
+	if recent {
+		el = c.order.PushFront(entry)
+	} else {
+		el = c.order.PushBack(entry)
+	}
+
+	c.entries[entry.dir] = el
+	c.size += entry.size
+}
+
+// drop unregisters an entry and deletes its files. The caller must hold the mutex.
+func (c *Cache) drop(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.order.Remove(el)
+	delete(c.entries, entry.dir)
+	c.size -= entry.size
+
+	_ = os.RemoveAll(entry.dir)
+}
+
+// load registers the entries found in the cache directory, the most recently used ones first.
+// Entries that were being generated when the previous run stopped are deleted.
+func (c *Cache) load() error {
+	trackDirs, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type found struct {
+		entry  *cacheEntry
+		usedAt time.Time
+	}
+
+	entries := make([]found, 0)
+	for _, trackDir := range trackDirs {
+		if !trackDir.IsDir() {
+			continue
+		}
+
+		trackPath := filepath.Join(c.dir, trackDir.Name())
+		profileDirs, err := os.ReadDir(trackPath)
+		if err != nil {
+			return err
+		}
+
+		for _, profileDir := range profileDirs {
+			entryDir := filepath.Join(trackPath, profileDir.Name())
+			if !profileDir.IsDir() || strings.HasPrefix(profileDir.Name(), cacheTmpPrefix) {
+				_ = os.RemoveAll(entryDir)
+				continue
+			}
+
+			info, err := profileDir.Info()
+			if err != nil {
+				return err
+			}
+
+			size, err := dirSize(entryDir)
+			if err != nil {
+				return err
+			}
+
+			entry := &cacheEntry{dir: entryDir, trackID: trackDir.Name(), size: size}
+			entries = append(entries, found{entry: entry, usedAt: info.ModTime()})
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b found) int {
+		return b.usedAt.Compare(a.usedAt)
+	})
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, f := range entries {
+		c.push(f.entry, false)
+	}
+
+	return nil
+}
+
+// dirSize returns the total size of the files in a directory and its subdirectories.
+func dirSize(dir string) (int64, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, file := range files {
+		if file.IsDir() { // Segments of renditions
+			sub, err := dirSize(filepath.Join(dir, file.Name()))
+			if err != nil {
+				return 0, err
+			}
+			size += sub
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+
+	return size, nil
+}
+
+// linkFiles hard-links every file of the source directory to the destination directory,
+// copying the files the links can't be made for, e.g. when the directories are on different devices.
+// Subdirectories are linked the same way.
+func linkFiles(srcDir, dstDir string) error {
+	files, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return errors.New("cached segments are missing")
+	}
+
+	for _, file := range files {
+		src := filepath.Join(srcDir, file.Name())
+		dst := filepath.Join(dstDir, file.Name())
+
+		if file.IsDir() {
+			if err := os.MkdirAll(dst, 0o755); err != nil {
+				return err
+			}
+			if err := linkFiles(src, dst); err != nil {
+				return err
+			}
+			continue
+		}
+
+		_ = os.Remove(dst)
+		if err := os.Link(src, dst); err == nil {
+			continue
+		}
+
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,214 @@
+package hls
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSegments returns a generator writing count segments of the given size and counting its calls.
+func fakeSegments(trackID string, count, size int, calls *int) func(dir string) error {
+	return func(dir string) error {
+		*calls++
+		for i := range count {
+			name := filepath.Join(dir, trackID+string(rune('0'+i))+ContainerTS.Extension())
+			if err := os.WriteFile(name, make([]byte, size), 0o644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestCache_Link(t *testing.T) {
+	t.Run("generates once and reuses the segments", func(t *testing.T) {
+		cache, err := NewCache(t.TempDir(), 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		calls := 0
+		for range 2 {
+			out := t.TempDir()
+			if err := cache.Link("a", "p", out, fakeSegments("a", 2, 10, &calls)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(out, "a1"+ContainerTS.Extension())); err != nil {
+				t.Errorf("expected linked segment: %v", err)
+			}
+		}
+
+		if calls != 1 {
+			t.Errorf("expected 1 generation, got %d", calls)
+		}
+		if cache.Size() != 20 {
+			t.Errorf("expected size 20, got %d", cache.Size())
+		}
+	})
+
+	t.Run("links the segments of renditions", func(t *testing.T) {
+		cache, _ := NewCache(t.TempDir(), 1000)
+
+		calls := 0
+		generate := func(dir string) error {
+			sub := filepath.Join(dir, "64k")
+			if err := os.MkdirAll(sub, 0o755); err != nil {
+				return err
+			}
+			if err := fakeSegments("a", 1, 10, &calls)(sub); err != nil {
+				return err
+			}
+			return fakeSegments("a", 1, 20, &calls)(dir)
+		}
+
+		for range 2 {
+			out := t.TempDir()
+			if err := cache.Link("a", "p", out, generate); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(out, "64k", "a0"+ContainerTS.Extension())); err != nil {
+				t.Errorf("expected linked rendition segment: %v", err)
+			}
+		}
+
+		if cache.Size() != 30 {
+			t.Errorf("expected size 30, got %d", cache.Size())
+		}
+	})
+
+	t.Run("profiles are cached separately", func(t *testing.T) {
+		cache, _ := NewCache(t.TempDir(), 1000)
+
+		calls := 0
+		cache.Link("a", "p1", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+		cache.Link("a", "p2", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+
+		if calls != 2 {
+			t.Errorf("expected 2 generations, got %d", calls)
+		}
+	})
+
+	t.Run("evicts the least recently used entries", func(t *testing.T) {
+		cache, _ := NewCache(t.TempDir(), 25)
+
+		calls := 0
+		cache.Link("a", "p", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+		cache.Link("b", "p", t.TempDir(), fakeSegments("b", 1, 10, &calls))
+		cache.Link("a", "p", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+		cache.Link("c", "p", t.TempDir(), fakeSegments("c", 1, 10, &calls))
+
+		if calls != 3 {
+			t.Fatalf("expected 3 generations, got %d", calls)
+		}
+		if cache.Size() != 20 {
+			t.Errorf("expected size 20, got %d", cache.Size())
+		}
+
+		cache.Link("a", "p", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+		if calls != 3 {
+			t.Errorf("expected a recently used entry to be kept")
+		}
+
+		cache.Link("b", "p", t.TempDir(), fakeSegments("b", 1, 10, &calls))
+		if calls != 4 {
+			t.Errorf("expected the least recently used entry to be evicted")
+		}
+	})
+
+	t.Run("failed generation leaves nothing behind", func(t *testing.T) {
+		dir := t.TempDir()
+		cache, _ := NewCache(dir, 1000)
+
+		err := cache.Link("a", "p", t.TempDir(), func(dir string) error {
+			os.WriteFile(filepath.Join(dir, "a0"+ContainerTS.Extension()), []byte("half"), 0o644)
+			return os.ErrInvalid
+		})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+
+		files, _ := os.ReadDir(filepath.Join(dir, "a"))
+		if len(files) != 0 || cache.Size() != 0 {
+			t.Errorf("expected empty cache, got %d files", len(files))
+		}
+	})
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	dir := t.TempDir()
+	cache, _ := NewCache(dir, 1000)
+
+	calls := 0
+	cache.Link("a", "p1", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+	cache.Link("a", "p2", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+	cache.Link("b", "p1", t.TempDir(), fakeSegments("b", 1, 10, &calls))
+
+	cache.Invalidate("a")
+
+	if cache.Size() != 10 {
+		t.Errorf("expected size 10, got %d", cache.Size())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a")); !os.IsNotExist(err) {
+		t.Errorf("expected invalidated track directory to be deleted")
+	}
+
+	cache.Link("a", "p1", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+	if calls != 4 {
+		t.Errorf("expected invalidated segments to be generated again")
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	t.Run("reuses entries after a restart", func(t *testing.T) {
+		dir := t.TempDir()
+		first, _ := NewCache(dir, 1000)
+
+		calls := 0
+		first.Link("a", "p", t.TempDir(), fakeSegments("a", 2, 10, &calls))
+
+		second, err := NewCache(dir, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second.Size() != 20 {
+			t.Errorf("expected size 20, got %d", second.Size())
+		}
+
+		second.Link("a", "p", t.TempDir(), fakeSegments("a", 2, 10, &calls))
+		if calls != 1 {
+			t.Errorf("expected cached segments to be reused, got %d generations", calls)
+		}
+	})
+
+	t.Run("deletes unfinished entries", func(t *testing.T) {
+		dir := t.TempDir()
+		unfinished := filepath.Join(dir, "a", cacheTmpPrefix+"1")
+		os.MkdirAll(unfinished, 0o755)
+		os.WriteFile(filepath.Join(unfinished, "a0"+ContainerTS.Extension()), []byte("half"), 0o644)
+
+		cache, err := NewCache(dir, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cache.Size() != 0 {
+			t.Errorf("expected empty cache, got size %d", cache.Size())
+		}
+		if _, err := os.Stat(unfinished); !os.IsNotExist(err) {
+			t.Errorf("expected unfinished entry to be deleted")
+		}
+	})
+
+	t.Run("shrinks to a lower limit", func(t *testing.T) {
+		dir := t.TempDir()
+		first, _ := NewCache(dir, 1000)
+
+		calls := 0
+		first.Link("a", "p", t.TempDir(), fakeSegments("a", 1, 10, &calls))
+		first.Link("b", "p", t.TempDir(), fakeSegments("b", 1, 10, &calls))
+
+		second, _ := NewCache(dir, 15)
+		if second.Size() > 15 {
+			t.Errorf("expected size within the limit, got %d", second.Size())
+		}
+	})
+}
@@ -1,9 +1,44 @@
 package hls
 
-const SegmentExtension = ".ts"
 const timeFormat = "2006-01-02T15:04:05.000Z"
 
 const (
 	DefaultMaxSegmentDuration = 5
 	DefaultLiveSegmentsAmount = 3
 )
+
+// The allowed ranges of the duration of segments and the size of the live window.
+const (
+	SegmentDurationMin = 1
+	SegmentDurationMax = 20
+	LiveSegmentsMin    = 2
+	LiveSegmentsMax    = 20
+)
+
+// partHoldBackParts is the number of parts Low-Latency clients stay behind the end of the playlist.
+const partHoldBackParts = 3
+
+// minSegmentDuration is the shortest segment (in seconds) worth generating, shorter tails are merged into the previous segment.
+const minSegmentDuration = 0.05
+
+// trackDateRangeClass is the class of the DATERANGE tags that mark the tracks in a playlist.
+const trackDateRangeClass = "com.airstation.track"
+
+const (
+	id3EncodingUTF8       = 3                              // The text encoding byte of ID3 frames for UTF-8
+	id3ArtworkDescription = "artwork"                      // The description of the URL frame with the artwork
+	id3SchemeURI          = "https://aomedia.org/emsg/ID3" // The scheme of event messages carrying ID3 tags
+)
+
+const (
+	tsMetadataStreamType = 0x15 // The stream type of metadata carried in PES packets
+	tsPrivateStreamID    = 0xbd // The PES stream ID of timed metadata
+	tsFirstElementaryPID = 0x100
+	tsFirstAudioStreamID = 0xc0 // The PES stream IDs of MPEG audio, which FFmpeg also uses for AAC
+	tsLastAudioStreamID  = 0xdf
+)
+
+const (
+	ptsClockRate = 90000     // The ticks of presentation timestamps per second
+	ptsMask      = 1<<33 - 1 // Presentation timestamps are 33-bit and wrap around
+)
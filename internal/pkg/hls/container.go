@@ -0,0 +1,58 @@
+package hls
+
+import "fmt"
+
+// Container is the format of the segment files.
+type Container string
+
+const (
+	ContainerTS   Container = "ts"   // MPEG-TS segments, each of them can be decoded on its own.
+	ContainerFMP4 Container = "fmp4" // Fragmented MP4 (CMAF) segments, decoded with the init segment they share.
+)
+
+// initSuffix is appended to the name of the segments to get the name of their init segment.
+const initSuffix = "_init.mp4"
+
+// ParseContainer returns the container with the given name.
+//
+// Parameters:
+//   - name: The name of the container, ts or fmp4.
+//
+// Returns:
+//   - The container, or an error if the name is unknown.
+func ParseContainer(name string) (Container, error) {
+	switch c := Container(name); c {
+	case ContainerTS, ContainerFMP4:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown segment container %q, expected %s or %s", name, ContainerTS, ContainerFMP4)
+	}
+}
+
+// Extension returns the extension of the segment files.
+func (c Container) Extension() string {
+	if c == ContainerFMP4 {
+		return ".m4s"
+	}
+	return ".ts"
+}
+
+// MimeType returns the media type of the segment files.
+func (c Container) MimeType() string {
+	if c == ContainerFMP4 {
+		return "video/iso.segment"
+	}
+	return "video/mp2t"
+}
+
+// InitName returns the name of the init segment of the segments with the given name prefix,
+// or an empty string if the segments don't need one.
+//
+// Parameters:
+//   - segName: The prefix of the segment file names.
+func (c Container) InitName(segName string) string {
+	if c == ContainerFMP4 {
+		return segName + initSuffix
+	}
+	return ""
+}
@@ -0,0 +1,14 @@
+package hls
+
+import "testing"
+
+func TestParseContainer(t *testing.T) {
+	for _, name := range []string{"ts", "fmp4"} {
+		if c, err := ParseContainer(name); err != nil || string(c) != name {
+			t.Errorf("ParseContainer(%q) = %q, %v", name, c, err)
+		}
+	}
+	if _, err := ParseContainer("mkv"); err == nil {
+		t.Error("expected an error for an unknown container")
+	}
+}
@@ -0,0 +1,177 @@
+package hls
+
+import (
+	"os"
+	"strconv"
+)
+
+// tsPacketSize is the size of an MPEG-TS packet.
+const tsPacketSize = 188
+
+// part is a partial segment, a byte range of a segment file that starts with a whole audio frame.
+type part struct {
+	start    float64 // The position of the part within the segment in seconds
+	duration float64
+	offset   int64
+	length   int64
+}
+
+// generateLowLatency constructs a Low-Latency HLS playlist. It ends at the elapsed time instead of
+// running ahead of it: the played segments are listed in full, followed by the parts of the current
+// segment that have started playing and a hint for the next part. The media sequence of the playlist is
+// the one of the oldest played segment, so the current segment keeps the sequence number it has in the
+// regular playlist.
+func (p *Playlist) generateLowLatency(elapsedTime float64, rendition string) string {
+	_, start := p.segmentAt(elapsedTime)
+	offset := max(elapsedTime-start, 0)
+	liveSegments := p.currentSegments(elapsedTime)
+
+	if len(liveSegments) == 0 {
+		p.edgePart = -1
+		return lowLatencyHeader(p.MaxSegmentDuration, p.PartDuration, p.mediaSequence, p.disconSequence)
+	}
+
+	p.slideWindow(liveSegments[0], offset)
+
+	played := p.playedSegments
+	mediaSeq, disconSeq := p.sequencesBefore(played)
+	playlist := lowLatencyHeader(p.MaxSegmentDuration, p.PartDuration, mediaSeq, disconSeq)
+	var prev *TimedSegment
+	for i, ts := range played {
+		seg := ts.Segment
+		playlist += p.segmentTags(ts, prev, rendition)
+		prev = &ts
+
+		// Parts of the last played segment stay listed, so players joining near its end can load them
+		if i == len(played)-1 {
+			playlist += p.hlsParts(seg, rendition)
+		}
+
+		playlist += hlsSegment(seg.Duration, p.segmentURI(seg, rendition), false)
+	}
+
+	current := liveSegments[0]
+	parts := segmentParts(p.segmentFile(current, rendition), current.Duration, p.PartDuration)
+	published := 0
+	for published < len(parts) && parts[published].start <= offset {
+		published++
+	}
+	p.edgePart = published - 1
+
+	playlist += p.segmentTags(p.timed(current), prev, rendition)
+	for _, pt := range parts[:published] {
+		playlist += hlsPart(pt, p.segmentURI(current, rendition))
+	}
+
+	switch {
+	case published < len(parts):
+		playlist += hlsPreloadHint(parts[published], p.segmentURI(current, rendition))
+	case len(liveSegments) > 1:
+		next := liveSegments[1]
+		if nextParts := segmentParts(p.segmentFile(next, rendition), next.Duration, p.PartDuration); len(nextParts) > 0 {
+			playlist += hlsPreloadHint(nextParts[0], p.segmentURI(next, rendition))
+		}
+	}
+
+	return playlist
+}
+
+// HasPart reports whether the last generated playlist lists the given part of the given segment,
+// which Low-Latency clients wait for with blocking playlist requests.
+//
+// Parameters:
+//   - msn: The media sequence number of the segment.
+//   - part: The index of the part within the segment, or -1 to wait for the whole segment.
+func (p *Playlist) HasPart(msn int64, part int) bool {
+	if msn < p.mediaSequence {
+		return true
+	}
+
+	return msn == p.mediaSequence && part >= 0 && part <= p.edgePart
+}
+
+// hlsParts lists all parts of a played segment.
+func (p *Playlist) hlsParts(seg *Segment, rendition string) string {
+	list := ""
+	for _, pt := range segmentParts(p.segmentFile(seg, rendition), seg.Duration, p.PartDuration) {
+		list += hlsPart(pt, p.segmentURI(seg, rendition))
+	}
+
+	return list
+}
+
+// segmentParts splits an MPEG-TS segment file into parts of up to the given duration. Parts are cut where
+// audio PES packets start, which always begin with a whole audio frame, so every part can be decoded on its own.
+// The durations of the parts are taken from the timestamps of the packets, except for the last part that lasts
+// until the end of the segment. Nothing is returned if the file can't be read or has no timed audio.
+func segmentParts(path string, duration, partDuration float64) []part {
+	data, err := os.ReadFile(path)
+	if err != nil || duration <= 0 {
+		return nil
+	}
+
+	frames := audioPESStarts(data)
+	if len(frames) == 0 {
+		return nil
+	}
+
+	// A part may end a little past the target due to rounding of the timestamps
+	const tolerance = 1e-3
+	position := func(i int) float64 {
+		return float64((frames[i].pts-frames[0].pts)&ptsMask) / ptsClockRate
+	}
+
+	var parts []part
+	var offset int64
+	first := 0
+	for {
+		start := position(first)
+		if first > 0 {
+			offset = frames[first].offset
+		}
+
+		next := first
+		for next+1 < len(frames) && position(next+1)-start <= partDuration+tolerance {
+			next++
+		}
+		// A packet longer than a part can't be split, so the part grows to hold it
+		if next == first && next+1 < len(frames) {
+			next++
+		}
+
+		if duration-start <= partDuration+tolerance || next == first || position(next) >= duration {
+			parts = append(parts, part{start: start, duration: max(duration-start, 0), offset: offset, length: int64(len(data)) - offset})
+			return parts
+		}
+
+		parts = append(parts, part{start: start, duration: position(next) - start, offset: offset, length: frames[next].offset - offset})
+		first = next
+	}
+}
+
+// lowLatencyHeader generates the header of a Low-Latency HLS playlist that supports blocking reloads.
+func lowLatencyHeader(dur int, partDur float64, mediaSeq, disconSeq int64) string {
+	return "#EXTM3U\n" +
+		"#EXT-X-VERSION:6\n" +
+		"#EXT-X-TARGETDURATION:" + strconv.Itoa(dur) + "\n" +
+		"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=" + strconv.FormatFloat(partDur*partHoldBackParts, 'f', 3, 64) + "\n" +
+		"#EXT-X-PART-INF:PART-TARGET=" + strconv.FormatFloat(partDur, 'f', 3, 64) + "\n" +
+		"#EXT-X-MEDIA-SEQUENCE:" + strconv.FormatInt(mediaSeq, 10) + "\n" +
+		"#EXT-X-DISCONTINUITY-SEQUENCE:" + strconv.FormatInt(disconSeq, 10) + "\n"
+}
+
+// hlsPart generates an HLS part entry for a byte range of the segment. Every part starts with a whole
+// audio frame, and audio frames don't depend on each other, so all parts are independent.
+func hlsPart(pt part, uri string) string {
+	return "#EXT-X-PART:DURATION=" + strconv.FormatFloat(pt.duration, 'f', 3, 64) +
+		",URI=\"" + uri + "\"" +
+		",BYTERANGE=\"" + strconv.FormatInt(pt.length, 10) + "@" + strconv.FormatInt(pt.offset, 10) + "\"" +
+		",INDEPENDENT=YES\n"
+}
+
+// hlsPreloadHint generates a hint for the part that is published next.
+func hlsPreloadHint(pt part, uri string) string {
+	return "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"" + uri + "\"" +
+		",BYTERANGE-START=" + strconv.FormatInt(pt.offset, 10) +
+		",BYTERANGE-LENGTH=" + strconv.FormatInt(pt.length, 10) + "\n"
+}
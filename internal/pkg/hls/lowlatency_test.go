@@ -0,0 +1,177 @@
+package hls
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testFrameDuration is the duration of the audio PES packets of the test segments.
+const testFrameDuration = 0.2
+
+// testAudioSegment returns an MPEG-TS segment with the given number of audio PES packets,
+// each starting with an ADTS frame header and taking two MPEG-TS packets.
+func testAudioSegment(frames int) []byte {
+	segment := testTSSegment(0)[:2*tsPacketSize]
+	for i := range frames {
+		frame := append([]byte{0xff, 0xf1}, bytes.Repeat([]byte{0xaa}, 298)...)
+		audio := pesPackets(0x100, int64(float64(i)*testFrameDuration*ptsClockRate), frame)
+		audio[7] = 0xc0
+		segment = append(segment, audio...)
+	}
+
+	return segment
+}
+
+// writeSegments creates 4-second segment files with the given number of audio PES packets.
+func writeSegments(t *testing.T, dir string, frames int, names ...string) []*Segment {
+	t.Helper()
+
+	segments := make([]*Segment, 0, len(names))
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, testAudioSegment(frames), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, NewSegment(4, path, i == 0))
+	}
+
+	return segments
+}
+
+func TestSegmentParts(t *testing.T) {
+	path := writeSegments(t, t.TempDir(), 23, "a0.ts")[0].Path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("splits on audio frames", func(t *testing.T) {
+		parts := segmentParts(path, 4.5, 1)
+		if len(parts) != 5 {
+			t.Fatalf("expected 5 parts, got %+v", parts)
+		}
+
+		var offset int64
+		for i, pt := range parts {
+			if pt.offset != offset || pt.length <= 0 || pt.length%tsPacketSize != 0 {
+				t.Errorf("unexpected part %d: %+v", i, pt)
+			}
+			if math.Abs(pt.start-float64(i)) > 1e-6 {
+				t.Errorf("expected part %d to start at %d seconds, got %v", i, i, pt.start)
+			}
+			offset += pt.length
+		}
+		if offset != int64(len(data)) {
+			t.Errorf("expected the parts to cover the file, got %d bytes", offset)
+		}
+		if parts[4].duration != 0.5 {
+			t.Errorf("expected a shorter last part, got %v", parts[4].duration)
+		}
+	})
+
+	t.Run("parts start with an audio frame", func(t *testing.T) {
+		for i, pt := range segmentParts(path, 4.5, 1)[1:] {
+			pkt := tsPacket(data[pt.offset : pt.offset+tsPacketSize])
+			if pkt.pid() != 0x100 || !pkt.unitStart() {
+				t.Fatalf("expected part %d to start with an audio PES packet, got PID %#x", i+1, pkt.pid())
+			}
+
+			pes := pkt.payload()
+			if pes[3] != tsFirstAudioStreamID || pesTimestamp(pes) != int64(i+1)*ptsClockRate {
+				t.Errorf("expected part %d to be timed at %d seconds, got %d", i+1, i+1, pesTimestamp(pes))
+			}
+			if frame := pes[9+int(pes[8]):]; frame[0] != 0xff || frame[1]&0xf0 != 0xf0 {
+				t.Errorf("expected part %d to start with an ADTS header, got %x", i+1, frame[:2])
+			}
+		}
+	})
+
+	t.Run("parts don't exceed the target", func(t *testing.T) {
+		parts := segmentParts(path, 4.5, 0.5)
+		for i, pt := range parts {
+			if pt.duration > 0.5+1e-6 {
+				t.Errorf("expected part %d to last up to 0.5 seconds, got %v", i, pt.duration)
+			}
+		}
+		// Two frames fit into a part, so there are more parts than the duration alone suggests
+		if len(parts) != 11 {
+			t.Errorf("expected 11 parts, got %d", len(parts))
+		}
+	})
+
+	t.Run("no timed audio", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "empty.ts")
+		if err := os.WriteFile(path, make([]byte, 10*tsPacketSize), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if parts := segmentParts(path, 4, 1); parts != nil {
+			t.Errorf("expected no parts, got %+v", parts)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if parts := segmentParts(filepath.Join(t.TempDir(), "none.ts"), 4, 1); parts != nil {
+			t.Errorf("expected no parts, got %+v", parts)
+		}
+	})
+}
+
+func TestGenerateLowLatency(t *testing.T) {
+	dir := t.TempDir()
+	current := writeSegments(t, dir, 20, "a0.ts", "a1.ts", "a2.ts")
+	playlist := NewPlaylist(current, nil)
+	playlist.PartDuration = 1
+
+	playlist.Generate(1)
+	got := playlist.Generate(5.5)
+
+	for _, want := range []string{
+		"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=3.000\n",
+		"#EXT-X-PART-INF:PART-TARGET=1.000\n",
+		"#EXT-X-MEDIA-SEQUENCE:1\n",
+		"#EXTINF:4.00,\n" + current[0].Path + "\n",
+		"#EXT-X-PART:DURATION=1.000,URI=\"" + current[1].Path + "\",BYTERANGE=\"2256@0\",INDEPENDENT=YES\n",
+		"#EXT-X-PART:DURATION=1.000,URI=\"" + current[1].Path + "\",BYTERANGE=\"1880@2256\",INDEPENDENT=YES\n",
+		"#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"" + current[1].Path + "\",BYTERANGE-START=4136,BYTERANGE-LENGTH=1880\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in playlist:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, current[2].Path) {
+		t.Errorf("expected upcoming segments to be left out:\n%s", got)
+	}
+
+	t.Run("blocking requests", func(t *testing.T) {
+		cases := []struct {
+			msn  int64
+			part int
+			want bool
+		}{
+			{1, -1, true},
+			{2, 1, true},
+			{2, 2, false},
+			{2, -1, false},
+			{3, 0, false},
+		}
+		for _, c := range cases {
+			if got := playlist.HasPart(c.msn, c.part); got != c.want {
+				t.Errorf("HasPart(%d, %d) = %v, want %v", c.msn, c.part, got, c.want)
+			}
+		}
+	})
+
+	t.Run("the media sequence follows the played segments", func(t *testing.T) {
+		got := playlist.Generate(9)
+		if !strings.Contains(got, "#EXT-X-MEDIA-SEQUENCE:1\n") || !strings.Contains(got, "#EXTINF:4.00,\n"+current[1].Path+"\n") {
+			t.Errorf("unexpected playlist:\n%s", got)
+		}
+		if playlist.MediaSequence() != 3 {
+			t.Errorf("expected media sequence 3, got %d", playlist.MediaSequence())
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package hls
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+)
+
+// Variant describes a rendition of the stream listed in a master playlist.
+type Variant struct {
+	Name    string // The name of the rendition, passed in the rendition query parameter of its playlist.
+	BitRate int    // The audio bitrate in kbps.
+	Codecs  string // The RFC 6381 codec string, e.g. mp4a.40.2.
+}
+
+// MasterPlaylist generates an HLS master playlist that lists the given variants. Their playlists
+// are referenced relative to the master one with the rendition query parameter, so the player
+// requests the same endpoint with the chosen rendition.
+//
+// Parameters:
+//   - variants: The renditions of the stream.
+//
+// Returns:
+//   - A string representing the master playlist.
+func MasterPlaylist(variants []Variant) string {
+	playlist := "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-INDEPENDENT-SEGMENTS\n"
+
+	for _, v := range variants {
+		playlist += "#EXT-X-STREAM-INF:BANDWIDTH=" + strconv.Itoa(v.BitRate*1000) +
+			",CODECS=\"" + v.Codecs + "\"\n" +
+			"?rendition=" + url.QueryEscape(v.Name) + "\n"
+	}
+
+	return playlist
+}
+
+// RenditionPath returns the path of a segment in the given rendition, which is stored in
+// a subdirectory named after the rendition next to the segment.
+//
+// Parameters:
+//   - path: The path of the segment.
+//   - rendition: The name of the rendition.
+func RenditionPath(path, rendition string) string {
+	return filepath.Join(filepath.Dir(path), rendition, filepath.Base(path))
+}
@@ -0,0 +1,62 @@
+package hls
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMasterPlaylist(t *testing.T) {
+	got := MasterPlaylist([]Variant{
+		{Name: "source", BitRate: 192, Codecs: "mp4a.40.2"},
+		{Name: "64k-he", BitRate: 64, Codecs: "mp4a.40.5"},
+	})
+
+	want := "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-INDEPENDENT-SEGMENTS\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=192000,CODECS=\"mp4a.40.2\"\n?rendition=source\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=64000,CODECS=\"mp4a.40.5\"\n?rendition=64k-he\n"
+	if got != want {
+		t.Errorf("MasterPlaylist() = %q, want %q", got, want)
+	}
+}
+
+func TestRenditionPath(t *testing.T) {
+	if got := RenditionPath("static/tmp/jazz/a0.ts", "128k"); got != "static/tmp/jazz/128k/a0.ts" {
+		t.Errorf("RenditionPath() = %q", got)
+	}
+}
+
+func TestGenerateRendition(t *testing.T) {
+	current := []*Segment{
+		{Duration: 5.0, Path: "tmp/a0.ts", IsFirst: true},
+		{Duration: 5.0, Path: "tmp/a1.ts"},
+	}
+	next := []*Segment{{Duration: 5.0, Path: "tmp/b0.ts", IsFirst: true}}
+	playlist := NewPlaylist(current, next)
+
+	main := playlist.Generate(6)
+	rendition := playlist.GenerateRendition(6, "64k")
+
+	if !strings.Contains(rendition, "\ntmp/64k/a1.ts\n") || !strings.Contains(rendition, "\ntmp/64k/b0.ts\n") {
+		t.Errorf("Expected rendition segments, got: %s", rendition)
+	}
+	if strings.Contains(rendition, "\ntmp/a1.ts\n") {
+		t.Errorf("Expected no main segments, got: %s", rendition)
+	}
+
+	// Both playlists are generated in lockstep, so their sequences match
+	if playlist.MediaSequence() != 1 {
+		t.Errorf("Expected media sequence 1, got %d", playlist.MediaSequence())
+	}
+	if headerLine(main, "#EXT-X-MEDIA-SEQUENCE") != headerLine(rendition, "#EXT-X-MEDIA-SEQUENCE") {
+		t.Errorf("Expected the same media sequence in both playlists")
+	}
+}
+
+func headerLine(playlist, tag string) string {
+	for line := range strings.SplitSeq(playlist, "\n") {
+		if strings.HasPrefix(line, tag) {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+package hls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata describes the track a segment belongs to, as players show it while the segment plays.
+type Metadata struct {
+	ID         string // The unique identifier of the track.
+	Title      string // The title of the track.
+	Artist     string // The artist of the track, empty if unknown.
+	ArtworkURL string // The URL of the picture shown along with the track, empty if there is none.
+}
+
+// EmbedMetadata embeds the metadata as a timed ID3 tag at the beginning of a segment file, so players
+// show it once they play the segment. MPEG-TS segments carry the tag in a metadata stream, fragmented MP4
+// segments in an event message box. A segment that already has a tag is left as it is.
+//
+// The file is replaced rather than rewritten, so the copies it's linked to, such as cached segments, stay intact.
+//
+// Parameters:
+//   - path: The path of the segment file.
+//   - container: The format of the segment file.
+//   - meta: The metadata to embed.
+//
+// Returns:
+//   - An error if the segment can't be read, parsed or saved.
+func EmbedMetadata(path string, container Container, meta Metadata) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tag := id3Tag(meta)
+	var embedded []byte
+	if container == ContainerFMP4 {
+		embedded, err = embedEventMessage(data, tag)
+	} else {
+		embedded, err = embedTSMetadata(data, tag)
+	}
+	if err != nil || embedded == nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(embedded)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// id3Tag builds an ID3v2.4 tag with the title and the artist of the track and the URL of its artwork.
+func id3Tag(meta Metadata) []byte {
+	var frames bytes.Buffer
+	frames.Write(id3Frame("TIT2", append([]byte{id3EncodingUTF8}, meta.Title...)))
+	if meta.Artist != "" {
+		frames.Write(id3Frame("TPE1", append([]byte{id3EncodingUTF8}, meta.Artist...)))
+	}
+	if meta.ArtworkURL != "" {
+		data := append([]byte{id3EncodingUTF8}, id3ArtworkDescription...)
+		data = append(append(data, 0), meta.ArtworkURL...)
+		frames.Write(id3Frame("WXXX", data))
+	}
+
+	tag := []byte{'I', 'D', '3', 4, 0, 0}
+	tag = append(tag, syncsafe(frames.Len())...)
+
+	return append(tag, frames.Bytes()...)
+}
+
+// id3Frame builds an ID3v2.4 frame with the given identifier and content.
+func id3Frame(id string, data []byte) []byte {
+	frame := append([]byte(id), syncsafe(len(data))...)
+	frame = append(frame, 0, 0)
+
+	return append(frame, data...)
+}
+
+// syncsafe encodes a size as a 4-byte integer with the most significant bit of every byte cleared.
+func syncsafe(size int) []byte {
+	return []byte{byte(size>>21) & 0x7f, byte(size>>14) & 0x7f, byte(size>>7) & 0x7f, byte(size) & 0x7f}
+}
+
+// embedEventMessage puts an event message box with the ID3 tag before the first movie fragment
+// of a fragmented MP4 segment. The event starts with the segment and lasts until the next one.
+// Nothing is returned if the segment already has an event message.
+func embedEventMessage(data, tag []byte) ([]byte, error) {
+	offset := 0
+	for offset+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		boxType := string(data[offset+4 : offset+8])
+		if boxType == "emsg" {
+			return nil, nil
+		}
+		if boxType == "moof" {
+			break
+		}
+		if size < 8 || offset+size > len(data) {
+			return nil, errors.New("malformed MP4 box")
+		}
+		offset += size
+	}
+	if offset+8 > len(data) {
+		return nil, errors.New("no movie fragment in the segment")
+	}
+
+	var box bytes.Buffer
+	box.Write(make([]byte, 4)) // Size, filled in below
+	box.WriteString("emsg")
+	box.Write([]byte{0, 0, 0, 0})              // Version 0 and flags
+	box.WriteString(id3SchemeURI + "\x00\x00") // The scheme and an empty value
+
+	// The timescale, the delta from the start of the segment, the unknown duration and the ID.
+	// The ID is derived from the tag, so the same tag is the same event for players.
+	for _, field := range []uint32{1000, 0, 0xffffffff, crc32.ChecksumIEEE(tag)} {
+		box.Write(binary.BigEndian.AppendUint32(nil, field))
+	}
+	box.Write(tag)
+
+	emsg := box.Bytes()
+	binary.BigEndian.PutUint32(emsg, uint32(len(emsg)))
+
+	embedded := make([]byte, 0, len(data)+len(emsg))
+	embedded = append(embedded, data[:offset]...)
+	embedded = append(embedded, emsg...)
+
+	return append(embedded, data[offset:]...), nil
+}
+
+// hlsDateRange generates a DATERANGE tag for a track that started playing at the given time.
+// It ends where the range of the next track starts.
+func hlsDateRange(meta Metadata, start time.Time) string {
+	tag := "#EXT-X-DATERANGE:ID=\"" + quotedString(meta.ID) + "-" + strconv.FormatInt(start.UnixMilli(), 10) + "\"" +
+		",CLASS=\"" + trackDateRangeClass + "\"" +
+		",START-DATE=\"" + formatTime(start) + "\"" +
+		",END-ON-NEXT=YES" +
+		",X-TITLE=\"" + quotedString(meta.Title) + "\""
+	if meta.Artist != "" {
+		tag += ",X-ARTIST=\"" + quotedString(meta.Artist) + "\""
+	}
+	if meta.ArtworkURL != "" {
+		tag += ",X-ARTWORK-URL=\"" + quotedString(meta.ArtworkURL) + "\""
+	}
+
+	return tag + "\n"
+}
+
+// quotedString makes the value fit in a quoted string attribute, which can't hold double quotes and line breaks.
+func quotedString(value string) string {
+	return strings.NewReplacer("\"", "'", "\r", " ", "\n", " ").Replace(value)
+}
+
+// formatTime formats the time the way playlist tags expect it.
+func formatTime(t time.Time) string {
+	return t.UTC().Round(time.Millisecond).Format(timeFormat)
+}
+
+// sameTrack reports whether the segments with the given metadata belong to the same track.
+func sameTrack(a, b *Metadata) bool {
+	return a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,254 @@
+package hls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withoutDateTimes drops the PROGRAM-DATE-TIME tags from a playlist, since they depend on the current time.
+func withoutDateTimes(playlist string) string {
+	kept := ""
+	for line := range strings.Lines(playlist) {
+		if !strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:") {
+			kept += line
+		}
+	}
+
+	return kept
+}
+
+// testTSSegment builds an MPEG-TS segment with a program association table, a program map table
+// with a single audio stream, and an audio PES packet with the given timestamp.
+func testTSSegment(pts int64) []byte {
+	pat := []byte{0x00, 0xb0, 0x0d, 0x00, 0x01, 0xc1, 0x00, 0x00, 0x00, 0x01, 0xf0, 0x00}
+	pat = binary.BigEndian.AppendUint32(pat, crc32MPEG2(pat))
+	pmt := []byte{0x02, 0xb0, 0x12, 0x00, 0x01, 0xc1, 0x00, 0x00, 0xe1, 0x00, 0xf0, 0x00, 0x0f, 0xe1, 0x00, 0xf0, 0x00}
+	pmt = binary.BigEndian.AppendUint32(pmt, crc32MPEG2(pmt))
+
+	segment := psiPacket(0, 0, pat)
+	segment = append(segment, psiPacket(0x1000, 0, pmt)...)
+	audio := pesPackets(0x100, pts, bytes.Repeat([]byte{0xaa}, 300))
+	audio[7] = 0xc0 // An audio stream ID instead of the private one
+
+	return append(segment, audio...)
+}
+
+func TestCRC32MPEG2(t *testing.T) {
+	// The program association table FFmpeg writes by default
+	pat := []byte{0x00, 0xb0, 0x0d, 0x00, 0x01, 0xc1, 0x00, 0x00, 0x00, 0x01, 0xf0, 0x00}
+	if got := crc32MPEG2(pat); got != 0x2ab104b2 {
+		t.Errorf("expected 0x2ab104b2, got %#x", got)
+	}
+}
+
+func TestID3Tag(t *testing.T) {
+	tag := id3Tag(Metadata{Title: "Song", Artist: "Band", ArtworkURL: "http://a/b.png"})
+
+	if !bytes.HasPrefix(tag, []byte{'I', 'D', '3', 4, 0, 0}) {
+		t.Fatalf("expected an ID3v2.4 header, got %v", tag[:6])
+	}
+
+	title := []byte{'T', 'I', 'T', '2', 0, 0, 0, 5, 0, 0, id3EncodingUTF8, 'S', 'o', 'n', 'g'}
+	if !bytes.Contains(tag, title) {
+		t.Errorf("expected a title frame in %q", tag)
+	}
+	if !bytes.Contains(tag, []byte("TPE1")) || !bytes.Contains(tag, []byte("WXXX\x00\x00\x00\x17\x00\x00\x03artwork\x00http://a/b.png")) {
+		t.Errorf("expected artist and artwork frames in %q", tag)
+	}
+
+	size := int(tag[6])<<21 | int(tag[7])<<14 | int(tag[8])<<7 | int(tag[9])
+	if size != len(tag)-10 {
+		t.Errorf("expected size %d, got %d", len(tag)-10, size)
+	}
+
+	if bytes.Contains(id3Tag(Metadata{Title: "Song"}), []byte("TPE1")) {
+		t.Error("expected no artist frame without an artist")
+	}
+}
+
+func TestEmbedTSMetadata(t *testing.T) {
+	segment := testTSSegment(900000)
+	tag := id3Tag(Metadata{Title: "Song"})
+
+	embedded, err := embedTSMetadata(segment, tag)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(embedded) != len(segment)+tsPacketSize {
+		t.Fatalf("expected one more packet, got %d bytes", len(embedded))
+	}
+
+	pmt, ok := psiSection(tsPacket(embedded[tsPacketSize : 2*tsPacketSize]).payload())
+	if !ok {
+		t.Fatal("expected a program map table in the second packet")
+	}
+	if crc32MPEG2(pmt) != 0 {
+		t.Error("expected a valid checksum of the program map table")
+	}
+	if pmt[5]>>1&0x1f != 1 {
+		t.Errorf("expected the version of the table to be incremented, got %d", pmt[5]>>1&0x1f)
+	}
+	pids, hasMetadata := programStreams(pmt)
+	if !hasMetadata || len(pids) != 2 || pids[1] != 0x101 {
+		t.Errorf("expected a metadata stream with PID 0x101, got %v", pids)
+	}
+
+	metadata := tsPacket(embedded[2*tsPacketSize : 3*tsPacketSize])
+	if metadata.pid() != 0x101 || !metadata.unitStart() {
+		t.Fatalf("expected the metadata packet after the table, got PID %#x", metadata.pid())
+	}
+	payload := metadata.payload()
+	if payload[3] != tsPrivateStreamID || pesTimestamp(payload) != 900000 {
+		t.Errorf("expected a private PES packet timed at 900000, got stream %#x at %d", payload[3], pesTimestamp(payload))
+	}
+	if !bytes.HasSuffix(payload, tag) {
+		t.Error("expected the packet to end with the tag")
+	}
+
+	again, err := embedTSMetadata(embedded, tag)
+	if err != nil || again != nil {
+		t.Errorf("expected a segment with metadata to be left as it is, got %d bytes and %v", len(again), err)
+	}
+
+	if _, err := embedTSMetadata([]byte("not a segment"), tag); err == nil {
+		t.Error("expected an error for data that isn't MPEG-TS")
+	}
+}
+
+func TestPESPacketsSplit(t *testing.T) {
+	packets := pesPackets(0x101, 0, bytes.Repeat([]byte{1}, 400))
+	if len(packets)%tsPacketSize != 0 || len(packets)/tsPacketSize != 3 {
+		t.Fatalf("expected 3 packets, got %d bytes", len(packets))
+	}
+
+	for i := range 3 {
+		pkt := tsPacket(packets[i*tsPacketSize : (i+1)*tsPacketSize])
+		if pkt[0] != 0x47 || pkt.continuity() != byte(i) || pkt.unitStart() != (i == 0) {
+			t.Errorf("packet %d: unexpected header %v", i, pkt[:4])
+		}
+	}
+}
+
+func TestEmbedEventMessage(t *testing.T) {
+	styp := []byte{0, 0, 0, 8, 's', 't', 'y', 'p'}
+	moof := []byte{0, 0, 0, 8, 'm', 'o', 'o', 'f'}
+	segment := append(append([]byte{}, styp...), moof...)
+	tag := id3Tag(Metadata{Title: "Song"})
+
+	embedded, err := embedEventMessage(segment, tag)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(embedded, styp) || !bytes.HasSuffix(embedded, moof) {
+		t.Fatal("expected the event message between the boxes")
+	}
+
+	emsg := embedded[len(styp) : len(embedded)-len(moof)]
+	if string(emsg[4:8]) != "emsg" || int(binary.BigEndian.Uint32(emsg)) != len(emsg) {
+		t.Errorf("expected an emsg box of %d bytes, got %q", len(emsg), emsg[:8])
+	}
+	if !bytes.Contains(emsg, []byte(id3SchemeURI)) || !bytes.HasSuffix(emsg, tag) {
+		t.Error("expected the ID3 scheme and the tag in the box")
+	}
+
+	if again, err := embedEventMessage(embedded, tag); err != nil || again != nil {
+		t.Errorf("expected a segment with an event message to be left as it is, got %v", err)
+	}
+	if _, err := embedEventMessage(styp, tag); err == nil {
+		t.Error("expected an error for a segment without a movie fragment")
+	}
+}
+
+func TestEmbedMetadataReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	cached := filepath.Join(dir, "cached.ts")
+	linked := filepath.Join(dir, "linked.ts")
+
+	segment := testTSSegment(0)
+	if err := os.WriteFile(cached, segment, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(cached, linked); err != nil {
+		t.Skip("hard links are not supported: " + err.Error())
+	}
+
+	if err := EmbedMetadata(linked, ContainerTS, Metadata{Title: "Song"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if data, _ := os.ReadFile(linked); len(data) != len(segment)+tsPacketSize {
+		t.Errorf("expected the metadata in the segment, got %d bytes", len(data))
+	}
+	if data, _ := os.ReadFile(cached); !bytes.Equal(data, segment) {
+		t.Error("expected the linked copy to stay intact")
+	}
+}
+
+func TestGenerateMetadataTags(t *testing.T) {
+	a := &Metadata{ID: "a", Title: "Song \"A\"", Artist: "Band"}
+	b := &Metadata{ID: "b", Title: "Song B"}
+	current := []*Segment{
+		{Duration: 5.0, Path: "a0.ts", IsFirst: true, Meta: a},
+		{Duration: 5.0, Path: "a1.ts", Meta: a},
+	}
+	next := []*Segment{
+		{Duration: 4.0, Path: "t0.ts", IsFirst: true},
+		{Duration: 5.0, Path: "b0.ts", IsFirst: true, Meta: b},
+	}
+	playlist := NewPlaylist(current, next)
+	playlist.LiveSegmentsAmount = 4
+
+	got := playlist.Generate(0)
+	timeline := playlist.Timeline()
+	start := timeline[0].Start
+
+	for i, offset := range []float64{0, 5, 10, 14} {
+		tag := "#EXT-X-PROGRAM-DATE-TIME:" + formatTime(start.Add(seconds(offset))) + "\n"
+		if !strings.Contains(got, tag) {
+			t.Errorf("segment %d: expected %q in:\n%s", i, tag, got)
+		}
+	}
+
+	rangeA := "#EXT-X-DATERANGE:ID=\"a-" + strconv.FormatInt(start.UnixMilli(), 10) + "\",CLASS=\"com.airstation.track\",START-DATE=\"" +
+		formatTime(start) + "\",END-ON-NEXT=YES,X-TITLE=\"Song 'A'\",X-ARTIST=\"Band\"\n"
+	if !strings.Contains(got, rangeA) {
+		t.Errorf("expected %q in:\n%s", rangeA, got)
+	}
+	if strings.Count(got, "#EXT-X-DATERANGE:") != 2 || !strings.Contains(got, "START-DATE=\""+formatTime(start.Add(14*time.Second))+"\"") {
+		t.Errorf("expected the next track to start after the transition, got:\n%s", got)
+	}
+
+	// The range of a track keeps its start once the playlist moves on
+	playlist.Generate(5)
+	if got := playlist.Generate(5); !strings.Contains(got, rangeA) {
+		t.Errorf("expected %q in:\n%s", rangeA, got)
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	a := &Metadata{ID: "a", Title: "A"}
+	b := &Metadata{ID: "b", Title: "B"}
+	current := []*Segment{
+		{Duration: 5.0, Path: "a0.ts", IsFirst: true, Meta: a},
+		{Duration: 5.0, Path: "a1.ts", Meta: a},
+		{Duration: 5.0, Path: "a2.ts", Meta: a},
+	}
+	next := []*Segment{{Duration: 5.0, Path: "b0.ts", IsFirst: true, Meta: b}}
+	playlist := NewPlaylist(current, next)
+
+	if got := playlist.Announce(0); len(got) != 1 || got[0].Path != "a0.ts" {
+		t.Fatalf("expected the first segment of the current track, got %v", got)
+	}
+	if got := playlist.Announce(0); len(got) != 0 {
+		t.Errorf("expected no segments for announced tracks, got %v", got)
+	}
+	if got := playlist.Announce(5); len(got) != 1 || got[0].Path != "b0.ts" {
+		t.Errorf("expected the first segment of the next track, got %v", got)
+	}
+}
@@ -0,0 +1,321 @@
+package hls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+// tsMetadataDescriptor is the descriptor of a metadata stream that carries ID3 tags.
+var tsMetadataDescriptor = []byte{0x26, 13, 0xff, 0xff, 'I', 'D', '3', ' ', 0xff, 'I', 'D', '3', ' ', 0x00, 0x0f}
+
+// tsPacket is an MPEG-TS packet.
+type tsPacket []byte
+
+func (p tsPacket) pid() int {
+	return int(p[1]&0x1f)<<8 | int(p[2])
+}
+
+func (p tsPacket) unitStart() bool {
+	return p[1]&0x40 != 0
+}
+
+func (p tsPacket) continuity() byte {
+	return p[3] & 0x0f
+}
+
+// payload returns the data that follows the header and the adaptation field of the packet.
+func (p tsPacket) payload() []byte {
+	control := p[3] >> 4 & 0x03
+	if control&0x01 == 0 {
+		return nil
+	}
+
+	offset := 4
+	if control&0x02 != 0 {
+		offset += 1 + int(p[4])
+	}
+	if offset >= len(p) {
+		return nil
+	}
+
+	return p[offset:]
+}
+
+// pesStart is a PES packet that starts in an MPEG-TS segment.
+type pesStart struct {
+	offset int64 // The offset of the MPEG-TS packet the PES packet starts in
+	pts    int64 // The presentation timestamp of the PES packet
+}
+
+// audioPESStarts returns the timed PES packets of the first audio stream of an MPEG-TS segment, in order.
+// Audio streams are told apart by the stream IDs of their PES packets, so the program tables aren't needed.
+func audioPESStarts(data []byte) []pesStart {
+	if len(data)%tsPacketSize != 0 {
+		return nil
+	}
+
+	audioPID := -1
+	var starts []pesStart
+	for offset := 0; offset < len(data); offset += tsPacketSize {
+		pkt := tsPacket(data[offset : offset+tsPacketSize])
+		if pkt[0] != 0x47 || !pkt.unitStart() || (audioPID >= 0 && pkt.pid() != audioPID) {
+			continue
+		}
+
+		payload := pkt.payload()
+		if len(payload) < 4 || payload[3] < tsFirstAudioStreamID || payload[3] > tsLastAudioStreamID {
+			continue
+		}
+
+		pts := pesTimestamp(payload)
+		if pts < 0 {
+			continue
+		}
+
+		audioPID = pkt.pid()
+		starts = append(starts, pesStart{offset: int64(offset), pts: pts})
+	}
+
+	return starts
+}
+
+// embedTSMetadata adds a metadata stream to an MPEG-TS segment and puts the ID3 tag into it right after
+// the first program map table, timed with the first audio frame. The stream is declared the way FFmpeg
+// declares timed ID3 metadata. Nothing is returned if the segment already has a metadata stream.
+func embedTSMetadata(data, tag []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%tsPacketSize != 0 || data[0] != 0x47 {
+		return nil, errors.New("not an MPEG-TS segment")
+	}
+
+	pmtPID := -1
+	pmtOffset := -1
+	var pmt []byte
+	var streams []int
+	pts := int64(-1)
+	used := make(map[int]bool)
+
+	for offset := 0; offset < len(data); offset += tsPacketSize {
+		pkt := tsPacket(data[offset : offset+tsPacketSize])
+		pid := pkt.pid()
+		used[pid] = true
+		if !pkt.unitStart() {
+			continue
+		}
+
+		switch {
+		case pid == 0 && pmtPID < 0:
+			pmtPID = programMapPID(pkt.payload())
+		case pid == pmtPID && pmt == nil:
+			section, ok := psiSection(pkt.payload())
+			if !ok || section[0] != 0x02 {
+				continue
+			}
+
+			var hasMetadata bool
+			streams, hasMetadata = programStreams(section)
+			if hasMetadata {
+				return nil, nil
+			}
+			pmt = section
+			pmtOffset = offset
+		case pts < 0 && slices.Contains(streams, pid):
+			pts = pesTimestamp(pkt.payload())
+		}
+	}
+
+	if pmt == nil {
+		return nil, errors.New("no program map table in the segment")
+	}
+	if pts < 0 {
+		return nil, errors.New("no timestamps in the segment")
+	}
+
+	metadataPID := tsFirstElementaryPID
+	for used[metadataPID] {
+		metadataPID++
+	}
+
+	extended := withMetadataStream(pmt, metadataPID)
+	if 1+len(extended) > tsPacketSize-4 {
+		return nil, errors.New("program map table doesn't fit in a packet")
+	}
+
+	tagPackets := pesPackets(metadataPID, pts, tag)
+	embedded := make([]byte, 0, len(data)+len(tagPackets))
+	for offset := 0; offset < len(data); offset += tsPacketSize {
+		pkt := tsPacket(data[offset : offset+tsPacketSize])
+
+		// Repeated copies of the table declare the metadata stream as well
+		if section, ok := psiSection(pkt.payload()); ok && pkt.pid() == pmtPID && pkt.unitStart() && bytes.Equal(section, pmt) {
+			embedded = append(embedded, psiPacket(pmtPID, pkt.continuity(), extended)...)
+		} else {
+			embedded = append(embedded, pkt...)
+		}
+
+		if offset == pmtOffset {
+			embedded = append(embedded, tagPackets...)
+		}
+	}
+
+	return embedded, nil
+}
+
+// psiSection returns the table section that starts in the payload of a packet.
+func psiSection(payload []byte) ([]byte, bool) {
+	if len(payload) == 0 {
+		return nil, false
+	}
+
+	start := 1 + int(payload[0])
+	if start+3 > len(payload) {
+		return nil, false
+	}
+
+	length := int(payload[start+1]&0x0f)<<8 | int(payload[start+2])
+	end := start + 3 + length
+	if length < 9 || end > len(payload) {
+		return nil, false
+	}
+
+	return payload[start:end], true
+}
+
+// programMapPID returns the PID of the program map table of the first program listed in a program association table.
+func programMapPID(payload []byte) int {
+	section, ok := psiSection(payload)
+	if !ok || section[0] != 0x00 {
+		return -1
+	}
+
+	for i := 8; i+4 <= len(section)-4; i += 4 {
+		program := int(section[i])<<8 | int(section[i+1])
+		if program != 0 {
+			return int(section[i+2]&0x1f)<<8 | int(section[i+3])
+		}
+	}
+
+	return -1
+}
+
+// programStreams returns the PIDs of the streams listed in a program map table
+// and whether one of them is a metadata stream.
+func programStreams(section []byte) ([]int, bool) {
+	if len(section) < 16 {
+		return nil, false
+	}
+
+	pids := make([]int, 0, 1)
+	hasMetadata := false
+	end := len(section) - 4
+	pos := 12 + (int(section[10]&0x0f)<<8 | int(section[11]))
+
+	for pos+5 <= end {
+		if section[pos] == tsMetadataStreamType {
+			hasMetadata = true
+		}
+		pids = append(pids, int(section[pos+1]&0x1f)<<8|int(section[pos+2]))
+		pos += 5 + (int(section[pos+3]&0x0f)<<8 | int(section[pos+4]))
+	}
+
+	return pids, hasMetadata
+}
+
+// withMetadataStream returns a copy of a program map table that also lists a metadata stream with the given PID.
+// The version of the table is incremented, so demuxers notice the change.
+func withMetadataStream(section []byte, pid int) []byte {
+	table := slices.Clone(section[:len(section)-4])
+	table = append(table, tsMetadataStreamType, 0xe0|byte(pid>>8)&0x1f, byte(pid), 0xf0, byte(len(tsMetadataDescriptor)))
+	table = append(table, tsMetadataDescriptor...)
+
+	length := len(table) - 3 + 4
+	table[1] = table[1]&0xf0 | byte(length>>8)&0x0f
+	table[2] = byte(length)
+
+	version := (table[5]>>1 + 1) & 0x1f
+	table[5] = table[5]&0xc1 | version<<1
+
+	return binary.BigEndian.AppendUint32(table, crc32MPEG2(table))
+}
+
+// psiPacket packs a table section into a packet, the rest of the packet is filled with stuffing bytes.
+func psiPacket(pid int, continuity byte, section []byte) []byte {
+	pkt := []byte{0x47, 0x40 | byte(pid>>8)&0x1f, byte(pid), 0x10 | continuity&0x0f, 0}
+	pkt = append(pkt, section...)
+
+	return append(pkt, bytes.Repeat([]byte{0xff}, tsPacketSize-len(pkt))...)
+}
+
+// pesPackets packs the data into a PES packet with the given presentation timestamp
+// and splits it into MPEG-TS packets of the given PID.
+func pesPackets(pid int, pts int64, data []byte) []byte {
+	pes := []byte{0, 0, 1, tsPrivateStreamID, 0, 0, 0x84, 0x80, 5}
+	pes = append(pes, encodeTimestamp(pts)...)
+	pes = append(pes, data...)
+	binary.BigEndian.PutUint16(pes[4:], uint16(len(pes)-6))
+
+	packets := make([]byte, 0, (len(pes)/(tsPacketSize-4)+1)*tsPacketSize)
+	for continuity := 0; len(pes) > 0; continuity++ {
+		chunk := pes[:min(len(pes), tsPacketSize-4)]
+		pes = pes[len(chunk):]
+
+		pkt := []byte{0x47, byte(pid>>8) & 0x1f, byte(pid), 0x10 | byte(continuity)&0x0f}
+		if continuity == 0 {
+			pkt[1] |= 0x40
+		}
+
+		// The last packet is filled up with an adaptation field of stuffing bytes
+		if stuffing := tsPacketSize - 4 - len(chunk); stuffing > 0 {
+			pkt[3] |= 0x20
+			field := bytes.Repeat([]byte{0xff}, stuffing)
+			field[0] = byte(stuffing - 1)
+			if stuffing > 1 {
+				field[1] = 0
+			}
+			pkt = append(pkt, field...)
+		}
+
+		packets = append(append(packets, pkt...), chunk...)
+	}
+
+	return packets
+}
+
+// pesTimestamp returns the presentation timestamp of the PES packet that starts in the payload, or -1 if it has none.
+func pesTimestamp(payload []byte) int64 {
+	if len(payload) < 14 || payload[0] != 0 || payload[1] != 0 || payload[2] != 1 || payload[7]&0x80 == 0 {
+		return -1
+	}
+
+	ts := payload[9:14]
+	return int64(ts[0]>>1&0x07)<<30 | int64(ts[1])<<22 | int64(ts[2]>>1)<<15 | int64(ts[3])<<7 | int64(ts[4]>>1)
+}
+
+// encodeTimestamp encodes a presentation timestamp the way PES headers carry it when there is no decoding timestamp.
+func encodeTimestamp(pts int64) []byte {
+	return []byte{
+		0x20 | byte(pts>>29)&0x0e | 0x01,
+		byte(pts >> 22),
+		byte(pts>>14)&0xfe | 0x01,
+		byte(pts >> 7),
+		byte(pts<<1)&0xfe | 0x01,
+	}
+}
+
+// crc32MPEG2 computes the checksum that ends table sections.
+func crc32MPEG2(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
@@ -2,27 +2,43 @@
 package hls
 
 import (
-	"math"
+	"path/filepath"
 	"strconv"
 	"time"
 )
 
 // Playlist represents an HLS playlist structure.
 type Playlist struct {
-	LiveSegmentsAmount int // The number of live segments in the playlist.
-	MaxSegmentDuration int // The maximum duration (in seconds) of a segment in the playlist.
+	LiveSegmentsAmount int    // The number of live segments in the playlist.
+	MaxSegmentDuration int    // The maximum duration (in seconds) of a segment in the playlist.
+	URIPrefix          string // The prefix prepended to the path of every segment in the playlist.
+
+	// PartDuration is the duration (in seconds) of partial segments, which turns on Low-Latency HLS.
+	// It's 0 by default, which keeps the regular live window.
+	PartDuration float64
+
+	// DVRWindow is the duration (in seconds) of the played segments that stay listed before the live window,
+	// so listeners can seek back. It's 0 by default, which lists only the segments that haven't played yet.
+	DVRWindow float64
 
 	mediaSequence        int64
 	disconSequence       int64
-	lastDisconUpdate     time.Time
 	currentTrackSegments []*Segment
 	nextTrackSegments    []*Segment
-	currentSegmentPath   string
+	currentSegment       *Segment
+	currentStart         time.Time         // The time the current segment started playing
+	groupStart           time.Time         // The time the group of the current segment started playing
+	playedSegments       []TimedSegment    // The segments that played before the current one, oldest first
+	edgePart             int               // The index of the last part of the current segment in the last generated playlist
+	trackMeta            *Metadata         // The metadata of the track the current segment is a part of
+	trackStart           time.Time         // The time the track of the current segment started playing
+	announced            map[Metadata]bool // The tracks in the live window that were announced
+	shrinkingFrom        int               // The size of the live window while it shrinks to LiveSegmentsAmount
 }
 
 // NewPlaylist creates and returns a new Playlist instance with the provided current and next track segments.
-// It initializes the playlist with default values for live segments amount, max segment duration, media sequence,
-// discontinuity sequence, and last discontinuity update time.
+// It initializes the playlist with default values for live segments amount, max segment duration, media sequence
+// and discontinuity sequence.
 //
 // Parameters:
 //   - cur: The list of segments for the current track.
@@ -35,20 +51,20 @@ func NewPlaylist(cur, next []*Segment) *Playlist {
 		LiveSegmentsAmount: DefaultLiveSegmentsAmount,
 		MaxSegmentDuration: DefaultMaxSegmentDuration,
 
-		mediaSequence:    0,
-		disconSequence:   0,
-		lastDisconUpdate: time.Now(),
+		mediaSequence:  0,
+		disconSequence: 0,
 
 		currentTrackSegments: cur,
 		nextTrackSegments:    next,
 
-		currentSegmentPath: "",
+		currentSegment: nil,
 	}
 }
 
 // Generate constructs and returns the HLS playlist as a string based on the elapsed time.
-// It updates the discontinuity sequence, calculates the starting segment index, collects live segments,
-// and formats them into the HLS playlist format.
+// It calculates the starting segment index, collects live segments, updates the media and discontinuity
+// sequences when the first live segment changes, and formats them into the HLS playlist format.
+// Segments may have different durations, e.g. the last segment of a track is usually shorter.
 //
 // Parameters:
 //   - elapsedTime: The elapsed time in seconds used to determine the current segment index.
@@ -56,22 +72,55 @@ func NewPlaylist(cur, next []*Segment) *Playlist {
 // Returns:
 //   - A string representing the generated HLS playlist.
 func (p *Playlist) Generate(elapsedTime float64) string {
-	offset := math.Mod(elapsedTime, float64(p.MaxSegmentDuration))
+	return p.generate(elapsedTime, "")
+}
+
+// GenerateRendition constructs the HLS playlist of a rendition based on the elapsed time.
+// It lists the same segments with the same sequences as Generate, taken from the rendition
+// subdirectory, so players can switch between renditions at any segment.
+//
+// Parameters:
+//   - elapsedTime: The elapsed time in seconds used to determine the current segment index.
+//   - rendition: The name of the rendition.
+//
+// Returns:
+//   - A string representing the generated HLS playlist.
+func (p *Playlist) GenerateRendition(elapsedTime float64, rendition string) string {
+	return p.generate(elapsedTime, rendition)
+}
+
+func (p *Playlist) generate(elapsedTime float64, rendition string) string {
+	if p.PartDuration > 0 {
+		return p.generateLowLatency(elapsedTime, rendition)
+	}
+
+	_, start := p.segmentAt(elapsedTime)
+	offset := max(elapsedTime-start, 0)
 	liveSegments := p.currentSegments(elapsedTime)
-	prevSegmentPath := p.currentSegmentPath
 
 	if len(liveSegments) > 0 {
-		p.currentSegmentPath = liveSegments[0].Path
+		p.slideWindow(liveSegments[0], offset)
+	}
+
+	// Players start at the live position, past the segments listed for seeking back
+	played := p.timeshiftSegments()
+	for _, ts := range played {
+		offset += ts.Duration
 	}
 
-	p.UpdateDisconSequence(elapsedTime)
-	if prevSegmentPath != p.currentSegmentPath {
-		p.mediaSequence++
+	mediaSeq, disconSeq := p.sequencesBefore(played)
+	playlist := hlsHeader(p.MaxSegmentDuration, mediaSeq, disconSeq, offset)
+	var prev *TimedSegment
+	for _, ts := range played {
+		playlist += p.segmentTags(ts, prev, rendition)
+		playlist += hlsSegment(ts.Duration, p.segmentURI(ts.Segment, rendition), false)
+		prev = &ts
 	}
 
-	playlist := hlsHeader(p.MaxSegmentDuration, p.mediaSequence, p.disconSequence, offset)
-	for _, seg := range liveSegments {
-		playlist += hlsSegment(seg.Duration, seg.Path, seg.IsFirst)
+	for _, ts := range p.timedWindow(liveSegments) {
+		playlist += p.segmentTags(ts, prev, rendition)
+		playlist += hlsSegment(ts.Duration, p.segmentURI(ts.Segment, rendition), false)
+		prev = &ts
 	}
 
 	return playlist
@@ -95,6 +144,92 @@ func (p *Playlist) ChangeNext(next []*Segment) {
 	p.nextTrackSegments = next
 }
 
+// ChangeCurrent replaces segments for the current track. The segments that have already been
+// exposed to listeners are expected to stay the same, only the upcoming ones may differ.
+//
+// Parameters:
+//   - cur: The new list of segments to be set as the current track segments.
+func (p *Playlist) ChangeCurrent(cur []*Segment) {
+	p.currentTrackSegments = cur
+}
+
+// TrimCurrent drops the current track segments that are not exposed in the live window yet,
+// so the next track segments follow right after the ones listeners may have already loaded.
+//
+// Parameters:
+//   - elapsedTime: The elapsed time in seconds used to determine the current segment index.
+func (p *Playlist) TrimCurrent(elapsedTime float64) {
+	index, _ := p.segmentAt(elapsedTime)
+	end := index + p.WindowSize()
+	if end < len(p.currentTrackSegments) {
+		p.currentTrackSegments = p.currentTrackSegments[:end]
+	}
+}
+
+// CurrentDuration returns the total duration (in seconds) of the current track segments.
+func (p *Playlist) CurrentDuration() float64 {
+	total := 0.0
+	for _, seg := range p.currentTrackSegments {
+		total += seg.Duration
+	}
+
+	return total
+}
+
+// PublishedUntil returns the position (in seconds) of the current track segments until which
+// they are exposed in the live window at the given elapsed time.
+//
+// Parameters:
+//   - elapsedTime: The elapsed time in seconds used to determine the current segment index.
+func (p *Playlist) PublishedUntil(elapsedTime float64) float64 {
+	index, position := p.segmentAt(elapsedTime)
+	end := min(index+p.WindowSize(), len(p.currentTrackSegments))
+
+	for _, seg := range p.currentTrackSegments[index:end] {
+		position += seg.Duration
+	}
+
+	return position
+}
+
+// AppendCurrent appends the provided segments to the current track segments list.
+// It is used for live inputs whose segments become available while they are playing.
+//
+// Parameters:
+//   - segments: The list of segments to append to the current track segments.
+func (p *Playlist) AppendCurrent(segments []*Segment) {
+	p.currentTrackSegments = append(p.currentTrackSegments, segments...)
+}
+
+// SpliceCurrent replaces the current track segments that start within the [from, to) range with the provided ones,
+// e.g. to air a voice-over mixed with the music. The first segment after the replaced range is marked as the
+// first one, so a discontinuity tag is put before it.
+//
+// Parameters:
+//   - from: The position (in seconds) of the current track segments where the replacement starts.
+//   - to: The position (in seconds) of the current track segments where the replacement ends.
+//   - segments: The segments to put in place of the replaced ones.
+func (p *Playlist) SpliceCurrent(from, to float64, segments []*Segment) {
+	head := make([]*Segment, 0, len(p.currentTrackSegments)+len(segments))
+	tail := make([]*Segment, 0, len(p.currentTrackSegments))
+	position := 0.0
+
+	for _, seg := range p.currentTrackSegments {
+		if position < from {
+			head = append(head, seg)
+		} else if position >= to {
+			tail = append(tail, seg)
+		}
+		position += seg.Duration
+	}
+
+	if len(tail) > 0 && !tail[0].IsFirst {
+		tail[0] = tail[0].AsFirst()
+	}
+
+	p.currentTrackSegments = append(append(head, segments...), tail...)
+}
+
 // AddSegments appends the provided segments to the next track segments list.
 //
 // Parameters:
@@ -108,26 +243,75 @@ func (p *Playlist) SetMediaSequence(sequence int64) {
 	p.mediaSequence = sequence
 }
 
-// UpdateDisconSequence updates the discontinuity sequence if a discontinuity is detected.
-//
-// Parameters:
-//   - elapsedTime: The elapsed time in seconds used to calculate the current segment index.
-func (p *Playlist) UpdateDisconSequence(elapsedTime float64) {
-	elapsedFromLastUpdate := time.Until(p.lastDisconUpdate).Seconds()
-	if math.Abs(elapsedFromLastUpdate) < float64(p.MaxSegmentDuration) {
+// SetDisconSequence set a new sequence number for disconSequence.
+func (p *Playlist) SetDisconSequence(sequence int64) {
+	p.disconSequence = sequence
+}
+
+// MediaSequence returns the media sequence of the last generated playlist.
+func (p *Playlist) MediaSequence() int64 {
+	return p.mediaSequence
+}
+
+// DisconSequence returns the discontinuity sequence of the last generated playlist.
+func (p *Playlist) DisconSequence() int64 {
+	return p.disconSequence
+}
+
+// slideWindow moves the start of the live window to the given segment. Every time the window moves,
+// the media sequence is incremented, and if the segment that just left the playlist was preceded by
+// a discontinuity tag, the discontinuity sequence is incremented as well. The segment that left is kept
+// among the played ones, which Low-Latency playlists list before the current one.
+// The segment starts playing right after the one that left, or offset seconds ago if it's the first one.
+func (p *Playlist) slideWindow(first *Segment, offset float64) {
+	prev := p.currentSegment
+	if prev != nil && prev.Path == first.Path {
 		return
 	}
 
-	index := p.calcCurrentSegmentIndex(elapsedTime)
+	start := time.Now().Add(-seconds(offset))
+	if prev != nil {
+		start = p.currentStart.Add(seconds(prev.Duration))
+		p.playedSegments = append(p.playedSegments, p.timed(prev))
 
-	// if the current track segment is the second and it is not the very first track,
-	// there was a discontinuty, so we increment the discontinuty counter
-	if index == 1 && p.mediaSequence > 1 {
+		// Low-Latency playlists list as many played segments as there are live ones
+		if drop := min(p.timeshiftStart(), len(p.playedSegments)-p.LiveSegmentsAmount); drop > 0 {
+			p.playedSegments = p.playedSegments[drop:]
+		}
+		if p.shrinkingFrom > p.LiveSegmentsAmount {
+			p.shrinkingFrom--
+		}
+	}
+
+	if prev == nil || first.IsFirst || first.Init != prev.Init {
+		p.groupStart = start
+	}
+	if first.Meta != nil && !sameTrack(first.Meta, p.trackMeta) {
+		p.trackMeta = first.Meta
+		p.trackStart = start
+	}
+	p.currentSegment = first
+	p.currentStart = start
+	p.mediaSequence++
+
+	if prev != nil && prev.IsFirst {
 		p.disconSequence++
-		p.lastDisconUpdate = time.Now()
 	}
 }
 
+// PlayingSegment returns the segment that plays at the given elapsed time, or nil if there is none.
+//
+// Parameters:
+//   - elapsedTime: The elapsed time in seconds used to determine the current segment index.
+func (p *Playlist) PlayingSegment(elapsedTime float64) *Segment {
+	segments := p.currentSegments(elapsedTime)
+	if len(segments) == 0 {
+		return nil
+	}
+
+	return segments[0]
+}
+
 func (p *Playlist) FirstNextTrackSegment() *Segment {
 	if len(p.nextTrackSegments) > 0 {
 		return p.nextTrackSegments[0]
@@ -136,13 +320,90 @@ func (p *Playlist) FirstNextTrackSegment() *Segment {
 	return nil
 }
 
-// currentSegments gathers enough segments from current and next tracks to meet liveSegmentsAmount
+// SetLiveSegmentsAmount changes the number of live segments in the playlist. A larger window is applied at once,
+// while a smaller one shrinks by a segment every time the window moves, so the segments that have already been
+// listed stay listed until they play.
+//
+// Parameters:
+//   - amount: The new number of live segments.
+func (p *Playlist) SetLiveSegmentsAmount(amount int) {
+	p.shrinkingFrom = p.WindowSize()
+	p.LiveSegmentsAmount = amount
+}
+
+// SetMaxSegmentDuration raises the target duration of the playlist to fit segments of the given duration.
+// It's never lowered, since players don't expect the target duration of a live playlist to change, and the
+// longer segments made before may still be listed.
+//
+// Parameters:
+//   - duration: The duration (in seconds) of the segments that are made from now on.
+func (p *Playlist) SetMaxSegmentDuration(duration int) {
+	p.MaxSegmentDuration = max(p.MaxSegmentDuration, duration)
+}
+
+// WindowSize returns the number of segments in the live window at the moment, which differs from
+// LiveSegmentsAmount while the window shrinks.
+func (p *Playlist) WindowSize() int {
+	return max(p.LiveSegmentsAmount, p.shrinkingFrom)
+}
+
+// PlayedFiles returns the names of the files of the played segments the playlist keeps listing,
+// init segments included, so they aren't removed while listeners may load them.
+func (p *Playlist) PlayedFiles() []string {
+	files := make([]string, 0, len(p.playedSegments))
+	for _, ts := range p.playedSegments {
+		files = append(files, filepath.Base(ts.Path))
+		if ts.Init != "" {
+			files = append(files, filepath.Base(ts.Init))
+		}
+	}
+
+	return files
+}
+
+// timeshiftSegments returns the played segments within the DVR window, oldest first.
+func (p *Playlist) timeshiftSegments() []TimedSegment {
+	if p.DVRWindow <= 0 {
+		return nil
+	}
+
+	return p.playedSegments[p.timeshiftStart():]
+}
+
+// timeshiftStart returns the index of the oldest played segment that fits in the DVR window.
+func (p *Playlist) timeshiftStart() int {
+	total := 0.0
+	for i := len(p.playedSegments) - 1; i >= 0; i-- {
+		total += p.playedSegments[i].Duration
+		if total > p.DVRWindow {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// sequencesBefore returns the media and discontinuity sequences of a playlist that lists
+// the given played segments before the current one.
+func (p *Playlist) sequencesBefore(played []TimedSegment) (int64, int64) {
+	disconSeq := p.disconSequence
+	for _, seg := range played {
+		if seg.IsFirst {
+			disconSeq--
+		}
+	}
+
+	return p.mediaSequence - int64(len(played)), disconSeq
+}
+
+// currentSegments gathers enough segments from current and next tracks to fill the live window
 func (p *Playlist) currentSegments(elapsedTime float64) []*Segment {
-	startIndex := p.calcCurrentSegmentIndex(elapsedTime)
-	liveSegments := make([]*Segment, 0, p.LiveSegmentsAmount)
+	startIndex, _ := p.segmentAt(elapsedTime)
+	size := p.WindowSize()
+	liveSegments := make([]*Segment, 0, size)
 
 	if startIndex < len(p.currentTrackSegments) {
-		endIndex := startIndex + p.LiveSegmentsAmount
+		endIndex := startIndex + size
 		if endIndex >= len(p.currentTrackSegments) {
 			endIndex = len(p.currentTrackSegments)
 		}
@@ -150,24 +411,71 @@ func (p *Playlist) currentSegments(elapsedTime float64) []*Segment {
 		liveSegments = append(liveSegments, p.currentTrackSegments[startIndex:endIndex]...)
 	}
 
-	if len(liveSegments) < p.LiveSegmentsAmount {
-		required := p.LiveSegmentsAmount - len(liveSegments)
+	if len(liveSegments) < size {
+		required := size - len(liveSegments)
 		liveSegments = append(liveSegments, p.nextTrackSegments[:min(len(p.nextTrackSegments), required)]...)
 	}
 
 	return liveSegments
 }
 
-func (p *Playlist) calcCurrentSegmentIndex(elapsedTime float64) int {
-	return int(math.Floor(elapsedTime / float64(p.MaxSegmentDuration)))
+// segmentAt returns the index of the current track segment that plays at the given elapsed time
+// and the position where it starts. Past the end of the current track segments, their number
+// and total duration are returned.
+func (p *Playlist) segmentAt(elapsedTime float64) (int, float64) {
+	start := 0.0
+	for i, seg := range p.currentTrackSegments {
+		if elapsedTime < start+seg.Duration {
+			return i, start
+		}
+		start += seg.Duration
+	}
+
+	return len(p.currentTrackSegments), start
+}
+
+// segmentFile returns the path of the segment file in the given rendition.
+func (p *Playlist) segmentFile(seg *Segment, rendition string) string {
+	if rendition != "" {
+		return RenditionPath(seg.Path, rendition)
+	}
+	return seg.Path
+}
+
+// segmentURI returns the URI of the segment in the given rendition.
+func (p *Playlist) segmentURI(seg *Segment, rendition string) string {
+	return p.URIPrefix + p.segmentFile(seg, rendition)
+}
+
+// segmentTags generates the tags that precede a segment: the discontinuity tag if it's the first segment
+// in the track, the EXT-X-MAP tag if it needs an init segment other than the previous listed segment,
+// the time the segment plays at, and the DATERANGE tag if its track starts with it or it's the first listed segment.
+func (p *Playlist) segmentTags(ts TimedSegment, prev *TimedSegment, rendition string) string {
+	tags := ""
+	if ts.IsFirst {
+		tags += "#EXT-X-DISCONTINUITY\n"
+	}
+
+	if ts.Init != "" && (prev == nil || ts.Init != prev.Init) {
+		init := ts.Init
+		if rendition != "" {
+			init = RenditionPath(init, rendition)
+		}
+		tags += "#EXT-X-MAP:URI=\"" + p.URIPrefix + init + "\"\n"
+	}
+
+	tags += "#EXT-X-PROGRAM-DATE-TIME:" + formatTime(ts.Start) + "\n"
+	if ts.Track != nil && (prev == nil || !sameTrack(ts.Track, prev.Track) || !ts.TrackStart.Equal(prev.TrackStart)) {
+		tags += hlsDateRange(*ts.Track, ts.TrackStart)
+	}
+
+	return tags
 }
 
 // hlsHeader generates the header string for an HLS playlist with the specified target duration.
 func hlsHeader(dur int, mediaSeq, disconSeq int64, offset float64) string {
-	currentTime := time.Now().UTC().Round(time.Millisecond).Format(timeFormat)
 	return "#EXTM3U\n" +
 		"#EXT-X-VERSION:6\n" +
-		"#EXT-X-PROGRAM-DATE-TIME:" + currentTime + "\n" +
 		"#EXT-X-TARGETDURATION:" + strconv.Itoa(dur) + "\n" +
 		"#EXT-X-MEDIA-SEQUENCE:" + strconv.FormatInt(mediaSeq, 10) + "\n" +
 		"#EXT-X-DISCONTINUITY-SEQUENCE:" + strconv.FormatInt(disconSeq, 10) + "\n" +
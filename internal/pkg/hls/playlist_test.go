@@ -2,6 +2,8 @@ package hls
 
 import (
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -159,6 +161,263 @@ func TestNext(t *testing.T) {
 	}
 }
 
+func TestChangeCurrent(t *testing.T) {
+	current := []*Segment{{Duration: 5.0, Path: "segment1.ts"}}
+	next := []*Segment{{Duration: 5.0, Path: "segment2.ts"}}
+	playlist := NewPlaylist(current, next)
+
+	playlist.ChangeCurrent([]*Segment{
+		{Duration: 5.0, Path: "segment1.ts"},
+		{Duration: 2.5, Path: "transition0.ts", IsFirst: true},
+	})
+
+	if len(playlist.currentTrackSegments) != 2 || playlist.currentTrackSegments[1].Path != "transition0.ts" {
+		t.Errorf("Expected currentTrackSegments to be replaced, got: %v", playlist.currentTrackSegments)
+	}
+
+	if len(playlist.nextTrackSegments) != 1 || playlist.nextTrackSegments[0].Path != "segment2.ts" {
+		t.Errorf("Expected nextTrackSegments to stay the same, got: %v", playlist.nextTrackSegments)
+	}
+}
+
+func TestTrimCurrent(t *testing.T) {
+	current := []*Segment{
+		{Duration: 5.0, Path: "a0.ts", IsFirst: true},
+		{Duration: 5.0, Path: "a1.ts"},
+		{Duration: 5.0, Path: "a2.ts"},
+		{Duration: 5.0, Path: "a3.ts"},
+		{Duration: 5.0, Path: "a4.ts"},
+		{Duration: 5.0, Path: "a5.ts"},
+	}
+
+	cases := []struct {
+		elapsed  float64
+		expected int
+	}{
+		{elapsed: 0, expected: 3},
+		{elapsed: 7, expected: 4},
+		{elapsed: 20, expected: 6},
+	}
+
+	for _, c := range cases {
+		playlist := NewPlaylist(current, nil)
+		playlist.TrimCurrent(c.elapsed)
+		if len(playlist.currentTrackSegments) != c.expected {
+			t.Errorf("elapsed %.0f: expected %d segments, got %d", c.elapsed, c.expected, len(playlist.currentTrackSegments))
+		}
+	}
+}
+
+func TestSetLiveSegmentsAmount(t *testing.T) {
+	current := make([]*Segment, 0, 10)
+	for i := range 10 {
+		current = append(current, &Segment{Duration: 5.0, Path: "a" + strconv.Itoa(i) + ".ts", IsFirst: i == 0})
+	}
+
+	playlist := NewPlaylist(current, nil)
+	playlist.SetLiveSegmentsAmount(5)
+	if got := strings.Count(playlist.Generate(0), "#EXTINF:"); got != 5 {
+		t.Errorf("expected the window to grow at once to 5 segments, got %d", got)
+	}
+
+	// The window shrinks by a segment every time it moves, so its end never moves back
+	playlist.SetLiveSegmentsAmount(3)
+	for i, expected := range []int{5, 5, 4, 3, 3} {
+		if got := strings.Count(playlist.Generate(float64(i*5)), "#EXTINF:"); got != expected {
+			t.Errorf("elapsed %d: expected %d segments, got %d", i*5, expected, got)
+		}
+	}
+}
+
+func TestGenerateDVRWindow(t *testing.T) {
+	current := []*Segment{{Duration: 5.0, Path: "a0.ts", IsFirst: true}}
+	for i := 1; i < 6; i++ {
+		current = append(current, &Segment{Duration: 5.0, Path: "a" + strconv.Itoa(i) + ".ts"})
+	}
+	next := []*Segment{{Duration: 5.0, Path: "b0.ts", IsFirst: true}}
+
+	playlist := NewPlaylist(current, next)
+	playlist.DVRWindow = 10
+	for _, elapsed := range []float64{0, 5, 10, 15, 22} {
+		playlist.Generate(elapsed)
+	}
+
+	got := withoutDateTimes(playlist.Generate(22))
+	expected := "#EXTM3U\n" +
+		"#EXT-X-VERSION:6\n" +
+		"#EXT-X-TARGETDURATION:5\n" +
+		"#EXT-X-MEDIA-SEQUENCE:3\n" +
+		"#EXT-X-DISCONTINUITY-SEQUENCE:1\n" +
+		"#EXT-X-START:TIME-OFFSET=12.00\n" +
+		"#EXTINF:5.00,\na2.ts\n" +
+		"#EXTINF:5.00,\na3.ts\n" +
+		"#EXTINF:5.00,\na4.ts\n" +
+		"#EXTINF:5.00,\na5.ts\n" +
+		"#EXT-X-DISCONTINUITY\n" +
+		"#EXTINF:5.00,\nb0.ts\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if files := playlist.PlayedFiles(); !slices.Contains(files, "a2.ts") || !slices.Contains(files, "a3.ts") {
+		t.Errorf("expected the files of the listed played segments, got %v", files)
+	}
+
+	// The first played segment is listed with its discontinuity while it's within the window
+	playlist = NewPlaylist(current, next)
+	playlist.DVRWindow = 60
+	playlist.Generate(0)
+	got = playlist.Generate(5)
+	if !strings.Contains(got, "#EXT-X-DISCONTINUITY-SEQUENCE:0\n") || !strings.Contains(got, "#EXT-X-DISCONTINUITY\n#EXT-X-PROGRAM-DATE-TIME") {
+		t.Errorf("expected the first segment with its discontinuity, got:\n%s", got)
+	}
+}
+
+func TestSetMaxSegmentDuration(t *testing.T) {
+	playlist := NewPlaylist(nil, nil)
+
+	playlist.SetMaxSegmentDuration(8)
+	if playlist.MaxSegmentDuration != 8 {
+		t.Errorf("expected the target duration to rise to 8, got %d", playlist.MaxSegmentDuration)
+	}
+
+	playlist.SetMaxSegmentDuration(2)
+	if playlist.MaxSegmentDuration != 8 {
+		t.Errorf("expected the target duration to stay 8, got %d", playlist.MaxSegmentDuration)
+	}
+}
+
+func TestVariableSegmentDurations(t *testing.T) {
+	current := []*Segment{
+		{Duration: 5.0, Path: "track0.ts", IsFirst: true},
+		{Duration: 5.0, Path: "track1.ts"},
+		{Duration: 2.4, Path: "track2.ts"},
+	}
+	next := []*Segment{
+		{Duration: 5.0, Path: "next0.ts", IsFirst: true},
+		{Duration: 5.0, Path: "next1.ts"},
+	}
+
+	t.Run("window starts at the short final segment", func(t *testing.T) {
+		playlist := NewPlaylist(current, next)
+		got := playlist.Generate(11.0)
+
+		if strings.Contains(got, "track1.ts") {
+			t.Errorf("segment track1.ts must have left the window: %s", got)
+		}
+		for _, path := range []string{"track2.ts", "next0.ts", "next1.ts"} {
+			if !strings.Contains(got, path) {
+				t.Errorf("expected segment %s in playlist: %s", path, got)
+			}
+		}
+		if !strings.Contains(got, "#EXTINF:2.40,\ntrack2.ts") {
+			t.Errorf("expected accurate duration of the final segment: %s", got)
+		}
+		if !strings.Contains(got, "#EXT-X-START:TIME-OFFSET=1.00\n") {
+			t.Errorf("expected offset within the final segment: %s", got)
+		}
+	})
+
+	t.Run("published position", func(t *testing.T) {
+		playlist := NewPlaylist(current, next)
+		playlist.LiveSegmentsAmount = 2
+
+		cases := []struct {
+			elapsed float64
+			want    float64
+		}{
+			{0, 10},
+			{6, 12.4},
+			{11, 12.4},
+			{13, 12.4},
+		}
+		for _, c := range cases {
+			if got := playlist.PublishedUntil(c.elapsed); got != c.want {
+				t.Errorf("PublishedUntil(%v) = %v, want %v", c.elapsed, got, c.want)
+			}
+		}
+	})
+}
+
+func TestCurrentDuration(t *testing.T) {
+	current := []*Segment{
+		{Duration: 5.0, Path: "segment1.ts"},
+		{Duration: 5.0, Path: "segment2.ts"},
+		{Duration: 2.5, Path: "segment3.ts"},
+	}
+	playlist := NewPlaylist(current, nil)
+
+	if got := playlist.CurrentDuration(); got != 12.5 {
+		t.Errorf("Expected current duration 12.5, got %f", got)
+	}
+
+	empty := NewPlaylist(nil, nil)
+	if got := empty.CurrentDuration(); got != 0 {
+		t.Errorf("Expected zero duration for empty playlist, got %f", got)
+	}
+}
+
+func TestGenerateSequences(t *testing.T) {
+	current := []*Segment{
+		{Duration: 5.0, Path: "a0.ts", IsFirst: true},
+		{Duration: 5.0, Path: "a1.ts"},
+		{Duration: 5.0, Path: "ax0.ts", IsFirst: true},
+	}
+	next := []*Segment{
+		{Duration: 5.0, Path: "b1.ts", IsFirst: true},
+		{Duration: 5.0, Path: "b2.ts"},
+	}
+	playlist := NewPlaylist(current, next)
+
+	cases := []struct {
+		elapsed   float64
+		mediaSeq  string
+		disconSeq string
+	}{
+		{elapsed: 0, mediaSeq: "1", disconSeq: "0"},
+		{elapsed: 2, mediaSeq: "1", disconSeq: "0"},
+		{elapsed: 5, mediaSeq: "2", disconSeq: "1"},
+		{elapsed: 10, mediaSeq: "3", disconSeq: "1"},
+		{elapsed: 15, mediaSeq: "4", disconSeq: "2"},
+	}
+
+	for _, c := range cases {
+		got := playlist.Generate(c.elapsed)
+		if !strings.Contains(got, "#EXT-X-MEDIA-SEQUENCE:"+c.mediaSeq+"\n") {
+			t.Errorf("elapsed %.0f: expected media sequence %s in playlist: %s", c.elapsed, c.mediaSeq, got)
+		}
+		if !strings.Contains(got, "#EXT-X-DISCONTINUITY-SEQUENCE:"+c.disconSeq+"\n") {
+			t.Errorf("elapsed %.0f: expected discontinuity sequence %s in playlist: %s", c.elapsed, c.disconSeq, got)
+		}
+	}
+}
+
+func TestGenerateURIPrefix(t *testing.T) {
+	current := []*Segment{{Duration: 5.0, Path: "static/tmp/jazz/a0.ts", IsFirst: true}}
+	playlist := NewPlaylist(current, nil)
+	playlist.URIPrefix = "../"
+
+	got := playlist.Generate(0)
+	if !strings.Contains(got, "\n../static/tmp/jazz/a0.ts\n") {
+		t.Errorf("Expected segment URI to be prefixed, got: %s", got)
+	}
+}
+
+func TestRestoreSequences(t *testing.T) {
+	current := []*Segment{{Duration: 5.0, Path: "a0.ts", IsFirst: true}}
+	playlist := NewPlaylist(current, nil)
+	playlist.SetMediaSequence(41)
+	playlist.SetDisconSequence(7)
+
+	got := playlist.Generate(0)
+	if !strings.Contains(got, "#EXT-X-MEDIA-SEQUENCE:42\n") {
+		t.Errorf("Expected restored media sequence to move once, got: %s", got)
+	}
+	if playlist.MediaSequence() != 42 || playlist.DisconSequence() != 7 {
+		t.Errorf("Expected sequences 42/7, got %d/%d", playlist.MediaSequence(), playlist.DisconSequence())
+	}
+}
+
 func TestAddSegments(t *testing.T) {
 	current := []*Segment{{Duration: 5.0, Path: "segment1.ts"}}
 	next := []*Segment{{Duration: 5.0, Path: "segment2.ts"}}
@@ -179,6 +438,56 @@ func TestAddSegments(t *testing.T) {
 	}
 }
 
+func TestAppendCurrent(t *testing.T) {
+	current := []*Segment{{Duration: 5.0, Path: "live0.ts"}}
+	next := []*Segment{{Duration: 5.0, Path: "segment1.ts"}}
+	playlist := NewPlaylist(current, next)
+
+	playlist.AppendCurrent([]*Segment{{Duration: 4.0, Path: "live1.ts"}})
+
+	if playlist.CurrentDuration() != 9.0 {
+		t.Errorf("Expected current duration to be 9.0, got: %f", playlist.CurrentDuration())
+	}
+
+	if len(playlist.nextTrackSegments) != 1 {
+		t.Errorf("Expected nextTrackSegments to stay untouched, got: %d segments", len(playlist.nextTrackSegments))
+	}
+}
+
+func TestSpliceCurrent(t *testing.T) {
+	current := []*Segment{
+		{Duration: 5.0, Path: "segment0.ts"},
+		{Duration: 5.0, Path: "segment1.ts"},
+		{Duration: 5.0, Path: "segment2.ts"},
+		{Duration: 5.0, Path: "segment3.ts"},
+	}
+	playlist := NewPlaylist(current, []*Segment{})
+
+	playlist.SpliceCurrent(5.0, 15.0, []*Segment{
+		{Duration: 5.0, Path: "voice0.ts", IsFirst: true},
+		{Duration: 5.0, Path: "voice1.ts"},
+	})
+
+	expected := []string{"segment0.ts", "voice0.ts", "voice1.ts", "segment3.ts"}
+	if len(playlist.currentTrackSegments) != len(expected) {
+		t.Fatalf("Expected %d segments, got %d", len(expected), len(playlist.currentTrackSegments))
+	}
+
+	for i, seg := range playlist.currentTrackSegments {
+		if seg.Path != expected[i] {
+			t.Errorf("Expected segment %d to be %s, got %s", i, expected[i], seg.Path)
+		}
+	}
+
+	if !playlist.currentTrackSegments[3].IsFirst {
+		t.Error("Expected the segment after the splice to start a discontinuity")
+	}
+
+	if current[3].IsFirst {
+		t.Error("Expected the original segments to stay untouched")
+	}
+}
+
 func TestCollectLiveSegments(t *testing.T) {
 	t.Run("full from current track", func(t *testing.T) {
 		current := []*Segment{
@@ -449,3 +758,24 @@ func TestHlsSegment(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateInitSegments(t *testing.T) {
+	current := []*Segment{
+		{Duration: 5.0, Path: "a0.m4s", IsFirst: true, Init: "a_init.mp4"},
+		{Duration: 5.0, Path: "a1.m4s", Init: "a_init.mp4"},
+	}
+	next := []*Segment{{Duration: 5.0, Path: "b0.m4s", IsFirst: true, Init: "b_init.mp4"}}
+	playlist := NewPlaylist(current, next)
+
+	got := withoutDateTimes(playlist.Generate(0))
+	want := "#EXT-X-DISCONTINUITY\n#EXT-X-MAP:URI=\"a_init.mp4\"\n#EXTINF:5.00,\na0.m4s\n" +
+		"#EXTINF:5.00,\na1.m4s\n" +
+		"#EXT-X-DISCONTINUITY\n#EXT-X-MAP:URI=\"b_init.mp4\"\n#EXTINF:5.00,\nb0.m4s\n"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("unexpected segments, got:\n%s", got)
+	}
+
+	if got := playlist.GenerateRendition(0, "64k"); !strings.Contains(got, "#EXT-X-MAP:URI=\"64k/a_init.mp4\"\n") {
+		t.Errorf("expected the init segment of the rendition, got:\n%s", got)
+	}
+}
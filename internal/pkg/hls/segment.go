@@ -4,6 +4,7 @@ import (
 	"math"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 // Segment represents a single segment in an HLS playlist.
@@ -11,6 +12,12 @@ type Segment struct {
 	Duration float64 // The length of the segment in seconds.
 	Path     string  // The file path or URL of the segment.
 	IsFirst  bool    // A flag indicating whether this segment is the first segment in the track.
+	Init     string  // The file path of the init segment needed to decode the segment, empty for MPEG-TS.
+	Offset   float64 // The position (in seconds) of the file the segment was cut from where the segment starts.
+
+	// Meta describes the track the segment belongs to. Segments without it, such as crossfade transitions
+	// and jingles, are considered a part of the track before them.
+	Meta *Metadata
 }
 
 // NewSegment creates and returns a new Segment instance with the provided duration, path, and first segment flag.
@@ -30,18 +37,27 @@ func NewSegment(duration float64, path string, isFirst bool) *Segment {
 	}
 }
 
+// AsFirst returns a copy of the segment marked as the first segment in the track.
+func (s *Segment) AsFirst() *Segment {
+	first := *s
+	first.IsFirst = true
+	return &first
+}
+
 // GenerateSegments creates a list of Segment instances for a given track based on its duration and segment duration.
 // It divides the track into segments of the specified duration and generates metadata for each segment.
+// The last segment covers the rest of the track, so it may be shorter than the others.
 //
 // Parameters:
 //   - trackDuration: The total duration of the track in seconds.
 //   - segmentDuration: The desired duration of each segment in seconds.
 //   - trackID: The unique identifier for the track, used to generate segment names.
 //   - outDir: The output directory where the segments will be stored.
+//   - container: The format of the segment files.
 //
 // Returns:
 //   - A slice of pointers to Segment instances representing the generated segments.
-func GenerateSegments(trackDuration float64, segmentDuration int, trackID, outDir string) []*Segment {
+func GenerateSegments(trackDuration float64, segmentDuration int, trackID, outDir string, container Container) []*Segment {
 	if trackDuration <= 0 || segmentDuration <= 0 {
 		return []*Segment{}
 	}
@@ -52,16 +68,27 @@ func GenerateSegments(trackDuration float64, segmentDuration int, trackID, outDi
 
 	remaining := trackDuration
 	index := 0
+	init := ""
+	if name := container.InitName(trackID); name != "" {
+		init = filepath.Join(outDir, name)
+	}
 
 	// Generate segments until the entire track is covered
 	for remaining > 0 {
-		segName := trackID + strconv.Itoa(index) + SegmentExtension
+		segName := trackID + strconv.Itoa(index) + container.Extension()
 		segPath := filepath.Join(outDir, segName)
 
-		// Use the smaller of the remaining or full segment duration
+		// Use the smaller of the remaining or full segment duration.
+		// A tail too short to hold an audio frame is merged into the last segment.
 		duration := math.Min(remaining, float64(segmentDuration))
+		if remaining-duration < minSegmentDuration {
+			duration = remaining
+		}
 		isFirst := index == 0
-		segments = append(segments, NewSegment(duration, segPath, isFirst))
+		seg := NewSegment(duration, segPath, isFirst)
+		seg.Init = init
+		seg.Offset = trackDuration - remaining
+		segments = append(segments, seg)
 
 		remaining -= duration
 		index++
@@ -69,3 +96,65 @@ func GenerateSegments(trackDuration float64, segmentDuration int, trackID, outDi
 
 	return segments
 }
+
+// ParseSegments extracts the segments listed in an HLS media playlist, such as the one FFmpeg writes
+// while segmenting a live input. Segment URIs are resolved against the given directory, and so are the URIs
+// of the init segments the fragmented MP4 segments are listed with.
+//
+// Parameters:
+//   - playlist: The content of the media playlist.
+//   - dir: The directory the segment URIs are relative to.
+//
+// Returns:
+//   - A slice of pointers to Segment instances in the order they are listed.
+func ParseSegments(playlist, dir string) []*Segment {
+	segments := make([]*Segment, 0)
+	duration := -1.0
+	offset := 0.0
+	init := ""
+
+	for line := range strings.Lines(playlist) {
+		line = strings.TrimSpace(line)
+
+		if value, ok := strings.CutPrefix(line, "#EXTINF:"); ok {
+			value, _, _ = strings.Cut(value, ",")
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				duration = parsed
+			}
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "#EXT-X-MAP:"); ok {
+			if uri := attributeValue(value, "URI"); uri != "" {
+				init = filepath.Join(dir, uri)
+			}
+			continue
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") || duration < 0 {
+			continue
+		}
+
+		seg := NewSegment(duration, filepath.Join(dir, line), false)
+		seg.Init = init
+		seg.Offset = offset
+		segments = append(segments, seg)
+		offset += duration
+		duration = -1
+	}
+
+	return segments
+}
+
+// attributeValue returns the value of an attribute in an attribute list of a playlist tag, without quotes.
+func attributeValue(list, name string) string {
+	for attr := range strings.SplitSeq(list, ",") {
+		key, value, ok := strings.Cut(attr, "=")
+		if ok && strings.TrimSpace(key) == name {
+			return strings.Trim(value, "\"")
+		}
+	}
+
+	return ""
+}
@@ -1,6 +1,7 @@
 package hls
 
 import (
+	"path/filepath"
 	"testing"
 )
 
@@ -137,11 +138,34 @@ func TestGenerateSegments(t *testing.T) {
 				{Duration: 5.0, Path: "/out/track52.ts", IsFirst: false},
 			},
 		},
+		{
+			name:            "Shorter final segment",
+			trackDuration:   12.25,
+			segmentDuration: 5,
+			trackID:         "track6",
+			outDir:          "/out",
+			expectedSegments: []*Segment{
+				{Duration: 5.0, Path: "/out/track60.ts", IsFirst: true},
+				{Duration: 5.0, Path: "/out/track61.ts", IsFirst: false},
+				{Duration: 2.25, Path: "/out/track62.ts", IsFirst: false},
+			},
+		},
+		{
+			name:            "Tiny tail is merged into the last segment",
+			trackDuration:   10.03125,
+			segmentDuration: 5,
+			trackID:         "track7",
+			outDir:          "/out",
+			expectedSegments: []*Segment{
+				{Duration: 5.0, Path: "/out/track70.ts", IsFirst: true},
+				{Duration: 5.03125, Path: "/out/track71.ts", IsFirst: false},
+			},
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			got := GenerateSegments(c.trackDuration, c.segmentDuration, c.trackID, c.outDir)
+			got := GenerateSegments(c.trackDuration, c.segmentDuration, c.trackID, c.outDir, ContainerTS)
 
 			if len(got) != len(c.expectedSegments) {
 				t.Fatalf("Expected %d segments, got %d", len(c.expectedSegments), len(got))
@@ -161,3 +185,55 @@ func TestGenerateSegments(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSegments(t *testing.T) {
+	playlist := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:5\n" +
+		"#EXT-X-MEDIA-SEQUENCE:4\n" +
+		"#EXTINF:5.000000,\n" +
+		"live4.ts\n" +
+		"#EXTINF:4.480000,\n" +
+		"live5.ts\n"
+
+	segments := ParseSegments(playlist, "tmp")
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 segments, got %d", len(segments))
+	}
+	if segments[0].Path != filepath.Join("tmp", "live4.ts") || segments[0].Duration != 5 {
+		t.Errorf("unexpected first segment: %+v", segments[0])
+	}
+	if segments[1].Path != filepath.Join("tmp", "live5.ts") || segments[1].Duration != 4.48 {
+		t.Errorf("unexpected second segment: %+v", segments[1])
+	}
+}
+
+func TestParseSegmentsFMP4(t *testing.T) {
+	playlist := "#EXTM3U\n" +
+		"#EXT-X-VERSION:7\n" +
+		"#EXT-X-TARGETDURATION:5\n" +
+		"#EXT-X-MAP:URI=\"live_init.mp4\"\n" +
+		"#EXTINF:5.000000,\n" +
+		"live0.m4s\n"
+
+	segments := ParseSegments(playlist, "tmp")
+	if len(segments) != 1 {
+		t.Fatalf("expected 1 segment, got %d", len(segments))
+	}
+	if segments[0].Init != filepath.Join("tmp", "live_init.mp4") {
+		t.Errorf("unexpected init segment: %q", segments[0].Init)
+	}
+}
+
+func TestGenerateSegmentsFMP4(t *testing.T) {
+	segments := GenerateSegments(7, 5, "track", "/out", ContainerFMP4)
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 segments, got %d", len(segments))
+	}
+
+	for i, seg := range segments {
+		if seg.Path != "/out/track"+string(rune('0'+i))+".m4s" || seg.Init != "/out/track_init.mp4" {
+			t.Errorf("unexpected segment %d: %+v", i, seg)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package hls
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimedSegment is a segment of the playback timeline with the time it plays at.
+type TimedSegment struct {
+	*Segment
+	Start      time.Time // The time the segment started playing.
+	GroupStart time.Time // The time the first segment of its group started playing. A group is a run of segments cut from the same file.
+	Track      *Metadata // The metadata of the track the segment is a part of, nil if it's unknown.
+	TrackStart time.Time // The time the track started playing, or the time the playlist started if the track was already playing.
+}
+
+// Timeline returns the segments that played within the live or the DVR window and the one that plays now, oldest first,
+// as of the last generated playlist. Segments that haven't started playing yet are left out.
+func (p *Playlist) Timeline() []TimedSegment {
+	if p.currentSegment == nil {
+		return nil
+	}
+
+	timeline := make([]TimedSegment, 0, len(p.playedSegments)+1)
+	timeline = append(timeline, p.playedSegments...)
+
+	return append(timeline, p.timed(p.currentSegment))
+}
+
+// timed returns the given segment with the times of the current segment.
+func (p *Playlist) timed(seg *Segment) TimedSegment {
+	return TimedSegment{Segment: seg, Start: p.currentStart, GroupStart: p.groupStart, Track: p.trackMeta, TrackStart: p.trackStart}
+}
+
+// timedWindow returns the segments of the live window with the times they are going to play at.
+// The first one must be the current segment.
+func (p *Playlist) timedWindow(segments []*Segment) []TimedSegment {
+	window := make([]TimedSegment, 0, len(segments))
+	for i, seg := range segments {
+		if i == 0 {
+			window = append(window, p.timed(seg))
+			continue
+		}
+
+		prev := window[i-1]
+		ts := prev
+		ts.Segment = seg
+		ts.Start = prev.Start.Add(seconds(prev.Duration))
+		if seg.IsFirst || seg.Init != prev.Init {
+			ts.GroupStart = ts.Start
+		}
+		if seg.Meta != nil && !sameTrack(seg.Meta, prev.Track) {
+			ts.Track = seg.Meta
+			ts.TrackStart = ts.Start
+		}
+		window = append(window, ts)
+	}
+
+	return window
+}
+
+// Announce returns the segments where the tracks of the live window start for listeners, at the elapsed time,
+// so their metadata can be embedded in them. Every track is returned once while it stays in the live window.
+//
+// Parameters:
+//   - elapsedTime: The elapsed time in seconds used to determine the current segment index.
+func (p *Playlist) Announce(elapsedTime float64) []*Segment {
+	announced := make(map[Metadata]bool)
+	starts := make([]*Segment, 0)
+
+	for _, seg := range p.currentSegments(elapsedTime) {
+		if seg.Meta == nil || announced[*seg.Meta] {
+			continue
+		}
+
+		announced[*seg.Meta] = true
+		if !p.announced[*seg.Meta] {
+			starts = append(starts, seg)
+		}
+	}
+	p.announced = announced
+
+	return starts
+}
+
+// Number returns the prefix of the segment file name and the index FFmpeg appended to it.
+// The prefix is taken from the init segment, so it's only known for fragmented MP4 segments.
+func (s *Segment) Number() (string, int, bool) {
+	prefix, ok := strings.CutSuffix(filepath.Base(s.Init), initSuffix)
+	if s.Init == "" || !ok {
+		return "", 0, false
+	}
+
+	name := strings.TrimSuffix(filepath.Base(s.Path), filepath.Ext(s.Path))
+	index, err := strconv.Atoi(strings.TrimPrefix(name, prefix))
+	if err != nil || !strings.HasPrefix(name, prefix) {
+		return "", 0, false
+	}
+
+	return prefix, index, true
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,52 @@
+package hls
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	current := []*Segment{
+		{Duration: 5.0, Path: "a0.m4s", IsFirst: true, Init: "a_init.mp4"},
+		{Duration: 2.0, Path: "a1.m4s", Init: "a_init.mp4"},
+	}
+	next := []*Segment{{Duration: 5.0, Path: "b0.m4s", IsFirst: true, Init: "b_init.mp4"}}
+	playlist := NewPlaylist(current, next)
+
+	if playlist.Timeline() != nil {
+		t.Error("expected no timeline before the first playlist")
+	}
+
+	playlist.Generate(1)
+	playlist.Generate(5)
+	playlist.Generate(7)
+	timeline := playlist.Timeline()
+
+	if len(timeline) != 3 {
+		t.Fatalf("expected 3 segments, got %d", len(timeline))
+	}
+	if got := timeline[1].Start.Sub(timeline[0].Start); got != 5*time.Second {
+		t.Errorf("expected the second segment 5s after the first one, got %v", got)
+	}
+	if !timeline[1].GroupStart.Equal(timeline[0].Start) {
+		t.Errorf("expected the segments of a track to share the group start")
+	}
+	if !timeline[2].GroupStart.Equal(timeline[2].Start) {
+		t.Errorf("expected the next track to start a new group")
+	}
+	if since := time.Since(timeline[0].Start); since < time.Second || since > 2*time.Second {
+		t.Errorf("expected the first segment to start about 1s ago, got %v", since)
+	}
+}
+
+func TestSegmentNumber(t *testing.T) {
+	seg := &Segment{Path: "tmp/01J912.m4s", Init: "tmp/01J9_init.mp4"}
+	prefix, number, ok := seg.Number()
+	if !ok || prefix != "01J9" || number != 12 {
+		t.Errorf("Number() = %q, %d, %v", prefix, number, ok)
+	}
+
+	if _, _, ok := (&Segment{Path: "tmp/a0.ts"}).Number(); ok {
+		t.Error("expected no number without an init segment")
+	}
+}
@@ -0,0 +1,107 @@
+package hls
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Timing holds the duration of segments and the size of the live window the station streams with.
+// It's shared by everything that depends on them, so they read the value that is in effect at the moment,
+// and it may be changed while the station is running. A nil Timing holds the default values.
+type Timing struct {
+	segmentDuration int
+	liveSegments    int
+	mutex           sync.RWMutex
+}
+
+// NewTiming creates a Timing with the given values.
+//
+// Parameters:
+//   - segmentDuration: The duration (in seconds) of segments.
+//   - liveSegments: The number of segments in the live window.
+//
+// Returns:
+//   - A pointer to the Timing, or an error if the values are out of the allowed ranges.
+func NewTiming(segmentDuration, liveSegments int) (*Timing, error) {
+	if err := ValidateTiming(segmentDuration, liveSegments); err != nil {
+		return nil, err
+	}
+
+	return &Timing{segmentDuration: segmentDuration, liveSegments: liveSegments}, nil
+}
+
+// ValidateTiming checks that the duration of segments and the size of the live window are within the allowed ranges.
+func ValidateTiming(segmentDuration, liveSegments int) error {
+	if segmentDuration < SegmentDurationMin || segmentDuration > SegmentDurationMax {
+		return fmt.Errorf("segment duration must be between %d and %d seconds", SegmentDurationMin, SegmentDurationMax)
+	}
+
+	if liveSegments < LiveSegmentsMin || liveSegments > LiveSegmentsMax {
+		return fmt.Errorf("live window must hold between %d and %d segments", LiveSegmentsMin, LiveSegmentsMax)
+	}
+
+	return nil
+}
+
+// Set changes the duration of segments and the size of the live window.
+//
+// Parameters:
+//   - segmentDuration: The duration (in seconds) of segments.
+//   - liveSegments: The number of segments in the live window.
+//
+// Returns:
+//   - An error if the values are out of the allowed ranges, the values are kept then.
+func (t *Timing) Set(segmentDuration, liveSegments int) error {
+	if err := ValidateTiming(segmentDuration, liveSegments); err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	t.segmentDuration = segmentDuration
+	t.liveSegments = liveSegments
+	t.mutex.Unlock()
+
+	return nil
+}
+
+// SegmentDuration returns the duration (in seconds) new segments are made with.
+func (t *Timing) SegmentDuration() int {
+	if t == nil {
+		return DefaultMaxSegmentDuration
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.segmentDuration
+}
+
+// LiveSegments returns the number of segments in the live window.
+func (t *Timing) LiveSegments() int {
+	if t == nil {
+		return DefaultLiveSegmentsAmount
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.liveSegments
+}
+
+// Window returns the duration of a live window of segments, which is the shortest a track can be.
+func (t *Timing) Window() float64 {
+	if t == nil {
+		return DefaultMaxSegmentDuration * DefaultLiveSegmentsAmount
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return float64(t.segmentDuration * t.liveSegments)
+}
+
+// SegmentInterval returns the duration of a segment as a time interval.
+func (t *Timing) SegmentInterval() time.Duration {
+	return time.Duration(t.SegmentDuration()) * time.Second
+}
@@ -0,0 +1,61 @@
+package hls
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewTiming(t *testing.T) {
+	cases := []struct {
+		segmentDuration int
+		liveSegments    int
+		valid           bool
+	}{
+		{segmentDuration: 5, liveSegments: 3, valid: true},
+		{segmentDuration: SegmentDurationMin, liveSegments: LiveSegmentsMax, valid: true},
+		{segmentDuration: SegmentDurationMax, liveSegments: LiveSegmentsMin, valid: true},
+		{segmentDuration: 0, liveSegments: 3, valid: false},
+		{segmentDuration: SegmentDurationMax + 1, liveSegments: 3, valid: false},
+		{segmentDuration: 5, liveSegments: LiveSegmentsMin - 1, valid: false},
+		{segmentDuration: 5, liveSegments: LiveSegmentsMax + 1, valid: false},
+	}
+
+	for _, c := range cases {
+		timing, err := NewTiming(c.segmentDuration, c.liveSegments)
+		if c.valid && (err != nil || timing.SegmentDuration() != c.segmentDuration || timing.LiveSegments() != c.liveSegments) {
+			t.Errorf("%d/%d: expected a valid timing, got %v", c.segmentDuration, c.liveSegments, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%d/%d: expected an error", c.segmentDuration, c.liveSegments)
+		}
+	}
+}
+
+func TestTimingDefaults(t *testing.T) {
+	var timing *Timing
+
+	if timing.SegmentDuration() != DefaultMaxSegmentDuration || timing.LiveSegments() != DefaultLiveSegmentsAmount {
+		t.Errorf("expected the default values, got %d/%d", timing.SegmentDuration(), timing.LiveSegments())
+	}
+	if timing.Window() != DefaultMaxSegmentDuration*DefaultLiveSegmentsAmount {
+		t.Errorf("expected the default window, got %.0f", timing.Window())
+	}
+}
+
+func TestTimingSet(t *testing.T) {
+	timing, _ := NewTiming(5, 3)
+
+	if err := timing.Set(2, 6); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if timing.Window() != 12 || timing.SegmentInterval() != 2*time.Second {
+		t.Errorf("expected a 12 second window of 2 second segments, got %.0f and %v", timing.Window(), timing.SegmentInterval())
+	}
+
+	if err := timing.Set(30, 6); err == nil {
+		t.Error("expected an error for a too long segment duration")
+	}
+	if timing.SegmentDuration() != 2 {
+		t.Errorf("expected the values to be kept, got %d", timing.SegmentDuration())
+	}
+}
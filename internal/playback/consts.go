@@ -0,0 +1,30 @@
+package playback
+
+import "errors"
+
+// errQueueEnded is returned when there is no track to play after the current one.
+var errQueueEnded = errors.New("playback queue has ended")
+
+// snapshotRefreshes is the number of state refresh cycles between the saves of the playback position.
+const snapshotRefreshes = 30
+
+// previousTrackLookup is the number of recent history entries searched for the previous track.
+// Every return to a previous track adds an entry, so walking back reaches half as deep.
+const previousTrackLookup = 50
+
+var (
+	errLiveOnAir   = errors.New("live source is on air")
+	errLiveOffAir  = errors.New("live source is not on air")
+	errLiveWaiting = errors.New("waiting for live source segments")
+	errHeadChanged = errors.New("head of the queue changed, its segments are not ready")
+
+	errInterludeOnAir = errors.New("a clip is on air, try again after it")
+)
+
+const (
+	duckVolume     = 0.25 // Volume of the music under a voice-over
+	duckFade       = 0.5  // Seconds it takes to lower and to restore the music under a voice-over
+	voiceOverInfix = "v"  // Separates the track ID and the clip name in the names of voice-over segments
+
+	jingleSegmentsPrefix = "jingle"
+)
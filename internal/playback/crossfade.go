@@ -0,0 +1,134 @@
+package playback
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+const (
+	maxCrossfadeDuration = 12  // Longer overlaps make tracks unrecognizable
+	transitionInfix      = "x" // Separates track IDs in the names of transition segments
+)
+
+// crossfadeCurves lists the fade curves of the ffmpeg acrossfade filter that can be selected.
+var crossfadeCurves = []string{"tri", "qsin", "hsin", "esin", "log", "ipar", "qua", "cub", "squ", "cbr", "par", "exp"}
+
+// Crossfade describes how the tail of a track is blended with the head of the following one.
+type Crossfade struct {
+	Duration float64 `json:"duration"` // Length of the overlap in seconds, 0 disables crossfading
+	Curve    string  `json:"curve"`    // Name of the fade curve used for both tracks
+}
+
+// Validate checks that the crossfade duration is in the allowed range and the curve is supported.
+func (cf Crossfade) Validate() error {
+	if cf.Duration < 0 || cf.Duration > maxCrossfadeDuration {
+		return fmt.Errorf("crossfade duration must be between 0 and %d seconds", maxCrossfadeDuration)
+	}
+
+	if cf.Duration > 0 && !slices.Contains(crossfadeCurves, cf.Curve) {
+		return fmt.Errorf("unsupported crossfade curve %q", cf.Curve)
+	}
+
+	return nil
+}
+
+// transition describes a planned crossfade between the current and the next track.
+type transition struct {
+	cutAt    float64 // Position of the current track where the transition segments begin
+	resumeAt float64 // Position of the next track where its own segments continue after the transition
+}
+
+// duration returns the length of the transition segments.
+func (tr transition) duration(current *track.Track, overlap float64) float64 {
+	return current.Duration - tr.cutAt + tr.resumeAt - overlap
+}
+
+// planTransition decides whether two tracks can be crossfaded and where the transition starts and ends.
+// Both edges are aligned to segment boundaries, so the regular segments of both tracks are reused
+// and only the region around the overlap is rendered again.
+//
+// Parameters:
+//   - cf: The crossfade settings.
+//   - current: The outgoing track.
+//   - next: The incoming track.
+//   - notBefore: The position of the outgoing track until which its segments are already exposed to listeners.
+//   - segDuration: The duration of a single segment.
+//
+// Returns:
+//   - The planned transition and true, or false if the tracks must be joined without a crossfade.
+func planTransition(cf Crossfade, current, next *track.Track, notBefore, segDuration float64) (transition, bool) {
+	if cf.Duration <= 0 || current == nil || next == nil {
+		return transition{}, false
+	}
+
+	if current.CrossfadeDisabled || next.CrossfadeDisabled {
+		return transition{}, false
+	}
+
+	tr := transition{
+		cutAt:    math.Floor((current.Duration-cf.Duration)/segDuration) * segDuration,
+		resumeAt: math.Ceil(cf.Duration/segDuration) * segDuration,
+	}
+
+	if tr.cutAt < notBefore {
+		return transition{}, false
+	}
+
+	if next.Duration-tr.resumeAt < segDuration {
+		return transition{}, false
+	}
+
+	return tr, true
+}
+
+// sliceSegments returns the segments that start within the [from, to) range of the track.
+// The first returned segment is marked as the first one, so the playlist puts a discontinuity
+// tag before it.
+func sliceSegments(segments []*hls.Segment, from, to float64) []*hls.Segment {
+	sliced := make([]*hls.Segment, 0, len(segments))
+	position := 0.0
+
+	for _, seg := range segments {
+		if position >= from && position < to {
+			sliced = append(sliced, seg)
+		}
+		position += seg.Duration
+	}
+
+	if len(sliced) > 0 && !sliced[0].IsFirst {
+		sliced[0] = sliced[0].AsFirst()
+	}
+
+	return sliced
+}
+
+// windowEnd returns the position of the track where the live window of the given size ends once the given segments,
+// starting at the offset, begin to play.
+func windowEnd(segments []*hls.Segment, offset float64, size int) float64 {
+	end := offset
+	for _, seg := range segments[:min(len(segments), size)] {
+		end += seg.Duration
+	}
+
+	return end
+}
+
+// cutDuration returns the duration (in seconds) a file was cut into the given segments at, which is the duration
+// of every segment but the last one. It's 0 if there are too few segments to tell.
+func cutDuration(segments []*hls.Segment) int {
+	if len(segments) < 2 {
+		return 0
+	}
+
+	return int(math.Round(segments[0].Duration))
+}
+
+// transitionName returns the base name of the segments for a transition between two tracks.
+// It starts with the ID of the outgoing track, so the segments are kept on disk while it plays.
+func transitionName(current, next *track.Track) string {
+	return current.ID + transitionInfix + next.ID
+}
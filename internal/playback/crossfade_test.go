@@ -0,0 +1,150 @@
+package playback
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+func TestCrossfade_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		cf      Crossfade
+		wantErr bool
+	}{
+		{name: "disabled", cf: Crossfade{Duration: 0}, wantErr: false},
+		{name: "valid", cf: Crossfade{Duration: 6, Curve: "qsin"}, wantErr: false},
+		{name: "negative duration", cf: Crossfade{Duration: -1, Curve: "tri"}, wantErr: true},
+		{name: "too long", cf: Crossfade{Duration: 13, Curve: "tri"}, wantErr: true},
+		{name: "unknown curve", cf: Crossfade{Duration: 4, Curve: "wobble"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.cf.Validate()
+			if (err != nil) != c.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanTransition(t *testing.T) {
+	cf := Crossfade{Duration: 6, Curve: "tri"}
+	current := &track.Track{ID: "a", Duration: 60}
+	next := &track.Track{ID: "b", Duration: 40}
+
+	t.Run("aligns edges to segment boundaries", func(t *testing.T) {
+		tr, ok := planTransition(cf, current, next, 15, 5)
+		if !ok {
+			t.Fatal("expected transition to be planned")
+		}
+		if tr.cutAt != 50 {
+			t.Errorf("expected cutAt 50, got %f", tr.cutAt)
+		}
+		if tr.resumeAt != 10 {
+			t.Errorf("expected resumeAt 10, got %f", tr.resumeAt)
+		}
+		if got := tr.duration(current, cf.Duration); got != 14 {
+			t.Errorf("expected transition duration 14, got %f", got)
+		}
+	})
+
+	t.Run("disabled for a track", func(t *testing.T) {
+		stinger := &track.Track{ID: "s", Duration: 40, CrossfadeDisabled: true}
+		if _, ok := planTransition(cf, current, stinger, 15, 5); ok {
+			t.Error("expected no transition into a track with disabled crossfade")
+		}
+		if _, ok := planTransition(cf, stinger, next, 15, 5); ok {
+			t.Error("expected no transition out of a track with disabled crossfade")
+		}
+	})
+
+	t.Run("zero duration", func(t *testing.T) {
+		if _, ok := planTransition(Crossfade{}, current, next, 15, 5); ok {
+			t.Error("expected no transition when crossfade is off")
+		}
+	})
+
+	t.Run("transition already published", func(t *testing.T) {
+		if _, ok := planTransition(cf, current, next, 55, 5); ok {
+			t.Error("expected no transition when its start is already exposed")
+		}
+	})
+
+	t.Run("next track too short", func(t *testing.T) {
+		short := &track.Track{ID: "c", Duration: 12}
+		if _, ok := planTransition(cf, current, short, 15, 5); ok {
+			t.Error("expected no transition into a too short track")
+		}
+	})
+}
+
+func TestSliceSegments(t *testing.T) {
+	segments := hls.GenerateSegments(20, 5, "track", "/tmp", hls.ContainerTS)
+
+	t.Run("middle range", func(t *testing.T) {
+		got := sliceSegments(segments, 5, 15)
+		if len(got) != 2 {
+			t.Fatalf("expected 2 segments, got %d", len(got))
+		}
+		if got[0].Path != "/tmp/track1.ts" || got[1].Path != "/tmp/track2.ts" {
+			t.Errorf("unexpected segments: %s, %s", got[0].Path, got[1].Path)
+		}
+		if !got[0].IsFirst {
+			t.Error("expected first sliced segment to start a discontinuity")
+		}
+		if segments[1].IsFirst {
+			t.Error("original segments must not be modified")
+		}
+	})
+
+	t.Run("whole track", func(t *testing.T) {
+		got := sliceSegments(segments, 0, 20)
+		if len(got) != len(segments) {
+			t.Errorf("expected %d segments, got %d", len(segments), len(got))
+		}
+	})
+
+	t.Run("empty range", func(t *testing.T) {
+		got := sliceSegments(segments, 20, 30)
+		if len(got) != 0 {
+			t.Errorf("expected no segments, got %d", len(got))
+		}
+	})
+}
+
+func TestWindowEnd(t *testing.T) {
+	t.Run("full window", func(t *testing.T) {
+		segments := hls.GenerateSegments(32.5, 5, "track", "/tmp", hls.ContainerTS)
+		if got := windowEnd(segments, 10, 3); got != 25 {
+			t.Errorf("expected window end 25, got %f", got)
+		}
+	})
+
+	t.Run("short track with a shorter final segment", func(t *testing.T) {
+		segments := hls.GenerateSegments(7.5, 5, "track", "/tmp", hls.ContainerTS)
+		if got := windowEnd(segments, 0, 3); got != 7.5 {
+			t.Errorf("expected window end 7.5, got %f", got)
+		}
+	})
+}
+
+func TestPlanTransitionShortFinalSegment(t *testing.T) {
+	cf := Crossfade{Duration: 4, Curve: "tri"}
+	current := &track.Track{ID: "a", Duration: 63.4}
+	next := &track.Track{ID: "b", Duration: 40}
+
+	tr, ok := planTransition(cf, current, next, 15, 5)
+	if !ok {
+		t.Fatal("expected transition to be planned")
+	}
+	if tr.cutAt != 55 {
+		t.Errorf("expected cutAt 55, got %f", tr.cutAt)
+	}
+	if got := tr.duration(current, cf.Duration); math.Abs(got-9.4) > 1e-9 {
+		t.Errorf("expected transition duration 9.4, got %f", got)
+	}
+}
@@ -0,0 +1,368 @@
+package playback
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/jingle"
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+// Clip is a short audio file that airs apart from the queue, such as a voice message.
+type Clip struct {
+	Name     string  // Shown as the current track and in the playback history
+	SegName  string  // Prefix of the clip segment files, unique for each clip
+	Path     string  // Path to the audio file
+	Duration float64 // Duration of the audio in seconds
+}
+
+// Airing tells how a clip was put on air.
+type Airing struct {
+	Over  bool    // Whether the clip plays over the current track, otherwise it airs after the track
+	Delay float64 // Seconds from the current playback position until the clip starts over the track, 0 if it airs after
+}
+
+// interlude is a clip that airs in its own slot between two tracks without touching the queue.
+type interlude struct {
+	segName  string         // Prefix of the clip segment files
+	track    *track.Track   // Pseudo track shown as the current one while the clip airs
+	segments []*hls.Segment // Segments of the clip
+	jingleID string         // ID of the jingle the clip plays, empty for other clips
+}
+
+// AirNext plays the clip right after the current track, or after the clips that are already waiting.
+// The queue is not changed, so the track that was planned next plays after the clip.
+//
+// Parameters:
+//   - clip: The clip to play.
+//
+// Returns:
+//   - An error if playback is paused or the clip segments cannot be prepared.
+func (s *State) AirNext(clip Clip) error {
+	s.mutex.Lock()
+	isPlaying := s.IsPlaying
+	s.mutex.Unlock()
+
+	if !isPlaying {
+		return errors.New("playback is paused")
+	}
+
+	segDuration := s.timing().SegmentDuration()
+	err := s.trackService.MakeHLSPlaylist(clip.Path, s.playlistDir, clip.SegName, segDuration)
+	if err != nil {
+		return err
+	}
+
+	it := &interlude{
+		segName:  clip.SegName,
+		track:    &track.Track{Name: clip.Name, Path: clip.Path, Duration: clip.Duration},
+		segments: hls.GenerateSegments(clip.Duration, segDuration, clip.SegName, s.playlistDir, s.trackService.Container()),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.IsPlaying {
+		return errors.New("playback is paused")
+	}
+
+	s.interludes = append(s.interludes, it)
+	s.planInterlude()
+
+	return nil
+}
+
+// AirOver plays the clip over the current track, which is ducked while the clip plays.
+// Segments that are already exposed to listeners cannot change, and the mix takes a while to render,
+// so the clip starts one segment after the live window, i.e. about the live window plus one segment
+// duration later. If the current track ends too soon to fit the clip, or a clip or live source is on air,
+// the clip airs after the track instead.
+//
+// Parameters:
+//   - clip: The clip to play.
+//
+// Returns:
+//   - How the clip was put on air, or an error if playback is paused or the mixed segments cannot be prepared.
+func (s *State) AirOver(clip Clip) (Airing, error) {
+	s.mutex.Lock()
+	if !s.IsPlaying {
+		s.mutex.Unlock()
+		return Airing{}, errors.New("playback is paused")
+	}
+
+	// The mix replaces whole segments of the track, so it's cut at the duration the track was cut at
+	cut := cutDuration(s.currentSegments)
+	if cut == 0 {
+		cut = s.timing().SegmentDuration()
+	}
+	segDuration := float64(cut)
+
+	current := s.CurrentTrack
+	trackOffset := s.trackOffset
+	isTrackSlot := s.live == nil && s.interlude == nil
+	// Rendering takes a while, so the mix starts one segment later than the live window reaches now
+	start := max(s.publishedUntil()+segDuration, s.duckedUntil)
+	limit := s.trackOffset + s.playlist.CurrentDuration()
+	if s.transitionAt > 0 {
+		limit = min(limit, s.transitionAt)
+	}
+	s.mutex.Unlock()
+
+	span := math.Ceil((clip.Duration+2*duckFade)/segDuration) * segDuration
+	if !isTrackSlot || start+span > limit {
+		return Airing{}, s.AirNext(clip)
+	}
+
+	segName := current.ID + voiceOverInfix + clip.SegName
+	duck := ffmpeg.Duck{Volume: duckVolume, Fade: duckFade, Voice: clip.Duration}
+	err := s.trackService.MakeHLSVoiceOver(current, clip.Path, start, span, duck, s.playlistDir, segName, cut)
+	if err != nil {
+		return Airing{}, err
+	}
+
+	segments := hls.GenerateSegments(span, cut, segName, s.playlistDir, s.trackService.Container())
+
+	defer s.recordStarted()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	isSameSlot := s.IsPlaying && s.live == nil && s.interlude == nil &&
+		trackID(s.CurrentTrack) == current.ID && s.trackOffset == trackOffset
+	if !isSameSlot || s.publishedUntil() > start {
+		return Airing{}, errors.New("playback state was changed, try again")
+	}
+
+	s.playlist.SpliceCurrent(start-s.trackOffset, start+span-s.trackOffset, segments)
+	s.isSlotCut = true
+	s.duckedUntil = start + span
+	s.UpdatedAt = time.Now().Unix()
+
+	s.markStarted(&track.Track{Name: clip.Name}, true)
+
+	return Airing{Over: true, Delay: max(start-s.CurrentTrackElapsed, 0)}, nil
+}
+
+// planInterlude puts the first waiting interlude into the next slot. If the crossfade into the next track
+// is already exposed to listeners or the current slot was rearranged, the interlude is planned after
+// the next track instead, once it is loaded. The caller must hold the mutex.
+func (s *State) planInterlude() {
+	if s.isInterludeNext || len(s.interludes) == 0 || s.live != nil || s.playlist == nil {
+		return
+	}
+
+	if s.transitionAt > 0 {
+		if s.isSlotCut || s.transitionAt < s.publishedUntil() {
+			return
+		}
+
+		// The crossfade is dropped, since the interlude airs between the tracks
+		s.playlist.ChangeCurrent(sliceSegments(s.currentSegments, s.trackOffset, math.Inf(1)))
+		s.transitionAt = 0
+	}
+
+	s.playlist.ChangeNext(s.interludes[0].segments)
+	s.nextOffset = 0
+	s.isInterludeNext = true
+}
+
+// loadInterlude moves the first waiting interlude to the current slot. If a track has just ended,
+// it counts as played, and the head of the queue is planned after the interlude. The caller must hold the mutex.
+func (s *State) loadInterlude() error {
+	if s.interlude == nil {
+		err := s.queueService.SpinQueue()
+		if err != nil {
+			return err
+		}
+	}
+
+	s.refill()
+
+	head, _, err := s.queueService.CurrentAndNextTrack()
+	if err != nil {
+		return err
+	}
+
+	// The queue was changed meanwhile, the new head is planned by prepareNextSlot
+	if trackID(s.nextTrack) != trackID(head) {
+		s.nextSegments = nil
+		head = nil
+	}
+
+	it := s.interludes[0]
+	s.interludes = s.interludes[1:]
+	if it.jingleID != "" && s.jingles != nil {
+		s.jingles.Aired(it.jingleID, time.Now())
+	}
+
+	s.interlude = it
+	s.isInterludeNext = false
+	s.CurrentTrack = it.track
+	s.CurrentTrackElapsed = 0
+	s.trackOffset = 0
+	s.currentSegments = it.segments
+	s.nextTrack = head
+	s.nextOffset = 0
+	s.transitionAt = 0
+	s.isSlotCut = false
+	s.duckedUntil = 0
+
+	nextSeg := s.nextSegments
+	if len(s.interludes) > 0 {
+		nextSeg = s.interludes[0].segments
+		s.isInterludeNext = true
+	}
+
+	s.playlist.Next(nextSeg)
+	s.markStarted(it.track, false)
+
+	return nil
+}
+
+// dueJingle counts the track that has just started and returns the jingle the rotation rules ask for
+// after it, if any. Failures are only logged. The caller must hold the mutex.
+func (s *State) dueJingle(current *track.Track) *jingle.Jingle {
+	if s.jingles == nil {
+		return nil
+	}
+
+	s.jingles.TrackStarted()
+
+	if len(s.interludes) > 0 { // Waiting clips already separate the tracks
+		return nil
+	}
+
+	now := time.Now()
+	end := now.Add(time.Duration((current.Duration - s.trackOffset) * float64(time.Second)))
+	j, err := s.jingles.Next(now, end)
+	if err != nil {
+		s.log.Warn("Jingle rotation failed: " + err.Error())
+		return nil
+	}
+
+	return j
+}
+
+// airJingle prepares the segments of the jingle and adds it to the waiting interludes,
+// unless a clip was put on air meanwhile. Failures are only logged.
+func (s *State) airJingle(j *jingle.Jingle) {
+	segName := jingleSegmentsPrefix + j.ID
+	segDuration := s.timing().SegmentDuration()
+	err := s.trackService.MakeHLSPlaylist(j.Path, s.playlistDir, segName, segDuration)
+	if err != nil {
+		s.log.Warn("Jingle is skipped: " + err.Error())
+		return
+	}
+
+	it := &interlude{
+		segName:  segName,
+		track:    &track.Track{Name: jingle.HistoryPrefix + j.Name, Path: j.Path, Duration: j.Duration},
+		segments: hls.GenerateSegments(j.Duration, segDuration, segName, s.playlistDir, s.trackService.Container()),
+		jingleID: j.ID,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.IsPlaying || len(s.interludes) > 0 {
+		return
+	}
+
+	s.interludes = append(s.interludes, it)
+	s.planInterlude()
+}
+
+// reloadHead plans the new head of the queue after the interlude or the ended live source that is on air.
+func (s *State) reloadHead(head *track.Track) error {
+	s.mutex.Lock()
+	isHeadChanged := trackID(s.nextTrack) != trackID(head)
+	s.mutex.Unlock()
+
+	if !isHeadChanged {
+		return nil
+	}
+
+	headSeg, err := s.makeHLSSegments(head, s.playlistDir)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.interlude == nil && s.live == nil {
+		return nil // The interlude or the live source ended while segments were generated
+	}
+
+	if !s.isInterludeNext {
+		s.playlist.ChangeNext(headSeg)
+	}
+
+	s.nextTrack = head
+	s.nextSegments = headSeg
+	s.nextOffset = 0
+
+	return nil
+}
+
+// skipInterlude ends the interlude that is on air as soon as possible.
+//
+// Returns:
+//   - The track or clip that plays after it, or false if no interlude is on air.
+func (s *State) skipInterlude() (*track.Track, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.interlude == nil {
+		return nil, false, nil
+	}
+
+	next := s.nextTrack
+	if s.isInterludeNext {
+		next = s.interludes[0].track
+	}
+
+	if next == nil {
+		return nil, true, errors.New("there is no track to play next")
+	}
+
+	s.playlist.TrimCurrent(s.slotElapsed())
+	s.isSlotCut = true
+	s.UpdatedAt = time.Now().Unix()
+
+	return next, true, nil
+}
+
+// filesInUse returns the prefixes of the segment files of live sources, clips and the played segments listed
+// for seeking back that must survive the cleanup, besides the files of the current and next tracks.
+// The caller must hold the mutex.
+func (s *State) filesInUse() []string {
+	keep := make([]string, 0, len(s.interludes)+2)
+	if s.playlist != nil {
+		keep = append(keep, s.playlist.PlayedFiles()...)
+	}
+
+	if s.live != nil {
+		keep = append(keep, s.live.id)
+	}
+
+	if s.interlude != nil {
+		keep = append(keep, s.interlude.segName)
+	}
+
+	for _, it := range s.interludes {
+		keep = append(keep, it.segName)
+	}
+
+	return keep
+}
+
+// clearInterludes drops the interludes that are on air or waiting. The caller must hold the mutex.
+func (s *State) clearInterludes() {
+	s.interlude = nil
+	s.interludes = nil
+	s.isInterludeNext = false
+	s.duckedUntil = 0
+}
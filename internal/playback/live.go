@@ -0,0 +1,153 @@
+package playback
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/events"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+// liveSlot is an external audio source that pre-empts the queue while it is connected.
+type liveSlot struct {
+	id      string       // Prefix of the live segment files
+	track   *track.Track // Pseudo track shown as the current one while the source is on air
+	isOnAir bool         // Whether the live segments are in the current slot
+	isEnded bool         // Whether the source has disconnected
+}
+
+// GoLive pre-empts the queue with a live source. The segments of the current track that are already exposed
+// to listeners are kept, and the live segments follow right after them. If playback is paused, it starts
+// with the live source.
+//
+// Parameters:
+//   - id: The prefix of the live segment files, which also identifies the source in further calls.
+//   - name: The name shown as the current track while the source is on air.
+//   - segments: The first segments of the live source.
+//
+// Returns:
+//   - An error if another live source is on air.
+func (s *State) GoLive(id, name string, segments []*hls.Segment) error {
+	s.mutex.Lock()
+
+	if s.live != nil {
+		s.mutex.Unlock()
+		return errors.New("another live source is on air")
+	}
+
+	s.live = &liveSlot{id: id, track: &track.Track{Name: name}}
+
+	if s.IsPlaying {
+		s.playlist.TrimCurrent(s.slotElapsed())
+		s.playlist.ChangeNext(segments)
+		s.transitionAt = 0
+		s.nextOffset = 0
+		s.isSlotCut = true
+		s.isInterludeNext = false // Waiting interludes air after the live source
+		s.UpdatedAt = time.Now().Unix()
+		s.mutex.Unlock()
+		return nil
+	}
+
+	s.playlist = s.newPlaylist(segments, []*hls.Segment{})
+	s.enterLiveSlot()
+	s.CurrentTrackElapsed = 0
+	s.trackOffset = 0
+	s.generatePlaylists()
+	s.UpdatedAt = time.Now().Unix()
+	s.IsPlaying = true
+	snapshot := s.snapshot()
+	s.mutex.Unlock()
+
+	s.playbackService.SaveSnapshot(snapshot)
+	s.events.Publish(events.PlaybackResumed{ChannelID: s.channelID, TrackName: name})
+	s.trackStarted(&track.Track{Name: name}, false)
+
+	return nil
+}
+
+// AddLiveSegments appends the segments of the live source as they become available.
+//
+// Parameters:
+//   - id: The identifier the live source was put on air with.
+//   - segments: The new segments.
+//
+// Returns:
+//   - An error if the live source is no longer on air, e.g. playback was paused.
+func (s *State) AddLiveSegments(id string, segments []*hls.Segment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.live == nil || s.live.id != id {
+		return errLiveOffAir
+	}
+
+	if s.live.isOnAir {
+		s.playlist.AppendCurrent(segments)
+	} else {
+		s.playlist.AddSegments(segments)
+	}
+
+	return nil
+}
+
+// EndLive hands the stream back to the queue once the live source disconnects.
+// The live segments that are already available play to the end, and the queue continues from its head.
+//
+// Parameters:
+//   - id: The identifier the live source was put on air with.
+func (s *State) EndLive(id string) {
+	current, _, err := s.queueService.CurrentAndNextTrack()
+	if err != nil {
+		s.log.Error(err.Error())
+	}
+
+	currentSeg, err := s.makeHLSSegments(current, s.playlistDir)
+	if err != nil {
+		s.log.Error(err.Error())
+		currentSeg = []*hls.Segment{}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.live == nil || s.live.id != id {
+		return
+	}
+
+	if !s.live.isOnAir { // The source disconnected before it went on air, so the queue goes on as after a skip
+		s.live = nil
+		s.playlist.ChangeNext(s.nextSegments)
+		s.planInterlude()
+		return
+	}
+
+	s.live.isEnded = true
+	s.playlist.ChangeNext(currentSeg)
+	s.nextTrack = current
+	s.nextSegments = currentSeg
+	s.nextOffset = 0
+}
+
+// isLive reports whether a live source pre-empts the queue.
+func (s *State) isLive() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.live != nil
+}
+
+// enterLiveSlot makes the live source the current slot. The caller must hold the mutex.
+func (s *State) enterLiveSlot() {
+	s.live.isOnAir = true
+	s.IsLive = true
+	s.CurrentTrack = s.live.track
+	s.currentSegments = nil
+	s.nextTrack = nil
+	s.nextSegments = nil
+	s.nextOffset = 0
+	s.transitionAt = 0
+	s.isSlotCut = false
+	s.duckedUntil = 0
+}
@@ -1,30 +1,55 @@
 package playback
 
 import (
+	"errors"
 	"log/slog"
+	"sync"
 	"time"
 )
 
 type Service struct {
-	store Store
-	log   *slog.Logger
+	store     Store
+	channelID string
+	log       *slog.Logger
+
+	// The history entry the last return to a previous track went back to, so the next one goes further back.
+	// It's reset once the playback moves on to any other track.
+	backCursor int
+	backTarget string // The ID of the track the last return went back to, until it starts airing
+	mutex      sync.Mutex
 }
 
-func NewService(store Store) *Service {
+// NewService creates a playback history service scoped to a single channel.
+func NewService(store Store, channelID string, log *slog.Logger) *Service {
 	return &Service{
-		store: store,
+		store:     store,
+		channelID: channelID,
+		log:       log,
 	}
 }
 
 // AddPlaybackHistory logs a playback event for a given track.
 //
 // Parameters:
+//   - trackID: The ID of the track that was played.
 //   - trackName: The name of the track that was played.
-func (s *Service) AddPlaybackHistory(trackName string) {
-	err := s.store.AddPlaybackHistory(time.Now().Unix(), trackName)
+func (s *Service) AddPlaybackHistory(trackID, trackName string) {
+	err := s.store.AddPlaybackHistory(s.channelID, time.Now().Unix(), trackID, trackName)
 	if err != nil {
 		s.log.Error("Failed to add playback history: " + err.Error())
 	}
+
+	// Clips and live sources don't interrupt walking back
+	if trackID == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	if trackID != s.backTarget {
+		s.backCursor = 0
+	}
+	s.backTarget = ""
+	s.mutex.Unlock()
 }
 
 // RecentPlaybackHistory retrieves the most recent playback history records.
@@ -35,10 +60,42 @@ func (s *Service) AddPlaybackHistory(trackName string) {
 // Returns:
 //   - A slice of PlaybackHistory pointers, or an error.
 func (s *Service) RecentPlaybackHistory(limit int) ([]*History, error) {
-	history, err := s.store.RecentPlaybackHistory(limit)
+	history, err := s.store.RecentPlaybackHistory(s.channelID, limit)
 	return history, err
 }
 
+// PreviousTrackID finds the most recently played track that differs from the current one. Repeated calls
+// walk further back in the history, until the playback moves on to a track other than the returned one.
+//
+// Parameters:
+//   - currentID: The ID of the track that is playing now.
+//
+// Returns:
+//   - The ID of the previous track, or an error if there is none in the history.
+func (s *Service) PreviousTrackID(currentID string) (string, error) {
+	history, err := s.store.RecentPlaybackHistory(s.channelID, previousTrackLookup)
+	if err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, item := range history {
+		if s.backCursor > 0 && item.ID >= s.backCursor {
+			continue
+		}
+
+		if item.TrackID != "" && item.TrackID != currentID {
+			s.backCursor = item.ID
+			s.backTarget = item.TrackID
+			return item.TrackID, nil
+		}
+	}
+
+	return "", errors.New("no previous track in playback history")
+}
+
 // DeleteOldPlaybackHistory removes outdated playback history entries from the store.
 func (s *Service) DeleteOldPlaybackHistory() {
 	_, err := s.store.DeleteOldPlaybackHistory()
@@ -46,3 +103,23 @@ func (s *Service) DeleteOldPlaybackHistory() {
 		s.log.Warn("Failed to delete old playback history: " + err.Error())
 	}
 }
+
+// SaveSnapshot persists the playback state, so it can be resumed after a restart.
+//
+// Parameters:
+//   - snapshot: The playback state to persist.
+func (s *Service) SaveSnapshot(snapshot *Snapshot) {
+	err := s.store.SavePlaybackSnapshot(s.channelID, snapshot)
+	if err != nil {
+		s.log.Warn("Failed to save playback snapshot: " + err.Error())
+	}
+}
+
+// Snapshot retrieves the last persisted playback state.
+//
+// Returns:
+//   - A Snapshot pointer, nil if nothing was saved yet, or an error.
+func (s *Service) Snapshot() (*Snapshot, error) {
+	snapshot, err := s.store.PlaybackSnapshot(s.channelID)
+	return snapshot, err
+}
@@ -2,23 +2,43 @@ package playback
 
 import (
 	"errors"
+	"log/slog"
+	"slices"
 	"testing"
 )
 
+var testLog = slog.New(slog.DiscardHandler)
+
 type mockStore struct {
-	addPlaybackHistoryFn      func(playedAt int64, trackName string) error
+	addPlaybackHistoryFn      func(playedAt int64, trackID, trackName string) error
 	recentPlaybackHistoryFn   func(limit int) ([]*History, error)
 	deleteOldPlaybackHistoryFn func() (int64, error)
+	savePlaybackSnapshotFn     func(snapshot *Snapshot) error
+	playbackSnapshotFn         func() (*Snapshot, error)
+}
+
+func (m *mockStore) SavePlaybackSnapshot(channelID string, snapshot *Snapshot) error {
+	if m.savePlaybackSnapshotFn != nil {
+		return m.savePlaybackSnapshotFn(snapshot)
+	}
+	return nil
+}
+
+func (m *mockStore) PlaybackSnapshot(channelID string) (*Snapshot, error) {
+	if m.playbackSnapshotFn != nil {
+		return m.playbackSnapshotFn()
+	}
+	return nil, nil
 }
 
-func (m *mockStore) AddPlaybackHistory(playedAt int64, trackName string) error {
+func (m *mockStore) AddPlaybackHistory(channelID string, playedAt int64, trackID, trackName string) error {
 	if m.addPlaybackHistoryFn != nil {
-		return m.addPlaybackHistoryFn(playedAt, trackName)
+		return m.addPlaybackHistoryFn(playedAt, trackID, trackName)
 	}
 	return nil
 }
 
-func (m *mockStore) RecentPlaybackHistory(limit int) ([]*History, error) {
+func (m *mockStore) RecentPlaybackHistory(channelID string, limit int) ([]*History, error) {
 	if m.recentPlaybackHistoryFn != nil {
 		return m.recentPlaybackHistoryFn(limit)
 	}
@@ -36,13 +56,13 @@ func TestService_AddPlaybackHistory(t *testing.T) {
 	t.Run("calls store with correct track name", func(t *testing.T) {
 		var gotName string
 		mock := &mockStore{
-			addPlaybackHistoryFn: func(playedAt int64, trackName string) error {
+			addPlaybackHistoryFn: func(playedAt int64, trackID, trackName string) error {
 				gotName = trackName
 				return nil
 			},
 		}
-		svc := NewService(mock)
-		svc.AddPlaybackHistory("Test Track")
+		svc := NewService(mock, "main", testLog)
+		svc.AddPlaybackHistory("1", "Test Track")
 		if gotName != "Test Track" {
 			t.Errorf("expected track name %q, got %q", "Test Track", gotName)
 		}
@@ -60,7 +80,7 @@ func TestService_RecentPlaybackHistory(t *testing.T) {
 				return expected, nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, "main", testLog)
 		history, err := svc.RecentPlaybackHistory(50)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -79,7 +99,7 @@ func TestService_RecentPlaybackHistory(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, "main", testLog)
 		_, err := svc.RecentPlaybackHistory(50)
 		if err == nil {
 			t.Error("expected error, got nil")
@@ -96,10 +116,113 @@ func TestService_DeleteOldPlaybackHistory(t *testing.T) {
 				return 5, nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, "main", testLog)
 		svc.DeleteOldPlaybackHistory()
 		if !called {
 			t.Error("DeleteOldPlaybackHistory was not called on store")
 		}
 	})
 }
+
+func TestService_SaveSnapshot(t *testing.T) {
+	t.Run("passes snapshot to store", func(t *testing.T) {
+		var got *Snapshot
+		mock := &mockStore{
+			savePlaybackSnapshotFn: func(snapshot *Snapshot) error {
+				got = snapshot
+				return nil
+			},
+		}
+		svc := NewService(mock, "main", testLog)
+		svc.SaveSnapshot(&Snapshot{TrackID: "1", Elapsed: 42})
+		if got == nil || got.TrackID != "1" || got.Elapsed != 42 {
+			t.Errorf("unexpected snapshot passed to store: %+v", got)
+		}
+	})
+
+	t.Run("does not panic on store error", func(t *testing.T) {
+		mock := &mockStore{
+			savePlaybackSnapshotFn: func(snapshot *Snapshot) error {
+				return errors.New("db error")
+			},
+		}
+		svc := NewService(mock, "main", testLog)
+		svc.SaveSnapshot(&Snapshot{})
+	})
+}
+
+func TestService_PreviousTrackID(t *testing.T) {
+	t.Run("skips entries of the current track", func(t *testing.T) {
+		mock := &mockStore{
+			recentPlaybackHistoryFn: func(limit int) ([]*History, error) {
+				return []*History{
+					{ID: 3, TrackID: "c", TrackName: "Current"},
+					{ID: 2, TrackID: "", TrackName: "Legacy entry"},
+					{ID: 1, TrackID: "p", TrackName: "Previous"},
+				}, nil
+			},
+		}
+		svc := NewService(mock, "main", testLog)
+		id, err := svc.PreviousTrackID("c")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != "p" {
+			t.Errorf("expected previous track %q, got %q", "p", id)
+		}
+	})
+
+	t.Run("walks back on repeated presses", func(t *testing.T) {
+		history := []*History{
+			{ID: 1, TrackID: "w"},
+			{ID: 2, TrackID: "x"},
+			{ID: 3, TrackID: "y"},
+			{ID: 4, TrackID: "z"},
+		}
+		mock := &mockStore{
+			addPlaybackHistoryFn: func(playedAt int64, trackID, trackName string) error {
+				history = append(history, &History{ID: len(history) + 1, TrackID: trackID})
+				return nil
+			},
+			recentPlaybackHistoryFn: func(limit int) ([]*History, error) {
+				recent := slices.Clone(history)
+				slices.Reverse(recent)
+				return recent, nil
+			},
+		}
+		svc := NewService(mock, "main", testLog)
+
+		current := "z"
+		for _, want := range []string{"y", "x", "w"} {
+			id, err := svc.PreviousTrackID(current)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id != want {
+				t.Fatalf("expected previous track %q, got %q", want, id)
+			}
+
+			svc.AddPlaybackHistory(id, "")
+			current = id
+		}
+
+		// Moving on to another track starts over from the latest entries
+		svc.AddPlaybackHistory("v", "")
+		if id, _ := svc.PreviousTrackID("v"); id != "w" {
+			t.Errorf("expected the track before the current one, got %q", id)
+		}
+	})
+
+	t.Run("no previous track", func(t *testing.T) {
+		mock := &mockStore{
+			recentPlaybackHistoryFn: func(limit int) ([]*History, error) {
+				return []*History{{ID: 1, TrackID: "c"}}, nil
+			},
+		}
+		svc := NewService(mock, "main", testLog)
+		_, err := svc.PreviousTrackID("c")
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
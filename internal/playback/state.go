@@ -6,12 +6,20 @@
 package playback
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/cheatsnake/airstation/internal/autodj"
+	"github.com/cheatsnake/airstation/internal/events"
+	"github.com/cheatsnake/airstation/internal/jingle"
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
 	"github.com/cheatsnake/airstation/internal/pkg/hls"
 	"github.com/cheatsnake/airstation/internal/queue"
 	"github.com/cheatsnake/airstation/internal/track"
@@ -21,17 +29,36 @@ import (
 // elapsed playback time, playlist management, and synchronization tools for safe concurrent access.
 type State struct {
 	CurrentTrack        *track.Track `json:"currentTrack"`        // The currently playing track
-	CurrentTrackElapsed float64      `json:"currentTrackElapsed"` // Seconds elapsed since the current track started playing
+	CurrentTrackElapsed float64      `json:"currentTrackElapsed"` // Position (in seconds) of the current track that is playing now
 	IsPlaying           bool         `json:"isPlaying"`           // Whether a track is currently playing
+	IsLive              bool         `json:"isLive"`              // Whether a live source pre-empts the queue
 	UpdatedAt           int64        `json:"updatedAt"`           // Unix timestamp of the last state update
 
-	NewTrackNotify chan string `json:"-"` // Channel to notify when a new track starts playing
-	PlayNotify     chan bool   `json:"-"` // Channel to notify when playback starts
-	PauseNotify    chan bool   `json:"-"` // Channel to notify when playback is paused
-
 	PlaylistStr string        `json:"-"` // Current HLS playlist as a string
 	playlist    *hls.Playlist // Internal representation of the HLS playlist
 	playlistDir string        // Directory where HLS playlist segments are stored
+	uriPrefix   string        // Prefix of segment URIs relative to the URL the playlist is served from
+	artworkURL  string        // URL of the picture players show along with the tracks, empty if there is none
+
+	renditions         []string          // Names of the extra renditions the segments are made in
+	renditionPlaylists map[string]string // Current HLS playlists of the renditions by their names
+	partDuration       float64           // Duration of Low-Latency HLS parts in seconds, 0 if the mode is off
+	dvrWindow          float64           // Duration of the played segments listed for seeking back in seconds, 0 if the mode is off
+	playlistUpdated    chan struct{}     // Closed once the playlists are generated again
+
+	crossfade       Crossfade      // Settings for blending consecutive tracks
+	nextTrack       *track.Track   // The track planned to play after the current one
+	currentSegments []*hls.Segment // All segments of the current track
+	nextSegments    []*hls.Segment // All segments of the next track
+	trackOffset     float64        // Position of the current track where its planned segments start
+	nextOffset      float64        // Position of the next track where its planned segments start
+	transitionAt    float64        // Position of the current track where the crossfade begins, 0 if there is none
+	isSlotCut       bool           // Whether the current slot was cut short by a skip or mixed with a voice-over, so its segments must stay as they are
+	duckedUntil     float64        // Position of the current track until which a voice-over is mixed in, 0 if there is none
+
+	interludes      []*interlude // Clips waiting to air between tracks
+	interlude       *interlude   // The clip on air, nil if a track or a live source is on air
+	isInterludeNext bool         // Whether the first waiting clip is planned in the next slot instead of the next track
 
 	refreshCount    int64   // Number of state refresh cycles completed
 	refreshInterval float64 // Time interval (in seconds) between state updates
@@ -39,7 +66,16 @@ type State struct {
 	trackService    *track.Service
 	queueService    *queue.Service
 	playbackService *Service
+	autoDJ          *autodj.Service // Keeps the queue filled, nil if not set
+	jingles         *jingle.Rotator // Splices jingles between tracks, nil if not set
+	segmentCache    *hls.Cache      // Keeps the segments of tracks between plays, nil if not set
+	live            *liveSlot       // The live source that pre-empts the queue, nil if there is none
+	events          *events.Bus     // Receives the playback events, nil if not set
+	channelID       string          // The channel the playback events are published for
 
+	started []events.TrackStarted // Tracks that started airing under the mutex, recorded once it's released
+
+	done  chan struct{}
 	log   *slog.Logger
 	mutex sync.Mutex
 }
@@ -52,10 +88,6 @@ func NewState(ts *track.Service, qs *queue.Service, ps *Service, tmpDir string,
 		IsPlaying:           false,
 		UpdatedAt:           time.Now().Unix(),
 
-		NewTrackNotify: make(chan string),
-		PlayNotify:     make(chan bool),
-		PauseNotify:    make(chan bool),
-
 		trackService:    ts,
 		queueService:    qs,
 		playbackService: ps,
@@ -64,42 +96,308 @@ func NewState(ts *track.Service, qs *queue.Service, ps *Service, tmpDir string,
 		playlistDir:     tmpDir,
 		refreshInterval: 1,
 
-		log: log,
+		playlistUpdated: make(chan struct{}),
+
+		done: make(chan struct{}),
+		log:  log,
 	}
 }
 
 // Run starts the state update loop which refreshes playback progress and switches tracks when needed.
-func (s *State) Run() {
+// The playback position is saved whenever a new slot starts and periodically in between. The loop exits
+// once Stop is called, or once the context is canceled, in which case the position is saved as well,
+// so it resumes from the same place after a restart.
+func (s *State) Run(ctx context.Context) {
 	ticker := time.NewTicker(time.Duration(s.refreshInterval) * time.Second)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			s.persist()
+			return
+		case <-ticker.C:
+		}
+
+		s.mutex.Lock()
 		if !s.IsPlaying {
+			s.mutex.Unlock()
 			continue
 		}
 
-		s.mutex.Lock()
 		s.CurrentTrackElapsed += s.refreshInterval
 		s.refreshCount++
 
-		if s.CurrentTrackElapsed >= s.CurrentTrack.Duration {
-			err := s.loadNextTrack()
-			if err != nil {
+		var due *jingle.Jingle
+		isSlotChanged := false
+		if overtime := s.slotElapsed() - s.playlist.CurrentDuration(); overtime >= 0 {
+			var err error
+			due, err = s.loadNextTrack()
+			switch {
+			case errors.Is(err, errQueueEnded):
+				s.mutex.Unlock()
+				s.Pause()
+				continue
+			case errors.Is(err, errLiveWaiting):
+				// The live source hasn't delivered the next segments yet, so the live window waits for them
+				s.CurrentTrackElapsed -= s.refreshInterval
+			case errors.Is(err, errHeadChanged):
+				// The current slot waits until the segments of the new head of the queue are prepared
+				s.CurrentTrackElapsed -= s.refreshInterval
+				isSlotChanged = true
+			case err != nil:
 				s.log.Error(err.Error())
+			default:
+				// The slot rarely ends exactly on a tick, the time played past its end belongs to the next one
+				s.CurrentTrackElapsed += overtime
+				isSlotChanged = true
 			}
 
-			go s.queueService.CleanupHLSPlaylists(s.playlistDir)
-			go s.playbackService.AddPlaybackHistory(s.CurrentTrack.Name)
+			if !errors.Is(err, errLiveWaiting) && !errors.Is(err, errHeadChanged) {
+				// Segments of the ended track may be as long as the longest ones the playlist has listed
+				delay := 2 * time.Duration(s.playlist.MaxSegmentDuration) * time.Second
+				go s.queueService.CleanupHLSPlaylists(s.playlistDir, delay, s.filesInUse()...)
+			}
 		}
 
-		s.PlaylistStr = s.playlist.Generate(s.CurrentTrackElapsed)
+		s.generatePlaylists()
 		s.UpdatedAt = time.Now().Unix()
+		snapshot := s.snapshot()
 		s.mutex.Unlock()
+
+		if isSlotChanged {
+			s.recordStarted()
+			s.prepareNextSlot(due)
+		}
+
+		// Within a slot the position is saved only now and then, a crash loses at most the time since the last save
+		if isSlotChanged || s.refreshCount%snapshotRefreshes == 0 {
+			s.playbackService.SaveSnapshot(snapshot)
+		}
+	}
+}
+
+// PlayingSegment returns the segment that plays at the moment and the name of the track it belongs to.
+// The segment is nil if the playback is paused.
+func (s *State) PlayingSegment() (*hls.Segment, string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.IsPlaying || s.playlist == nil {
+		return nil, ""
+	}
+
+	name := ""
+	if s.CurrentTrack != nil {
+		name = s.CurrentTrack.Name
+	}
+
+	return s.playlist.PlayingSegment(s.slotElapsed()), name
+}
+
+// NowPlaying returns the track or clip that is on air, or nil if the playback is paused.
+func (s *State) NowPlaying() *track.Track {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.IsPlaying {
+		return nil
+	}
+
+	return s.CurrentTrack
+}
+
+// persist saves the current position of the playback if it's playing.
+func (s *State) persist() {
+	s.mutex.Lock()
+	if !s.IsPlaying {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.UpdatedAt = time.Now().Unix()
+	snapshot := s.snapshot()
+	s.mutex.Unlock()
+
+	s.playbackService.SaveSnapshot(snapshot)
+}
+
+// Stop halts playback without persisting it and terminates the state update loop.
+// It is used when the state is discarded, e.g. on channel deletion.
+func (s *State) Stop() {
+	s.mutex.Lock()
+	s.IsPlaying = false
+	s.PlaylistStr = ""
+	s.renditionPlaylists = nil
+	s.live = nil
+	close(s.done)
+	s.mutex.Unlock()
+}
+
+// RenditionPlaylist returns the current HLS playlist of a rendition.
+//
+// Parameters:
+//   - name: The name of the rendition.
+//
+// Returns:
+//   - The playlist, empty if the playback is paused, and false if there is no such rendition.
+func (s *State) RenditionPlaylist(name string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !slices.Contains(s.renditions, name) {
+		return "", false
+	}
+
+	return s.renditionPlaylists[name], true
+}
+
+// HasPart reports whether the current playlists list the given part of the given segment.
+// It's used to answer Low-Latency HLS blocking playlist requests.
+//
+// Parameters:
+//   - msn: The media sequence number of the segment.
+//   - part: The index of the part within the segment, or -1 to wait for the whole segment.
+//
+// Returns:
+//   - Whether the part is listed, and a channel that is closed once the playlists change.
+func (s *State) HasPart(msn int64, part int) (bool, <-chan struct{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.IsPlaying || s.playlist == nil {
+		return false, s.playlistUpdated
 	}
+
+	return s.playlist.HasPart(msn, part), s.playlistUpdated
+}
+
+// Timeline returns the segments that played within the live window and the one that plays now,
+// with the times they play at. It's empty if the playback is paused.
+func (s *State) Timeline() []hls.TimedSegment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.IsPlaying || s.playlist == nil {
+		return nil
+	}
+
+	return s.playlist.Timeline()
+}
+
+// MediaSequence returns the media sequence number of the segment that plays at the moment.
+func (s *State) MediaSequence() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.playlist == nil {
+		return 0
+	}
+
+	return s.playlist.MediaSequence()
+}
+
+// SetPartDuration turns on Low-Latency HLS with parts of the given duration, 0 turns it off.
+// The new duration is applied starting from the next playback start.
+func (s *State) SetPartDuration(duration float64) {
+	s.mutex.Lock()
+	s.partDuration = duration
+	s.mutex.Unlock()
+}
+
+// SetDVRWindow keeps the segments that played within the given duration (in seconds) listed in the playlists,
+// so listeners can seek back, 0 turns it off. The new window is applied starting from the next playback start.
+func (s *State) SetDVRWindow(duration float64) {
+	s.mutex.Lock()
+	s.dvrWindow = duration
+	s.mutex.Unlock()
+}
+
+// SetRenditions sets the names of the extra renditions the segments are made in,
+// so their playlists are generated along with the main one.
+func (s *State) SetRenditions(names []string) {
+	s.mutex.Lock()
+	s.renditions = names
+	s.mutex.Unlock()
+}
+
+// SetURIPrefix sets the prefix prepended to segment paths in the generated playlists.
+// It is required when the playlist is served from a URL nested deeper than the segments directory.
+// The new prefix is applied starting from the next playback start.
+func (s *State) SetURIPrefix(prefix string) {
+	s.mutex.Lock()
+	s.uriPrefix = prefix
+	s.mutex.Unlock()
+}
+
+// SetArtworkURL sets the URL of the picture players show along with the tracks.
+// It's embedded in the segments of the tracks that are planned after the change.
+func (s *State) SetArtworkURL(url string) {
+	s.mutex.Lock()
+	s.artworkURL = url
+	s.mutex.Unlock()
+}
+
+// SetSegmentCache sets the cache the segments of tracks are taken from instead of segmenting
+// a track every time it airs.
+func (s *State) SetSegmentCache(c *hls.Cache) {
+	s.mutex.Lock()
+	s.segmentCache = c
+	s.mutex.Unlock()
+}
+
+// SetAutoDJ sets the service that refills the queue before the playback reads it.
+func (s *State) SetAutoDJ(a *autodj.Service) {
+	s.mutex.Lock()
+	s.autoDJ = a
+	s.mutex.Unlock()
+}
+
+// SetJingles sets the rotator that splices jingles between the tracks.
+func (s *State) SetJingles(r *jingle.Rotator) {
+	s.mutex.Lock()
+	s.jingles = r
+	s.mutex.Unlock()
+}
+
+// SetEvents sets the bus the playback events of the channel are published on.
+func (s *State) SetEvents(bus *events.Bus, channelID string) {
+	s.mutex.Lock()
+	s.events = bus
+	s.channelID = channelID
+	s.mutex.Unlock()
+}
+
+// SetCrossfade changes the settings for blending consecutive tracks.
+// The new settings are applied starting from the next planned transition.
+func (s *State) SetCrossfade(cf Crossfade) error {
+	err := cf.Validate()
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.crossfade = cf
+	s.mutex.Unlock()
+
+	return nil
 }
 
 // Play starts playback by loading the current and next tracks into the HLS playlist.
 func (s *State) Play() error {
+	if s.isLive() {
+		return errLiveOnAir
+	}
+
+	err := s.queueService.Rewind()
+	if err != nil {
+		return err
+	}
+
+	s.refill()
+
 	current, next, err := s.queueService.CurrentAndNextTrack()
 	if err != nil {
 		return err
@@ -109,20 +407,81 @@ func (s *State) Play() error {
 		return errors.New("playback queue is empty")
 	}
 
-	err = s.initHLSPlaylist(current, next)
+	err = s.start(current, next, 0, 0, 0)
 	if err != nil {
 		return err
 	}
 
-	s.mutex.Lock()
-	s.CurrentTrack = current
-	s.PlaylistStr = s.playlist.Generate(s.CurrentTrackElapsed)
-	s.UpdatedAt = time.Now().Unix()
-	s.IsPlaying = true
-	s.mutex.Unlock()
+	s.trackStarted(current, false)
+
+	return nil
+}
+
+// Resume restores the playback saved before the last shutdown. If the track that was playing is still
+// at the head of the queue, it continues from the saved position, otherwise the queue starts from the beginning.
+// Playback stays paused if it was paused before the shutdown, and starts as usual if nothing was saved.
+//
+// Parameters:
+//   - withOfflineTime: Whether to add the time spent offline to the saved position,
+//     so the station continues as if it had never stopped.
+//
+// Returns:
+//   - An error if the queue cannot be read or the playlist cannot be prepared.
+func (s *State) Resume(withOfflineTime bool) error {
+	snapshot, err := s.playbackService.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	if snapshot == nil {
+		return s.Play()
+	}
 
-	s.PlayNotify <- true
-	go s.playbackService.AddPlaybackHistory(current.Name)
+	if !snapshot.IsPlaying {
+		return nil
+	}
+
+	s.refill()
+
+	current, next, err := s.queueService.CurrentAndNextTrack()
+	if err != nil {
+		return err
+	}
+
+	if current == nil || current.ID != snapshot.TrackID {
+		return s.Play()
+	}
+
+	elapsed := snapshot.Elapsed
+	if withOfflineTime {
+		elapsed += float64(max(time.Now().Unix()-snapshot.UpdatedAt, 0))
+	}
+
+	// The first generated playlist moves the live window once, so the sequences
+	// are restored one step behind to keep the numbering of the same segments.
+	mediaSeq := snapshot.MediaSequence - 1
+	disconSeq := snapshot.DisconSequence
+
+	if elapsed < current.Duration {
+		segDuration := float64(s.timing().SegmentDuration())
+		mediaSeq += int64(math.Floor(elapsed/segDuration) - math.Floor(snapshot.Elapsed/segDuration))
+		return s.start(current, next, elapsed, mediaSeq, disconSeq)
+	}
+
+	current, next, elapsed, err = s.skipPlayed(current, next, elapsed)
+	if errors.Is(err, errQueueEnded) {
+		return nil // The queue was played through while the station was offline
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.start(current, next, elapsed, mediaSeq+1, disconSeq+1)
+	if err != nil {
+		return err
+	}
+
+	s.trackStarted(current, false)
 
 	return nil
 }
@@ -132,52 +491,509 @@ func (s *State) Pause() {
 	s.mutex.Lock()
 	s.CurrentTrack = nil
 	s.CurrentTrackElapsed = 0
+	s.nextTrack = nil
+	s.currentSegments = nil
+	s.nextSegments = nil
+	s.trackOffset = 0
+	s.nextOffset = 0
+	s.transitionAt = 0
+	s.isSlotCut = false
+	s.live = nil
+	s.IsLive = false
+	s.clearInterludes()
 	s.playlist = nil
 	s.PlaylistStr = ""
+	s.renditionPlaylists = nil
 	s.IsPlaying = false
 	s.UpdatedAt = time.Now().Unix()
+	snapshot := s.snapshot()
 	s.mutex.Unlock()
 
-	s.PauseNotify <- false
+	s.playbackService.SaveSnapshot(snapshot)
+	s.events.Publish(events.PlaybackPaused{ChannelID: s.channelID})
 }
 
 // Reload refreshes the current playlist based on updated queue state, used after queue changes.
+// While a live source is on air, queue changes are picked up once it disconnects.
 func (s *State) Reload() error {
-	if !s.IsPlaying {
+	s.mutex.Lock()
+	isPlaying := s.IsPlaying
+	isLiveOnAir := s.live != nil && !s.live.isEnded
+	isHeadWaiting := s.interlude != nil || s.live != nil
+	s.mutex.Unlock()
+
+	if !isPlaying || isLiveOnAir {
 		return nil
 	}
 
+	s.refill()
+
 	current, next, err := s.queueService.CurrentAndNextTrack()
 	if err != nil {
 		return err
 	}
 
+	if isHeadWaiting { // The head of the queue hasn't played yet, it airs after the clip or the live source
+		return s.reloadHead(current)
+	}
+
 	isCurrentTrackChanged := current != nil && s.CurrentTrack.ID != current.ID
 	if isCurrentTrackChanged { // Restart if current track changed
 		s.Pause()
-		err = s.Play()
+		return s.Play()
+	}
+
+	if current == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	isNextTrackChanged := trackID(s.nextTrack) != trackID(next) ||
+		(next != nil && (s.nextTrack.CueIn != next.CueIn || s.nextTrack.CueOut != next.CueOut))
+	isPlanChanged := isNextTrackChanged ||
+		s.CurrentTrack.CrossfadeDisabled != current.CrossfadeDisabled ||
+		(next != nil && s.nextTrack.CrossfadeDisabled != next.CrossfadeDisabled)
+	nextSeg := s.nextSegments
+	s.mutex.Unlock()
+
+	if !isPlanChanged {
+		return nil
+	}
+
+	if isNextTrackChanged { // Change segments for next track if it or its cue points changed
+		nextSeg, err = s.makeHLSSegments(next, s.playlistDir)
 		if err != nil {
 			return err
 		}
 	}
 
-	segment := s.playlist.FirstNextTrackSegment()
-	isNextTrackChanged := segment != nil && !strings.Contains(segment.Path, next.ID)
-	if isNextTrackChanged { // Change segments for next track if it changed
-		nextSeg, err := s.makeHLSSegments(next, s.playlistDir)
+	s.mutex.Lock()
+	cf := s.crossfade
+	currentSeg := s.currentSegments
+	trackOffset := s.trackOffset
+	notBefore := s.publishedUntil()
+	isTransitionPublished := s.transitionAt > 0 && s.transitionAt < notBefore
+	isSlotCut := s.isSlotCut
+	isInterludeNext := s.isInterludeNext
+	s.mutex.Unlock()
+
+	// The transition with the previous next track is already exposed to listeners,
+	// so it stays as is and the new next track simply starts from the beginning.
+	// A planned interlude stays in the next slot, and the new next track airs after it.
+	plan := &slotPlan{next: nextSeg}
+	if !isTransitionPublished && !isSlotCut && !isInterludeNext {
+		plan, err = s.joinTracks(cf, current, next, currentSeg, nextSeg, trackOffset, notBefore)
 		if err != nil {
 			return err
 		}
-		s.mutex.Lock()
-		s.playlist.ChangeNext(nextSeg)
-		s.mutex.Unlock()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.playlist == nil || s.CurrentTrack == nil || s.CurrentTrack.ID != current.ID {
+		return nil // Playback state was changed while segments were generated
+	}
+
+	if plan.current != nil {
+		s.playlist.ChangeCurrent(plan.current)
+		s.transitionAt = plan.transitionAt
+	}
+
+	if !s.isInterludeNext {
+		s.playlist.ChangeNext(plan.next)
+	}
+
+	s.CurrentTrack = current
+	s.nextTrack = next
+	s.nextSegments = nextSeg
+	s.nextOffset = plan.nextOffset
+
+	return nil
+}
+
+// start begins playback of the current track from the given position.
+func (s *State) start(current, next *track.Track, elapsed float64, mediaSeq, disconSeq int64) error {
+	err := s.initHLSPlaylist(current, next, elapsed)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.playlist.SetMediaSequence(mediaSeq)
+	s.playlist.SetDisconSequence(disconSeq)
+	s.CurrentTrack = current
+	s.CurrentTrackElapsed = elapsed
+	s.generatePlaylists()
+	s.UpdatedAt = time.Now().Unix()
+	s.IsPlaying = true
+	snapshot := s.snapshot()
+	s.mutex.Unlock()
+
+	s.playbackService.SaveSnapshot(snapshot)
+	s.events.Publish(events.PlaybackResumed{ChannelID: s.channelID, TrackID: current.ID, TrackName: current.Name})
+
+	if s.jingles != nil {
+		s.jingles.TrackStarted()
 	}
 
 	return nil
 }
 
-// initHLSPlaylist prepares HLS segments for the current and next tracks, initializing a new playlist.
-func (s *State) initHLSPlaylist(current, next *track.Track) error {
+// skipPlayed advances the queue past the tracks that would have been played during the elapsed time,
+// counted from the beginning of the current track, according to the play-order mode of the queue.
+// Crossfade overlaps are not taken into account.
+//
+// Returns:
+//   - The current and next tracks and the position of the current one, or errQueueEnded
+//     if the queue would have been played through in stop-at-end or consume mode.
+func (s *State) skipPlayed(current, next *track.Track, elapsed float64) (*track.Track, *track.Track, float64, error) {
+	mode, err := s.queueService.Mode()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if mode == queue.ModeRepeatOne { // The current track repeats, so only the position within it moves on
+		if current.Duration <= 0 {
+			return nil, nil, 0, errors.New("current track has no duration")
+		}
+
+		return current, next, math.Mod(elapsed, current.Duration), nil
+	}
+
+	tracks, err := s.queueService.Queue()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	total := 0.0
+	for _, t := range tracks {
+		total += t.Duration
+	}
+
+	if total <= 0 {
+		return nil, nil, 0, errors.New("playback queue is empty")
+	}
+
+	// The queue is spinning in a loop and every track plays once per cycle, so full cycles can be skipped at once.
+	// In the other modes, each track plays at most once, so the queue ends once they're all skipped.
+	if mode == queue.ModeSequential || mode == queue.ModeShuffle {
+		elapsed = math.Mod(elapsed, total)
+	}
+
+	for range tracks {
+		if elapsed < current.Duration {
+			break
+		}
+
+		elapsed -= current.Duration
+		err = s.queueService.SpinQueue()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		current, next, err = s.queueService.CurrentAndNextTrack()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		if current == nil {
+			return nil, nil, 0, errQueueEnded
+		}
+	}
+
+	return current, next, elapsed, nil
+}
+
+// newPlaylist creates a playlist of the given segments with the settings of the playback.
+func (s *State) newPlaylist(cur, next []*hls.Segment) *hls.Playlist {
+	timing := s.timing()
+	playlist := hls.NewPlaylist(cur, next)
+	playlist.URIPrefix = s.uriPrefix
+	playlist.PartDuration = s.partDuration
+	playlist.DVRWindow = s.dvrWindow
+	playlist.MaxSegmentDuration = timing.SegmentDuration()
+	playlist.LiveSegmentsAmount = timing.LiveSegments()
+
+	return playlist
+}
+
+// applyTiming adapts the playlist to the segment duration and the live window in effect,
+// which may have been changed while the station is running. The caller must hold the mutex.
+func (s *State) applyTiming() {
+	timing := s.timing()
+	s.playlist.SetMaxSegmentDuration(timing.SegmentDuration())
+	if size := timing.LiveSegments(); size != s.playlist.LiveSegmentsAmount {
+		s.playlist.SetLiveSegmentsAmount(size)
+	}
+}
+
+// timing returns the segment duration and the live window of the station.
+func (s *State) timing() *hls.Timing {
+	return s.trackService.Timing()
+}
+
+// generatePlaylists generates the main playlist and the playlists of the renditions at the current position.
+// The rendition playlists are generated right after the main one, so they list the same segments. The caller must hold the mutex.
+func (s *State) generatePlaylists() {
+	s.applyTiming()
+	elapsed := s.slotElapsed()
+	for _, seg := range s.playlist.Announce(elapsed) {
+		s.embedMetadata(seg)
+	}
+
+	s.PlaylistStr = s.playlist.Generate(elapsed)
+
+	if len(s.renditions) > 0 {
+		playlists := make(map[string]string, len(s.renditions))
+		for _, name := range s.renditions {
+			playlists[name] = s.playlist.GenerateRendition(elapsed, name)
+		}
+		s.renditionPlaylists = playlists
+	}
+
+	// Wakes up the blocking playlist requests
+	close(s.playlistUpdated)
+	s.playlistUpdated = make(chan struct{})
+}
+
+// embedMetadata embeds the metadata of the track into the segment, in every rendition, before players load it.
+// Failures are only logged, since players still get the metadata from the playlist.
+func (s *State) embedMetadata(seg *hls.Segment) {
+	paths := []string{seg.Path}
+	for _, name := range s.renditions {
+		paths = append(paths, hls.RenditionPath(seg.Path, name))
+	}
+
+	for _, path := range paths {
+		if err := hls.EmbedMetadata(path, s.trackService.Container(), *seg.Meta); err != nil {
+			s.log.Warn("Failed to embed track metadata: "+err.Error(), "segment", path)
+		}
+	}
+}
+
+// snapshot captures the persisted part of the playback state. The caller must hold the mutex.
+func (s *State) snapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Elapsed:   s.CurrentTrackElapsed,
+		IsPlaying: s.IsPlaying,
+		UpdatedAt: s.UpdatedAt,
+	}
+
+	if s.CurrentTrack != nil {
+		snapshot.TrackID = s.CurrentTrack.ID
+	}
+
+	if s.playlist != nil {
+		snapshot.MediaSequence = s.playlist.MediaSequence()
+		snapshot.DisconSequence = s.playlist.DisconSequence()
+	}
+
+	return snapshot
+}
+
+// Skip ends the current track as soon as possible and moves on to the next one in the queue.
+// The segments of the current track that are already exposed to listeners are kept in the playlist,
+// so the stream continues without a restart and the next track follows right after them.
+//
+// Returns:
+//   - The track that plays after the skip, or an error if playback is paused or the segments cannot be prepared.
+func (s *State) Skip() (*track.Track, error) {
+	s.mutex.Lock()
+	isPlaying := s.IsPlaying
+	isLive := s.live != nil
+	s.mutex.Unlock()
+
+	if !isPlaying {
+		return nil, errors.New("playback is paused")
+	}
+
+	if isLive {
+		return nil, errLiveOnAir
+	}
+
+	if next, ok, err := s.skipInterlude(); ok {
+		return next, err
+	}
+
+	current, next, err := s.queueService.CurrentAndNextTrack()
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		return nil, errors.New("playback queue is empty")
+	}
+
+	if next == nil {
+		return nil, errors.New("there is no track to play next")
+	}
+
+	return s.skipTo(current.ID, next)
+}
+
+// skipTo cuts the current slot short, so the given track plays right after it.
+//
+// Parameters:
+//   - currentID: The ID of the track expected to be playing, the skip fails if another one is.
+//   - next: The track to play next.
+//
+// Returns:
+//   - The track or clip that plays after the skip.
+func (s *State) skipTo(currentID string, next *track.Track) (*track.Track, error) {
+	s.mutex.Lock()
+	nextSeg := s.nextSegments
+	isNextTrackChanged := trackID(s.nextTrack) != trackID(next)
+	s.mutex.Unlock()
+
+	var err error
+	if isNextTrackChanged {
+		nextSeg, err = s.makeHLSSegments(next, s.playlistDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.playlist == nil || s.CurrentTrack == nil || s.CurrentTrack.ID != currentID {
+		return nil, errors.New("playback state was changed, try again")
+	}
+
+	// When the crossfade is already exposed to listeners, the current slot ends with it anyway,
+	// and the next track continues after the mixed part unless it was replaced.
+	isTransitionPublished := s.transitionAt > 0 && s.transitionAt < s.publishedUntil()
+	if !isTransitionPublished {
+		s.playlist.TrimCurrent(s.slotElapsed())
+		s.transitionAt = 0
+	}
+
+	// A planned interlude still airs before the next track
+	if (!isTransitionPublished || isNextTrackChanged) && !s.isInterludeNext {
+		s.playlist.ChangeNext(nextSeg)
+		s.nextOffset = 0
+	}
+
+	s.nextTrack = next
+	s.nextSegments = nextSeg
+	s.isSlotCut = true
+	s.UpdatedAt = time.Now().Unix()
+
+	if s.isInterludeNext {
+		return s.interludes[0].track, nil
+	}
+
+	return next, nil
+}
+
+// Previous goes back to the track that was played before the current one according to the playback history.
+//
+// Returns:
+//   - The track that plays after the skip, or an error if there is no previous track or the skip fails.
+func (s *State) Previous() (*track.Track, error) {
+	current, err := s.jumpableTrack()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.playbackService.PreviousTrackID(current.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks, err := s.trackService.FindTracks([]string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tracks) == 0 {
+		return nil, errors.New("previous track no longer exists")
+	}
+
+	return s.jumpTo(current, tracks[0])
+}
+
+// Jump starts playing the track at the given position of the queue right away.
+//
+// Parameters:
+//   - position: The zero-based position of the track in the queue, 0 is the current track.
+//
+// Returns:
+//   - The track that plays after the skip, or an error if the position is invalid or the skip fails.
+func (s *State) Jump(position int) (*track.Track, error) {
+	current, err := s.jumpableTrack()
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := s.queueService.Queue()
+	if err != nil {
+		return nil, err
+	}
+
+	if position < 1 || position >= len(q) {
+		return nil, fmt.Errorf("position must be between 1 and %d", len(q)-1)
+	}
+
+	return s.jumpTo(current, q[position])
+}
+
+// jumpableTrack returns the track that is playing, or an error if the playback can't jump to another one now.
+func (s *State) jumpableTrack() (*track.Track, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.IsPlaying || s.CurrentTrack == nil {
+		return nil, errors.New("playback is paused")
+	}
+
+	if s.live != nil {
+		return nil, errLiveOnAir
+	}
+
+	if s.interlude != nil {
+		return nil, errInterludeOnAir
+	}
+
+	return s.CurrentTrack, nil
+}
+
+// jumpTo moves the target track in the queue, so it plays once the current slot is cut short.
+// Targets the play-order mode of the queue doesn't let air are rejected before the queue is changed.
+func (s *State) jumpTo(current, target *track.Track) (*track.Track, error) {
+	mode, err := s.queueService.Mode()
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == queue.ModeStopAtEnd {
+		isPlayed, err := s.queueService.IsPlayed(target.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if isPlayed {
+			return nil, errors.New("track was already played, the queue stops at its end")
+		}
+	}
+
+	if mode == queue.ModeRepeatOne {
+		// The head of the queue repeats, so the target replaces it and then repeats instead
+		err = s.queueService.PlayFirst(target)
+	} else {
+		err = s.queueService.PlayNext(target)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.skipTo(current.ID, target)
+}
+
+// initHLSPlaylist prepares HLS segments for the current and next tracks, initializing a new playlist
+// whose live window starts at the given position of the current track.
+func (s *State) initHLSPlaylist(current, next *track.Track, elapsed float64) error {
 	currentSeg, err := s.makeHLSSegments(current, s.playlistDir)
 	if err != nil {
 		return err
@@ -189,54 +1005,301 @@ func (s *State) initHLSPlaylist(current, next *track.Track) error {
 	}
 
 	s.mutex.Lock()
-	s.playlist = hls.NewPlaylist(currentSeg, nextSeg)
+	cf := s.crossfade
+	s.mutex.Unlock()
+
+	timing := s.timing()
+	segDuration := float64(timing.SegmentDuration())
+	notBefore := (math.Floor(elapsed/segDuration) + float64(timing.LiveSegments())) * segDuration
+	plan, err := s.joinTracks(cf, current, next, currentSeg, nextSeg, 0, notBefore)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.playlist = s.newPlaylist(plan.current, plan.next)
+	s.nextTrack = next
+	s.currentSegments = currentSeg
+	s.nextSegments = nextSeg
+	s.trackOffset = 0
+	s.nextOffset = plan.nextOffset
+	s.transitionAt = plan.transitionAt
+	s.isSlotCut = false
+	s.duckedUntil = 0
 	s.UpdatedAt = time.Now().Unix()
 	s.mutex.Unlock()
 
 	return nil
 }
 
-// loadNextTrack advances the queue, resets elapsed time, and updates playlist with next segments.
-func (s *State) loadNextTrack() error {
-	s.CurrentTrackElapsed = 0
-	err := s.queueService.SpinQueue()
-	if err != nil {
-		return err
+// loadNextTrack advances the queue and moves the track planned next to the current slot. Its segments are
+// already prepared, while the slot after it stays empty until prepareNextSlot fills it once the mutex is released.
+// A pending live source takes the next slot instead of the queue, and once it ends,
+// the queue continues from its head. The caller must hold the mutex.
+//
+// Returns:
+//   - The jingle that is due after the new track, or nil.
+func (s *State) loadNextTrack() (*jingle.Jingle, error) {
+	if s.live != nil && !s.live.isOnAir {
+		return nil, s.loadLiveSlot()
 	}
 
-	current, next, err := s.queueService.CurrentAndNextTrack()
+	if s.live != nil && !s.live.isEnded {
+		return nil, errLiveWaiting
+	}
+
+	if s.isInterludeNext {
+		return nil, s.loadInterlude()
+	}
+
+	// The head of the queue hasn't played yet, it was waiting for the live source or the interlude to end
+	isAfterLive := s.live != nil
+	isAfterInterlude := s.interlude != nil
+	if !isAfterLive && !isAfterInterlude {
+		err := s.queueService.SpinQueue()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.refill()
+
+	current, _, err := s.queueService.CurrentAndNextTrack()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if current == nil {
+		return nil, errQueueEnded
 	}
 
+	if (isAfterLive || isAfterInterlude) && trackID(s.nextTrack) != current.ID { // The queue was changed meanwhile
+		return nil, errHeadChanged
+	}
+
+	s.CurrentTrackElapsed = s.nextOffset
+	s.trackOffset = s.nextOffset
+	s.live = nil
+	s.IsLive = false
+	s.interlude = nil
 	s.CurrentTrack = current
-	nextTrackSegments, err := s.makeHLSSegments(next, s.playlistDir)
+
+	due := s.dueJingle(current)
+
+	// Waiting interludes air right after the track, so it isn't crossfaded
+	nextSeg := []*hls.Segment{}
+	if len(s.interludes) > 0 {
+		nextSeg = s.interludes[0].segments
+		s.isInterludeNext = true
+	}
+
+	s.markStarted(current, false)
+	s.playlist.Next(nextSeg)
+	s.playlist.ChangeCurrent(sliceSegments(s.nextSegments, s.trackOffset, math.Inf(1)))
+	s.currentSegments = s.nextSegments
+	s.nextSegments = nil
+	s.nextOffset = 0
+	s.transitionAt = 0
+	s.isSlotCut = false
+	s.duckedUntil = 0
+	s.nextTrack = nil // The track after the current one is planned by prepareNextSlot
+
+	return due, nil
+}
+
+// prepareNextSlot prepares what airs after the slot that has just started: the jingle that is due,
+// and the segments of the next track with the crossfade into it. FFmpeg runs outside of the mutex,
+// so the playlists keep being served meanwhile.
+func (s *State) prepareNextSlot(due *jingle.Jingle) {
+	if due != nil {
+		s.airJingle(due)
+	}
+
+	err := s.Reload()
 	if err != nil {
-		return err
+		s.log.Error("Next track preparation failed: " + err.Error())
 	}
+}
+
+// loadLiveSlot moves the live source to the current slot. The track it cut counts as played.
+func (s *State) loadLiveSlot() error {
+	if s.interlude == nil {
+		err := s.queueService.SpinQueue()
+		if err != nil {
+			return err
+		}
+	}
+
+	s.interlude = nil
+
+	s.CurrentTrackElapsed = 0
+	s.trackOffset = 0
+	s.playlist.Next([]*hls.Segment{})
+	s.enterLiveSlot()
+	s.markStarted(s.CurrentTrack, false)
 
-	s.NewTrackNotify <- current.Name
-	s.playlist.Next(nextTrackSegments)
 	return nil
 }
 
+// slotPlan holds the segments of the current and the next playlist slots.
+type slotPlan struct {
+	current      []*hls.Segment // Segments of the current track, including the crossfade transition
+	next         []*hls.Segment // Segments of the next track
+	nextOffset   float64        // Position of the next track where its segments start
+	transitionAt float64        // Position of the current track where the crossfade begins, 0 if there is none
+}
+
+// joinTracks builds the segments of the current and next playlist slots. If the tracks can be crossfaded,
+// the tail of the current slot is replaced with transition segments, and the next slot skips the part
+// of the next track that was already mixed into the transition.
+func (s *State) joinTracks(cf Crossfade, current, next *track.Track, currentSeg, nextSeg []*hls.Segment, currentOffset, notBefore float64) (*slotPlan, error) {
+	plan := &slotPlan{
+		current: sliceSegments(currentSeg, currentOffset, math.Inf(1)),
+		next:    nextSeg,
+	}
+
+	// Tracks cut at different durations, e.g. right after the duration was changed, can't share transition segments
+	segDuration := cutDuration(currentSeg)
+	if segDuration == 0 || cutDuration(nextSeg) != segDuration {
+		return plan, nil
+	}
+
+	tr, ok := planTransition(cf, current, next, notBefore, float64(segDuration))
+	if !ok {
+		return plan, nil
+	}
+
+	name := transitionName(current, next)
+	fade := ffmpeg.Fade{Duration: cf.Duration, Curve: cf.Curve}
+	err := s.trackService.MakeHLSCrossfade(current, next, tr.cutAt, tr.resumeAt, fade, s.playlistDir, name, segDuration)
+	if err != nil {
+		s.log.Warn("Crossfade failed, tracks are joined without it: " + err.Error())
+		return plan, nil
+	}
+
+	transitionSeg := hls.GenerateSegments(tr.duration(current, cf.Duration), segDuration, name, s.playlistDir, s.trackService.Container())
+	plan.current = append(sliceSegments(currentSeg, currentOffset, tr.cutAt), transitionSeg...)
+	plan.next = sliceSegments(nextSeg, tr.resumeAt, math.Inf(1))
+	plan.nextOffset = tr.resumeAt
+	plan.transitionAt = tr.cutAt
+
+	return plan, nil
+}
+
+// trackStarted adds the track to the playback history and publishes that it started airing on the channel.
+// The history is written right away, since returning to the previous track relies on it,
+// so the caller must not hold the mutex.
+func (s *State) trackStarted(t *track.Track, overlay bool) {
+	s.playbackService.AddPlaybackHistory(t.ID, t.Name)
+	s.events.Publish(events.TrackStarted{ChannelID: s.channelID, TrackID: t.ID, TrackName: t.Name, Overlay: overlay})
+}
+
+// markStarted notes that the track started airing. It's added to the playback history and published
+// by recordStarted once the mutex is released. The caller must hold the mutex.
+func (s *State) markStarted(t *track.Track, overlay bool) {
+	s.started = append(s.started, events.TrackStarted{ChannelID: s.channelID, TrackID: t.ID, TrackName: t.Name, Overlay: overlay})
+}
+
+// recordStarted adds the tracks noted by markStarted to the playback history and publishes that they started airing.
+func (s *State) recordStarted() {
+	s.mutex.Lock()
+	started := s.started
+	s.started = nil
+	s.mutex.Unlock()
+
+	for _, e := range started {
+		s.playbackService.AddPlaybackHistory(e.TrackID, e.TrackName)
+		s.events.Publish(e)
+	}
+}
+
+// slotElapsed returns the seconds elapsed since the first planned segment of the current track.
+func (s *State) slotElapsed() float64 {
+	return s.CurrentTrackElapsed - s.trackOffset
+}
+
+// publishedUntil returns the position of the current track until which its segments
+// have already been exposed to listeners in the live playlist.
+func (s *State) publishedUntil() float64 {
+	if s.playlist == nil {
+		return s.CurrentTrackElapsed
+	}
+
+	return s.trackOffset + s.playlist.PublishedUntil(s.slotElapsed())
+}
+
 // makeHLSSegments generates HLS segments for a given track.
 func (s *State) makeHLSSegments(track *track.Track, dir string) ([]*hls.Segment, error) {
 	if track == nil {
 		return []*hls.Segment{}, nil
 	}
 
-	err := s.trackService.MakeHLSPlaylist(track.Path, dir, track.ID, hls.DefaultMaxSegmentDuration)
+	segDuration := s.timing().SegmentDuration()
+	generate := func(dir string) error {
+		return s.trackService.MakeHLSTrack(track, dir, track.ID, segDuration)
+	}
+
+	var err error
+	if s.segmentCache != nil {
+		profile := s.trackService.HLSProfile(track, segDuration)
+		err = s.segmentCache.Link(track.ID, profile, dir, generate)
+	} else {
+		err = generate(dir)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	segments := hls.GenerateSegments(
 		track.Duration,
-		hls.DefaultMaxSegmentDuration,
+		segDuration,
 		track.ID,
 		dir,
+		s.trackService.Container(),
 	)
 
+	meta := trackMetadata(track, s.artworkURL)
+	for _, seg := range segments {
+		seg.Meta = meta
+	}
+
 	return segments, nil
 }
+
+// trackMetadata describes the track for players. Names of the "Artist - Title" form are split into the two.
+func trackMetadata(t *track.Track, artworkURL string) *hls.Metadata {
+	meta := &hls.Metadata{ID: t.ID, Title: t.Name, ArtworkURL: artworkURL}
+	if artist, title, ok := strings.Cut(t.Name, " - "); ok && artist != "" && title != "" {
+		meta.Artist = strings.TrimSpace(artist)
+		meta.Title = strings.TrimSpace(title)
+	}
+
+	return meta
+}
+
+// refill tops up the queue with the auto-DJ, if it is set. Failures are only logged,
+// so the playback goes on with the tracks that are already queued.
+func (s *State) refill() {
+	if s.autoDJ == nil {
+		return
+	}
+
+	added, err := s.autoDJ.Refill()
+	if err != nil {
+		s.log.Warn("Auto-DJ refill failed: " + err.Error())
+		return
+	}
+
+	if added > 0 {
+		s.log.Info(fmt.Sprintf("Auto-DJ added %d tracks to the queue", added))
+	}
+}
+
+// trackID returns the ID of the track, or an empty string if there is no track.
+func trackID(t *track.Track) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.ID
+}
@@ -0,0 +1,399 @@
+package playback
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+	"github.com/cheatsnake/airstation/internal/queue"
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+// memoryQueue keeps the queue in memory and spins it the way the SQLite store does.
+type memoryQueue struct {
+	tracks  []*track.Track
+	removed []*track.Track // Tracks that may be queued again
+	played  []string
+	mode    queue.Mode
+}
+
+func (m *memoryQueue) Queue(channelID string) ([]*track.Track, error) {
+	return slices.Clone(m.tracks), nil
+}
+
+func (m *memoryQueue) AddToQueue(channelID string, tracks []*track.Track) error {
+	m.tracks = append(m.tracks, tracks...)
+	return nil
+}
+
+func (m *memoryQueue) RemoveFromQueue(channelID string, trackIDs []string) error {
+	m.tracks = slices.DeleteFunc(m.tracks, func(t *track.Track) bool {
+		if slices.Contains(trackIDs, t.ID) {
+			m.removed = append(m.removed, t)
+			return true
+		}
+		return false
+	})
+	return nil
+}
+
+func (m *memoryQueue) ReorderQueue(channelID string, trackIDs []string) error {
+	known := slices.Concat(m.tracks, m.removed)
+	tracks := make([]*track.Track, 0, len(trackIDs))
+	for _, id := range trackIDs {
+		i := slices.IndexFunc(known, func(t *track.Track) bool { return t.ID == id })
+		if i >= 0 {
+			tracks = append(tracks, known[i])
+		}
+	}
+	m.tracks = tracks
+	return nil
+}
+
+func (m *memoryQueue) SpinQueue(channelID string) error {
+	if len(m.tracks) == 0 {
+		return nil
+	}
+	m.played = append(m.played, m.tracks[0].ID)
+	m.tracks = append(m.tracks[1:], m.tracks[0])
+	return nil
+}
+
+func (m *memoryQueue) CurrentAndNextTrack(channelID string) (*track.Track, *track.Track, error) {
+	switch len(m.tracks) {
+	case 0:
+		return nil, nil, nil
+	case 1:
+		return m.tracks[0], m.tracks[0], nil
+	}
+	return m.tracks[0], m.tracks[1], nil
+}
+
+func (m *memoryQueue) PlayedTrackIDs(channelID string) ([]string, error) {
+	return m.played, nil
+}
+
+func (m *memoryQueue) ResetPlayed(channelID string) error {
+	m.played = nil
+	return nil
+}
+
+func (m *memoryQueue) QueueMode(channelID string) (queue.Mode, error) {
+	if m.mode == "" {
+		return queue.ModeSequential, nil
+	}
+	return m.mode, nil
+}
+
+func (m *memoryQueue) SetQueueMode(channelID string, mode queue.Mode) error {
+	m.mode = mode
+	return nil
+}
+
+// memoryLibrary serves the tracks the tests play.
+type memoryLibrary struct {
+	track.Store
+	tracks []*track.Track
+}
+
+func (m *memoryLibrary) TracksByIDs(IDs []string) ([]*track.Track, error) {
+	var found []*track.Track
+	for _, t := range m.tracks {
+		if slices.Contains(IDs, t.ID) {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+// newTestState creates a state that plays the given queue. The segments of its tracks are cached up front,
+// so they are never made with FFmpeg.
+func newTestState(t *testing.T, mock *mockStore, q *memoryQueue) *State {
+	t.Helper()
+
+	library := &memoryLibrary{tracks: slices.Clone(q.tracks)}
+	ts := track.NewService(library, ffmpeg.NewCLI(hls.ContainerTS), ffmpeg.LoudnessTarget{}, nil, nil, nil, testLog)
+	cache, err := hls.NewCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segDuration := ts.Timing().SegmentDuration()
+	for _, tr := range q.tracks {
+		profile := ts.HLSProfile(tr, segDuration)
+		err := cache.Link(tr.ID, profile, t.TempDir(), func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, tr.ID+".m3u8"), []byte("#EXTM3U"), 0o644)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state := NewState(ts, queue.NewService(q, nil, "main"), NewService(mock, "main", testLog), t.TempDir(), testLog)
+	state.SetSegmentCache(cache)
+
+	return state
+}
+
+func TestState_Run(t *testing.T) {
+	t.Run("saves the position on shutdown", func(t *testing.T) {
+		var got *Snapshot
+		mock := &mockStore{
+			savePlaybackSnapshotFn: func(snapshot *Snapshot) error {
+				got = snapshot
+				return nil
+			},
+		}
+		state := NewState(nil, nil, NewService(mock, "main", testLog), t.TempDir(), testLog)
+		state.IsPlaying = true
+		state.CurrentTrack = &track.Track{ID: "a"}
+		state.CurrentTrackElapsed = 42
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		state.Run(ctx)
+
+		if got == nil {
+			t.Fatal("expected the snapshot to be saved")
+		}
+		if got.TrackID != "a" || got.Elapsed != 42 || !got.IsPlaying {
+			t.Errorf("unexpected snapshot %+v", got)
+		}
+	})
+
+	t.Run("paused playback is not saved again", func(t *testing.T) {
+		saved := false
+		mock := &mockStore{
+			savePlaybackSnapshotFn: func(snapshot *Snapshot) error {
+				saved = true
+				return nil
+			},
+		}
+		state := NewState(nil, nil, NewService(mock, "main", testLog), t.TempDir(), testLog)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		state.Run(ctx)
+
+		if saved {
+			t.Error("expected no snapshot for paused playback")
+		}
+	})
+}
+
+func TestTrackMetadata(t *testing.T) {
+	cases := []struct {
+		name   string
+		artist string
+		title  string
+	}{
+		{name: "Band - Song", artist: "Band", title: "Song"},
+		{name: "Band - Song - Live", artist: "Band", title: "Song - Live"},
+		{name: "Song", artist: "", title: "Song"},
+		{name: " - Song", artist: "", title: " - Song"},
+	}
+
+	for _, c := range cases {
+		meta := trackMetadata(&track.Track{ID: "a", Name: c.name}, "http://logo")
+		if meta.Artist != c.artist || meta.Title != c.title || meta.ID != "a" || meta.ArtworkURL != "http://logo" {
+			t.Errorf("%q: unexpected metadata %+v", c.name, meta)
+		}
+	}
+}
+
+func TestState_TrackStarted(t *testing.T) {
+	var got []string
+	mock := &mockStore{
+		addPlaybackHistoryFn: func(playedAt int64, trackID, trackName string) error {
+			got = append(got, trackName)
+			return nil
+		},
+	}
+	state := NewState(nil, nil, NewService(mock, "main", testLog), t.TempDir(), testLog)
+
+	state.trackStarted(&track.Track{ID: "1", Name: "First"}, false)
+	state.trackStarted(&track.Track{Name: "Voice"}, true)
+
+	want := []string{"First", "Voice"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected history %v, got %v", want, got)
+	}
+}
+
+func TestState_RecordStarted(t *testing.T) {
+	var got []string
+	mock := &mockStore{
+		addPlaybackHistoryFn: func(playedAt int64, trackID, trackName string) error {
+			got = append(got, trackName)
+			return nil
+		},
+	}
+	state := NewState(nil, nil, NewService(mock, "main", testLog), t.TempDir(), testLog)
+
+	state.mutex.Lock()
+	state.markStarted(&track.Track{ID: "1", Name: "First"}, false)
+	state.markStarted(&track.Track{Name: "Voice"}, true)
+	state.mutex.Unlock()
+
+	if len(got) != 0 {
+		t.Fatalf("expected no history before recording, got %v", got)
+	}
+
+	state.recordStarted()
+	state.recordStarted()
+
+	want := []string{"First", "Voice"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected history %v, got %v", want, got)
+	}
+}
+
+func TestState_Resume(t *testing.T) {
+	cases := []struct {
+		name        string
+		mode        queue.Mode
+		elapsed     float64
+		wantTrack   string
+		wantElapsed float64
+	}{
+		{name: "sequential skips full cycles", mode: queue.ModeSequential, elapsed: 75, wantTrack: "b", wantElapsed: 5},
+		{name: "shuffle skips full cycles", mode: queue.ModeShuffle, elapsed: 75, wantTrack: "b", wantElapsed: 5},
+		{name: "repeat one keeps the track", mode: queue.ModeRepeatOne, elapsed: 55, wantTrack: "a", wantElapsed: 5},
+		{name: "consume skips played tracks", mode: queue.ModeConsume, elapsed: 15, wantTrack: "b", wantElapsed: 5},
+		{name: "consume ends the queue", mode: queue.ModeConsume, elapsed: 75},
+		{name: "stop at end skips played tracks", mode: queue.ModeStopAtEnd, elapsed: 15, wantTrack: "b", wantElapsed: 5},
+		{name: "stop at end ends the queue", mode: queue.ModeStopAtEnd, elapsed: 75},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockStore{
+				playbackSnapshotFn: func() (*Snapshot, error) {
+					return &Snapshot{TrackID: "a", Elapsed: c.elapsed, IsPlaying: true, UpdatedAt: time.Now().Unix()}, nil
+				},
+			}
+			q := &memoryQueue{
+				tracks: []*track.Track{
+					{ID: "a", Name: "A", Duration: 10},
+					{ID: "b", Name: "B", Duration: 20},
+					{ID: "c", Name: "C", Duration: 30},
+				},
+				mode: c.mode,
+			}
+			state := newTestState(t, mock, q)
+
+			err := state.Resume(false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if c.wantTrack == "" {
+				if state.IsPlaying {
+					t.Errorf("expected playback to stay paused, %q is playing", state.CurrentTrack.ID)
+				}
+				return
+			}
+
+			if !state.IsPlaying || state.CurrentTrack.ID != c.wantTrack || state.CurrentTrackElapsed != c.wantElapsed {
+				t.Errorf("expected %q at %v, got %q at %v", c.wantTrack, c.wantElapsed, trackID(state.CurrentTrack), state.CurrentTrackElapsed)
+			}
+		})
+	}
+}
+
+func TestState_JumpAndPrevious(t *testing.T) {
+	cases := []struct {
+		name      string
+		mode      queue.Mode
+		played    []string
+		previous  bool
+		position  int
+		wantTrack string
+	}{
+		{name: "jump in sequential mode", mode: queue.ModeSequential, position: 2, wantTrack: "c"},
+		{name: "jump in shuffle mode", mode: queue.ModeShuffle, position: 2, wantTrack: "c"},
+		{name: "jump in repeat one mode", mode: queue.ModeRepeatOne, position: 2, wantTrack: "c"},
+		{name: "jump in consume mode", mode: queue.ModeConsume, position: 2, wantTrack: "c"},
+		{name: "jump in stop at end mode", mode: queue.ModeStopAtEnd, position: 1, wantTrack: "b"},
+		{name: "jump to a played track in stop at end mode", mode: queue.ModeStopAtEnd, played: []string{"c"}, position: 2},
+		{name: "previous in sequential mode", mode: queue.ModeSequential, previous: true, wantTrack: "c"},
+		{name: "previous in shuffle mode", mode: queue.ModeShuffle, previous: true, wantTrack: "c"},
+		{name: "previous in repeat one mode", mode: queue.ModeRepeatOne, previous: true, wantTrack: "c"},
+		{name: "previous in consume mode", mode: queue.ModeConsume, previous: true, wantTrack: "c"},
+		{name: "previous in stop at end mode", mode: queue.ModeStopAtEnd, played: []string{"c"}, previous: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockStore{
+				recentPlaybackHistoryFn: func(limit int) ([]*History, error) {
+					return []*History{{ID: 2, TrackID: "a"}, {ID: 1, TrackID: "c"}}, nil
+				},
+			}
+			q := &memoryQueue{
+				tracks: []*track.Track{
+					{ID: "a", Name: "A", Duration: 10},
+					{ID: "b", Name: "B", Duration: 20},
+					{ID: "c", Name: "C", Duration: 30},
+				},
+				played: c.played,
+				mode:   c.mode,
+			}
+			state := newTestState(t, mock, q)
+
+			err := state.Play()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got *track.Track
+			if c.previous {
+				got, err = state.Previous()
+			} else {
+				got, err = state.Jump(c.position)
+			}
+
+			if c.wantTrack == "" {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if ids := queueIDs(q); !slices.Equal(ids, []string{"a", "b", "c"}) {
+					t.Errorf("expected the queue to stay unchanged, got %v", ids)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.ID != c.wantTrack {
+				t.Errorf("expected %q to play next, got %q", c.wantTrack, got.ID)
+			}
+
+			state.mutex.Lock()
+			_, err = state.loadNextTrack()
+			current := state.CurrentTrack
+			state.mutex.Unlock()
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if current.ID != c.wantTrack {
+				t.Errorf("expected %q to air after the skip, got %q", c.wantTrack, current.ID)
+			}
+		})
+	}
+}
+
+func queueIDs(q *memoryQueue) []string {
+	ids := make([]string, 0, len(q.tracks))
+	for _, t := range q.tracks {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
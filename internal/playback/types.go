@@ -3,11 +3,24 @@ package playback
 type History struct {
 	ID        int    `json:"id"`
 	PlayedAt  int64  `json:"playedAt"`
+	TrackID   string `json:"trackId"`
 	TrackName string `json:"trackName"`
 }
 
+// Snapshot is the persisted part of the playback state used to resume playback after a restart.
+type Snapshot struct {
+	TrackID        string  // ID of the track that was playing, empty if playback was paused
+	Elapsed        float64 // Position (in seconds) of the track that was playing
+	IsPlaying      bool    // Whether playback was running
+	MediaSequence  int64   // Media sequence of the last generated HLS playlist
+	DisconSequence int64   // Discontinuity sequence of the last generated HLS playlist
+	UpdatedAt      int64   // Unix timestamp of the moment the snapshot was taken
+}
+
 type Store interface {
-	AddPlaybackHistory(playedAt int64, trackName string) error
-	RecentPlaybackHistory(limit int) ([]*History, error)
+	AddPlaybackHistory(channelID string, playedAt int64, trackID, trackName string) error
+	RecentPlaybackHistory(channelID string, limit int) ([]*History, error)
 	DeleteOldPlaybackHistory() (int64, error)
+	SavePlaybackSnapshot(channelID string, snapshot *Snapshot) error
+	PlaybackSnapshot(channelID string) (*Snapshot, error)
 }
@@ -1,22 +1,32 @@
 package queue
 
 import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"path"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/cheatsnake/airstation/internal/events"
 	"github.com/cheatsnake/airstation/internal/pkg/fs"
-	"github.com/cheatsnake/airstation/internal/pkg/hls"
 	"github.com/cheatsnake/airstation/internal/track"
 )
 
 type Service struct {
-	store Store
+	store     Store
+	events    *events.Bus
+	channelID string
 }
 
-func NewService(store Store) *Service {
+// NewService creates a queue service scoped to a single channel.
+// Changes of the queue are published on the given bus, which may be nil.
+func NewService(store Store, bus *events.Bus, channelID string) *Service {
 	return &Service{
-		store: store,
+		store:     store,
+		events:    bus,
+		channelID: channelID,
 	}
 }
 
@@ -25,7 +35,7 @@ func NewService(store Store) *Service {
 // Returns:
 //   - A slice of Track pointers or an error.
 func (s *Service) Queue() ([]*track.Track, error) {
-	q, err := s.store.Queue()
+	q, err := s.store.Queue(s.channelID)
 	return q, err
 }
 
@@ -37,8 +47,8 @@ func (s *Service) Queue() ([]*track.Track, error) {
 // Returns:
 //   - An error if the operation fails.
 func (s *Service) AddToQueue(tracks []*track.Track) error {
-	err := s.store.AddToQueue(tracks)
-	return err
+	err := s.store.AddToQueue(s.channelID, tracks)
+	return s.changed(err)
 }
 
 // ReorderQueue updates the order of tracks in the playback queue.
@@ -49,8 +59,8 @@ func (s *Service) AddToQueue(tracks []*track.Track) error {
 // Returns:
 //   - An error if reordering fails.
 func (s *Service) ReorderQueue(ids []string) error {
-	err := s.store.ReorderQueue(ids)
-	return err
+	err := s.store.ReorderQueue(s.channelID, ids)
+	return s.changed(err)
 }
 
 // RemoveFromQueue removes specific tracks from the playback queue.
@@ -61,53 +71,380 @@ func (s *Service) ReorderQueue(ids []string) error {
 // Returns:
 //   - An error if removal fails.
 func (s *Service) RemoveFromQueue(ids []string) error {
-	err := s.store.RemoveFromQueue(ids)
-	return err
+	err := s.store.RemoveFromQueue(s.channelID, ids)
+	return s.changed(err)
 }
 
-// SpinQueue rotates the playback queue, moving the current track to the end.
+// SpinQueue advances the playback queue according to its play-order mode. In most modes the current track
+// is moved to the end of the queue, in consume mode it is removed, and in repeat-one mode it stays in place.
 //
 // Returns:
 //   - An error if the operation fails.
 func (s *Service) SpinQueue() error {
-	err := s.store.SpinQueue()
+	return s.changed(s.spin())
+}
+
+// spin advances the queue according to its play-order mode.
+func (s *Service) spin() error {
+	mode, err := s.store.QueueMode(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case ModeRepeatOne:
+		return nil
+	case ModeConsume:
+		current, _, err := s.store.CurrentAndNextTrack(s.channelID)
+		if err != nil || current == nil {
+			return err
+		}
+		return s.store.RemoveFromQueue(s.channelID, []string{current.ID})
+	case ModeShuffle:
+		err = s.store.SpinQueue(s.channelID)
+		if err != nil {
+			return err
+		}
+		return s.shuffleNext()
+	default:
+		return s.store.SpinQueue(s.channelID)
+	}
+}
+
+// Mode retrieves the play-order mode of the queue.
+//
+// Returns:
+//   - The current mode, or an error.
+func (s *Service) Mode() (Mode, error) {
+	mode, err := s.store.QueueMode(s.channelID)
+	return mode, err
+}
+
+// SetMode changes the play-order mode of the queue and starts its progress over.
+//
+// Parameters:
+//   - mode: The new play-order mode.
+//
+// Returns:
+//   - An error if the mode is unknown or cannot be saved.
+func (s *Service) SetMode(mode Mode) error {
+	if !slices.Contains(modes, mode) {
+		return fmt.Errorf("unknown queue mode %q", mode)
+	}
+
+	err := s.store.SetQueueMode(s.channelID, mode)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.ResetPlayed(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	if mode == ModeShuffle {
+		err = s.shuffleNext()
+	}
+
+	return s.changed(err)
+}
+
+// Rewind starts the queue over if it has been played through in stop-at-end mode.
+// It does nothing in other modes.
+//
+// Returns:
+//   - An error if the operation fails.
+func (s *Service) Rewind() error {
+	mode, err := s.store.QueueMode(s.channelID)
+	if err != nil || mode != ModeStopAtEnd {
+		return err
+	}
+
+	current, _, err := s.store.CurrentAndNextTrack(s.channelID)
+	if err != nil || current == nil {
+		return err
+	}
+
+	played, err := s.store.PlayedTrackIDs(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(played, current.ID) {
+		return s.store.ResetPlayed(s.channelID)
+	}
+
+	return nil
+}
+
+// changed publishes that the queue changed, unless the change failed.
+func (s *Service) changed(err error) error {
+	if err == nil {
+		s.events.Publish(events.QueueChanged{ChannelID: s.channelID})
+	}
+
+	return err
+}
+
+// shuffleNext puts a random track that hasn't been played yet right after the current one.
+// Once every track is played, the progress starts over.
+func (s *Service) shuffleNext() error {
+	q, err := s.store.Queue(s.channelID)
+	if err != nil || len(q) < 3 {
+		return err
+	}
+
+	played, err := s.store.PlayedTrackIDs(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	candidates := make([]*track.Track, 0, len(q)-1)
+	for _, t := range q[1:] {
+		if !slices.Contains(played, t.ID) {
+			candidates = append(candidates, t)
+		}
+	}
+
+	if len(candidates) == 0 {
+		err = s.store.ResetPlayed(s.channelID)
+		if err != nil {
+			return err
+		}
+		candidates = q[1:]
+	}
+
+	pick := candidates[rand.IntN(len(candidates))]
+	if pick.ID == q[1].ID {
+		return nil
+	}
+
+	ids := make([]string, 0, len(q))
+	ids = append(ids, q[0].ID, pick.ID)
+	for _, t := range q[1:] {
+		if t.ID != pick.ID {
+			ids = append(ids, t.ID)
+		}
+	}
+
+	err = s.store.ReorderQueue(s.channelID, ids)
 	return err
 }
 
-// CurrentAndNextTrack retrieves the currently playing track and the next track in the queue.
+// ReplaceQueue replaces the tracks of the queue with the given ones and starts the play-order progress over.
+//
+// Parameters:
+//   - tracks: The new tracks in the order they should play.
+//   - keepCurrent: Whether the current track stays at the head of the queue.
+//
+// Returns:
+//   - An error if the queue cannot be updated.
+func (s *Service) ReplaceQueue(tracks []*track.Track, keepCurrent bool) error {
+	q, err := s.store.Queue(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(tracks)+1)
+	if keepCurrent && len(q) > 0 {
+		ids = append(ids, q[0].ID)
+	}
+	for _, t := range tracks {
+		if !slices.Contains(ids, t.ID) {
+			ids = append(ids, t.ID)
+		}
+	}
+
+	removed := make([]string, 0, len(q))
+	for _, t := range q {
+		if !slices.Contains(ids, t.ID) {
+			removed = append(removed, t.ID)
+		}
+	}
+
+	if len(removed) > 0 {
+		err = s.store.RemoveFromQueue(s.channelID, removed)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(tracks) > 0 {
+		err = s.store.AddToQueue(s.channelID, tracks)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.store.ReorderQueue(s.channelID, ids)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.ResetPlayed(s.channelID)
+	return s.changed(err)
+}
+
+// PlayNext moves a track right after the current one, adding it to the queue if it's not there.
+//
+// Parameters:
+//   - t: The track to play next.
+//
+// Returns:
+//   - An error if the queue is empty, the track is already playing, or reordering fails.
+func (s *Service) PlayNext(t *track.Track) error {
+	q, err := s.store.Queue(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	if len(q) == 0 {
+		return errors.New("playback queue is empty")
+	}
+
+	if q[0].ID == t.ID {
+		return errors.New("track is already playing")
+	}
+
+	ids := make([]string, 0, len(q)+1)
+	ids = append(ids, q[0].ID, t.ID)
+	for _, qt := range q[1:] {
+		if qt.ID != t.ID {
+			ids = append(ids, qt.ID)
+		}
+	}
+
+	err = s.store.ReorderQueue(s.channelID, ids)
+	return s.changed(err)
+}
+
+// PlayFirst moves a track to the head of the queue, adding it to the queue if it's not there,
+// so it becomes the current track. The track that was current moves right after it.
+//
+// Parameters:
+//   - t: The track to make current.
+//
+// Returns:
+//   - An error if the queue is empty, the track is already current, or reordering fails.
+func (s *Service) PlayFirst(t *track.Track) error {
+	q, err := s.store.Queue(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	if len(q) == 0 {
+		return errors.New("playback queue is empty")
+	}
+
+	if q[0].ID == t.ID {
+		return errors.New("track is already playing")
+	}
+
+	ids := make([]string, 0, len(q)+1)
+	ids = append(ids, t.ID)
+	for _, qt := range q {
+		if qt.ID != t.ID {
+			ids = append(ids, qt.ID)
+		}
+	}
+
+	err = s.store.ReorderQueue(s.channelID, ids)
+	return s.changed(err)
+}
+
+// IsPlayed reports whether the track has already been played since the progress of the play-order mode started over.
+//
+// Parameters:
+//   - id: The ID of the track.
+//
+// Returns:
+//   - Whether the track is played, or an error.
+func (s *Service) IsPlayed(id string) (bool, error) {
+	played, err := s.store.PlayedTrackIDs(s.channelID)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(played, id), nil
+}
+
+// CurrentAndNextTrack retrieves the currently playing track and the track that plays after it
+// according to the play-order mode of the queue.
 //
 // Returns:
 //   - Pointers to the current and next tracks, and an error if retrieval fails.
+//     The current track is nil if the queue is empty or has been played through in stop-at-end mode.
+//     The next track is nil if playback stops after the current one.
 func (s *Service) CurrentAndNextTrack() (*track.Track, *track.Track, error) {
-	current, next, err := s.store.CurrentAndNextTrack()
-	return current, next, err
+	current, next, err := s.store.CurrentAndNextTrack(s.channelID)
+	if err != nil || current == nil {
+		return current, next, err
+	}
+
+	mode, err := s.store.QueueMode(s.channelID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	isSingle := current.ID == next.ID
+
+	switch mode {
+	case ModeRepeatOne:
+		return current, current, nil
+	case ModeConsume:
+		if isSingle {
+			return current, nil, nil
+		}
+	case ModeStopAtEnd:
+		played, err := s.store.PlayedTrackIDs(s.channelID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if slices.Contains(played, current.ID) {
+			return nil, nil, nil
+		}
+		if isSingle || slices.Contains(played, next.ID) {
+			return current, nil, nil
+		}
+	}
+
+	return current, next, nil
 }
 
 // CleanupHLSPlaylists removes old HLS playlist files that are no longer needed.
 //
 // Parameters:
 //   - dirPath: Directory containing the HLS playlist files.
+//   - delay: Time to wait before the cleanup, so listeners can load the last segments of the ended track.
+//   - keep: Extra prefixes of files that are still in use besides the current and next tracks.
 //
 // Returns:
 //   - An error if file cleanup fails.
-func (s *Service) CleanupHLSPlaylists(dirPath string) error {
+func (s *Service) CleanupHLSPlaylists(dirPath string, delay time.Duration, keep ...string) error {
 	// waiting for all the listeners to listen to the last segments of ended track
-	time.Sleep(hls.DefaultMaxSegmentDuration * 2 * time.Second)
-	current, next, err := s.store.CurrentAndNextTrack()
+	time.Sleep(delay)
+	current, next, err := s.store.CurrentAndNextTrack(s.channelID)
 	if err != nil {
 		return err
 	}
 
-	utilized := []string{current.ID, next.ID}
+	utilized := make([]string, 0, 2+len(keep))
+	utilized = append(utilized, keep...)
+	for _, t := range []*track.Track{current, next} {
+		if t != nil {
+			utilized = append(utilized, t.ID)
+		}
+	}
 	tmpFiles, err := fs.ListFilesFromDir(dirPath, "")
 	if err != nil {
 		return err
 	}
 
+	// Segments of renditions are listed with their subdirectory, but named the same as the main ones
 	for _, tmpFile := range tmpFiles {
 		keep := false
 		for _, prefix := range utilized {
-			if strings.HasPrefix(tmpFile, prefix) {
+			if strings.HasPrefix(path.Base(tmpFile), prefix) {
 				keep = true
 				break
 			}
@@ -2,62 +2,98 @@ package queue
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/cheatsnake/airstation/internal/events"
 	"github.com/cheatsnake/airstation/internal/track"
 )
 
 type mockStore struct {
+	channelID             string
 	queueFn               func() ([]*track.Track, error)
 	addToQueueFn          func(tracks []*track.Track) error
 	removeFromQueueFn     func(trackIDs []string) error
 	reorderQueueFn        func(trackIDs []string) error
 	spinQueueFn           func() error
 	currentAndNextTrackFn func() (*track.Track, *track.Track, error)
+	mode                  Mode
+	played                []string
 }
 
-func (m *mockStore) Queue() ([]*track.Track, error) {
+func (m *mockStore) Queue(channelID string) ([]*track.Track, error) {
+	m.channelID = channelID
 	if m.queueFn != nil {
 		return m.queueFn()
 	}
 	return nil, nil
 }
 
-func (m *mockStore) AddToQueue(tracks []*track.Track) error {
+func (m *mockStore) AddToQueue(channelID string, tracks []*track.Track) error {
+	m.channelID = channelID
 	if m.addToQueueFn != nil {
 		return m.addToQueueFn(tracks)
 	}
 	return nil
 }
 
-func (m *mockStore) RemoveFromQueue(trackIDs []string) error {
+func (m *mockStore) RemoveFromQueue(channelID string, trackIDs []string) error {
+	m.channelID = channelID
 	if m.removeFromQueueFn != nil {
 		return m.removeFromQueueFn(trackIDs)
 	}
 	return nil
 }
 
-func (m *mockStore) ReorderQueue(trackIDs []string) error {
+func (m *mockStore) ReorderQueue(channelID string, trackIDs []string) error {
+	m.channelID = channelID
 	if m.reorderQueueFn != nil {
 		return m.reorderQueueFn(trackIDs)
 	}
 	return nil
 }
 
-func (m *mockStore) SpinQueue() error {
+func (m *mockStore) SpinQueue(channelID string) error {
+	m.channelID = channelID
 	if m.spinQueueFn != nil {
 		return m.spinQueueFn()
 	}
 	return nil
 }
 
-func (m *mockStore) CurrentAndNextTrack() (*track.Track, *track.Track, error) {
+func (m *mockStore) CurrentAndNextTrack(channelID string) (*track.Track, *track.Track, error) {
+	m.channelID = channelID
 	if m.currentAndNextTrackFn != nil {
 		return m.currentAndNextTrackFn()
 	}
 	return nil, nil, nil
 }
 
+func (m *mockStore) PlayedTrackIDs(channelID string) ([]string, error) {
+	m.channelID = channelID
+	return m.played, nil
+}
+
+func (m *mockStore) ResetPlayed(channelID string) error {
+	m.channelID = channelID
+	m.played = nil
+	return nil
+}
+
+func (m *mockStore) QueueMode(channelID string) (Mode, error) {
+	m.channelID = channelID
+	if m.mode == "" {
+		return ModeSequential, nil
+	}
+	return m.mode, nil
+}
+
+func (m *mockStore) SetQueueMode(channelID string, mode Mode) error {
+	m.channelID = channelID
+	m.mode = mode
+	return nil
+}
+
 func TestService_Queue(t *testing.T) {
 	t.Run("returns store results", func(t *testing.T) {
 		expected := []*track.Track{
@@ -69,7 +105,7 @@ func TestService_Queue(t *testing.T) {
 				return expected, nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		q, err := svc.Queue()
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -85,7 +121,7 @@ func TestService_Queue(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		_, err := svc.Queue()
 		if err == nil {
 			t.Error("expected error, got nil")
@@ -102,7 +138,7 @@ func TestService_AddToQueue(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		input := []*track.Track{{ID: "1", Name: "Track A"}}
 		err := svc.AddToQueue(input)
 		if err != nil {
@@ -119,7 +155,7 @@ func TestService_AddToQueue(t *testing.T) {
 				return errors.New("insert failed")
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		err := svc.AddToQueue([]*track.Track{{ID: "1"}})
 		if err == nil {
 			t.Error("expected error, got nil")
@@ -127,6 +163,39 @@ func TestService_AddToQueue(t *testing.T) {
 	})
 }
 
+func TestService_PublishesQueueChanged(t *testing.T) {
+	bus := events.NewBus()
+	sub := bus.Subscribe(events.DefaultBuffer)
+	defer sub.Close()
+
+	failing := true
+	mock := &mockStore{
+		addToQueueFn: func(tracks []*track.Track) error {
+			if failing {
+				return errors.New("db error")
+			}
+			return nil
+		},
+	}
+	svc := NewService(mock, bus, "main")
+
+	_ = svc.AddToQueue([]*track.Track{{ID: "1"}})
+	if len(sub.Events()) != 0 {
+		t.Fatal("expected no event for a failed change")
+	}
+
+	failing = false
+	if err := svc.AddToQueue([]*track.Track{{ID: "1"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := <-sub.Events()
+	changed, ok := e.(events.QueueChanged)
+	if !ok || changed.ChannelID != "main" {
+		t.Errorf("expected QueueChanged for main, got %#v", e)
+	}
+}
+
 func TestService_ReorderQueue(t *testing.T) {
 	t.Run("propagates to store", func(t *testing.T) {
 		var got []string
@@ -136,7 +205,7 @@ func TestService_ReorderQueue(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		input := []string{"id3", "id1", "id2"}
 		err := svc.ReorderQueue(input)
 		if err != nil {
@@ -153,7 +222,7 @@ func TestService_ReorderQueue(t *testing.T) {
 				return errors.New("reorder failed")
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		err := svc.ReorderQueue([]string{"id1"})
 		if err == nil {
 			t.Error("expected error, got nil")
@@ -170,7 +239,7 @@ func TestService_RemoveFromQueue(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		input := []string{"id1", "id2"}
 		err := svc.RemoveFromQueue(input)
 		if err != nil {
@@ -187,7 +256,7 @@ func TestService_RemoveFromQueue(t *testing.T) {
 				return errors.New("remove failed")
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		err := svc.RemoveFromQueue([]string{"id1"})
 		if err == nil {
 			t.Error("expected error, got nil")
@@ -204,7 +273,7 @@ func TestService_SpinQueue(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		err := svc.SpinQueue()
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -220,7 +289,7 @@ func TestService_SpinQueue(t *testing.T) {
 				return errors.New("spin failed")
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		err := svc.SpinQueue()
 		if err == nil {
 			t.Error("expected error, got nil")
@@ -237,7 +306,7 @@ func TestService_CurrentAndNextTrack(t *testing.T) {
 				return current, next, nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		c, n, err := svc.CurrentAndNextTrack()
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -253,7 +322,7 @@ func TestService_CurrentAndNextTrack(t *testing.T) {
 				return nil, nil, nil
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		c, n, err := svc.CurrentAndNextTrack()
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -269,10 +338,355 @@ func TestService_CurrentAndNextTrack(t *testing.T) {
 				return nil, nil, errors.New("db error")
 			},
 		}
-		svc := NewService(mock)
+		svc := NewService(mock, nil, "main")
 		_, _, err := svc.CurrentAndNextTrack()
 		if err == nil {
 			t.Error("expected error, got nil")
 		}
 	})
 }
+
+func TestService_ChannelScope(t *testing.T) {
+	mock := &mockStore{}
+	svc := NewService(mock, nil, "jazz")
+
+	calls := map[string]func() error{
+		"Queue":               func() error { _, err := svc.Queue(); return err },
+		"AddToQueue":          func() error { return svc.AddToQueue(nil) },
+		"RemoveFromQueue":     func() error { return svc.RemoveFromQueue(nil) },
+		"ReorderQueue":        func() error { return svc.ReorderQueue(nil) },
+		"SpinQueue":           func() error { return svc.SpinQueue() },
+		"CurrentAndNextTrack": func() error { _, _, err := svc.CurrentAndNextTrack(); return err },
+		"Mode":                func() error { _, err := svc.Mode(); return err },
+		"SetMode":             func() error { return svc.SetMode(ModeSequential) },
+	}
+
+	for name, call := range calls {
+		mock.channelID = ""
+		if err := call(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if mock.channelID != "jazz" {
+			t.Errorf("%s: expected channel %q passed to store, got %q", name, "jazz", mock.channelID)
+		}
+	}
+}
+
+func TestService_PlayNext(t *testing.T) {
+	queue := []*track.Track{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+
+	t.Run("moves queued track after the current one", func(t *testing.T) {
+		var got []string
+		mock := &mockStore{
+			queueFn: func() ([]*track.Track, error) { return queue, nil },
+			reorderQueueFn: func(trackIDs []string) error {
+				got = trackIDs
+				return nil
+			},
+		}
+		svc := NewService(mock, nil, "main")
+		err := svc.PlayNext(&track.Track{ID: "3"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(got, ",") != "1,3,2" {
+			t.Errorf("expected order 1,3,2, got %v", got)
+		}
+	})
+
+	t.Run("inserts track missing from the queue", func(t *testing.T) {
+		var got []string
+		mock := &mockStore{
+			queueFn: func() ([]*track.Track, error) { return queue, nil },
+			reorderQueueFn: func(trackIDs []string) error {
+				got = trackIDs
+				return nil
+			},
+		}
+		svc := NewService(mock, nil, "main")
+		err := svc.PlayNext(&track.Track{ID: "4"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(got, ",") != "1,4,2,3" {
+			t.Errorf("expected order 1,4,2,3, got %v", got)
+		}
+	})
+
+	t.Run("current track is rejected", func(t *testing.T) {
+		mock := &mockStore{
+			queueFn: func() ([]*track.Track, error) { return queue, nil },
+		}
+		svc := NewService(mock, nil, "main")
+		err := svc.PlayNext(&track.Track{ID: "1"})
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("empty queue", func(t *testing.T) {
+		svc := NewService(&mockStore{}, nil, "main")
+		err := svc.PlayNext(&track.Track{ID: "1"})
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestService_PlayFirst(t *testing.T) {
+	queue := []*track.Track{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+
+	t.Run("moves queued track to the head", func(t *testing.T) {
+		var got []string
+		mock := &mockStore{
+			queueFn: func() ([]*track.Track, error) { return queue, nil },
+			reorderQueueFn: func(trackIDs []string) error {
+				got = trackIDs
+				return nil
+			},
+		}
+		svc := NewService(mock, nil, "main")
+		err := svc.PlayFirst(&track.Track{ID: "3"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(got, ",") != "3,1,2" {
+			t.Errorf("expected order 3,1,2, got %v", got)
+		}
+	})
+
+	t.Run("inserts track missing from the queue", func(t *testing.T) {
+		var got []string
+		mock := &mockStore{
+			queueFn: func() ([]*track.Track, error) { return queue, nil },
+			reorderQueueFn: func(trackIDs []string) error {
+				got = trackIDs
+				return nil
+			},
+		}
+		svc := NewService(mock, nil, "main")
+		err := svc.PlayFirst(&track.Track{ID: "4"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(got, ",") != "4,1,2,3" {
+			t.Errorf("expected order 4,1,2,3, got %v", got)
+		}
+	})
+
+	t.Run("current track is rejected", func(t *testing.T) {
+		mock := &mockStore{
+			queueFn: func() ([]*track.Track, error) { return queue, nil },
+		}
+		svc := NewService(mock, nil, "main")
+		err := svc.PlayFirst(&track.Track{ID: "1"})
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestService_IsPlayed(t *testing.T) {
+	svc := NewService(&mockStore{played: []string{"1"}}, nil, "main")
+
+	for id, want := range map[string]bool{"1": true, "2": false} {
+		got, err := svc.IsPlayed(id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("%s: expected played %v, got %v", id, want, got)
+		}
+	}
+}
+
+func TestService_SpinQueueModes(t *testing.T) {
+	current := &track.Track{ID: "1"}
+	next := &track.Track{ID: "2"}
+
+	t.Run("repeat one keeps the queue", func(t *testing.T) {
+		mock := &mockStore{
+			mode: ModeRepeatOne,
+			spinQueueFn: func() error {
+				t.Error("SpinQueue should not be called on store")
+				return nil
+			},
+		}
+		svc := NewService(mock, nil, "main")
+		if err := svc.SpinQueue(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("consume removes the current track", func(t *testing.T) {
+		var removed []string
+		mock := &mockStore{
+			mode: ModeConsume,
+			currentAndNextTrackFn: func() (*track.Track, *track.Track, error) {
+				return current, next, nil
+			},
+			removeFromQueueFn: func(trackIDs []string) error {
+				removed = trackIDs
+				return nil
+			},
+		}
+		svc := NewService(mock, nil, "main")
+		if err := svc.SpinQueue(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(removed) != 1 || removed[0] != "1" {
+			t.Errorf("expected current track to be removed, got %v", removed)
+		}
+	})
+
+	t.Run("shuffle puts an unplayed track next", func(t *testing.T) {
+		var got []string
+		mock := &mockStore{
+			mode:   ModeShuffle,
+			played: []string{"2", "3"},
+			queueFn: func() ([]*track.Track, error) {
+				return []*track.Track{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}}, nil
+			},
+			reorderQueueFn: func(trackIDs []string) error {
+				got = trackIDs
+				return nil
+			},
+		}
+		svc := NewService(mock, nil, "main")
+		if err := svc.SpinQueue(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(got, ",") != "1,4,2,3" {
+			t.Errorf("expected order 1,4,2,3, got %v", got)
+		}
+	})
+}
+
+func TestService_CurrentAndNextTrackModes(t *testing.T) {
+	current := &track.Track{ID: "1"}
+	next := &track.Track{ID: "2"}
+	pair := func() (*track.Track, *track.Track, error) { return current, next, nil }
+	single := func() (*track.Track, *track.Track, error) { return current, current, nil }
+
+	tests := []struct {
+		name     string
+		mode     Mode
+		played   []string
+		store    func() (*track.Track, *track.Track, error)
+		expected [2]string
+	}{
+		{"sequential", ModeSequential, nil, pair, [2]string{"1", "2"}},
+		{"repeat one", ModeRepeatOne, nil, pair, [2]string{"1", "1"}},
+		{"consume last track", ModeConsume, nil, single, [2]string{"1", ""}},
+		{"stop at end with next played", ModeStopAtEnd, []string{"2"}, pair, [2]string{"1", ""}},
+		{"stop at end played through", ModeStopAtEnd, []string{"1", "2"}, pair, [2]string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockStore{mode: tt.mode, played: tt.played, currentAndNextTrackFn: tt.store}
+			svc := NewService(mock, nil, "main")
+			c, n, err := svc.CurrentAndNextTrack()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := [2]string{trackID(c), trackID(n)}
+			if got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_SetMode(t *testing.T) {
+	t.Run("saves mode and resets progress", func(t *testing.T) {
+		mock := &mockStore{played: []string{"1"}}
+		svc := NewService(mock, nil, "main")
+		if err := svc.SetMode(ModeConsume); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mock.mode != ModeConsume {
+			t.Errorf("expected mode %q, got %q", ModeConsume, mock.mode)
+		}
+		if len(mock.played) != 0 {
+			t.Errorf("expected played tracks to be reset, got %v", mock.played)
+		}
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		svc := NewService(&mockStore{}, nil, "main")
+		if err := svc.SetMode("random"); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestService_Rewind(t *testing.T) {
+	mock := &mockStore{
+		mode:   ModeStopAtEnd,
+		played: []string{"1", "2"},
+		currentAndNextTrackFn: func() (*track.Track, *track.Track, error) {
+			return &track.Track{ID: "1"}, &track.Track{ID: "2"}, nil
+		},
+	}
+	svc := NewService(mock, nil, "main")
+	if err := svc.Rewind(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mock.played) != 0 {
+		t.Errorf("expected played tracks to be reset, got %v", mock.played)
+	}
+}
+
+func trackID(t *track.Track) string {
+	if t == nil {
+		return ""
+	}
+	return t.ID
+}
+
+func TestService_ReplaceQueue(t *testing.T) {
+	queue := []*track.Track{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+	replacement := []*track.Track{{ID: "3"}, {ID: "4"}}
+
+	tests := []struct {
+		name        string
+		keepCurrent bool
+		removed     string
+		order       string
+	}{
+		{"hard", false, "1,2", "3,4"},
+		{"keeps current", true, "2", "1,3,4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var removed, order []string
+			mock := &mockStore{
+				played:  []string{"1"},
+				queueFn: func() ([]*track.Track, error) { return queue, nil },
+				removeFromQueueFn: func(trackIDs []string) error {
+					removed = trackIDs
+					return nil
+				},
+				reorderQueueFn: func(trackIDs []string) error {
+					order = trackIDs
+					return nil
+				},
+			}
+			svc := NewService(mock, nil, "main")
+			if err := svc.ReplaceQueue(replacement, tt.keepCurrent); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(removed, ",") != tt.removed {
+				t.Errorf("expected removed %s, got %v", tt.removed, removed)
+			}
+			if strings.Join(order, ",") != tt.order {
+				t.Errorf("expected order %s, got %v", tt.order, order)
+			}
+			if len(mock.played) != 0 {
+				t.Errorf("expected played tracks to be reset, got %v", mock.played)
+			}
+		})
+	}
+}
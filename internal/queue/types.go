@@ -2,11 +2,32 @@ package queue
 
 import "github.com/cheatsnake/airstation/internal/track"
 
+// Mode defines the order in which the tracks of the queue are played.
+type Mode string
+
+const (
+	ModeSequential Mode = "sequential"  // Plays tracks in order and starts over after the last one
+	ModeShuffle    Mode = "shuffle"     // Plays tracks in random order without repeats until all of them are played
+	ModeRepeatOne  Mode = "repeat_one"  // Repeats the current track
+	ModeConsume    Mode = "consume"     // Removes tracks from the queue once they are played
+	ModeStopAtEnd  Mode = "stop_at_end" // Plays tracks in order once and stops after the last one
+)
+
+var modes = []Mode{ModeSequential, ModeShuffle, ModeRepeatOne, ModeConsume, ModeStopAtEnd}
+
+type BodyWithMode struct {
+	Mode Mode `json:"mode"`
+}
+
 type Store interface {
-	Queue() ([]*track.Track, error)
-	AddToQueue(tracks []*track.Track) error
-	RemoveFromQueue(trackIDs []string) error
-	ReorderQueue(trackIDs []string) error
-	SpinQueue() error
-	CurrentAndNextTrack() (*track.Track, *track.Track, error)
+	Queue(channelID string) ([]*track.Track, error)
+	AddToQueue(channelID string, tracks []*track.Track) error
+	RemoveFromQueue(channelID string, trackIDs []string) error
+	ReorderQueue(channelID string, trackIDs []string) error
+	SpinQueue(channelID string) error
+	CurrentAndNextTrack(channelID string) (*track.Track, *track.Track, error)
+	PlayedTrackIDs(channelID string) ([]string, error)
+	ResetPlayed(channelID string) error
+	QueueMode(channelID string) (Mode, error)
+	SetQueueMode(channelID string, mode Mode) error
 }
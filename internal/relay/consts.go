@@ -0,0 +1,14 @@
+package relay
+
+import "time"
+
+const (
+	bitRate        = 128                    // Bitrate in kbps of MP3 streams
+	chunkSize      = 4096                   // Size of the chunks the encoded audio is delivered in
+	burstSize      = 128 << 10              // About 8 seconds of 128 kbps audio sent to new listeners at once
+	listenerBuffer = 64                     // Number of chunks a listener may fall behind before it's disconnected
+	feedInterval   = 500 * time.Millisecond // How often the playing segment is checked
+)
+
+// MetaInterval is the number of audio bytes between ICY metadata blocks.
+const MetaInterval = 16000
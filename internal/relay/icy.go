@@ -0,0 +1,93 @@
+package relay
+
+import (
+	"io"
+	"strings"
+)
+
+// maxMetaLength is the longest metadata block the one-byte length prefix can describe.
+const maxMetaLength = 255 * 16
+
+// ICYWriter interleaves the audio written to it with ICY (SHOUTcast) metadata blocks,
+// which players use to show the title of the playing track.
+type ICYWriter struct {
+	w         io.Writer
+	interval  int           // Number of audio bytes between metadata blocks
+	remaining int           // Number of audio bytes until the next metadata block
+	title     func() string // Provides the title put into the next metadata block
+	sent      string        // The title of the last sent metadata block
+}
+
+// NewICYWriter creates a writer that puts a metadata block after every interval bytes of audio.
+//
+// Parameters:
+//   - w: The connection of the listener.
+//   - interval: The number of audio bytes between metadata blocks, announced in the icy-metaint header.
+//   - title: Provides the title of the playing track.
+//
+// Returns:
+//   - A pointer to a new ICYWriter instance.
+func NewICYWriter(w io.Writer, interval int, title func() string) *ICYWriter {
+	return &ICYWriter{
+		w:         w,
+		interval:  interval,
+		remaining: interval,
+		title:     title,
+	}
+}
+
+// Write writes the audio, inserting metadata blocks where they are due.
+func (iw *ICYWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), iw.remaining)
+		m, err := iw.w.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+		iw.remaining -= n
+
+		if iw.remaining == 0 {
+			if _, err := iw.w.Write(iw.metadata()); err != nil {
+				return written, err
+			}
+			iw.remaining = iw.interval
+		}
+	}
+
+	return written, nil
+}
+
+// metadata returns the next metadata block. The title is only sent when it changes,
+// otherwise an empty block is sent.
+func (iw *ICYWriter) metadata() []byte {
+	title := iw.title()
+	if title == iw.sent {
+		return []byte{0}
+	}
+	iw.sent = title
+
+	return icyMetadata(title)
+}
+
+// icyMetadata formats the title as a metadata block: a byte with the length of the block divided by 16,
+// followed by the StreamTitle field padded with zeros.
+func icyMetadata(title string) []byte {
+	// Quotes can't be escaped inside the field, so they are replaced with typographic ones
+	title = strings.ReplaceAll(title, "'", "’")
+	meta := "StreamTitle='" + title + "';"
+
+	if len(meta) > maxMetaLength {
+		meta = meta[:maxMetaLength-2] + "';"
+	}
+
+	blocks := (len(meta) + 15) / 16
+	block := make([]byte, 1+blocks*16)
+	block[0] = byte(blocks)
+	copy(block[1:], meta)
+
+	return block
+}
@@ -0,0 +1,75 @@
+package relay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestICYWriter(t *testing.T) {
+	t.Run("inserts metadata after every interval", func(t *testing.T) {
+		var out bytes.Buffer
+		title := "Song"
+		w := NewICYWriter(&out, 4, func() string { return title })
+
+		w.Write([]byte("abc"))
+		w.Write([]byte("defgh"))
+		title = "Next"
+		w.Write([]byte("ijk"))
+
+		meta := icyMetadata("Song")
+		next := icyMetadata("Next")
+		want := append([]byte("abcd"), meta...)
+		want = append(want, "efgh"...)
+		want = append(want, 0)
+		want = append(want, "ijk"...)
+
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("unexpected output %q, want %q", out.Bytes(), want)
+		}
+
+		w.Write([]byte("l"))
+		if !bytes.HasSuffix(out.Bytes(), next) {
+			t.Errorf("expected the changed title to be sent")
+		}
+	})
+
+	t.Run("reports written audio bytes only", func(t *testing.T) {
+		var out bytes.Buffer
+		w := NewICYWriter(&out, 2, func() string { return "" })
+
+		n, err := w.Write([]byte("abcde"))
+		if err != nil || n != 5 {
+			t.Errorf("expected 5 bytes written, got %d (%v)", n, err)
+		}
+	})
+}
+
+func TestICYMetadata(t *testing.T) {
+	t.Run("padded to 16 bytes", func(t *testing.T) {
+		block := icyMetadata("Artist - Title")
+		if (len(block)-1)%16 != 0 || int(block[0])*16 != len(block)-1 {
+			t.Fatalf("unexpected block length %d with prefix %d", len(block), block[0])
+		}
+		if !strings.HasPrefix(string(block[1:]), "StreamTitle='Artist - Title';") {
+			t.Errorf("unexpected block %q", block)
+		}
+	})
+
+	t.Run("quotes are replaced", func(t *testing.T) {
+		block := icyMetadata("Don't Stop")
+		if strings.Count(string(block), "'") != 2 {
+			t.Errorf("expected only the field quotes, got %q", block)
+		}
+	})
+
+	t.Run("long titles are cut", func(t *testing.T) {
+		block := icyMetadata(strings.Repeat("a", 5000))
+		if len(block) != 1+maxMetaLength || block[0] != 255 {
+			t.Errorf("unexpected block length %d", len(block))
+		}
+		if !strings.HasSuffix(strings.TrimRight(string(block), "\x00"), "';") {
+			t.Errorf("expected the field to be closed")
+		}
+	})
+}
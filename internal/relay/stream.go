@@ -0,0 +1,309 @@
+// Package relay serves the playback of a channel as a continuous MP3 or AAC stream for the players
+// that can't play HLS, such as hardware radios and car head units. The segments of the channel are fed
+// to an encoder as they start playing, and the encoded audio is fanned out to every connected listener.
+package relay
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/hls"
+)
+
+// Source provides the segments of the playback timeline.
+type Source interface {
+	// PlayingSegment returns the segment that plays at the moment and the name of its track,
+	// or a nil segment if nothing plays.
+	PlayingSegment() (*hls.Segment, string)
+}
+
+// Stream is a continuous audio stream of a channel in a single format.
+// The encoder runs only while there are listeners.
+type Stream struct {
+	source    Source
+	ffmpegCLI *ffmpeg.CLI
+	format    string
+	log       *slog.Logger
+
+	listeners map[*Listener]struct{}
+	burst     []byte        // The recently encoded audio sent to new listeners at once
+	title     string        // The name of the track that plays
+	stop      chan struct{} // Closed to stop the encoder, nil if it doesn't run
+	mutex     sync.Mutex
+}
+
+// Listener receives the audio of a stream.
+type Listener struct {
+	chunks chan []byte
+}
+
+// NewStream creates a stream of the channel playback.
+//
+// Parameters:
+//   - source: The playback timeline of the channel.
+//   - cli: The FFmpeg CLI used to encode the stream.
+//   - format: The format of the stream, ffmpeg.StreamMP3 or ffmpeg.StreamADTS.
+//   - log: The logger.
+//
+// Returns:
+//   - A pointer to a new Stream instance.
+func NewStream(source Source, cli *ffmpeg.CLI, format string, log *slog.Logger) *Stream {
+	return &Stream{
+		source:    source,
+		ffmpegCLI: cli,
+		format:    format,
+		log:       log,
+		listeners: make(map[*Listener]struct{}),
+	}
+}
+
+// Chunks returns the channel the encoded audio is delivered to. It's closed when the listener
+// falls too far behind or the stream stops.
+func (l *Listener) Chunks() <-chan []byte {
+	return l.chunks
+}
+
+// Subscribe connects a new listener to the stream, starting the encoder if it doesn't run.
+// The recently encoded audio is delivered first, so players can start without waiting.
+//
+// Returns:
+//   - The listener, which has to be unsubscribed once it disconnects.
+func (s *Stream) Subscribe() *Listener {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	l := &Listener{chunks: make(chan []byte, listenerBuffer)}
+	if len(s.burst) > 0 {
+		l.chunks <- append([]byte(nil), s.burst...)
+	}
+	s.listeners[l] = struct{}{}
+
+	if s.stop == nil {
+		s.stop = make(chan struct{})
+		go s.run(s.stop)
+	}
+
+	return l
+}
+
+// Unsubscribe disconnects a listener, stopping the encoder if it was the last one.
+//
+// Parameters:
+//   - l: The listener returned by Subscribe.
+func (s *Stream) Unsubscribe(l *Listener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.drop(l)
+
+	if len(s.listeners) == 0 && s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+		s.burst = nil
+	}
+}
+
+// Listeners returns the number of connected listeners.
+func (s *Stream) Listeners() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.listeners)
+}
+
+// Title returns the name of the track that plays in the stream.
+func (s *Stream) Title() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.title
+}
+
+// Close disconnects all listeners and stops the encoder.
+func (s *Stream) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for l := range s.listeners {
+		s.drop(l)
+	}
+
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.burst = nil
+}
+
+// run encodes the playing segments until it's stopped. Segments that need another init segment
+// are passed to a new encoder, whose output continues the stream. If an encoder fails, the listeners
+// are disconnected, so their players reconnect and start it again.
+func (s *Stream) run(stop chan struct{}) {
+	fd := &feeder{}
+	for {
+		cmd, input, output, err := s.ffmpegCLI.StartStreamEncoder(s.format, bitRate)
+		if err != nil {
+			s.log.Error("Stream encoder failed: " + err.Error())
+			s.stopWith(stop)
+			return
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.read(output)
+		}()
+
+		restart := s.feed(input, fd, stop, done)
+
+		input.Close()
+		<-done
+		if err := cmd.Wait(); err != nil && !isStopped(stop) {
+			s.log.Warn("Stream encoder stopped: " + err.Error())
+			restart = false
+		}
+
+		if !restart {
+			break
+		}
+	}
+
+	s.stopWith(stop)
+}
+
+// feeder keeps track of the segments written to the encoders of a stream.
+type feeder struct {
+	last string // The path of the last written segment
+	init string // The init segment the current encoder was given
+}
+
+// feed writes every segment to the encoder once it starts playing. It returns true when a segment
+// needs another init segment than the encoder was given, so a new encoder has to take over
+// and is given the segment on its first poll.
+func (s *Stream) feed(input io.Writer, fd *feeder, stop, done chan struct{}) bool {
+	ticker := time.NewTicker(feedInterval)
+	defer ticker.Stop()
+
+	started := false
+	for {
+		seg, title := s.source.PlayingSegment()
+		if seg != nil && seg.Path != fd.last {
+			if started && seg.Init != fd.init {
+				return true
+			}
+
+			if !started && seg.Init != "" {
+				if err := writeFile(input, seg.Init); err != nil {
+					s.log.Warn("Stream init segment is skipped: " + err.Error())
+				}
+			}
+			fd.init = seg.Init
+			fd.last = seg.Path
+			started = true
+			s.setTitle(title)
+
+			if err := writeFile(input, seg.Path); err != nil {
+				s.log.Warn("Stream segment is skipped: " + err.Error())
+			}
+		}
+
+		select {
+		case <-stop:
+			return false
+		case <-done:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// read delivers the encoded audio to the listeners until the encoder output ends.
+func (s *Stream) read(output io.Reader) {
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := output.Read(buf)
+		if n > 0 {
+			s.broadcast(append([]byte(nil), buf[:n]...))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// broadcast sends a chunk to every listener and keeps it for the next ones.
+// Listeners that don't keep up with the stream are disconnected.
+func (s *Stream) broadcast(chunk []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.burst = append(s.burst, chunk...)
+	if len(s.burst) > burstSize {
+		s.burst = s.burst[len(s.burst)-burstSize:]
+	}
+
+	for l := range s.listeners {
+		select {
+		case l.chunks <- chunk:
+		default:
+			s.drop(l)
+		}
+	}
+}
+
+func (s *Stream) setTitle(title string) {
+	s.mutex.Lock()
+	s.title = title
+	s.mutex.Unlock()
+}
+
+// stopWith disconnects the listeners if the stream is still run by the given encoder.
+func (s *Stream) stopWith(stop chan struct{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stop != stop {
+		return
+	}
+
+	for l := range s.listeners {
+		s.drop(l)
+	}
+	close(s.stop)
+	s.stop = nil
+	s.burst = nil
+}
+
+// drop removes a listener and closes its channel. The caller must hold the mutex.
+func (s *Stream) drop(l *Listener) {
+	if _, ok := s.listeners[l]; !ok {
+		return
+	}
+
+	delete(s.listeners, l)
+	close(l.chunks)
+}
+
+func isStopped(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func writeFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
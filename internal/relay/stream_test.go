@@ -0,0 +1,86 @@
+package relay
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+// newTestStream returns a stream with a listener registered without starting the encoder.
+func newTestStream() *Stream {
+	return NewStream(nil, nil, "mp3", slog.New(slog.DiscardHandler))
+}
+
+func (s *Stream) addListener() *Listener {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	l := &Listener{chunks: make(chan []byte, listenerBuffer)}
+	s.listeners[l] = struct{}{}
+	return l
+}
+
+func TestStream_Broadcast(t *testing.T) {
+	t.Run("fans out to every listener", func(t *testing.T) {
+		s := newTestStream()
+		a, b := s.addListener(), s.addListener()
+
+		s.broadcast([]byte("chunk"))
+
+		for _, l := range []*Listener{a, b} {
+			if got := <-l.Chunks(); string(got) != "chunk" {
+				t.Errorf("unexpected chunk %q", got)
+			}
+		}
+		if s.Listeners() != 2 {
+			t.Errorf("expected 2 listeners, got %d", s.Listeners())
+		}
+	})
+
+	t.Run("keeps the recent audio for new listeners", func(t *testing.T) {
+		s := newTestStream()
+		s.broadcast(bytes.Repeat([]byte{1}, burstSize))
+		s.broadcast([]byte{2, 3})
+
+		s.mutex.Lock()
+		burst := s.burst
+		s.mutex.Unlock()
+
+		if len(burst) != burstSize || !bytes.HasSuffix(burst, []byte{2, 3}) {
+			t.Errorf("expected the last %d bytes, got %d", burstSize, len(burst))
+		}
+	})
+
+	t.Run("disconnects listeners that fall behind", func(t *testing.T) {
+		s := newTestStream()
+		slow := s.addListener()
+
+		for range listenerBuffer + 1 {
+			s.broadcast([]byte{0})
+		}
+
+		if s.Listeners() != 0 {
+			t.Errorf("expected the slow listener to be dropped")
+		}
+
+		count := 0
+		for range slow.Chunks() {
+			count++
+		}
+		if count != listenerBuffer {
+			t.Errorf("expected %d buffered chunks, got %d", listenerBuffer, count)
+		}
+	})
+}
+
+func TestStream_Close(t *testing.T) {
+	s := newTestStream()
+	l := s.addListener()
+
+	s.Close()
+
+	if _, ok := <-l.Chunks(); ok {
+		t.Error("expected the listener channel to be closed")
+	}
+	s.Unsubscribe(l) // Unsubscribing a dropped listener is safe
+}
@@ -0,0 +1,18 @@
+package schedule
+
+import "time"
+
+const (
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+
+	minNameLen  = 3
+	maxNameLen  = 128
+	minPriority = 0
+	maxPriority = 100
+
+	checkInterval = time.Second
+)
+
+// referenceWeek is an arbitrary week starting on Monday used to compare recurring entries.
+var referenceWeek = [3]int{2001, 1, 1}
@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// span is a single airing of an entry.
+type span struct {
+	start time.Time
+	end   time.Time
+}
+
+func (s span) overlaps(other span) bool {
+	return s.start.Before(other.end) && other.start.Before(s.end)
+}
+
+// spans returns the airings of the entry that overlap the [from, to) window.
+func (e *Entry) spans(from, to time.Time, loc *time.Location) []span {
+	from, to = from.In(loc), to.In(loc)
+	startClock, _ := time.Parse(clockLayout, e.Start)
+	endClock, _ := time.Parse(clockLayout, e.End)
+
+	var spans []span
+	// An airing started the day before may still be running after midnight
+	day := time.Date(from.Year(), from.Month(), from.Day()-1, 0, 0, 0, 0, loc)
+	for !day.After(to) {
+		if e.airsOn(day) {
+			s := span{
+				start: time.Date(day.Year(), day.Month(), day.Day(), startClock.Hour(), startClock.Minute(), 0, 0, loc),
+				end:   time.Date(day.Year(), day.Month(), day.Day(), endClock.Hour(), endClock.Minute(), 0, 0, loc),
+			}
+			if !s.end.After(s.start) {
+				s.end = s.end.AddDate(0, 0, 1)
+			}
+			if s.overlaps(span{start: from, end: to}) {
+				spans = append(spans, s)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return spans
+}
+
+// airsOn reports whether an airing of the entry starts on the given day.
+func (e *Entry) airsOn(day time.Time) bool {
+	switch e.Recurrence {
+	case RecurrenceOnce:
+		return day.Format(dateLayout) == e.Date
+	case RecurrenceDaily:
+		return true
+	case RecurrenceWeekly:
+		return slices.Contains(e.Weekdays, int(day.Weekday()))
+	}
+
+	return false
+}
+
+// comparisonWindow returns a window that contains every possible overlap of two entries.
+func comparisonWindow(a, b *Entry, loc *time.Location) (time.Time, time.Time) {
+	for _, e := range []*Entry{a, b} {
+		if e.Recurrence == RecurrenceOnce {
+			date, _ := time.ParseInLocation(dateLayout, e.Date, loc)
+			return date, date.AddDate(0, 0, 2)
+		}
+	}
+
+	// Recurring entries repeat every week at most
+	from := time.Date(referenceWeek[0], time.Month(referenceWeek[1]), referenceWeek[2], 0, 0, 0, 0, loc)
+	return from, from.AddDate(0, 0, 8)
+}
+
+// overlap reports whether two entries can ever be on air at the same time.
+func overlap(a, b *Entry, loc *time.Location) bool {
+	from, to := comparisonWindow(a, b, loc)
+	for _, sa := range a.spans(from, to, loc) {
+		for _, sb := range b.spans(from, to, loc) {
+			if sa.overlaps(sb) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+var offsetPattern = regexp.MustCompile(`^(?:UTC|GMT)?\s*([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// location resolves the station timezone, which is either an IANA name or a UTC offset like "UTC+3".
+// Unknown or empty timezones fall back to UTC.
+func location(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+
+	if loc, err := time.LoadLocation(timezone); err == nil {
+		return loc
+	}
+
+	match := offsetPattern.FindStringSubmatch(timezone)
+	if match == nil {
+		return time.UTC
+	}
+
+	hours, _ := strconv.Atoi(match[2])
+	minutes, _ := strconv.Atoi(match[3])
+	offset := hours*3600 + minutes*60
+	if match[1] == "-" {
+		offset = -offset
+	}
+
+	return time.FixedZone(timezone, offset)
+}
@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntry_Spans(t *testing.T) {
+	loc := time.UTC
+	morning := &Entry{Recurrence: RecurrenceWeekly, Weekdays: []int{1, 2, 3, 4, 5}, Start: "07:00", End: "10:00"}
+	night := &Entry{Recurrence: RecurrenceDaily, Start: "23:00", End: "01:00"}
+	oneOff := &Entry{Recurrence: RecurrenceOnce, Date: "2025-03-14", Start: "18:00", End: "19:30"}
+
+	tests := []struct {
+		name     string
+		entry    *Entry
+		at       time.Time
+		expected bool
+	}{
+		{"weekday inside", morning, time.Date(2025, 3, 10, 8, 30, 0, 0, loc), true}, // Monday
+		{"weekday at end", morning, time.Date(2025, 3, 10, 10, 0, 0, 0, loc), false},
+		{"weekend", morning, time.Date(2025, 3, 9, 8, 30, 0, 0, loc), false}, // Sunday
+		{"after midnight", night, time.Date(2025, 3, 10, 0, 30, 0, 0, loc), true},
+		{"before start", night, time.Date(2025, 3, 10, 22, 59, 0, 0, loc), false},
+		{"one-off date", oneOff, time.Date(2025, 3, 14, 19, 0, 0, 0, loc), true},
+		{"one-off other date", oneOff, time.Date(2025, 3, 15, 19, 0, 0, 0, loc), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onAir := len(tt.entry.spans(tt.at, tt.at.Add(time.Nanosecond), loc)) > 0
+			if onAir != tt.expected {
+				t.Errorf("expected on air %v, got %v", tt.expected, onAir)
+			}
+		})
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	loc := time.UTC
+	weekdays := &Entry{Recurrence: RecurrenceWeekly, Weekdays: []int{1, 2, 3, 4, 5}, Start: "07:00", End: "10:00"}
+	saturday := &Entry{Recurrence: RecurrenceWeekly, Weekdays: []int{6}, Start: "08:00", End: "09:00"}
+	lateSunday := &Entry{Recurrence: RecurrenceWeekly, Weekdays: []int{0}, Start: "23:00", End: "08:00"}
+	friday := &Entry{Recurrence: RecurrenceOnce, Date: "2025-03-14", Start: "09:30", End: "11:00"}
+	saturdayOnce := &Entry{Recurrence: RecurrenceOnce, Date: "2025-03-15", Start: "09:30", End: "11:00"}
+
+	tests := []struct {
+		name     string
+		a, b     *Entry
+		expected bool
+	}{
+		{"different days", weekdays, saturday, false},
+		{"crosses midnight into monday", weekdays, lateSunday, true},
+		{"one-off on a weekday", weekdays, friday, true},
+		{"one-off on a weekend", weekdays, saturdayOnce, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlap(tt.a, tt.b, loc); got != tt.expected {
+				t.Errorf("expected overlap %v, got %v", tt.expected, got)
+			}
+			if got := overlap(tt.b, tt.a, loc); got != tt.expected {
+				t.Errorf("expected reversed overlap %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		timezone string
+		offset   int
+	}{
+		{"", 0},
+		{"Europe/Berlin", 3600}, // In winter
+		{"UTC+3", 3 * 3600},
+		{"GMT-05:30", -(5*3600 + 30*60)},
+		{"somewhere", 0},
+	}
+
+	winter := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			_, offset := winter.In(location(tt.timezone)).Zone()
+			if offset != tt.offset {
+				t.Errorf("expected offset %d, got %d", tt.offset, offset)
+			}
+		})
+	}
+}
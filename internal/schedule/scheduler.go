@@ -0,0 +1,152 @@
+package schedule
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/playback"
+	"github.com/cheatsnake/airstation/internal/playlist"
+	"github.com/cheatsnake/airstation/internal/queue"
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+// Scheduler swaps the queue of a channel at the boundaries of its schedule entries.
+type Scheduler struct {
+	service         *Service
+	queueService    *queue.Service
+	playlistService *playlist.Service
+	state           *playback.State
+	log             *slog.Logger
+
+	active   *Entry         // The entry whose playlist is in the queue now
+	saved    []*track.Track // The queue before the first entry went on air, restored once no entry is active
+	leftover string         // The track that kept playing after a soft swap, removed from the queue once it ends
+}
+
+// NewScheduler creates a scheduler for the channel the given services are scoped to.
+func NewScheduler(ss *Service, qs *queue.Service, pls *playlist.Service, state *playback.State, log *slog.Logger) *Scheduler {
+	return &Scheduler{
+		service:         ss,
+		queueService:    qs,
+		playlistService: pls,
+		state:           state,
+		log:             log,
+	}
+}
+
+// Run checks the schedule every second until the stop channel is closed.
+func (sc *Scheduler) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			err := sc.Check(now)
+			if err != nil {
+				sc.log.Error("Schedule check failed: " + err.Error())
+			}
+		}
+	}
+}
+
+// Check swaps the queue if the entry on air has changed since the last check.
+// The queue that was playing before the first entry is restored when no entry is on air anymore.
+//
+// Parameters:
+//   - now: The current time.
+//
+// Returns:
+//   - An error if the schedule cannot be read or the queue cannot be swapped.
+func (sc *Scheduler) Check(now time.Time) error {
+	err := sc.dropLeftover()
+	if err != nil {
+		return err
+	}
+
+	entry, err := sc.service.Active(now)
+	if err != nil {
+		return err
+	}
+
+	if entryID(entry) == entryID(sc.active) {
+		return nil
+	}
+
+	if entry == nil {
+		tracks, hard := sc.saved, sc.active.HardStart
+		sc.log.Info(fmt.Sprintf("Schedule entry %q is over, restoring the queue", sc.active.Name))
+		sc.active, sc.saved = nil, nil
+		return sc.swap(tracks, hard)
+	}
+
+	pl, err := sc.playlistService.Playlist(entry.PlaylistID)
+	if err != nil {
+		return fmt.Errorf("failed to load playlist of schedule entry %q: %w", entry.Name, err)
+	}
+
+	if sc.active == nil {
+		sc.saved, err = sc.queueService.Queue()
+		if err != nil {
+			return err
+		}
+	}
+
+	sc.log.Info(fmt.Sprintf("Schedule entry %q is on air", entry.Name))
+	sc.active = entry
+
+	return sc.swap(pl.Tracks, entry.HardStart)
+}
+
+// swap replaces the queue with the given tracks. Unless the swap is hard,
+// the current track plays to the end before the new tracks start.
+func (sc *Scheduler) swap(tracks []*track.Track, hard bool) error {
+	current := sc.state.NowPlaying()
+	keepCurrent := !hard && current != nil
+
+	err := sc.queueService.ReplaceQueue(tracks, keepCurrent)
+	if err != nil {
+		return err
+	}
+
+	sc.leftover = ""
+	if keepCurrent && !slices.ContainsFunc(tracks, func(t *track.Track) bool { return t.ID == current.ID }) {
+		sc.leftover = current.ID
+	}
+
+	err = sc.state.Reload()
+	return err
+}
+
+// dropLeftover removes the track kept by a soft swap once it has finished playing.
+func (sc *Scheduler) dropLeftover() error {
+	if sc.leftover == "" {
+		return nil
+	}
+
+	current := sc.state.NowPlaying()
+	if current != nil && current.ID == sc.leftover {
+		return nil
+	}
+
+	err := sc.queueService.RemoveFromQueue([]string{sc.leftover})
+	if err != nil {
+		return err
+	}
+
+	sc.leftover = ""
+	err = sc.state.Reload()
+	return err
+}
+
+func entryID(e *Entry) string {
+	if e == nil {
+		return ""
+	}
+
+	return e.ID
+}
@@ -0,0 +1,193 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/playlist"
+	"github.com/cheatsnake/airstation/internal/station"
+)
+
+type Service struct {
+	store           Store
+	playlistService *playlist.Service
+	stationService  *station.Service
+	channelID       string
+}
+
+// NewService creates a schedule service scoped to a single channel.
+func NewService(store Store, pls *playlist.Service, ss *station.Service, channelID string) *Service {
+	return &Service{
+		store:           store,
+		playlistService: pls,
+		stationService:  ss,
+		channelID:       channelID,
+	}
+}
+
+// Entries retrieves all schedule entries of the channel.
+//
+// Returns:
+//   - A slice of Entry pointers or an error.
+func (s *Service) Entries() ([]*Entry, error) {
+	entries, err := s.store.ScheduleEntries(s.channelID)
+	return entries, err
+}
+
+// AddEntry validates and saves a new schedule entry.
+//
+// Parameters:
+//   - entry: The entry to add.
+//
+// Returns:
+//   - The saved entry, or an error if it is invalid or overlaps another entry with the same priority.
+func (s *Service) AddEntry(entry *Entry) (*Entry, error) {
+	entry.ChannelID = s.channelID
+	err := s.check(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.store.AddScheduleEntry(entry)
+	return saved, err
+}
+
+// EditEntry validates and updates an existing schedule entry.
+//
+// Parameters:
+//   - entry: The entry with its ID and new fields.
+//
+// Returns:
+//   - An error if the entry doesn't exist, is invalid or overlaps another entry with the same priority.
+func (s *Service) EditEntry(entry *Entry) error {
+	_, err := s.entry(entry.ID)
+	if err != nil {
+		return err
+	}
+
+	entry.ChannelID = s.channelID
+	err = s.check(entry)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.EditScheduleEntry(entry)
+	return err
+}
+
+// DeleteEntry removes a schedule entry.
+//
+// Parameters:
+//   - id: The ID of the entry.
+//
+// Returns:
+//   - An error if the entry doesn't exist or cannot be removed.
+func (s *Service) DeleteEntry(id string) error {
+	_, err := s.entry(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.DeleteScheduleEntry(id)
+	return err
+}
+
+// Active returns the entry that is on air at the given time. If several entries overlap,
+// the one with the highest priority wins, and a one-off entry wins over a recurring one.
+//
+// Parameters:
+//   - at: The moment to check.
+//
+// Returns:
+//   - The active entry, or nil if none is on air, and an error if entries cannot be read.
+func (s *Service) Active(at time.Time) (*Entry, error) {
+	entries, err := s.store.ScheduleEntries(s.channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return nil, err
+	}
+
+	var active *Entry
+	for _, e := range entries {
+		if len(e.spans(at, at.Add(time.Nanosecond), loc)) == 0 {
+			continue
+		}
+		if active == nil || outranks(e, active) {
+			active = e
+		}
+	}
+
+	return active, nil
+}
+
+// check validates the entry, normalizes it and looks for conflicts with other entries of the channel.
+func (s *Service) check(entry *Entry) error {
+	err := validateEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	normalizeEntry(entry)
+
+	pl, err := s.playlistService.Playlist(entry.PlaylistID)
+	if err != nil || pl == nil {
+		return errors.New("playlist not found")
+	}
+
+	entries, err := s.store.ScheduleEntries(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return err
+	}
+
+	for _, other := range entries {
+		if other.ID == entry.ID || other.Priority != entry.Priority {
+			continue
+		}
+		if overlap(entry, other, loc) {
+			return fmt.Errorf("entry overlaps %q with the same priority", other.Name)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) entry(id string) (*Entry, error) {
+	entry, err := s.store.ScheduleEntry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil || entry.ChannelID != s.channelID {
+		return nil, errors.New("schedule entry not found")
+	}
+
+	return entry, nil
+}
+
+func (s *Service) location() (*time.Location, error) {
+	info, err := s.stationService.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	return location(info.Timezone), nil
+}
+
+// outranks reports whether entry a wins over entry b when both are on air.
+func outranks(a, b *Entry) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+
+	return a.Recurrence == RecurrenceOnce && b.Recurrence != RecurrenceOnce
+}
@@ -0,0 +1,175 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/playlist"
+	"github.com/cheatsnake/airstation/internal/station"
+)
+
+type mockStore struct {
+	entries []*Entry
+}
+
+func (m *mockStore) ScheduleEntries(channelID string) ([]*Entry, error) {
+	return m.entries, nil
+}
+
+func (m *mockStore) ScheduleEntry(id string) (*Entry, error) {
+	for _, e := range m.entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockStore) AddScheduleEntry(entry *Entry) (*Entry, error) {
+	entry.ID = "new"
+	m.entries = append(m.entries, entry)
+	return entry, nil
+}
+
+func (m *mockStore) EditScheduleEntry(entry *Entry) error {
+	return nil
+}
+
+func (m *mockStore) DeleteScheduleEntry(id string) error {
+	return nil
+}
+
+type mockPlaylistStore struct{}
+
+func (m *mockPlaylistStore) AddPlaylist(name, description string, trackIDs []string) (*playlist.Playlist, error) {
+	return nil, nil
+}
+
+func (m *mockPlaylistStore) Playlists() ([]*playlist.Playlist, error) {
+	return nil, nil
+}
+
+func (m *mockPlaylistStore) Playlist(id string) (*playlist.Playlist, error) {
+	if id != "pl" {
+		return nil, errors.New("not found")
+	}
+	return &playlist.Playlist{ID: id}, nil
+}
+
+func (m *mockPlaylistStore) IsPlaylistExists(name string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockPlaylistStore) EditPlaylist(id, name, description string, trackIDs []string) error {
+	return nil
+}
+
+func (m *mockPlaylistStore) DeletePlaylist(id string) error {
+	return nil
+}
+
+type mockStationStore struct {
+	timezone string
+}
+
+func (m *mockStationStore) StationProperties() ([]*station.Property, error) {
+	return []*station.Property{{Key: "timezone", Value: m.timezone}}, nil
+}
+
+func (m *mockStationStore) UpsertStationProperty(key, value string) (*station.Property, error) {
+	return nil, nil
+}
+
+func (m *mockStationStore) DeleteStationProperty(key string) error {
+	return nil
+}
+
+func newTestService(store *mockStore, timezone string) *Service {
+	return NewService(store, playlist.NewService(&mockPlaylistStore{}), station.NewService(&mockStationStore{timezone}), "main")
+}
+
+func TestService_AddEntry(t *testing.T) {
+	existing := &Entry{ID: "1", ChannelID: "main", PlaylistID: "pl", Name: "Morning", Recurrence: RecurrenceDaily, Start: "07:00", End: "10:00"}
+
+	t.Run("adds entry", func(t *testing.T) {
+		store := &mockStore{entries: []*Entry{existing}}
+		entry, err := newTestService(store, "").AddEntry(&Entry{
+			PlaylistID: "pl", Name: "Evening", Recurrence: RecurrenceWeekly, Weekdays: []int{5, 1, 5}, Start: "18:00", End: "20:00",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry.ChannelID != "main" {
+			t.Errorf("expected channel main, got %q", entry.ChannelID)
+		}
+		if len(entry.Weekdays) != 2 || entry.Weekdays[0] != 1 {
+			t.Errorf("expected sorted unique weekdays, got %v", entry.Weekdays)
+		}
+	})
+
+	t.Run("rejects overlap with the same priority", func(t *testing.T) {
+		store := &mockStore{entries: []*Entry{existing}}
+		_, err := newTestService(store, "").AddEntry(&Entry{
+			PlaylistID: "pl", Name: "Show", Recurrence: RecurrenceOnce, Date: "2025-03-14", Start: "09:00", End: "11:00",
+		})
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("allows overlap with a higher priority", func(t *testing.T) {
+		store := &mockStore{entries: []*Entry{existing}}
+		_, err := newTestService(store, "").AddEntry(&Entry{
+			PlaylistID: "pl", Name: "Show", Recurrence: RecurrenceOnce, Date: "2025-03-14", Start: "09:00", End: "11:00", Priority: 1,
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("rejects invalid entries", func(t *testing.T) {
+		invalid := []*Entry{
+			{PlaylistID: "pl", Name: "Show", Recurrence: "hourly", Start: "07:00", End: "08:00"},
+			{PlaylistID: "pl", Name: "Show", Recurrence: RecurrenceDaily, Start: "7am", End: "08:00"},
+			{PlaylistID: "pl", Name: "Show", Recurrence: RecurrenceDaily, Start: "07:00", End: "07:00"},
+			{PlaylistID: "pl", Name: "Show", Recurrence: RecurrenceWeekly, Start: "07:00", End: "08:00"},
+			{PlaylistID: "pl", Name: "Show", Recurrence: RecurrenceOnce, Date: "14.03.2025", Start: "07:00", End: "08:00"},
+			{PlaylistID: "missing", Name: "Show", Recurrence: RecurrenceDaily, Start: "07:00", End: "08:00"},
+		}
+		for _, entry := range invalid {
+			if _, err := newTestService(&mockStore{}, "").AddEntry(entry); err == nil {
+				t.Errorf("expected error for %+v, got nil", entry)
+			}
+		}
+	})
+}
+
+func TestService_Active(t *testing.T) {
+	daily := &Entry{ID: "1", Name: "Daily", Recurrence: RecurrenceDaily, Start: "07:00", End: "10:00"}
+	special := &Entry{ID: "2", Name: "Special", Recurrence: RecurrenceOnce, Date: "2025-03-14", Start: "08:00", End: "09:00"}
+	store := &mockStore{entries: []*Entry{daily, special}}
+	svc := newTestService(store, "UTC+3")
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected string
+	}{
+		{"recurring entry", time.Date(2025, 3, 13, 4, 30, 0, 0, time.UTC), "1"},
+		{"one-off wins over recurring", time.Date(2025, 3, 14, 5, 30, 0, 0, time.UTC), "2"},
+		{"nothing on air", time.Date(2025, 3, 14, 8, 30, 0, 0, time.UTC), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, err := svc.Active(tt.at)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entryID(active) != tt.expected {
+				t.Errorf("expected entry %q, got %q", tt.expected, entryID(active))
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package schedule
+
+// Recurrence defines how often a schedule entry repeats.
+type Recurrence string
+
+const (
+	RecurrenceOnce   Recurrence = "once"   // Airs once on the given date
+	RecurrenceDaily  Recurrence = "daily"  // Airs every day
+	RecurrenceWeekly Recurrence = "weekly" // Airs on the given days of the week
+)
+
+// Entry describes when the playlist of a show replaces the queue of a channel.
+// Times are interpreted in the station timezone.
+type Entry struct {
+	ID         string     `json:"id"`
+	ChannelID  string     `json:"channelId"`
+	PlaylistID string     `json:"playlistId"`
+	Name       string     `json:"name"`
+	Recurrence Recurrence `json:"recurrence"`
+	Date       string     `json:"date"`      // The date of a one-off entry in YYYY-MM-DD format.
+	Weekdays   []int      `json:"weekdays"`  // Days of the week of a weekly entry, 0 is Sunday.
+	Start      string     `json:"start"`     // Start time in HH:MM format.
+	End        string     `json:"end"`       // End time in HH:MM format, earlier than the start if the entry ends after midnight.
+	Priority   int        `json:"priority"`  // Entries with higher priority win when they overlap.
+	HardStart  bool       `json:"hardStart"` // Whether the queue is swapped at the exact time instead of after the current track.
+}
+
+type Store interface {
+	ScheduleEntries(channelID string) ([]*Entry, error)
+	ScheduleEntry(id string) (*Entry, error)
+	AddScheduleEntry(entry *Entry) (*Entry, error)
+	EditScheduleEntry(entry *Entry) error
+	DeleteScheduleEntry(id string) error
+}
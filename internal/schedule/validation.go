@@ -0,0 +1,71 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+func validateEntry(entry *Entry) error {
+	if len(entry.Name) < minNameLen {
+		return fmt.Errorf("name must be at least %d characters", minNameLen)
+	}
+	if len(entry.Name) > maxNameLen {
+		return fmt.Errorf("name must be at most %d characters", maxNameLen)
+	}
+	if entry.PlaylistID == "" {
+		return errors.New("playlist is required")
+	}
+	if entry.Priority < minPriority || entry.Priority > maxPriority {
+		return fmt.Errorf("priority must be between %d and %d", minPriority, maxPriority)
+	}
+
+	start, err := time.Parse(clockLayout, entry.Start)
+	if err != nil {
+		return errors.New("start must be in HH:MM format")
+	}
+	end, err := time.Parse(clockLayout, entry.End)
+	if err != nil {
+		return errors.New("end must be in HH:MM format")
+	}
+	if start.Equal(end) {
+		return errors.New("start and end must differ")
+	}
+
+	switch entry.Recurrence {
+	case RecurrenceOnce:
+		if _, err := time.Parse(dateLayout, entry.Date); err != nil {
+			return errors.New("date must be in YYYY-MM-DD format")
+		}
+	case RecurrenceDaily:
+	case RecurrenceWeekly:
+		if len(entry.Weekdays) == 0 {
+			return errors.New("weekly entry must have at least one weekday")
+		}
+		for _, day := range entry.Weekdays {
+			if day < int(time.Sunday) || day > int(time.Saturday) {
+				return errors.New("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown recurrence %q", entry.Recurrence)
+	}
+
+	return nil
+}
+
+// normalizeEntry drops the fields that don't apply to the recurrence of the entry.
+func normalizeEntry(entry *Entry) {
+	if entry.Recurrence != RecurrenceOnce {
+		entry.Date = ""
+	}
+
+	if entry.Recurrence != RecurrenceWeekly {
+		entry.Weekdays = []int{}
+		return
+	}
+
+	slices.Sort(entry.Weekdays)
+	entry.Weekdays = slices.Compact(entry.Weekdays)
+}
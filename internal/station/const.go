@@ -9,4 +9,7 @@ const (
 	propTimezone    = "timezone"
 	propLinks       = "links"
 	propTheme       = "theme"
+
+	propSegmentDuration = "segmentDuration"
+	propLiveSegments    = "liveSegments"
 )
@@ -1,5 +1,7 @@
 package station
 
+import "strconv"
+
 type Service struct {
 	store Store
 }
@@ -103,3 +105,45 @@ func (s *Service) EditInfo(editedInfo *Info) (*Info, error) {
 
 	return freshInfo, nil
 }
+
+// Streaming returns the streaming settings saved through the API.
+//
+// Returns:
+//   - The settings, with zero values for the ones that were never saved, or an error if they can't be read.
+func (s *Service) Streaming() (*Streaming, error) {
+	rawProps, err := s.store.StationProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &Streaming{}
+	for _, prop := range rawProps {
+		switch prop.Key {
+		case propSegmentDuration:
+			settings.SegmentDuration, _ = strconv.Atoi(prop.Value)
+		case propLiveSegments:
+			settings.LiveSegments, _ = strconv.Atoi(prop.Value)
+		}
+	}
+
+	return settings, nil
+}
+
+// EditStreaming saves the streaming settings. The caller is expected to validate them.
+//
+// Parameters:
+//   - settings: The new settings.
+//
+// Returns:
+//   - The saved settings, or an error if they can't be saved.
+func (s *Service) EditStreaming(settings *Streaming) (*Streaming, error) {
+	if _, err := s.store.UpsertStationProperty(propSegmentDuration, strconv.Itoa(settings.SegmentDuration)); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.UpsertStationProperty(propLiveSegments, strconv.Itoa(settings.LiveSegments)); err != nil {
+		return nil, err
+	}
+
+	return s.Streaming()
+}
@@ -258,3 +258,67 @@ func TestService_EditInfo(t *testing.T) {
 		}
 	})
 }
+
+func TestService_Streaming(t *testing.T) {
+	t.Run("parses saved settings", func(t *testing.T) {
+		mock := &mockStore{
+			stationPropertiesFn: func() ([]*Property, error) {
+				return []*Property{
+					{Key: "name", Value: "My Station"},
+					{Key: "segmentDuration", Value: "4"},
+					{Key: "liveSegments", Value: "6"},
+				}, nil
+			},
+		}
+		svc := NewService(mock)
+		settings, err := svc.Streaming()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if settings.SegmentDuration != 4 || settings.LiveSegments != 6 {
+			t.Errorf("expected 4/6, got %d/%d", settings.SegmentDuration, settings.LiveSegments)
+		}
+	})
+
+	t.Run("zero values when never saved", func(t *testing.T) {
+		svc := NewService(&mockStore{})
+		settings, err := svc.Streaming()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if settings.SegmentDuration != 0 || settings.LiveSegments != 0 {
+			t.Errorf("expected zero values, got %d/%d", settings.SegmentDuration, settings.LiveSegments)
+		}
+	})
+}
+
+func TestService_EditStreaming(t *testing.T) {
+	t.Run("upserts both settings", func(t *testing.T) {
+		saved := make(map[string]string)
+		mock := &mockStore{
+			upsertStationPropertyFn: func(key, value string) (*Property, error) {
+				saved[key] = value
+				return &Property{Key: key, Value: value}, nil
+			},
+		}
+		svc := NewService(mock)
+		if _, err := svc.EditStreaming(&Streaming{SegmentDuration: 2, LiveSegments: 8}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if saved["segmentDuration"] != "2" || saved["liveSegments"] != "8" {
+			t.Errorf("unexpected upserts: %v", saved)
+		}
+	})
+
+	t.Run("propagates store error", func(t *testing.T) {
+		mock := &mockStore{
+			upsertStationPropertyFn: func(key, value string) (*Property, error) {
+				return nil, errors.New("db error")
+			},
+		}
+		svc := NewService(mock)
+		if _, err := svc.EditStreaming(&Streaming{SegmentDuration: 2, LiveSegments: 8}); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
@@ -11,6 +11,13 @@ type Info struct {
 	Theme       string `json:"theme"`
 }
 
+// Streaming holds the settings of the HLS stream that can be changed while the station is running.
+// Zero values mean the setting was never saved, so the configured one is used.
+type Streaming struct {
+	SegmentDuration int `json:"segmentDuration"` // The duration of segments in seconds.
+	LiveSegments    int `json:"liveSegments"`    // The number of segments in the live window.
+}
+
 type Property struct {
 	Key   string
 	Value string
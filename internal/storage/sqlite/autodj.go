@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cheatsnake/airstation/internal/autodj"
+)
+
+type AutoDJStore struct {
+	db    *sql.DB
+	mutex *sync.Mutex
+}
+
+func NewAutoDJStore(db *sql.DB, mutex *sync.Mutex) AutoDJStore {
+	return AutoDJStore{
+		db:    db,
+		mutex: mutex,
+	}
+}
+
+// AutoDJSettings returns the auto-DJ settings of a channel, or nil if they were never saved
+func (as *AutoDJStore) AutoDJSettings(channelID string) (*autodj.Settings, error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	query := `
+		SELECT enabled, threshold, playlist_id, avoid_recent_hours, weight_by_play_count
+		FROM autodj_settings
+		WHERE channel_id = ?`
+
+	var settings autodj.Settings
+	var playlistID sql.NullString
+	err := as.db.QueryRow(query, channelID).Scan(
+		&settings.Enabled, &settings.Threshold, &playlistID,
+		&settings.AvoidRecentHours, &settings.WeightByPlayCount,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query auto-DJ settings: %w", err)
+	}
+
+	settings.PlaylistID = playlistID.String
+
+	return &settings, nil
+}
+
+func (as *AutoDJStore) SaveAutoDJSettings(channelID string, settings *autodj.Settings) error {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	query := `
+		INSERT INTO autodj_settings (channel_id, enabled, threshold, playlist_id, avoid_recent_hours, weight_by_play_count)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			enabled = excluded.enabled,
+			threshold = excluded.threshold,
+			playlist_id = excluded.playlist_id,
+			avoid_recent_hours = excluded.avoid_recent_hours,
+			weight_by_play_count = excluded.weight_by_play_count`
+
+	playlistID := sql.NullString{String: settings.PlaylistID, Valid: settings.PlaylistID != ""}
+	_, err := as.db.Exec(
+		query,
+		channelID, settings.Enabled, settings.Threshold, playlistID,
+		settings.AvoidRecentHours, settings.WeightByPlayCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save auto-DJ settings: %w", err)
+	}
+
+	return nil
+}
+
+// TrackPlayCounts returns how many times each track was played on a channel according to the playback history
+func (as *AutoDJStore) TrackPlayCounts(channelID string) (map[string]int, error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	query := `
+		SELECT track_id, COUNT(*)
+		FROM playback_history
+		WHERE channel_id = ? AND track_id != ''
+		GROUP BY track_id`
+
+	rows, err := as.db.Query(query, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query play counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// RecentlyPlayedTrackIDs returns IDs of tracks played on a channel since the given unix time
+func (as *AutoDJStore) RecentlyPlayedTrackIDs(channelID string, since int64) ([]string, error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	query := `
+		SELECT DISTINCT track_id
+		FROM playback_history
+		WHERE channel_id = ? AND played_at >= ? AND track_id != ''`
+
+	rows, err := as.db.Query(query, channelID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recently played tracks: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/autodj"
+)
+
+func TestAutoDJStore_Settings(t *testing.T) {
+	inst := setupTestDB(t)
+
+	settings, err := inst.AutoDJStore.AutoDJSettings("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings != nil {
+		t.Fatalf("expected nil settings before saving, got %+v", settings)
+	}
+
+	a := addTestTrack(t, inst, "Track A", "/a.aac", 60.0, 128)
+	pl, err := inst.PlaylistStore.AddPlaylist("Fallback", "", []string{a.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saved := &autodj.Settings{Enabled: true, Threshold: 5, PlaylistID: pl.ID, AvoidRecentHours: 3, WeightByPlayCount: true}
+	if err := inst.AutoDJStore.SaveAutoDJSettings("main", saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	settings, err = inst.AutoDJStore.AutoDJSettings("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *settings != *saved {
+		t.Errorf("expected %+v, got %+v", saved, settings)
+	}
+
+	if err := inst.PlaylistStore.DeletePlaylist(pl.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settings, _ = inst.AutoDJStore.AutoDJSettings("main")
+	if settings.PlaylistID != "" {
+		t.Errorf("expected playlist to be unset after its deletion, got %q", settings.PlaylistID)
+	}
+}
+
+func TestAutoDJStore_PlayHistory(t *testing.T) {
+	inst := setupTestDB(t)
+	now := time.Now().Unix()
+
+	inst.PlaybackStore.AddPlaybackHistory("main", now-10*3600, "a", "Track A")
+	inst.PlaybackStore.AddPlaybackHistory("main", now-60, "a", "Track A")
+	inst.PlaybackStore.AddPlaybackHistory("main", now-30, "b", "Track B")
+	inst.PlaybackStore.AddPlaybackHistory("main", now-20, "", "Voice")
+
+	counts, err := inst.AutoDJStore.TrackPlayCounts("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counts["a"] != 2 || counts["b"] != 1 || len(counts) != 2 {
+		t.Errorf("unexpected play counts: %v", counts)
+	}
+
+	recent, err := inst.AutoDJStore.RecentlyPlayedTrackIDs("main", now-3600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recent) != 2 {
+		t.Errorf("expected 2 recently played tracks, got %v", recent)
+	}
+
+	recent, _ = inst.AutoDJStore.RecentlyPlayedTrackIDs("main", now-45)
+	if len(recent) != 1 || recent[0] != "b" {
+		t.Errorf("expected only track b, got %v", recent)
+	}
+}
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cheatsnake/airstation/internal/channel"
+)
+
+type ChannelStore struct {
+	db    *sql.DB
+	mutex *sync.Mutex
+}
+
+func NewChannelStore(db *sql.DB, mutex *sync.Mutex) ChannelStore {
+	return ChannelStore{
+		db:    db,
+		mutex: mutex,
+	}
+}
+
+// Channels returns all channels, the default one goes first
+func (cs *ChannelStore) Channels() ([]*channel.Channel, error) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	query := `
+		SELECT id, name, description
+		FROM channels
+		ORDER BY id = ? DESC, name ASC`
+
+	rows, err := cs.db.Query(query, channel.DefaultID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channels: %w", err)
+	}
+	defer rows.Close()
+
+	channels := make([]*channel.Channel, 0)
+	for rows.Next() {
+		var ch channel.Channel
+		if err := rows.Scan(&ch.ID, &ch.Name, &ch.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan channel: %w", err)
+		}
+		channels = append(channels, &ch)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return channels, nil
+}
+
+// Channel returns a channel by its ID or nil if it doesn't exist
+func (cs *ChannelStore) Channel(id string) (*channel.Channel, error) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	var ch channel.Channel
+	err := cs.db.QueryRow(`SELECT id, name, description FROM channels WHERE id = ?`, id).
+		Scan(&ch.ID, &ch.Name, &ch.Description)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query channel: %w", err)
+	}
+
+	return &ch, nil
+}
+
+// AddChannel inserts a new channel
+func (cs *ChannelStore) AddChannel(id, name, description string) (*channel.Channel, error) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	query := `INSERT INTO channels (id, name, description) VALUES (?, ?, ?)`
+	_, err := cs.db.Exec(query, id, name, description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert channel: %w", err)
+	}
+
+	return &channel.Channel{ID: id, Name: name, Description: description}, nil
+}
+
+// EditChannel updates name and description of a channel
+func (cs *ChannelStore) EditChannel(id, name, description string) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	query := `UPDATE channels SET name = ?, description = ? WHERE id = ?`
+	_, err := cs.db.Exec(query, name, description, id)
+	if err != nil {
+		return fmt.Errorf("failed to update channel: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteChannel removes a channel together with its queue, playback history, state, auto-DJ settings and schedule
+func (cs *ChannelStore) DeleteChannel(id string) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`DELETE FROM queue WHERE channel_id = ?`,
+		`DELETE FROM playback_history WHERE channel_id = ?`,
+		`DELETE FROM playback_state WHERE channel_id = ?`,
+		`DELETE FROM autodj_settings WHERE channel_id = ?`,
+		`DELETE FROM schedule WHERE channel_id = ?`,
+		`DELETE FROM voice_messages WHERE channel_id = ?`,
+		`DELETE FROM jingle_rotation WHERE channel_id = ?`,
+		`DELETE FROM channels WHERE id = ?`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, id); err != nil {
+			return fmt.Errorf("failed to delete channel: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/cheatsnake/airstation/internal/channel"
+	"github.com/cheatsnake/airstation/internal/track"
+)
+
+func TestChannelStore_DefaultChannel(t *testing.T) {
+	inst := setupTestDB(t)
+
+	ch, err := inst.ChannelStore.Channel(channel.DefaultID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ch == nil {
+		t.Fatal("expected default channel to be created by migrations")
+	}
+}
+
+func TestChannelStore_AddAndList(t *testing.T) {
+	inst := setupTestDB(t)
+
+	added, err := inst.ChannelStore.AddChannel("jazz", "Jazz", "Smooth tunes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added.ID != "jazz" || added.Name != "Jazz" {
+		t.Errorf("unexpected channel: %+v", added)
+	}
+
+	chs, err := inst.ChannelStore.Channels()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chs) != 2 {
+		t.Fatalf("expected 2 channels, got %d", len(chs))
+	}
+	if chs[0].ID != channel.DefaultID {
+		t.Errorf("expected default channel first, got %q", chs[0].ID)
+	}
+}
+
+func TestChannelStore_ChannelNotFound(t *testing.T) {
+	inst := setupTestDB(t)
+
+	ch, err := inst.ChannelStore.Channel("nonexistent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ch != nil {
+		t.Errorf("expected nil channel, got %+v", ch)
+	}
+}
+
+func TestChannelStore_EditChannel(t *testing.T) {
+	inst := setupTestDB(t)
+	inst.ChannelStore.AddChannel("jazz", "Jazz", "")
+
+	err := inst.ChannelStore.EditChannel("jazz", "Jazz Club", "Late night")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ch, _ := inst.ChannelStore.Channel("jazz")
+	if ch.Name != "Jazz Club" || ch.Description != "Late night" {
+		t.Errorf("unexpected channel after edit: %+v", ch)
+	}
+}
+
+func TestChannelStore_DeleteChannel(t *testing.T) {
+	inst := setupTestDB(t)
+	a := addTestTrack(t, inst, "Track A", "/a.aac", 60.0, 128)
+	inst.ChannelStore.AddChannel("jazz", "Jazz", "")
+	inst.QueueStore.AddToQueue("jazz", []*track.Track{a})
+	inst.PlaybackStore.AddPlaybackHistory("jazz", 1000, a.ID, "Track A")
+
+	err := inst.ChannelStore.DeleteChannel("jazz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ch, _ := inst.ChannelStore.Channel("jazz")
+	if ch != nil {
+		t.Error("expected channel to be deleted")
+	}
+
+	queue, _ := inst.QueueStore.Queue("jazz")
+	if len(queue) != 0 {
+		t.Errorf("expected channel queue to be deleted, got %d tracks", len(queue))
+	}
+
+	history, _ := inst.PlaybackStore.RecentPlaybackHistory("jazz", 10)
+	if len(history) != 0 {
+		t.Errorf("expected channel history to be deleted, got %d entries", len(history))
+	}
+}
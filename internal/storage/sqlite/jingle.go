@@ -0,0 +1,150 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cheatsnake/airstation/internal/jingle"
+	sqltool "github.com/cheatsnake/airstation/internal/pkg/sql"
+	"github.com/cheatsnake/airstation/internal/pkg/ulid"
+)
+
+const jingleColumns = "id, name, path, duration"
+
+type JingleStore struct {
+	db    *sql.DB
+	mutex *sync.Mutex
+}
+
+func NewJingleStore(db *sql.DB, mutex *sync.Mutex) JingleStore {
+	return JingleStore{
+		db:    db,
+		mutex: mutex,
+	}
+}
+
+// Jingles returns all jingles of the library ordered by name
+func (js *JingleStore) Jingles() ([]*jingle.Jingle, error) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	return js.queryJingles(`SELECT ` + jingleColumns + ` FROM jingles ORDER BY name, id`)
+}
+
+func (js *JingleStore) AddJingle(name, path string, duration float64) (*jingle.Jingle, error) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	j := &jingle.Jingle{
+		ID:       ulid.New(),
+		Name:     name,
+		Path:     path,
+		Duration: duration,
+	}
+
+	query := `INSERT INTO jingles (` + jingleColumns + `) VALUES (?, ?, ?, ?)`
+	_, err := js.db.Exec(query, j.ID, j.Name, j.Path, j.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert jingle: %w", err)
+	}
+
+	return j, nil
+}
+
+func (js *JingleStore) JinglesByIDs(ids []string) ([]*jingle.Jingle, error) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	if len(ids) == 0 {
+		return []*jingle.Jingle{}, nil
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM jingles WHERE %s", jingleColumns, sqltool.BuildInClause("id", len(ids)))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	return js.queryJingles(query, args...)
+}
+
+func (js *JingleStore) DeleteJingles(ids []string) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	for _, id := range ids {
+		_, err := js.db.Exec(`DELETE FROM jingles WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete jingle with ID %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// JingleRotation returns the jingle rotation rules of a channel, or nil if they were never saved
+func (js *JingleStore) JingleRotation(channelID string) (*jingle.Rotation, error) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	query := `
+		SELECT enabled, every_songs, every_minutes, top_of_hour
+		FROM jingle_rotation
+		WHERE channel_id = ?`
+
+	var rotation jingle.Rotation
+	err := js.db.QueryRow(query, channelID).Scan(
+		&rotation.Enabled, &rotation.EverySongs, &rotation.EveryMinutes, &rotation.TopOfHour,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query jingle rotation: %w", err)
+	}
+
+	return &rotation, nil
+}
+
+func (js *JingleStore) SaveJingleRotation(channelID string, rotation *jingle.Rotation) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	query := `
+		INSERT INTO jingle_rotation (channel_id, enabled, every_songs, every_minutes, top_of_hour)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			enabled = excluded.enabled,
+			every_songs = excluded.every_songs,
+			every_minutes = excluded.every_minutes,
+			top_of_hour = excluded.top_of_hour`
+
+	_, err := js.db.Exec(query, channelID, rotation.Enabled, rotation.EverySongs, rotation.EveryMinutes, rotation.TopOfHour)
+	if err != nil {
+		return fmt.Errorf("failed to save jingle rotation: %w", err)
+	}
+
+	return nil
+}
+
+func (js *JingleStore) queryJingles(query string, args ...any) ([]*jingle.Jingle, error) {
+	rows, err := js.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jingles: %w", err)
+	}
+	defer rows.Close()
+
+	jingles := make([]*jingle.Jingle, 0)
+	for rows.Next() {
+		var j jingle.Jingle
+		err := rows.Scan(&j.ID, &j.Name, &j.Path, &j.Duration)
+		if err != nil {
+			return nil, err
+		}
+		jingles = append(jingles, &j)
+	}
+
+	return jingles, rows.Err()
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/cheatsnake/airstation/internal/jingle"
+)
+
+func TestJingleStore_Library(t *testing.T) {
+	inst := setupTestDB(t)
+
+	id, err := inst.JingleStore.AddJingle("Station ID", "/jingles/id.m4a", 6.5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sweeper, err := inst.JingleStore.AddJingle("Night sweeper", "/jingles/sweeper.m4a", 9)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	jingles, err := inst.JingleStore.Jingles()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(jingles) != 2 || jingles[0].ID != sweeper.ID || jingles[1].Duration != 6.5 {
+		t.Errorf("unexpected jingles: %+v", jingles)
+	}
+
+	found, err := inst.JingleStore.JinglesByIDs([]string{id.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 1 || found[0].Path != "/jingles/id.m4a" {
+		t.Errorf("unexpected jingles by IDs: %+v", found)
+	}
+
+	if err := inst.JingleStore.DeleteJingles([]string{id.ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	jingles, err = inst.JingleStore.Jingles()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(jingles) != 1 || jingles[0].ID != sweeper.ID {
+		t.Errorf("expected only the sweeper to remain, got %+v", jingles)
+	}
+}
+
+func TestJingleStore_Rotation(t *testing.T) {
+	inst := setupTestDB(t)
+
+	rotation, err := inst.JingleStore.JingleRotation("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotation != nil {
+		t.Errorf("expected nil rotation before saving, got %+v", rotation)
+	}
+
+	saved := &jingle.Rotation{Enabled: true, EverySongs: 3, TopOfHour: true}
+	if err := inst.JingleStore.SaveJingleRotation("main", saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saved.EveryMinutes = 15
+	if err := inst.JingleStore.SaveJingleRotation("main", saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rotation, err = inst.JingleStore.JingleRotation("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotation == nil || *rotation != *saved {
+		t.Errorf("expected %+v, got %+v", saved, rotation)
+	}
+}
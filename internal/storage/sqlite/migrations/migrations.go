@@ -88,4 +88,240 @@ var migrations = []Migration{
 			return nil
 		},
 	},
+	{
+		Version: 3,
+		Name:    "add_tracks_crossfade_disabled",
+		Up: func(tx *sql.Tx) error {
+			query := `ALTER TABLE tracks ADD COLUMN crossfade_disabled INTEGER NOT NULL DEFAULT 0;`
+			if _, err := tx.Exec(query); err != nil {
+				return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+			}
+			return nil
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create_channels",
+		Up: func(tx *sql.Tx) error {
+			queries := []string{
+				`CREATE TABLE IF NOT EXISTS channels (
+                    id TEXT PRIMARY KEY,
+                    name TEXT NOT NULL,
+                    description TEXT NOT NULL DEFAULT ''
+                );`,
+				`INSERT OR IGNORE INTO channels (id, name) VALUES ('main', 'Main');`,
+				`CREATE TABLE queue_scoped (
+                    id INTEGER PRIMARY KEY AUTOINCREMENT,
+                    channel_id TEXT NOT NULL DEFAULT 'main',
+                    track_id TEXT NOT NULL,
+                    FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE,
+                    FOREIGN KEY (track_id) REFERENCES tracks (id),
+                    UNIQUE (channel_id, track_id)
+                );`,
+				`INSERT INTO queue_scoped (id, track_id) SELECT id, track_id FROM queue;`,
+				`DROP TABLE queue;`,
+				`ALTER TABLE queue_scoped RENAME TO queue;`,
+				`ALTER TABLE playback_history ADD COLUMN channel_id TEXT NOT NULL DEFAULT 'main';`,
+				`CREATE INDEX IF NOT EXISTS idx_playback_history_channel ON playback_history (channel_id, played_at);`,
+			}
+
+			for _, query := range queries {
+				if _, err := tx.Exec(query); err != nil {
+					return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create_playback_state",
+		Up: func(tx *sql.Tx) error {
+			query := `CREATE TABLE IF NOT EXISTS playback_state (
+                    channel_id TEXT PRIMARY KEY,
+                    track_id TEXT NOT NULL,
+                    elapsed REAL NOT NULL,
+                    is_playing INTEGER NOT NULL,
+                    media_sequence INTEGER NOT NULL,
+                    discon_sequence INTEGER NOT NULL,
+                    updated_at INTEGER NOT NULL,
+                    FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE
+                );`
+			if _, err := tx.Exec(query); err != nil {
+				return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add_playback_history_track_id",
+		Up: func(tx *sql.Tx) error {
+			query := `ALTER TABLE playback_history ADD COLUMN track_id TEXT NOT NULL DEFAULT '';`
+			if _, err := tx.Exec(query); err != nil {
+				return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+			}
+			return nil
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_queue_mode",
+		Up: func(tx *sql.Tx) error {
+			queries := []string{
+				`ALTER TABLE channels ADD COLUMN queue_mode TEXT NOT NULL DEFAULT 'sequential';`,
+				`ALTER TABLE queue ADD COLUMN played INTEGER NOT NULL DEFAULT 0;`,
+			}
+
+			for _, query := range queries {
+				if _, err := tx.Exec(query); err != nil {
+					return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create_autodj_settings",
+		Up: func(tx *sql.Tx) error {
+			query := `CREATE TABLE IF NOT EXISTS autodj_settings (
+                    channel_id TEXT PRIMARY KEY,
+                    enabled INTEGER NOT NULL,
+                    threshold INTEGER NOT NULL,
+                    playlist_id TEXT,
+                    avoid_recent_hours INTEGER NOT NULL,
+                    weight_by_play_count INTEGER NOT NULL,
+                    FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE,
+                    FOREIGN KEY (playlist_id) REFERENCES playlist (id) ON DELETE SET NULL
+                );`
+			if _, err := tx.Exec(query); err != nil {
+				return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+			}
+			return nil
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create_schedule",
+		Up: func(tx *sql.Tx) error {
+			queries := []string{
+				`CREATE TABLE IF NOT EXISTS schedule (
+                    id TEXT PRIMARY KEY,
+                    channel_id TEXT NOT NULL,
+                    playlist_id TEXT NOT NULL,
+                    name TEXT NOT NULL,
+                    recurrence TEXT NOT NULL,
+                    date TEXT NOT NULL DEFAULT '',
+                    weekdays TEXT NOT NULL DEFAULT '',
+                    start_time TEXT NOT NULL,
+                    end_time TEXT NOT NULL,
+                    priority INTEGER NOT NULL DEFAULT 0,
+                    hard_start INTEGER NOT NULL DEFAULT 0,
+                    FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE,
+                    FOREIGN KEY (playlist_id) REFERENCES playlist (id) ON DELETE CASCADE
+                );`,
+				`CREATE INDEX IF NOT EXISTS idx_schedule_channel ON schedule (channel_id);`,
+			}
+
+			for _, query := range queries {
+				if _, err := tx.Exec(query); err != nil {
+					return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create_voice_messages",
+		Up: func(tx *sql.Tx) error {
+			queries := []string{
+				`CREATE TABLE IF NOT EXISTS voice_messages (
+                    id TEXT PRIMARY KEY,
+                    channel_id TEXT NOT NULL,
+                    name TEXT NOT NULL,
+                    path TEXT NOT NULL,
+                    duration REAL NOT NULL,
+                    created_at INTEGER NOT NULL,
+                    expires_at INTEGER NOT NULL,
+                    aired_at INTEGER NOT NULL DEFAULT 0,
+                    FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE
+                );`,
+				`CREATE INDEX IF NOT EXISTS idx_voice_messages_channel ON voice_messages (channel_id, expires_at);`,
+			}
+
+			for _, query := range queries {
+				if _, err := tx.Exec(query); err != nil {
+					return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create_jingles",
+		Up: func(tx *sql.Tx) error {
+			queries := []string{
+				`CREATE TABLE IF NOT EXISTS jingles (
+                    id TEXT PRIMARY KEY,
+                    name TEXT NOT NULL,
+                    path TEXT NOT NULL,
+                    duration REAL NOT NULL
+                );`,
+				`CREATE TABLE IF NOT EXISTS jingle_rotation (
+                    channel_id TEXT PRIMARY KEY,
+                    enabled INTEGER NOT NULL DEFAULT 0,
+                    every_songs INTEGER NOT NULL DEFAULT 0,
+                    every_minutes INTEGER NOT NULL DEFAULT 0,
+                    top_of_hour INTEGER NOT NULL DEFAULT 0,
+                    FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE
+                );`,
+			}
+
+			for _, query := range queries {
+				if _, err := tx.Exec(query); err != nil {
+					return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add_tracks_loudness",
+		Up: func(tx *sql.Tx) error {
+			queries := []string{
+				`ALTER TABLE tracks ADD COLUMN loudness REAL NOT NULL DEFAULT 0;`,
+				`ALTER TABLE tracks ADD COLUMN true_peak REAL NOT NULL DEFAULT 0;`,
+				`ALTER TABLE tracks ADD COLUMN loudness_gain REAL NOT NULL DEFAULT 0;`,
+			}
+
+			for _, query := range queries {
+				if _, err := tx.Exec(query); err != nil {
+					return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_tracks_cue_points",
+		Up: func(tx *sql.Tx) error {
+			queries := []string{
+				`ALTER TABLE tracks ADD COLUMN cue_in REAL NOT NULL DEFAULT 0;`,
+				`ALTER TABLE tracks ADD COLUMN cue_out REAL NOT NULL DEFAULT 0;`,
+				`UPDATE tracks SET cue_out = duration;`,
+			}
+
+			for _, query := range queries {
+				if _, err := tx.Exec(query); err != nil {
+					return fmt.Errorf("failed to execute query: %w, query: %s", err, query)
+				}
+			}
+			return nil
+		},
+	},
 }
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -20,13 +21,13 @@ func NewPlaybackStore(db *sql.DB, mutex *sync.Mutex) PlaybackStore {
 	}
 }
 
-func (ps *PlaybackStore) AddPlaybackHistory(playedAt int64, trackName string) error {
+func (ps *PlaybackStore) AddPlaybackHistory(channelID string, playedAt int64, trackID, trackName string) error {
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 
-	query := `INSERT INTO playback_history (played_at, track_name) VALUES (?, ?)`
+	query := `INSERT INTO playback_history (channel_id, played_at, track_id, track_name) VALUES (?, ?, ?, ?)`
 
-	_, err := ps.db.Exec(query, playedAt, trackName)
+	_, err := ps.db.Exec(query, channelID, playedAt, trackID, trackName)
 	if err != nil {
 		return fmt.Errorf("failed to insert playback entry: %v", err)
 	}
@@ -34,18 +35,19 @@ func (ps *PlaybackStore) AddPlaybackHistory(playedAt int64, trackName string) er
 	return nil
 }
 
-func (ps *PlaybackStore) RecentPlaybackHistory(limit int) ([]*playback.History, error) {
+func (ps *PlaybackStore) RecentPlaybackHistory(channelID string, limit int) ([]*playback.History, error) {
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 
 	query := `
-		SELECT id, played_at, track_name 
-		FROM playback_history 
-		ORDER BY played_at DESC`
+		SELECT id, played_at, track_id, track_name 
+		FROM playback_history
+		WHERE channel_id = ?
+		ORDER BY played_at DESC, id DESC`
 
 	query += fmt.Sprintf(" LIMIT %d", limit)
 
-	rows, err := ps.db.Query(query)
+	rows, err := ps.db.Query(query, channelID)
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +56,7 @@ func (ps *PlaybackStore) RecentPlaybackHistory(limit int) ([]*playback.History,
 	var history []*playback.History
 	for rows.Next() {
 		var item playback.History
-		if err := rows.Scan(&item.ID, &item.PlayedAt, &item.TrackName); err != nil {
+		if err := rows.Scan(&item.ID, &item.PlayedAt, &item.TrackID, &item.TrackName); err != nil {
 			return nil, err
 		}
 		history = append(history, &item)
@@ -82,3 +84,54 @@ func (ps *PlaybackStore) DeleteOldPlaybackHistory() (int64, error) {
 
 	return rowsAffected, nil
 }
+
+func (ps *PlaybackStore) SavePlaybackSnapshot(channelID string, snapshot *playback.Snapshot) error {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	query := `
+		INSERT INTO playback_state (channel_id, track_id, elapsed, is_playing, media_sequence, discon_sequence, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			track_id = excluded.track_id,
+			elapsed = excluded.elapsed,
+			is_playing = excluded.is_playing,
+			media_sequence = excluded.media_sequence,
+			discon_sequence = excluded.discon_sequence,
+			updated_at = excluded.updated_at`
+
+	_, err := ps.db.Exec(
+		query,
+		channelID, snapshot.TrackID, snapshot.Elapsed, snapshot.IsPlaying,
+		snapshot.MediaSequence, snapshot.DisconSequence, snapshot.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save playback state: %w", err)
+	}
+
+	return nil
+}
+
+func (ps *PlaybackStore) PlaybackSnapshot(channelID string) (*playback.Snapshot, error) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	query := `
+		SELECT track_id, elapsed, is_playing, media_sequence, discon_sequence, updated_at
+		FROM playback_state
+		WHERE channel_id = ?`
+
+	var snapshot playback.Snapshot
+	err := ps.db.QueryRow(query, channelID).Scan(
+		&snapshot.TrackID, &snapshot.Elapsed, &snapshot.IsPlaying,
+		&snapshot.MediaSequence, &snapshot.DisconSequence, &snapshot.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query playback state: %w", err)
+	}
+
+	return &snapshot, nil
+}
@@ -3,13 +3,15 @@ package sqlite
 import (
 	"testing"
 	"time"
+
+	"github.com/cheatsnake/airstation/internal/playback"
 )
 
 func TestPlaybackStore_AddPlaybackHistory(t *testing.T) {
 	inst := setupTestDB(t)
 
 	now := time.Now().Unix()
-	err := inst.PlaybackStore.AddPlaybackHistory(now, "Test Track")
+	err := inst.PlaybackStore.AddPlaybackHistory("main", now, "", "Test Track")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -19,12 +21,12 @@ func TestPlaybackStore_RecentPlaybackHistory(t *testing.T) {
 	inst := setupTestDB(t)
 
 	now := time.Now().Unix()
-	inst.PlaybackStore.AddPlaybackHistory(now-100, "Track A")
-	inst.PlaybackStore.AddPlaybackHistory(now-50, "Track B")
-	inst.PlaybackStore.AddPlaybackHistory(now, "Track C")
+	inst.PlaybackStore.AddPlaybackHistory("main", now-100, "", "Track A")
+	inst.PlaybackStore.AddPlaybackHistory("main", now-50, "", "Track B")
+	inst.PlaybackStore.AddPlaybackHistory("main", now, "", "Track C")
 
 	t.Run("returns limited results", func(t *testing.T) {
-		history, err := inst.PlaybackStore.RecentPlaybackHistory(2)
+		history, err := inst.PlaybackStore.RecentPlaybackHistory("main", 2)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -34,7 +36,7 @@ func TestPlaybackStore_RecentPlaybackHistory(t *testing.T) {
 	})
 
 	t.Run("returns in descending order", func(t *testing.T) {
-		history, err := inst.PlaybackStore.RecentPlaybackHistory(10)
+		history, err := inst.PlaybackStore.RecentPlaybackHistory("main", 10)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -57,8 +59,8 @@ func TestPlaybackStore_DeleteOldPlaybackHistory(t *testing.T) {
 	thirtyOneDaysAgo := now - 31*24*60*60
 	recentTime := now - 100
 
-	inst.PlaybackStore.AddPlaybackHistory(thirtyOneDaysAgo, "Old Track")
-	inst.PlaybackStore.AddPlaybackHistory(recentTime, "Recent Track")
+	inst.PlaybackStore.AddPlaybackHistory("main", thirtyOneDaysAgo, "", "Old Track")
+	inst.PlaybackStore.AddPlaybackHistory("main", recentTime, "", "Recent Track")
 
 	deleted, err := inst.PlaybackStore.DeleteOldPlaybackHistory()
 	if err != nil {
@@ -68,7 +70,7 @@ func TestPlaybackStore_DeleteOldPlaybackHistory(t *testing.T) {
 		t.Errorf("expected 1 deleted, got %d", deleted)
 	}
 
-	history, err := inst.PlaybackStore.RecentPlaybackHistory(10)
+	history, err := inst.PlaybackStore.RecentPlaybackHistory("main", 10)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -79,3 +81,70 @@ func TestPlaybackStore_DeleteOldPlaybackHistory(t *testing.T) {
 		t.Errorf("expected Recent Track remaining, got %q", history[0].TrackName)
 	}
 }
+
+func TestPlaybackStore_ChannelScope(t *testing.T) {
+	inst := setupTestDB(t)
+	inst.ChannelStore.AddChannel("jazz", "Jazz", "")
+
+	inst.PlaybackStore.AddPlaybackHistory("main", 1000, "", "Track A")
+	inst.PlaybackStore.AddPlaybackHistory("jazz", 1001, "", "Track B")
+
+	history, err := inst.PlaybackStore.RecentPlaybackHistory("jazz", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 1 || history[0].TrackName != "Track B" {
+		t.Errorf("expected only jazz history, got %v", history)
+	}
+}
+
+func TestPlaybackStore_Snapshot(t *testing.T) {
+	inst := setupTestDB(t)
+
+	t.Run("missing snapshot returns nil", func(t *testing.T) {
+		snapshot, err := inst.PlaybackStore.PlaybackSnapshot("main")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if snapshot != nil {
+			t.Errorf("expected nil snapshot, got %+v", snapshot)
+		}
+	})
+
+	t.Run("save overwrites previous snapshot", func(t *testing.T) {
+		first := &playback.Snapshot{TrackID: "a", Elapsed: 10, IsPlaying: true, MediaSequence: 3, DisconSequence: 1, UpdatedAt: 100}
+		second := &playback.Snapshot{TrackID: "b", Elapsed: 25.5, IsPlaying: true, MediaSequence: 7, DisconSequence: 2, UpdatedAt: 200}
+
+		if err := inst.PlaybackStore.SavePlaybackSnapshot("main", first); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := inst.PlaybackStore.SavePlaybackSnapshot("main", second); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got, err := inst.PlaybackStore.PlaybackSnapshot("main")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if *got != *second {
+			t.Errorf("expected %+v, got %+v", second, got)
+		}
+	})
+}
+
+func TestPlaybackStore_HistoryTrackID(t *testing.T) {
+	inst := setupTestDB(t)
+	inst.PlaybackStore.AddPlaybackHistory("main", 1000, "id-a", "Track A")
+	inst.PlaybackStore.AddPlaybackHistory("main", 1000, "id-b", "Track B")
+
+	history, err := inst.PlaybackStore.RecentPlaybackHistory("main", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(history))
+	}
+	if history[0].TrackID != "id-b" {
+		t.Errorf("expected latest entry id-b first, got %q", history[0].TrackID)
+	}
+}
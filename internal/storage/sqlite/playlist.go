@@ -93,7 +93,7 @@ func (ps *PlaylistStore) Playlist(id string) (*playlist.Playlist, error) {
 	}
 
 	rows, err := ps.db.Query(`
-		SELECT t.id, t.name, t.path, t.bitRate, t.duration
+		SELECT `+trackColumns+`
 		FROM playlist_track pt
 		JOIN tracks t ON pt.track_id = t.id
 		WHERE pt.playlist_id = ?
@@ -106,11 +106,11 @@ func (ps *PlaylistStore) Playlist(id string) (*playlist.Playlist, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var t track.Track
-		if err := rows.Scan(&t.ID, &t.Name, &t.Path, &t.BitRate, &t.Duration); err != nil {
-			return nil, fmt.Errorf("failed to scan track: %w", err)
+		t, err := scanTrack(rows)
+		if err != nil {
+			return nil, err
 		}
-		p.Tracks = append(p.Tracks, &t)
+		p.Tracks = append(p.Tracks, t)
 	}
 
 	p.TrackCount = len(p.Tracks)
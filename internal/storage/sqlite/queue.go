@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 
+	"github.com/cheatsnake/airstation/internal/queue"
 	"github.com/cheatsnake/airstation/internal/track"
 )
 
@@ -21,30 +23,30 @@ func NewQueueStore(db *sql.DB, mutex *sync.Mutex) QueueStore {
 	}
 }
 
-func (qs *QueueStore) Queue() ([]*track.Track, error) {
+func (qs *QueueStore) Queue(channelID string) ([]*track.Track, error) {
 	qs.mutex.Lock()
 	defer qs.mutex.Unlock()
 
 	tracks := make([]*track.Track, 0, 10)
 
 	query := `
-		SELECT t.id, t.name, t.path, t.duration, t.bitRate
+		SELECT ` + trackColumns + `
 		FROM tracks t
 		JOIN queue q ON t.id = q.track_id
+		WHERE q.channel_id = ?
 		ORDER BY q.id ASC`
-	rows, err := qs.db.Query(query)
+	rows, err := qs.db.Query(query, channelID)
 	if err != nil {
 		return tracks, fmt.Errorf("failed to query tracks in queue: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var track track.Track
-		err := rows.Scan(&track.ID, &track.Name, &track.Path, &track.Duration, &track.BitRate)
+		track, err := scanTrack(rows)
 		if err != nil {
-			return tracks, fmt.Errorf("failed to scan track: %w", err)
+			return tracks, err
 		}
-		tracks = append(tracks, &track)
+		tracks = append(tracks, track)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -54,18 +56,18 @@ func (qs *QueueStore) Queue() ([]*track.Track, error) {
 	return tracks, nil
 }
 
-func (qs *QueueStore) AddToQueue(tracks []*track.Track) error {
+func (qs *QueueStore) AddToQueue(channelID string, tracks []*track.Track) error {
 	qs.mutex.Lock()
 	defer qs.mutex.Unlock()
 
 	query := `
-			INSERT INTO queue (track_id)
-			VALUES (?)
-			ON CONFLICT (track_id) DO NOTHING
+			INSERT INTO queue (channel_id, track_id)
+			VALUES (?, ?)
+			ON CONFLICT (channel_id, track_id) DO NOTHING
 		`
 
 	for _, track := range tracks {
-		_, err := qs.db.Exec(query, track.ID)
+		_, err := qs.db.Exec(query, channelID, track.ID)
 		if err != nil {
 			return fmt.Errorf("failed to add track to queue: %w", err)
 		}
@@ -74,13 +76,13 @@ func (qs *QueueStore) AddToQueue(tracks []*track.Track) error {
 	return nil
 }
 
-func (qs *QueueStore) RemoveFromQueue(trackIDs []string) error {
+func (qs *QueueStore) RemoveFromQueue(channelID string, trackIDs []string) error {
 	qs.mutex.Lock()
 	defer qs.mutex.Unlock()
 
-	query := `DELETE FROM queue WHERE track_id = ?`
+	query := `DELETE FROM queue WHERE channel_id = ? AND track_id = ?`
 	for _, id := range trackIDs {
-		_, err := qs.db.Exec(query, id)
+		_, err := qs.db.Exec(query, channelID, id)
 		if err != nil {
 			return fmt.Errorf("failed to remove track from queue: %w", err)
 		}
@@ -89,53 +91,71 @@ func (qs *QueueStore) RemoveFromQueue(trackIDs []string) error {
 	return nil
 }
 
-func (qs *QueueStore) ReorderQueue(trackIDs []string) error {
+func (qs *QueueStore) ReorderQueue(channelID string, trackIDs []string) error {
 	qs.mutex.Lock()
 	defer qs.mutex.Unlock()
 
-	_, err := qs.db.Exec(`DELETE FROM queue`)
+	tx, err := qs.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Tracks keep their played flags, so reordering doesn't reset the progress of the play-order mode
+	played, err := playedTrackIDs(tx, channelID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM queue WHERE channel_id = ?`, channelID)
 	if err != nil {
 		return fmt.Errorf("failed to clear queue: %w", err)
 	}
 
-	query := `INSERT INTO queue (track_id) VALUES (?)`
+	query := `INSERT INTO queue (channel_id, track_id, played) VALUES (?, ?, ?)`
 	for _, id := range trackIDs {
-		_, err := qs.db.Exec(query, id)
+		_, err := tx.Exec(query, channelID, id, slices.Contains(played, id))
 		if err != nil {
 			return fmt.Errorf("failed to reorder queue: %w", err)
 		}
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (qs *QueueStore) CurrentAndNextTrack() (*track.Track, *track.Track, error) {
+func (qs *QueueStore) CurrentAndNextTrack(channelID string) (*track.Track, *track.Track, error) {
 	qs.mutex.Lock()
 	defer qs.mutex.Unlock()
 
 	query := `
-	SELECT t.id, t.name, t.path, t.duration, t.bitRate
+	SELECT ` + trackColumns + `
 	FROM tracks t
 	JOIN queue q ON t.id = q.track_id
+	WHERE q.channel_id = ?
 	ORDER BY q.id ASC
 	LIMIT 2`
-	rows, err := qs.db.Query(query)
+	rows, err := qs.db.Query(query, channelID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to query first and second tracks: %w", err)
 	}
 	defer rows.Close()
 
-	var firstTrack, secondTrack track.Track
+	var firstTrack, secondTrack *track.Track
 	count := 0
 
 	for rows.Next() {
 		if count == 0 {
-			err := rows.Scan(&firstTrack.ID, &firstTrack.Name, &firstTrack.Path, &firstTrack.Duration, &firstTrack.BitRate)
+			firstTrack, err = scanTrack(rows)
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed to scan first track: %w", err)
 			}
 		} else if count == 1 {
-			err := rows.Scan(&secondTrack.ID, &secondTrack.Name, &secondTrack.Path, &secondTrack.Duration, &secondTrack.BitRate)
+			secondTrack, err = scanTrack(rows)
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed to scan second track: %w", err)
 			}
@@ -150,13 +170,13 @@ func (qs *QueueStore) CurrentAndNextTrack() (*track.Track, *track.Track, error)
 	if count == 0 {
 		return nil, nil, nil
 	} else if count == 1 {
-		return &firstTrack, &firstTrack, nil
+		return firstTrack, firstTrack, nil
 	}
 
-	return &firstTrack, &secondTrack, nil
+	return firstTrack, secondTrack, nil
 }
 
-func (qs *QueueStore) SpinQueue() error {
+func (qs *QueueStore) SpinQueue(channelID string) error {
 	qs.mutex.Lock()
 	defer qs.mutex.Unlock()
 
@@ -169,8 +189,8 @@ func (qs *QueueStore) SpinQueue() error {
 	var firstTrackID string
 	var firstTrackQueueID int
 
-	query := `SELECT id, track_id FROM queue ORDER BY id ASC LIMIT 1`
-	err = tx.QueryRow(query).Scan(&firstTrackQueueID, &firstTrackID)
+	query := `SELECT id, track_id FROM queue WHERE channel_id = ? ORDER BY id ASC LIMIT 1`
+	err = tx.QueryRow(query, channelID).Scan(&firstTrackQueueID, &firstTrackID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil // Queue is empty
@@ -185,7 +205,7 @@ func (qs *QueueStore) SpinQueue() error {
 		return fmt.Errorf("failed to get max ID: %w", err)
 	}
 
-	query = `UPDATE queue SET id = ? WHERE id = ?`
+	query = `UPDATE queue SET id = ?, played = 1 WHERE id = ?`
 	_, err = tx.Exec(query, maxID+1, firstTrackQueueID)
 	if err != nil {
 		return fmt.Errorf("failed to update first track ID: %w", err)
@@ -198,3 +218,76 @@ func (qs *QueueStore) SpinQueue() error {
 
 	return nil
 }
+
+func (qs *QueueStore) PlayedTrackIDs(channelID string) ([]string, error) {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	ids, err := playedTrackIDs(qs.db, channelID)
+	return ids, err
+}
+
+func (qs *QueueStore) ResetPlayed(channelID string) error {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	_, err := qs.db.Exec(`UPDATE queue SET played = 0 WHERE channel_id = ?`, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to reset played tracks: %w", err)
+	}
+
+	return nil
+}
+
+func (qs *QueueStore) QueueMode(channelID string) (queue.Mode, error) {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	var mode queue.Mode
+	err := qs.db.QueryRow(`SELECT queue_mode FROM channels WHERE id = ?`, channelID).Scan(&mode)
+	if err != nil {
+		return "", fmt.Errorf("failed to query queue mode: %w", err)
+	}
+
+	return mode, nil
+}
+
+func (qs *QueueStore) SetQueueMode(channelID string, mode queue.Mode) error {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	_, err := qs.db.Exec(`UPDATE channels SET queue_mode = ? WHERE id = ?`, mode, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to update queue mode: %w", err)
+	}
+
+	return nil
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func playedTrackIDs(q querier, channelID string) ([]string, error) {
+	rows, err := q.Query(`SELECT track_id FROM queue WHERE channel_id = ? AND played = 1`, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query played tracks: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan played track: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return ids, nil
+}
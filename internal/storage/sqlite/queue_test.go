@@ -3,6 +3,7 @@ package sqlite
 import (
 	"testing"
 
+	"github.com/cheatsnake/airstation/internal/queue"
 	"github.com/cheatsnake/airstation/internal/track"
 )
 
@@ -12,12 +13,12 @@ func TestQueueStore_AddAndRetrieve(t *testing.T) {
 	b := addTestTrack(t, inst, "Track B", "/b.aac", 120.0, 192)
 	c := addTestTrack(t, inst, "Track C", "/c.aac", 180.0, 256)
 
-	err := inst.QueueStore.AddToQueue([]*track.Track{a, b, c})
+	err := inst.QueueStore.AddToQueue("main", []*track.Track{a, b, c})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	queue, err := inst.QueueStore.Queue()
+	queue, err := inst.QueueStore.Queue("main")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -34,16 +35,16 @@ func TestQueueStore_AddToQueue_Deduplicate(t *testing.T) {
 	a := addTestTrack(t, inst, "Track A", "/a.aac", 60.0, 128)
 	b := addTestTrack(t, inst, "Track B", "/b.aac", 120.0, 192)
 
-	err := inst.QueueStore.AddToQueue([]*track.Track{a})
+	err := inst.QueueStore.AddToQueue("main", []*track.Track{a})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	err = inst.QueueStore.AddToQueue([]*track.Track{a, b})
+	err = inst.QueueStore.AddToQueue("main", []*track.Track{a, b})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	queue, err := inst.QueueStore.Queue()
+	queue, err := inst.QueueStore.Queue("main")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -58,14 +59,14 @@ func TestQueueStore_ReorderQueue(t *testing.T) {
 	b := addTestTrack(t, inst, "Track B", "/b.aac", 120.0, 192)
 	c := addTestTrack(t, inst, "Track C", "/c.aac", 180.0, 256)
 
-	inst.QueueStore.AddToQueue([]*track.Track{a, b, c})
+	inst.QueueStore.AddToQueue("main", []*track.Track{a, b, c})
 
-	err := inst.QueueStore.ReorderQueue([]string{c.ID, a.ID, b.ID})
+	err := inst.QueueStore.ReorderQueue("main", []string{c.ID, a.ID, b.ID})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	queue, err := inst.QueueStore.Queue()
+	queue, err := inst.QueueStore.Queue("main")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -85,14 +86,14 @@ func TestQueueStore_RemoveFromQueue(t *testing.T) {
 	a := addTestTrack(t, inst, "Track A", "/a.aac", 60.0, 128)
 	b := addTestTrack(t, inst, "Track B", "/b.aac", 120.0, 192)
 
-	inst.QueueStore.AddToQueue([]*track.Track{a, b})
+	inst.QueueStore.AddToQueue("main", []*track.Track{a, b})
 
-	err := inst.QueueStore.RemoveFromQueue([]string{a.ID})
+	err := inst.QueueStore.RemoveFromQueue("main", []string{a.ID})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	queue, err := inst.QueueStore.Queue()
+	queue, err := inst.QueueStore.Queue("main")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -110,14 +111,14 @@ func TestQueueStore_SpinQueue(t *testing.T) {
 	b := addTestTrack(t, inst, "Track B", "/b.aac", 120.0, 192)
 	c := addTestTrack(t, inst, "Track C", "/c.aac", 180.0, 256)
 
-	inst.QueueStore.AddToQueue([]*track.Track{a, b, c})
+	inst.QueueStore.AddToQueue("main", []*track.Track{a, b, c})
 
-	err := inst.QueueStore.SpinQueue()
+	err := inst.QueueStore.SpinQueue("main")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	queue, err := inst.QueueStore.Queue()
+	queue, err := inst.QueueStore.Queue("main")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -135,7 +136,7 @@ func TestQueueStore_SpinQueue(t *testing.T) {
 func TestQueueStore_SpinQueue_Empty(t *testing.T) {
 	inst := setupTestDB(t)
 
-	err := inst.QueueStore.SpinQueue()
+	err := inst.QueueStore.SpinQueue("main")
 	if err != nil {
 		t.Fatalf("unexpected error on empty queue: %v", err)
 	}
@@ -147,7 +148,7 @@ func TestQueueStore_CurrentAndNextTrack(t *testing.T) {
 	b := addTestTrack(t, inst, "Track B", "/b.aac", 120.0, 192)
 
 	t.Run("empty queue returns nil", func(t *testing.T) {
-		current, next, err := inst.QueueStore.CurrentAndNextTrack()
+		current, next, err := inst.QueueStore.CurrentAndNextTrack("main")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -157,8 +158,8 @@ func TestQueueStore_CurrentAndNextTrack(t *testing.T) {
 	})
 
 	t.Run("single track returns it as both current and next", func(t *testing.T) {
-		inst.QueueStore.AddToQueue([]*track.Track{a})
-		current, next, err := inst.QueueStore.CurrentAndNextTrack()
+		inst.QueueStore.AddToQueue("main", []*track.Track{a})
+		current, next, err := inst.QueueStore.CurrentAndNextTrack("main")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -168,12 +169,12 @@ func TestQueueStore_CurrentAndNextTrack(t *testing.T) {
 		if next.ID != a.ID {
 			t.Errorf("expected next same as current when single, got %q", next.ID)
 		}
-		inst.QueueStore.RemoveFromQueue([]string{a.ID})
+		inst.QueueStore.RemoveFromQueue("main", []string{a.ID})
 	})
 
 	t.Run("two tracks return distinct current and next", func(t *testing.T) {
-		inst.QueueStore.AddToQueue([]*track.Track{a, b})
-		current, next, err := inst.QueueStore.CurrentAndNextTrack()
+		inst.QueueStore.AddToQueue("main", []*track.Track{a, b})
+		current, next, err := inst.QueueStore.CurrentAndNextTrack("main")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -185,3 +186,89 @@ func TestQueueStore_CurrentAndNextTrack(t *testing.T) {
 		}
 	})
 }
+
+func TestQueueStore_ChannelScope(t *testing.T) {
+	inst := setupTestDB(t)
+	a := addTestTrack(t, inst, "Track A", "/a.aac", 60.0, 128)
+	b := addTestTrack(t, inst, "Track B", "/b.aac", 120.0, 192)
+	inst.ChannelStore.AddChannel("jazz", "Jazz", "")
+
+	inst.QueueStore.AddToQueue("main", []*track.Track{a, b})
+	inst.QueueStore.AddToQueue("jazz", []*track.Track{b})
+
+	mainQueue, _ := inst.QueueStore.Queue("main")
+	jazzQueue, _ := inst.QueueStore.Queue("jazz")
+	if len(mainQueue) != 2 || len(jazzQueue) != 1 {
+		t.Fatalf("expected queues of 2 and 1 tracks, got %d and %d", len(mainQueue), len(jazzQueue))
+	}
+
+	inst.QueueStore.SpinQueue("main")
+	current, _, _ := inst.QueueStore.CurrentAndNextTrack("jazz")
+	if current.ID != b.ID {
+		t.Errorf("expected jazz queue to be untouched by spin, got %q", current.ID)
+	}
+
+	inst.QueueStore.ReorderQueue("jazz", []string{a.ID})
+	mainQueue, _ = inst.QueueStore.Queue("main")
+	if len(mainQueue) != 2 {
+		t.Errorf("expected main queue to be untouched by reorder, got %d tracks", len(mainQueue))
+	}
+}
+
+func TestQueueStore_PlayedTracks(t *testing.T) {
+	inst := setupTestDB(t)
+	a := addTestTrack(t, inst, "Track A", "/a.aac", 60.0, 128)
+	b := addTestTrack(t, inst, "Track B", "/b.aac", 120.0, 192)
+	c := addTestTrack(t, inst, "Track C", "/c.aac", 180.0, 256)
+
+	if err := inst.QueueStore.AddToQueue("main", []*track.Track{a, b, c}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := inst.QueueStore.SpinQueue("main"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	played, err := inst.QueueStore.PlayedTrackIDs("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(played) != 1 || played[0] != a.ID {
+		t.Fatalf("expected %q to be played, got %v", a.ID, played)
+	}
+
+	if err := inst.QueueStore.ReorderQueue("main", []string{a.ID, b.ID, c.ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	played, _ = inst.QueueStore.PlayedTrackIDs("main")
+	if len(played) != 1 || played[0] != a.ID {
+		t.Errorf("expected played flag to survive reordering, got %v", played)
+	}
+
+	if err := inst.QueueStore.ResetPlayed("main"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	played, _ = inst.QueueStore.PlayedTrackIDs("main")
+	if len(played) != 0 {
+		t.Errorf("expected no played tracks after reset, got %v", played)
+	}
+}
+
+func TestQueueStore_QueueMode(t *testing.T) {
+	inst := setupTestDB(t)
+
+	mode, err := inst.QueueStore.QueueMode("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mode != queue.ModeSequential {
+		t.Errorf("expected default mode %q, got %q", queue.ModeSequential, mode)
+	}
+
+	if err := inst.QueueStore.SetQueueMode("main", queue.ModeShuffle); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mode, _ = inst.QueueStore.QueueMode("main")
+	if mode != queue.ModeShuffle {
+		t.Errorf("expected mode %q, got %q", queue.ModeShuffle, mode)
+	}
+}
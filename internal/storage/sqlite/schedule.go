@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cheatsnake/airstation/internal/pkg/ulid"
+	"github.com/cheatsnake/airstation/internal/schedule"
+)
+
+const scheduleColumns = "id, channel_id, playlist_id, name, recurrence, date, weekdays, start_time, end_time, priority, hard_start"
+
+type ScheduleStore struct {
+	db    *sql.DB
+	mutex *sync.Mutex
+}
+
+func NewScheduleStore(db *sql.DB, mutex *sync.Mutex) ScheduleStore {
+	return ScheduleStore{
+		db:    db,
+		mutex: mutex,
+	}
+}
+
+// ScheduleEntries returns all schedule entries of a channel ordered by their start time
+func (ss *ScheduleStore) ScheduleEntries(channelID string) ([]*schedule.Entry, error) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	query := `SELECT ` + scheduleColumns + ` FROM schedule WHERE channel_id = ? ORDER BY start_time, id`
+
+	rows, err := ss.db.Query(query, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedule: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*schedule.Entry, 0)
+	for rows.Next() {
+		entry, err := scanScheduleEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// ScheduleEntry returns a schedule entry by its ID, or nil if it doesn't exist
+func (ss *ScheduleStore) ScheduleEntry(id string) (*schedule.Entry, error) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	query := `SELECT ` + scheduleColumns + ` FROM schedule WHERE id = ?`
+
+	entry, err := scanScheduleEntry(ss.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query schedule entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+func (ss *ScheduleStore) AddScheduleEntry(entry *schedule.Entry) (*schedule.Entry, error) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	saved := *entry
+	saved.ID = ulid.New()
+
+	query := `INSERT INTO schedule (` + scheduleColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := ss.db.Exec(
+		query,
+		saved.ID, saved.ChannelID, saved.PlaylistID, saved.Name, saved.Recurrence, saved.Date,
+		joinWeekdays(saved.Weekdays), saved.Start, saved.End, saved.Priority, saved.HardStart,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert schedule entry: %w", err)
+	}
+
+	return &saved, nil
+}
+
+func (ss *ScheduleStore) EditScheduleEntry(entry *schedule.Entry) error {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	query := `
+		UPDATE schedule
+		SET playlist_id = ?, name = ?, recurrence = ?, date = ?, weekdays = ?, start_time = ?, end_time = ?, priority = ?, hard_start = ?
+		WHERE id = ?`
+
+	_, err := ss.db.Exec(
+		query,
+		entry.PlaylistID, entry.Name, entry.Recurrence, entry.Date, joinWeekdays(entry.Weekdays),
+		entry.Start, entry.End, entry.Priority, entry.HardStart, entry.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule entry: %w", err)
+	}
+
+	return nil
+}
+
+func (ss *ScheduleStore) DeleteScheduleEntry(id string) error {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	_, err := ss.db.Exec(`DELETE FROM schedule WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule entry: %w", err)
+	}
+
+	return nil
+}
+
+func scanScheduleEntry(row rowScanner) (*schedule.Entry, error) {
+	var entry schedule.Entry
+	var weekdays string
+	err := row.Scan(
+		&entry.ID, &entry.ChannelID, &entry.PlaylistID, &entry.Name, &entry.Recurrence, &entry.Date,
+		&weekdays, &entry.Start, &entry.End, &entry.Priority, &entry.HardStart,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Weekdays = splitWeekdays(weekdays)
+
+	return &entry, nil
+}
+
+// joinWeekdays stores weekdays as a comma-separated list
+func joinWeekdays(days []int) string {
+	parts := make([]string, len(days))
+	for i, day := range days {
+		parts[i] = strconv.Itoa(day)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func splitWeekdays(s string) []int {
+	days := make([]int, 0, 7)
+	for part := range strings.SplitSeq(s, ",") {
+		day, err := strconv.Atoi(part)
+		if err == nil {
+			days = append(days, day)
+		}
+	}
+
+	return days
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/cheatsnake/airstation/internal/schedule"
+)
+
+func TestScheduleStore_CRUD(t *testing.T) {
+	inst := setupTestDB(t)
+	a := addTestTrack(t, inst, "Track A", "/a.aac", 60.0, 128)
+	pl, err := inst.PlaylistStore.AddPlaylist("Morning", "", []string{a.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry, err := inst.ScheduleStore.AddScheduleEntry(&schedule.Entry{
+		ChannelID: "main", PlaylistID: pl.ID, Name: "Morning show", Recurrence: schedule.RecurrenceWeekly,
+		Weekdays: []int{1, 2, 3, 4, 5}, Start: "07:00", End: "10:00", Priority: 2, HardStart: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.ID == "" {
+		t.Fatal("expected entry ID to be generated")
+	}
+
+	got, err := inst.ScheduleStore.ScheduleEntry(entry.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "Morning show" || len(got.Weekdays) != 5 || got.Weekdays[4] != 5 || !got.HardStart || got.Priority != 2 {
+		t.Errorf("unexpected entry: %+v", got)
+	}
+
+	got.Recurrence = schedule.RecurrenceOnce
+	got.Date = "2025-03-14"
+	got.Weekdays = []int{}
+	if err := inst.ScheduleStore.EditScheduleEntry(got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := inst.ScheduleStore.ScheduleEntries("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Date != "2025-03-14" || len(entries[0].Weekdays) != 0 {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	if err := inst.ScheduleStore.DeleteScheduleEntry(entry.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err = inst.ScheduleStore.ScheduleEntry(entry.ID)
+	if err != nil || got != nil {
+		t.Errorf("expected deleted entry to be nil, got %+v, %v", got, err)
+	}
+}
+
+func TestScheduleStore_PlaylistDeletion(t *testing.T) {
+	inst := setupTestDB(t)
+	pl, err := inst.PlaylistStore.AddPlaylist("Evening", "", []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = inst.ScheduleStore.AddScheduleEntry(&schedule.Entry{
+		ChannelID: "main", PlaylistID: pl.ID, Name: "Evening show", Recurrence: schedule.RecurrenceDaily, Start: "18:00", End: "20:00",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := inst.PlaylistStore.DeletePlaylist(pl.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, _ := inst.ScheduleStore.ScheduleEntries("main")
+	if len(entries) != 0 {
+		t.Errorf("expected entries of deleted playlist to be removed, got %d", len(entries))
+	}
+}
@@ -16,6 +16,11 @@ type Instance struct {
 	PlaybackStore
 	PlaylistStore
 	StationStore
+	ChannelStore
+	AutoDJStore
+	ScheduleStore
+	VoiceStore
+	JingleStore
 
 	db    *sql.DB
 	log   *slog.Logger
@@ -54,6 +59,11 @@ func New(dbPath string, log *slog.Logger) (*Instance, error) {
 	instance.PlaybackStore = NewPlaybackStore(db, &instance.mutex)
 	instance.PlaylistStore = NewPlaylistStore(db, &instance.mutex)
 	instance.StationStore = NewStationStore(db, &instance.mutex)
+	instance.ChannelStore = NewChannelStore(db, &instance.mutex)
+	instance.AutoDJStore = NewAutoDJStore(db, &instance.mutex)
+	instance.ScheduleStore = NewScheduleStore(db, &instance.mutex)
+	instance.VoiceStore = NewVoiceStore(db, &instance.mutex)
+	instance.JingleStore = NewJingleStore(db, &instance.mutex)
 
 	return instance, nil
 }
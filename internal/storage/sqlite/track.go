@@ -12,6 +12,14 @@ import (
 	"github.com/cheatsnake/airstation/internal/track"
 )
 
+// trackColumns lists the tracks table columns (aliased as "t") in the order expected by scanTrack.
+const trackColumns = "t.id, t.name, t.path, t.duration, t.bitRate, t.cue_in, t.cue_out, t.crossfade_disabled, t.loudness, t.true_peak, t.loudness_gain"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 type TrackStore struct {
 	db    *sql.DB
 	mutex *sync.Mutex
@@ -46,7 +54,7 @@ func (ts *TrackStore) Tracks(page, limit int, search, sortBy, sortOrder string)
 		return tracks, 0, fmt.Errorf("failed to get total track count: %w", err)
 	}
 
-	query := "SELECT id, name, path, duration, bitRate FROM tracks"
+	query := "SELECT " + trackColumns + " FROM tracks t"
 	if search != "" {
 		query += " WHERE name LIKE ?"
 	}
@@ -65,12 +73,11 @@ func (ts *TrackStore) Tracks(page, limit int, search, sortBy, sortOrder string)
 	defer rows.Close()
 
 	for rows.Next() {
-		var track track.Track
-		err := rows.Scan(&track.ID, &track.Name, &track.Path, &track.Duration, &track.BitRate)
+		track, err := scanTrack(rows)
 		if err != nil {
-			return tracks, 0, fmt.Errorf("failed to scan track: %w", err)
+			return tracks, 0, err
 		}
-		tracks = append(tracks, &track)
+		tracks = append(tracks, track)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -91,10 +98,11 @@ func (ts *TrackStore) AddTrack(name, path string, duration float64, bitRate int)
 		Path:     path,
 		Duration: duration,
 		BitRate:  bitRate,
+		CueOut:   duration,
 	}
 
-	query := `INSERT INTO tracks (id, name, path, duration, bitRate) VALUES (?, ?, ?, ?, ?)`
-	_, err := ts.db.Exec(query, track.ID, track.Name, track.Path, track.Duration, track.BitRate)
+	query := `INSERT INTO tracks (id, name, path, duration, bitRate, cue_in, cue_out) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := ts.db.Exec(query, track.ID, track.Name, track.Path, track.Duration, track.BitRate, track.CueIn, track.CueOut)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert track: %w", err)
 	}
@@ -126,9 +134,16 @@ func (ts *TrackStore) EditTrack(track *track.Track) (*track.Track, error) {
 	SET name = ?,
 		path = ?,
 		duration = ?,
-		bitRate = ?
+		bitRate = ?,
+		cue_in = ?,
+		cue_out = ?,
+		crossfade_disabled = ?,
+		loudness = ?,
+		true_peak = ?,
+		loudness_gain = ?
 	WHERE id = ?`
-	_, err := ts.db.Exec(query, track.Name, track.Path, track.Duration, track.BitRate, track.ID)
+	_, err := ts.db.Exec(query, track.Name, track.Path, track.Duration, track.BitRate, track.CueIn, track.CueOut,
+		track.CrossfadeDisabled, track.Loudness.Integrated, track.Loudness.TruePeak, track.Loudness.Gain, track.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update track: %w", err)
 	}
@@ -140,19 +155,18 @@ func (ts *TrackStore) TrackByID(ID string) (*track.Track, error) {
 	ts.mutex.Lock()
 	defer ts.mutex.Unlock()
 
-	query := `SELECT id, name, path, duration, bitRate FROM tracks WHERE id = ?`
+	query := "SELECT " + trackColumns + " FROM tracks t WHERE t.id = ?"
 	row := ts.db.QueryRow(query, ID)
 
-	var track track.Track
-	err := row.Scan(&track.ID, &track.Name, &track.Path, &track.Duration, &track.BitRate)
+	track, err := scanTrack(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("track with ID %s not found", ID)
 		}
-		return nil, fmt.Errorf("failed to scan track: %w", err)
+		return nil, err
 	}
 
-	return &track, nil
+	return track, nil
 }
 
 func (ts *TrackStore) TracksByIDs(IDs []string) ([]*track.Track, error) {
@@ -161,8 +175,8 @@ func (ts *TrackStore) TracksByIDs(IDs []string) ([]*track.Track, error) {
 
 	tracks := make([]*track.Track, 0, len(IDs))
 
-	whereClause := sqltool.BuildInClause("id", len(IDs))
-	query := fmt.Sprintf("SELECT id, name, path, duration, bitRate FROM tracks WHERE %s", whereClause)
+	whereClause := sqltool.BuildInClause("t.id", len(IDs))
+	query := fmt.Sprintf("SELECT %s FROM tracks t WHERE %s", trackColumns, whereClause)
 	args := make([]interface{}, len(IDs))
 	for i, id := range IDs {
 		args[i] = id
@@ -175,12 +189,11 @@ func (ts *TrackStore) TracksByIDs(IDs []string) ([]*track.Track, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var track track.Track
-		err := rows.Scan(&track.ID, &track.Name, &track.Path, &track.Duration, &track.BitRate)
+		track, err := scanTrack(rows)
 		if err != nil {
-			return tracks, fmt.Errorf("failed to scan track: %w", err)
+			return tracks, err
 		}
-		tracks = append(tracks, &track)
+		tracks = append(tracks, track)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -189,3 +202,18 @@ func (ts *TrackStore) TracksByIDs(IDs []string) ([]*track.Track, error) {
 
 	return tracks, nil
 }
+
+// scanTrack reads a single track selected with trackColumns.
+func scanTrack(row rowScanner) (*track.Track, error) {
+	var t track.Track
+	err := row.Scan(&t.ID, &t.Name, &t.Path, &t.Duration, &t.BitRate, &t.CueIn, &t.CueOut, &t.CrossfadeDisabled,
+		&t.Loudness.Integrated, &t.Loudness.TruePeak, &t.Loudness.Gain)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan track: %w", err)
+	}
+
+	return &t, nil
+}
@@ -166,6 +166,7 @@ func TestTrackStore_EditTrack(t *testing.T) {
 	tr.Path = "/tracks/updated.aac"
 	tr.Duration = 90.0
 	tr.BitRate = 256
+	tr.CrossfadeDisabled = true
 
 	edited, err := inst.TrackStore.EditTrack(tr)
 	if err != nil {
@@ -182,6 +183,53 @@ func TestTrackStore_EditTrack(t *testing.T) {
 	if fetched.Name != "Updated" {
 		t.Errorf("persisted name %q, want %q", fetched.Name, "Updated")
 	}
+	if !fetched.CrossfadeDisabled {
+		t.Error("expected crossfade to be persisted as disabled")
+	}
+}
+
+func TestTrackStore_CuePoints(t *testing.T) {
+	inst := setupTestDB(t)
+	tr := addTestTrack(t, inst, "Cued", "/tracks/cued.m4a", 60.0, 192)
+
+	if tr.CueIn != 0 || tr.CueOut != 60.0 {
+		t.Fatalf("new track cues = %v..%v, want 0..60", tr.CueIn, tr.CueOut)
+	}
+
+	tr.CueIn, tr.CueOut, tr.Duration = 2.5, 57.5, 55
+	if _, err := inst.TrackStore.EditTrack(tr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fetched, err := inst.TrackStore.TrackByID(tr.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetched.CueIn != 2.5 || fetched.CueOut != 57.5 || fetched.Duration != 55 {
+		t.Errorf("persisted cues %v..%v (%v s), want 2.5..57.5 (55 s)", fetched.CueIn, fetched.CueOut, fetched.Duration)
+	}
+}
+
+func TestTrackStore_EditTrackLoudness(t *testing.T) {
+	inst := setupTestDB(t)
+	tr := addTestTrack(t, inst, "Loud", "/tracks/loud.m4a", 60.0, 192)
+
+	if tr.Loudness != (track.Loudness{}) {
+		t.Fatalf("new track loudness = %+v, want zero", tr.Loudness)
+	}
+
+	tr.Loudness = track.Loudness{Integrated: -8.5, TruePeak: 0.3, Gain: -5.5}
+	if _, err := inst.TrackStore.EditTrack(tr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fetched, err := inst.TrackStore.TrackByID(tr.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetched.Loudness != tr.Loudness {
+		t.Errorf("persisted loudness %+v, want %+v", fetched.Loudness, tr.Loudness)
+	}
 }
 
 func TestTrackStore_DeleteTracks(t *testing.T) {
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cheatsnake/airstation/internal/voice"
+)
+
+const voiceColumns = "id, channel_id, name, path, duration, created_at, expires_at, aired_at"
+
+type VoiceStore struct {
+	db    *sql.DB
+	mutex *sync.Mutex
+}
+
+func NewVoiceStore(db *sql.DB, mutex *sync.Mutex) VoiceStore {
+	return VoiceStore{
+		db:    db,
+		mutex: mutex,
+	}
+}
+
+// VoiceMessages returns the voice messages of a channel, the newest first
+func (vs *VoiceStore) VoiceMessages(channelID string) ([]*voice.Message, error) {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	query := `SELECT ` + voiceColumns + ` FROM voice_messages WHERE channel_id = ? ORDER BY created_at DESC, id DESC`
+
+	return vs.queryMessages(query, channelID)
+}
+
+// VoiceMessage returns a voice message by its ID, or nil if it doesn't exist
+func (vs *VoiceStore) VoiceMessage(id string) (*voice.Message, error) {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	query := `SELECT ` + voiceColumns + ` FROM voice_messages WHERE id = ?`
+
+	msg, err := scanVoiceMessage(vs.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query voice message: %w", err)
+	}
+
+	return msg, nil
+}
+
+func (vs *VoiceStore) AddVoiceMessage(msg *voice.Message) error {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	query := `INSERT INTO voice_messages (` + voiceColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := vs.db.Exec(
+		query,
+		msg.ID, msg.ChannelID, msg.Name, msg.Path, msg.Duration, msg.CreatedAt, msg.ExpiresAt, msg.AiredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert voice message: %w", err)
+	}
+
+	return nil
+}
+
+func (vs *VoiceStore) MarkVoiceMessageAired(id string, airedAt int64) error {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	_, err := vs.db.Exec(`UPDATE voice_messages SET aired_at = ? WHERE id = ?`, airedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update voice message: %w", err)
+	}
+
+	return nil
+}
+
+func (vs *VoiceStore) DeleteVoiceMessage(id string) error {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	_, err := vs.db.Exec(`DELETE FROM voice_messages WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete voice message: %w", err)
+	}
+
+	return nil
+}
+
+// ExpiredVoiceMessages returns the voice messages of a channel that expire at or before the given time
+func (vs *VoiceStore) ExpiredVoiceMessages(channelID string, now int64) ([]*voice.Message, error) {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	query := `SELECT ` + voiceColumns + ` FROM voice_messages WHERE channel_id = ? AND expires_at <= ? ORDER BY expires_at`
+
+	return vs.queryMessages(query, channelID, now)
+}
+
+func (vs *VoiceStore) queryMessages(query string, args ...any) ([]*voice.Message, error) {
+	rows, err := vs.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query voice messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]*voice.Message, 0)
+	for rows.Next() {
+		msg, err := scanVoiceMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+func scanVoiceMessage(row rowScanner) (*voice.Message, error) {
+	var msg voice.Message
+	err := row.Scan(&msg.ID, &msg.ChannelID, &msg.Name, &msg.Path, &msg.Duration, &msg.CreatedAt, &msg.ExpiresAt, &msg.AiredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
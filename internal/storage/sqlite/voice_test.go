@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/cheatsnake/airstation/internal/voice"
+)
+
+func TestVoiceStore_CRUD(t *testing.T) {
+	inst := setupTestDB(t)
+
+	older := &voice.Message{ID: "01a", ChannelID: "main", Name: "Hello", Path: "/voice/01a.m4a", Duration: 7.5, CreatedAt: 100, ExpiresAt: 200}
+	newer := &voice.Message{ID: "01b", ChannelID: "main", Name: "Bye", Path: "/voice/01b.m4a", Duration: 4, CreatedAt: 150, ExpiresAt: 500}
+	for _, msg := range []*voice.Message{older, newer} {
+		if err := inst.VoiceStore.AddVoiceMessage(msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	messages, err := inst.VoiceStore.VoiceMessages("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != "01b" {
+		t.Errorf("expected the newest message first, got %+v", messages)
+	}
+
+	if err := inst.VoiceStore.MarkVoiceMessageAired("01a", 120); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := inst.VoiceStore.VoiceMessage("01a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.AiredAt != 120 || got.Duration != 7.5 || got.Path != "/voice/01a.m4a" {
+		t.Errorf("unexpected message: %+v", got)
+	}
+
+	expired, err := inst.VoiceStore.ExpiredVoiceMessages("main", 300)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != "01a" {
+		t.Errorf("expected only the older message to expire, got %+v", expired)
+	}
+
+	if err := inst.VoiceStore.DeleteVoiceMessage("01a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err = inst.VoiceStore.VoiceMessage("01a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("expected deleted message to be nil, got %+v", got)
+	}
+}
@@ -1,11 +1,16 @@
 package storage
 
 import (
+	"github.com/cheatsnake/airstation/internal/autodj"
+	"github.com/cheatsnake/airstation/internal/channel"
+	"github.com/cheatsnake/airstation/internal/jingle"
 	"github.com/cheatsnake/airstation/internal/playback"
 	"github.com/cheatsnake/airstation/internal/playlist"
 	"github.com/cheatsnake/airstation/internal/queue"
+	"github.com/cheatsnake/airstation/internal/schedule"
 	"github.com/cheatsnake/airstation/internal/station"
 	"github.com/cheatsnake/airstation/internal/track"
+	"github.com/cheatsnake/airstation/internal/voice"
 )
 
 type Storage interface {
@@ -14,6 +19,11 @@ type Storage interface {
 	playback.Store
 	playlist.Store
 	station.Store
+	channel.Store
+	autodj.Store
+	schedule.Store
+	voice.Store
+	jingle.Store
 
 	Close() error
 }
@@ -1,9 +1,10 @@
 package track
 
-import "github.com/cheatsnake/airstation/internal/pkg/hls"
+import (
+	"errors"
+)
 
 const (
-	minAllowedTrackDuration = hls.DefaultMaxSegmentDuration * hls.DefaultLiveSegmentsAmount
 	maxAllowedTrackDuration = 36000 // 10 hours (just an adequate barrier)
 	defaultAudioBitRate     = 192   // best balance between quallity and size
 )
@@ -15,3 +16,15 @@ const (
 	wavExtension  = "wav"
 	flacExtension = "flac"
 )
+
+const (
+	silenceNoiseLevel  = -50 // dB, the level below which the audio is considered silent
+	minSilenceDuration = 0.5 // Seconds of silence at the edges of a track that are trimmed
+	cueEdgeTolerance   = 0.1 // Seconds between a silence and an edge of a track, for it to count as leading or trailing
+)
+
+// normalizationPageSize is the number of tracks loaded at once when the whole library is normalized.
+const normalizationPageSize = 100
+
+// ErrNormalizationRunning is returned when a bulk normalization is requested while another one is in progress.
+var ErrNormalizationRunning = errors.New("tracks normalization is already running")
@@ -2,12 +2,15 @@
 package track
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"math"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/cheatsnake/airstation/internal/events"
 	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
 	"github.com/cheatsnake/airstation/internal/pkg/fs"
 	"github.com/cheatsnake/airstation/internal/pkg/hls"
@@ -17,9 +20,14 @@ import (
 type Service struct {
 	store     Store       // An instance of Storage for managing audio file storage.
 	ffmpegCLI *ffmpeg.CLI // A pointer to the FFmpeg CLI wrapper for executing media processing commands.
+	loudness  ffmpeg.LoudnessTarget
+	events    *events.Bus // Receives the library changes and the ingest progress.
+	cache     Invalidator // Drops the segments prepared from the tracks whose audio changed, may be nil.
+	timing    *hls.Timing // The segment duration and the live window, a track must be long enough to fill the window.
 	log       *slog.Logger
 
-	LoadedTracksNotify chan int // Notification of the number of loaded tracks
+	normalizing atomic.Bool    // Whether a bulk normalization is in progress
+	background  sync.WaitGroup // Tracks the bulk normalization goroutine
 }
 
 // New creates and returns a new instance of Service.
@@ -27,71 +35,287 @@ type Service struct {
 // Parameters:
 //   - store: An implementation of TrackStore for managing audio file storage.
 //   - ffmpegCLI: A pointer to the FFmpeg CLI wrapper for executing media processing commands.
+//   - loudness: The loudness tracks are normalized to during ingest.
+//   - bus: The bus the library changes are published on, may be nil.
+//   - cache: The cache of segments prepared from the tracks, may be nil.
+//   - timing: The segment duration and the live window of the station.
 //
 // Returns:
 //   - A pointer to an initialized Service instance.
-func NewService(store Store, ffmpegCLI *ffmpeg.CLI, log *slog.Logger) *Service {
+func NewService(store Store, ffmpegCLI *ffmpeg.CLI, loudness ffmpeg.LoudnessTarget, bus *events.Bus, cache Invalidator, timing *hls.Timing, log *slog.Logger) *Service {
 	return &Service{
 		store:     store,
 		ffmpegCLI: ffmpegCLI,
+		loudness:  loudness,
+		events:    bus,
+		cache:     cache,
+		timing:    timing,
 		log:       log,
-
-		LoadedTracksNotify: make(chan int),
 	}
 }
 
-// AddTrack adds a new audio track to the database, extracting metadata and modifying its duration if necessary.
+// AddTrack adds a new audio track to the database, extracting its metadata.
+// Leading and trailing silence of the track is skipped with cue points.
 //
 // Parameters:
+//   - ctx: Stops the audio analysis when canceled.
 //   - name: The name to assign to the new track.
 //   - path: The file path of the audio track to be added.
+//   - loudness: The loudness measured while the track was prepared.
 //
 // Returns:
 //   - A pointer to the newly added Track, or an error if any step in the process fails.
-func (s *Service) AddTrack(name, path string) (*Track, error) {
-	metadata, err := s.ffmpegCLI.AudioMetadata(path)
+func (s *Service) AddTrack(ctx context.Context, name, path string, loudness Loudness) (*Track, error) {
+	metadata, err := s.ffmpegCLI.AudioMetadata(ctx, path)
 	if err != nil {
 		return nil, err
 	}
 
-	modDuration, err := s.modifyTrackDuration(path, metadata)
-	if err != nil {
+	cues := s.detectCues(ctx, path, metadata.Duration)
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	duration := cues.Out - cues.In
 
-	if modDuration < minAllowedTrackDuration {
+	if duration < s.timing.Window() {
 		return nil, fmt.Errorf("%s is too short for streaming", name)
 	}
 
-	if modDuration > maxAllowedTrackDuration {
+	if duration > maxAllowedTrackDuration {
 		return nil, fmt.Errorf("%s is too long for streaming", name)
 	}
 
 	trackName := defineTrackName(name, metadata.Name)
-	newTrack, err := s.store.AddTrack(trackName, path, modDuration, metadata.BitRate)
+	newTrack, err := s.store.AddTrack(trackName, path, metadata.Duration, metadata.BitRate)
 	if err != nil {
 		return nil, err
 	}
 
-	return newTrack, nil
+	if loudness == (Loudness{}) && duration == metadata.Duration {
+		return newTrack, nil
+	}
+
+	newTrack.Loudness = loudness
+	newTrack.CueIn, newTrack.CueOut, newTrack.Duration = cues.In, cues.Out, duration
+
+	return s.store.EditTrack(newTrack)
+}
+
+// EditCues moves the cue points of a track. The new cue points are used the next time
+// the track is prepared for playback.
+//
+// Parameters:
+//   - ctx: Stops reading the audio duration when canceled.
+//   - id: The ID of the track.
+//   - cues: The new cue points, in seconds of the audio file.
+//
+// Returns:
+//   - The updated track, or an error if the cue points are invalid or the track can't be updated.
+func (s *Service) EditCues(ctx context.Context, id string, cues Cues) (*Track, error) {
+	t, err := s.store.TrackByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.ffmpegCLI.AudioMetadata(ctx, t.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cues.In < 0 || cues.Out > metadata.Duration || cues.In >= cues.Out {
+		return nil, fmt.Errorf("cue points must be within 0 and %.3f seconds, and cue-in must be before cue-out", metadata.Duration)
+	}
+
+	duration := cues.Out - cues.In
+
+	if minDuration := s.timing.Window(); duration < minDuration {
+		return nil, fmt.Errorf("track must be at least %g seconds long between the cue points", minDuration)
+	}
+
+	t.CueIn, t.CueOut, t.Duration = cues.In, cues.Out, duration
+
+	t, err = s.store.EditTrack(t)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(t.ID)
+	s.events.Publish(events.LibraryChanged{Updated: []string{t.ID}})
+
+	return t, nil
+}
+
+// detectCues finds the cue points that skip the leading and trailing silence of an audio file.
+// If the detection fails, the whole file is played.
+func (s *Service) detectCues(ctx context.Context, path string, duration float64) Cues {
+	silences, err := s.ffmpegCLI.DetectSilence(ctx, path, duration, silenceNoiseLevel, minSilenceDuration)
+	if err != nil {
+		s.log.Warn("Failed to detect silence: "+err.Error(), "track", filepath.Base(path))
+		return Cues{Out: duration}
+	}
+
+	return cuePoints(silences, duration, s.timing.Window())
 }
 
 // PrepareTrack converts the audio file at filePath to AAC format with a fixed bitrate,
-// saving the output to a new file with an .m4a extension.
+// saving the output to a new file with an .m4a extension. The loudness of the audio is
+// normalized to the station target in two passes. If the audio can't be measured,
+// it's converted as is.
 //
 // Parameters:
+//   - ctx: Stops the conversion when canceled, no output file is left behind.
 //   - filePath: The full path of the original audio file.
 //
 // Returns:
-//   - The path to the converted .m4a file, or an error if the conversion fails.
-func (s *Service) PrepareTrack(filePath string) (string, error) {
+//   - The path to the converted .m4a file and its loudness, or an error if the conversion fails.
+func (s *Service) PrepareTrack(ctx context.Context, filePath string) (string, Loudness, error) {
 	newPath := replaceExtension(filePath, m4aExtension)
-	err := s.ffmpegCLI.ConvertAudioToAAC(filePath, newPath, defaultAudioBitRate)
+
+	loudness, err := s.normalize(ctx, filePath, newPath)
+	if err == nil {
+		return newPath, loudness, nil
+	}
+
+	if ctx.Err() != nil {
+		return "", Loudness{}, ctx.Err()
+	}
+
+	s.log.Warn("Failed to normalize track loudness: "+err.Error(), "track", filepath.Base(filePath))
+
+	err = s.ffmpegCLI.ConvertAudioToAAC(ctx, filePath, newPath, defaultAudioBitRate)
+	if err != nil {
+		return "", Loudness{}, err
+	}
+
+	return newPath, Loudness{}, nil
+}
+
+// NormalizeTracks re-normalizes the loudness of already imported tracks to the current station target.
+// The work is done in the background, only one bulk normalization can run at a time.
+//
+// Parameters:
+//   - ctx: Stops the normalization when canceled, the tracks processed so far keep their new loudness.
+//   - ids: A slice of track IDs to normalize. If empty, the whole library is normalized.
+//
+// Returns:
+//   - ErrNormalizationRunning if another normalization is in progress.
+func (s *Service) NormalizeTracks(ctx context.Context, ids []string) error {
+	if !s.normalizing.CompareAndSwap(false, true) {
+		return ErrNormalizationRunning
+	}
+
+	s.background.Go(func() {
+		defer s.normalizing.Store(false)
+
+		count, err := s.normalizeTracks(ctx, ids)
+		if err != nil {
+			s.log.Error("Tracks normalization failed: " + err.Error())
+		}
+
+		s.log.Info(fmt.Sprintf("Normalized loudness of %d track(s).", count))
+	})
+
+	return nil
+}
+
+// Wait blocks until the background work started by the service, such as a bulk normalization, is finished.
+func (s *Service) Wait() {
+	s.background.Wait()
+}
+
+// normalizeTracks normalizes the given tracks, or the whole library page by page if ids is empty.
+func (s *Service) normalizeTracks(ctx context.Context, ids []string) (int, error) {
+	if len(ids) > 0 {
+		tracks, err := s.store.TracksByIDs(ids)
+		if err != nil {
+			return 0, err
+		}
+
+		return s.normalizeBatch(ctx, tracks), ctx.Err()
+	}
+
+	count := 0
+	for page := 1; ; page++ {
+		tracks, total, err := s.store.Tracks(page, normalizationPageSize, "", "id", "asc")
+		if err != nil {
+			return count, err
+		}
+
+		count += s.normalizeBatch(ctx, tracks)
+
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		if len(tracks) == 0 || page*normalizationPageSize >= total {
+			return count, nil
+		}
+	}
+}
+
+// normalizeBatch normalizes each track in place and returns the number of successfully processed tracks.
+// It stops early when the context is canceled.
+func (s *Service) normalizeBatch(ctx context.Context, tracks []*Track) int {
+	updated := make([]string, 0, len(tracks))
+	for _, t := range tracks {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := s.normalizeTrack(ctx, t); err != nil {
+			s.log.Warn("Failed to normalize track loudness: "+err.Error(), "track", t.Name)
+			continue
+		}
+		updated = append(updated, t.ID)
+	}
+
+	if len(updated) > 0 {
+		s.events.Publish(events.LibraryChanged{Updated: updated})
+	}
+
+	return len(updated)
+}
+
+// normalizeTrack normalizes the loudness of a stored track, replacing its file.
+func (s *Service) normalizeTrack(ctx context.Context, t *Track) error {
+	dir, name := filepath.Split(t.Path)
+	tmpPath := filepath.Join(dir, "xtmp-"+name)
+
+	loudness, err := s.normalize(ctx, t.Path, tmpPath)
+	if err != nil {
+		fs.DeleteFile(tmpPath)
+		return err
+	}
+
+	err = fs.RenameFile(tmpPath, t.Path)
 	if err != nil {
-		return "", err
+		fs.DeleteFile(tmpPath)
+		return err
 	}
+	s.invalidate(t.ID)
 
-	return newPath, nil
+	t.Loudness = loudness
+	_, err = s.store.EditTrack(t)
+	return err
+}
+
+// normalize measures the loudness of the input file and writes its normalized AAC copy to the output path.
+func (s *Service) normalize(ctx context.Context, inputPath, outputPath string) (Loudness, error) {
+	measured, err := s.ffmpegCLI.AnalyzeLoudness(ctx, inputPath, s.loudness)
+	if err != nil {
+		return Loudness{}, err
+	}
+
+	result, err := s.ffmpegCLI.ConvertAudioToAACNormalized(ctx, inputPath, outputPath, defaultAudioBitRate, s.loudness, measured)
+	if err != nil {
+		return Loudness{}, err
+	}
+
+	return Loudness{
+		Integrated: measured.InputIntegrated,
+		TruePeak:   measured.InputTruePeak,
+		Gain:       result.OutputIntegrated - measured.InputIntegrated,
+	}, nil
 }
 
 // Tracks retrieves a paginated list of tracks from the store, applying optional search, sort, and order.
@@ -144,6 +368,7 @@ func (s *Service) DeleteTracks(ids []string) error {
 	if err != nil {
 		return err
 	}
+	s.invalidate(trackIDs(tracks)...)
 
 	for _, t := range tracks {
 		err := fs.DeleteFile(t.Path)
@@ -152,9 +377,18 @@ func (s *Service) DeleteTracks(ids []string) error {
 		}
 	}
 
+	s.events.Publish(events.LibraryChanged{Deleted: trackIDs(tracks)})
+
 	return err
 }
 
+// invalidate drops the cached segments of the tracks before anything else can reuse them.
+func (s *Service) invalidate(ids ...string) {
+	if s.cache != nil {
+		s.cache.Invalidate(ids...)
+	}
+}
+
 // FindTracks fetches track records by their IDs.
 //
 // Parameters:
@@ -167,6 +401,54 @@ func (s *Service) FindTracks(ids []string) ([]*Track, error) {
 	return tracks, err
 }
 
+// MakeHLSTrack generates an HLS playlist for the part of the track between its cue points.
+//
+// Parameters:
+//   - t: The track to segment.
+//   - outDir: Output directory for the HLS segments and playlist.
+//   - segName: Prefix for the segment files.
+//   - segDuration: Duration of each HLS segment in seconds.
+//
+// Returns:
+//   - An error if playlist generation fails.
+func (s *Service) MakeHLSTrack(t *Track, outDir string, segName string, segDuration int) error {
+	err := s.ffmpegCLI.MakeHLSPlaylist(t.Path, t.CueIn, t.Duration, outDir, segName, segDuration)
+	return err
+}
+
+// HLSProfile describes the segments MakeHLSTrack makes for the track, so they can be cached.
+// The profile changes when the segment container or duration, the cue points of the track or the renditions change.
+//
+// Parameters:
+//   - t: The track to segment.
+//   - segDuration: Duration of each HLS segment in seconds.
+//
+// Returns:
+//   - The profile name, safe to use as a directory name.
+func (s *Service) HLSProfile(t *Track, segDuration int) string {
+	profile := fmt.Sprintf("%s%d-%.3f-%.3f", s.ffmpegCLI.Container(), segDuration, t.CueIn, t.CueIn+t.Duration)
+	for _, r := range s.ffmpegCLI.Renditions() {
+		profile += "-" + r.Name
+	}
+
+	return profile
+}
+
+// Container returns the format of the HLS segment files.
+func (s *Service) Container() hls.Container {
+	return s.ffmpegCLI.Container()
+}
+
+// Renditions returns the extra renditions the HLS segments are made in besides the main one.
+func (s *Service) Renditions() []ffmpeg.Rendition {
+	return s.ffmpegCLI.Renditions()
+}
+
+// Timing returns the segment duration and the live window of the station.
+func (s *Service) Timing() *hls.Timing {
+	return s.timing
+}
+
 // MakeHLSPlaylist generates an HLS playlist for streaming using FFmpeg.
 //
 // Parameters:
@@ -178,19 +460,96 @@ func (s *Service) FindTracks(ids []string) ([]*Track, error) {
 // Returns:
 //   - An error if playlist generation fails.
 func (s *Service) MakeHLSPlaylist(trackPath string, outDir string, segName string, segDuration int) error {
-	err := s.ffmpegCLI.MakeHLSPlaylist(trackPath, outDir, segName, segDuration)
+	err := s.ffmpegCLI.MakeHLSPlaylist(trackPath, 0, 0, outDir, segName, segDuration)
+	return err
+}
+
+// MakeHLSCrossfade generates HLS segments for a transition between two tracks, where the tail of
+// the outgoing track is blended with the head of the incoming one.
+//
+// Parameters:
+//   - from: The outgoing track.
+//   - to: The incoming track.
+//   - fromStart: The position of the outgoing track (after its cue-in) where the transition starts.
+//   - toEnd: The position of the incoming track (after its cue-in) where the transition ends.
+//   - fade: The overlap duration and curve of the crossfade.
+//   - outDir: Output directory for the HLS segments and playlist.
+//   - segName: Prefix for the segment files.
+//   - segDuration: Duration of each HLS segment in seconds.
+//
+// Returns:
+//   - An error if segments generation fails.
+func (s *Service) MakeHLSCrossfade(from, to *Track, fromStart, toEnd float64, fade ffmpeg.Fade, outDir, segName string, segDuration int) error {
+	err := s.ffmpegCLI.MakeHLSCrossfade(
+		from.Path, to.Path,
+		from.CueIn+fromStart, from.CueIn+from.Duration,
+		to.CueIn, to.CueIn+toEnd,
+		fade, outDir, segName, segDuration, defaultAudioBitRate,
+	)
 	return err
 }
 
+// MakeHLSVoiceOver generates HLS segments for a part of the track mixed with a voice, while the track is ducked.
+//
+// Parameters:
+//   - t: The track the voice is mixed with.
+//   - voicePath: The path to the voice audio file.
+//   - start: The position of the track (after its cue-in) where the mix starts.
+//   - duration: The duration of the mix.
+//   - duck: The volume of the ducked track and the timing of the voice.
+//   - outDir: Output directory for the HLS segments and playlist.
+//   - segName: Prefix for the segment files.
+//   - segDuration: Duration of each HLS segment in seconds.
+//
+// Returns:
+//   - An error if segments generation fails.
+func (s *Service) MakeHLSVoiceOver(t *Track, voicePath string, start, duration float64, duck ffmpeg.Duck, outDir, segName string, segDuration int) error {
+	err := s.ffmpegCLI.MakeHLSVoiceOver(t.Path, voicePath, t.CueIn+start, duration, duck, outDir, segName, segDuration, defaultAudioBitRate)
+	return err
+}
+
+// SetCrossfadeDisabled allows or forbids blending the given tracks with their neighbours during playback.
+//
+// Parameters:
+//   - ids: A slice of strings contains track IDs.
+//   - disabled: Whether crossfading must be turned off for the tracks.
+//
+// Returns:
+//   - An error if the tracks could not be updated.
+func (s *Service) SetCrossfadeDisabled(ids []string, disabled bool) error {
+	tracks, err := s.store.TracksByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tracks {
+		if t.CrossfadeDisabled == disabled {
+			continue
+		}
+
+		t.CrossfadeDisabled = disabled
+		_, err := s.store.EditTrack(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.events.Publish(events.LibraryChanged{Updated: trackIDs(tracks)})
+
+	return nil
+}
+
 // LoadTracksFromDisk scans a directory for audio files, converts them if needed,
-// adds them to the store, and deletes the original copies.
+// adds them to the store, and deletes the original copies. When the context is canceled,
+// the files that are not loaded yet are left for the next scan.
 //
 // Parameters:
+//   - ctx: Stops the loading when canceled.
 //   - tracksDir: Directory path to load tracks from.
 //
 // Returns:
 //   - A slice of loaded Track pointers, or an error.
-func (s *Service) LoadTracksFromDisk(tracksDir string) ([]*Track, error) {
+func (s *Service) LoadTracksFromDisk(ctx context.Context, tracksDir string) ([]*Track, error) {
 	tracks := make([]*Track, 0)
 
 	mp3Filenames, err := fs.ListFilesFromDir(tracksDir, mp3Extension)
@@ -219,60 +578,84 @@ func (s *Service) LoadTracksFromDisk(tracksDir string) ([]*Track, error) {
 	trackFilenames = append(trackFilenames, wavFilenames...)
 	trackFilenames = append(trackFilenames, flacFilenames...)
 
-	for _, trackFilename := range trackFilenames {
-		trackPath := filepath.Join(tracksDir, trackFilename)
-		preparedTrackPath, err := s.PrepareTrack(trackPath)
-		if err != nil {
-			s.log.Warn("Failed to prepare a track for streaming: "+err.Error(), "track", trackFilename)
-			continue
+	for i, trackFilename := range trackFilenames {
+		if ctx.Err() != nil {
+			break
 		}
 
-		track, err := s.AddTrack(trackFilename, preparedTrackPath)
-		if err != nil {
-			s.log.Warn("Failed to save track to database: "+err.Error(), "track", trackFilename)
-			continue
-		}
+		progress := events.IngestProgress{File: trackFilename, Processed: i + 1, Total: len(trackFilenames)}
 
-		err = fs.DeleteFile(trackPath)
+		track, err := s.loadTrack(ctx, filepath.Join(tracksDir, trackFilename), trackFilename)
 		if err != nil {
-			s.log.Warn("Failed to delete original copy of prepared track: "+err.Error(), "track", trackFilename)
+			s.log.Warn(err.Error(), "track", trackFilename)
+			progress.Error = err.Error()
+		} else {
+			tracks = append(tracks, track)
 		}
 
-		tracks = append(tracks, track)
+		s.events.Publish(progress)
 	}
 
 	if len(tracks) > 0 {
 		s.log.Info(fmt.Sprintf("Loaded %d new track(s) from disk.", len(tracks)))
-		s.LoadedTracksNotify <- len(tracks)
+		s.events.Publish(events.LibraryChanged{Added: trackIDs(tracks)})
 	}
 
-	return tracks, nil
+	return tracks, ctx.Err()
 }
 
-// modifyTrackDuration changes the original track duration (slightly) to avoid small HLS segments.
-func (s *Service) modifyTrackDuration(path string, metadata ffmpeg.AudioMetadata) (float64, error) {
-	roundDur := roundDuration(metadata.Duration, hls.DefaultMaxSegmentDuration)
-	roundDur -= 0.001 // need to avoid extra ms after padding/trimming
+// loadTrack prepares an audio file for streaming, adds it to the store and deletes the original copy.
+// If the track can't be added, the prepared copy is deleted and the original is kept.
+func (s *Service) loadTrack(ctx context.Context, trackPath, trackFilename string) (*Track, error) {
+	preparedTrackPath, loudness, err := s.PrepareTrack(ctx, trackPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare a track for streaming: %w", err)
+	}
+
+	track, err := s.AddTrack(ctx, trackFilename, preparedTrackPath, loudness)
+	if err != nil {
+		fs.DeleteFile(preparedTrackPath)
+		return nil, fmt.Errorf("failed to save track to database: %w", err)
+	}
 
-	if err := s.ffmpegCLI.TrimAudio(path, roundDur); err != nil {
-		return 0, err
+	err = fs.DeleteFile(trackPath)
+	if err != nil {
+		s.log.Warn("Failed to delete original copy of prepared track: "+err.Error(), "track", trackFilename)
 	}
 
-	return roundDur, nil
+	return track, nil
 }
 
-// roundDuration define proper track length to be multiple for segment duration.
-func roundDuration(trackDuration, segmentDuration float64) float64 {
-	remainder := math.Mod(trackDuration, segmentDuration)
+// cuePoints returns the cue points that skip the silence at the edges of the audio. If less than
+// the minimum duration is left between them, the whole audio is played.
+func cuePoints(silences []ffmpeg.Silence, duration, minDuration float64) Cues {
+	cues := Cues{Out: duration}
+	if len(silences) == 0 {
+		return cues
+	}
+
+	if first := silences[0]; first.Start <= cueEdgeTolerance {
+		cues.In = first.End
+	}
+
+	if last := silences[len(silences)-1]; last.End >= duration-cueEdgeTolerance {
+		cues.Out = last.Start
+	}
+
+	if cues.Out-cues.In < minDuration {
+		return Cues{Out: duration}
+	}
 
-	// if the difference is not significant (less than 1.2 second), just crop it
-	if remainder < 1.2 {
-		return math.Floor(trackDuration - remainder)
+	return cues
+}
+
+func trackIDs(tracks []*Track) []string {
+	ids := make([]string, 0, len(tracks))
+	for _, t := range tracks {
+		ids = append(ids, t.ID)
 	}
 
-	// padding := segmentDuration - remainder
-	// return math.Floor(trackDuration + padding)
-	return math.Floor(trackDuration)
+	return ids
 }
 
 func defineTrackName(fileName, metaName string) string {
@@ -1,60 +1,47 @@
 package track
 
 import (
-	"math"
+	"log/slog"
+	"path/filepath"
+	"slices"
 	"testing"
 
-	"github.com/cheatsnake/airstation/internal/pkg/hls"
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
 )
 
-func TestRoundDuration(t *testing.T) {
-	segDur := float64(hls.DefaultMaxSegmentDuration)
-
-	t.Run("exact multiple stays the same", func(t *testing.T) {
-		got := roundDuration(30.0, segDur)
-		want := 30.0
-		if got != want {
-			t.Errorf("roundDuration(30, %v) = %v, want %v", segDur, got, want)
-		}
-	})
-
-	t.Run("small remainder gets floored", func(t *testing.T) {
-		got := roundDuration(31.0, segDur)
-		want := 30.0
+func TestCuePoints(t *testing.T) {
+	t.Run("no silence keeps the whole track", func(t *testing.T) {
+		got := cuePoints(nil, 180, 15)
+		want := Cues{In: 0, Out: 180}
 		if got != want {
-			t.Errorf("roundDuration(31, %v) = %v, want %v", segDur, got, want)
+			t.Errorf("cuePoints() = %+v, want %+v", got, want)
 		}
 	})
 
-	t.Run("remainder less than 1.2 seconds is cropped", func(t *testing.T) {
-		got := roundDuration(31.1, segDur)
-		want := 30.0
+	t.Run("leading and trailing silence are skipped", func(t *testing.T) {
+		silences := []ffmpeg.Silence{{Start: 0, End: 2.5}, {Start: 60, End: 61}, {Start: 176, End: 180}}
+		got := cuePoints(silences, 180, 15)
+		want := Cues{In: 2.5, Out: 176}
 		if got != want {
-			t.Errorf("roundDuration(31.1, %v) = %v, want %v", segDur, got, want)
+			t.Errorf("cuePoints() = %+v, want %+v", got, want)
 		}
 	})
 
-	t.Run("larger remainder floors to whole segment", func(t *testing.T) {
-		got := roundDuration(33.0, segDur)
-		want := math.Floor(33.0)
+	t.Run("silence in the middle is kept", func(t *testing.T) {
+		silences := []ffmpeg.Silence{{Start: 60, End: 65}}
+		got := cuePoints(silences, 180, 15)
+		want := Cues{In: 0, Out: 180}
 		if got != want {
-			t.Errorf("roundDuration(33, %v) = %v, want %v", segDur, got, want)
+			t.Errorf("cuePoints() = %+v, want %+v", got, want)
 		}
 	})
 
-	t.Run("zero duration returns zero", func(t *testing.T) {
-		got := roundDuration(0, segDur)
-		want := 0.0
+	t.Run("silent track is played as is", func(t *testing.T) {
+		silences := []ffmpeg.Silence{{Start: 0, End: 180}}
+		got := cuePoints(silences, 180, 15)
+		want := Cues{In: 0, Out: 180}
 		if got != want {
-			t.Errorf("roundDuration(0, %v) = %v, want %v", segDur, got, want)
-		}
-	})
-
-	t.Run("single segment duration", func(t *testing.T) {
-		got := roundDuration(5.0, segDur)
-		want := 5.0
-		if got != want {
-			t.Errorf("roundDuration(5, %v) = %v, want %v", segDur, got, want)
+			t.Errorf("cuePoints() = %+v, want %+v", got, want)
 		}
 	})
 }
@@ -135,4 +122,42 @@ func TestReplaceExtension(t *testing.T) {
 			t.Errorf("expected %q, got %q", want, got)
 		}
 	})
-}
\ No newline at end of file
+}
+
+type mockStore struct {
+	Store
+	tracks []*Track
+}
+
+func (m *mockStore) TracksByIDs(IDs []string) ([]*Track, error) {
+	return m.tracks, nil
+}
+
+func (m *mockStore) DeleteTracks(IDs []string) error {
+	return nil
+}
+
+type mockCache struct {
+	invalidated []string
+}
+
+func (m *mockCache) Invalidate(trackIDs ...string) {
+	m.invalidated = append(m.invalidated, trackIDs...)
+}
+
+func TestService_DeleteTracks(t *testing.T) {
+	store := &mockStore{tracks: []*Track{
+		{ID: "a", Path: filepath.Join(t.TempDir(), "a.m4a")},
+		{ID: "b", Path: filepath.Join(t.TempDir(), "b.m4a")},
+	}}
+	cache := &mockCache{}
+	svc := NewService(store, nil, ffmpeg.LoudnessTarget{}, nil, cache, nil, slog.New(slog.DiscardHandler))
+
+	if err := svc.DeleteTracks([]string{"a", "b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{"a", "b"}; !slices.Equal(cache.invalidated, want) {
+		t.Errorf("expected the cached segments of %v to be dropped, got %v", want, cache.invalidated)
+	}
+}
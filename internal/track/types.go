@@ -5,8 +5,28 @@ type Track struct {
 	ID       string  `json:"id"`       // A unique identifier for the track, typically generated using ULID.
 	Name     string  `json:"name"`     // The name of the audio track.
 	Path     string  `json:"path"`     // The file path of the audio track.
-	Duration float64 `json:"duration"` // The duration of the audio track in seconds.
+	Duration float64 `json:"duration"` // The duration of the audio track in seconds, between the cue points.
 	BitRate  int     `json:"bitRate"`  // The bit rate of the audio track in kilobits per second (kbps).
+	CueIn    float64 `json:"cueIn"`    // The position of the audio file in seconds where the track starts playing.
+	CueOut   float64 `json:"cueOut"`   // The position of the audio file in seconds where the track stops playing.
+
+	CrossfadeDisabled bool `json:"crossfadeDisabled"` // Whether the track must be played without blending into its neighbours.
+
+	Loudness Loudness `json:"loudness"` // The loudness of the track measured during normalization.
+}
+
+// Loudness describes the loudness of an audio file measured before its last normalization
+// and the gain applied to reach the station target. Zero values mean the track was never normalized.
+type Loudness struct {
+	Integrated float64 `json:"integrated"` // The integrated loudness in LUFS.
+	TruePeak   float64 `json:"truePeak"`   // The true peak in dBTP.
+	Gain       float64 `json:"gain"`       // The gain in dB applied to the track.
+}
+
+// Invalidator drops what was prepared from the audio of tracks, such as their cached segments,
+// once the tracks are deleted or their audio changes.
+type Invalidator interface {
+	Invalidate(trackIDs ...string)
 }
 
 type Store interface {
@@ -26,6 +46,12 @@ type Page struct {
 	Total  int      `json:"total"`  // The total number of tracks matching the query.
 }
 
+// Cues holds the cue points of a track.
+type Cues struct {
+	In  float64 `json:"cueIn"`
+	Out float64 `json:"cueOut"`
+}
+
 type BodyWithIDs struct {
 	IDs []string `json:"ids"`
 }
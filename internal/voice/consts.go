@@ -0,0 +1,18 @@
+package voice
+
+import "time"
+
+const (
+	DefaultTTL = 24 * time.Hour
+	minTTL     = time.Hour
+	maxTTL     = 30 * 24 * time.Hour
+
+	maxNameLength  = 128
+	maxDuration    = 300 // 5 minutes is more than enough for a message between tracks
+	voiceBitRate   = 192 // kbps, the same as uploaded tracks are converted to
+	voiceExtension = ".m4a"
+
+	segmentsPrefix  = "voice"
+	historyPrefix   = "Voice message: "
+	cleanupInterval = 10 * time.Minute
+)
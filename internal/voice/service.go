@@ -0,0 +1,279 @@
+// Package voice manages voice messages recorded in the studio. Messages are normalized, stored apart
+// from the music library until they expire, and put on air between or over the tracks of a channel.
+package voice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cheatsnake/airstation/internal/pkg/ffmpeg"
+	"github.com/cheatsnake/airstation/internal/pkg/fs"
+	"github.com/cheatsnake/airstation/internal/pkg/ulid"
+	"github.com/cheatsnake/airstation/internal/playback"
+)
+
+// Service manages the voice messages of a single channel.
+type Service struct {
+	store     Store
+	state     *playback.State
+	ffmpegCLI *ffmpeg.CLI
+	dir       string
+	channelID string
+	log       *slog.Logger
+}
+
+// NewService creates a new voice message Service.
+//
+// Parameters:
+//   - store: The storage of voice messages.
+//   - state: The playback state of the channel the messages air on.
+//   - cli: The FFmpeg CLI used to normalize the recorded clips.
+//   - dir: The directory where the normalized clips are stored.
+//   - channelID: The ID of the channel.
+//   - log: The logger.
+//
+// Returns:
+//   - A pointer to a new Service instance.
+func NewService(store Store, state *playback.State, cli *ffmpeg.CLI, dir, channelID string, log *slog.Logger) *Service {
+	return &Service{
+		store:     store,
+		state:     state,
+		ffmpegCLI: cli,
+		dir:       dir,
+		channelID: channelID,
+		log:       log,
+	}
+}
+
+// Messages returns the stored voice messages of the channel, the newest first.
+func (s *Service) Messages() ([]*Message, error) {
+	return s.store.VoiceMessages(s.channelID)
+}
+
+// AddMessage normalizes a recorded clip, stores it as a voice message and puts it on air.
+// The recorded file is removed in any case.
+//
+// Parameters:
+//   - ctx: Stops the conversion when canceled.
+//   - name: The name of the message shown in the playback history.
+//   - recordedPath: The path to the recorded clip in any format FFmpeg can decode, such as webm or ogg.
+//   - mode: When the message airs.
+//   - ttl: How long the message is kept.
+//
+// Returns:
+//   - The stored message with how it airs, or an error if the input is invalid, the clip cannot be converted
+//     or aired. The message is stored even if it cannot be aired, e.g. playback is paused.
+func (s *Service) AddMessage(ctx context.Context, name, recordedPath string, mode Mode, ttl time.Duration) (*Airing, error) {
+	defer func() {
+		if err := fs.DeleteFile(recordedPath); err != nil {
+			s.log.Warn("Failed to delete recorded voice message: " + err.Error())
+		}
+	}()
+
+	name = strings.TrimSpace(name)
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	if err := validateMode(mode); err != nil {
+		return nil, err
+	}
+
+	if err := validateTTL(ttl); err != nil {
+		return nil, err
+	}
+
+	id := ulid.New()
+	path := filepath.Join(s.dir, id+voiceExtension)
+
+	err := s.ffmpegCLI.NormalizeVoice(ctx, recordedPath, path, voiceBitRate)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.ffmpegCLI.AudioMetadata(ctx, path)
+	if err != nil {
+		_ = fs.DeleteFile(path)
+		return nil, err
+	}
+
+	if metadata.Duration <= 0 || metadata.Duration > maxDuration {
+		_ = fs.DeleteFile(path)
+		return nil, fmt.Errorf("voice message must be shorter than %d seconds", maxDuration)
+	}
+
+	now := time.Now()
+	msg := &Message{
+		ID:        id,
+		ChannelID: s.channelID,
+		Name:      name,
+		Path:      path,
+		Duration:  metadata.Duration,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+
+	err = s.store.AddVoiceMessage(msg)
+	if err != nil {
+		_ = fs.DeleteFile(path)
+		return nil, err
+	}
+
+	airing, err := s.air(msg, mode)
+	if err != nil {
+		return airing, fmt.Errorf("voice message is saved, but not aired: %w", err)
+	}
+
+	return airing, nil
+}
+
+// AirMessage puts a stored voice message on air again.
+//
+// Parameters:
+//   - id: The ID of the message.
+//   - mode: When the message airs.
+//
+// Returns:
+//   - The aired message with how it airs, or an error if it doesn't exist, has expired or cannot be aired.
+func (s *Service) AirMessage(id string, mode Mode) (*Airing, error) {
+	if err := validateMode(mode); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.message(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.ExpiresAt <= time.Now().Unix() {
+		return nil, errors.New("voice message has expired")
+	}
+
+	airing, err := s.air(msg, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return airing, nil
+}
+
+// DeleteMessage removes a voice message and its audio file.
+//
+// Parameters:
+//   - id: The ID of the message.
+//
+// Returns:
+//   - An error if the message doesn't exist or cannot be deleted.
+func (s *Service) DeleteMessage(id string) error {
+	msg, err := s.message(id)
+	if err != nil {
+		return err
+	}
+
+	return s.delete(msg)
+}
+
+// DeleteExpired removes the messages of the channel that have expired.
+//
+// Returns:
+//   - The number of deleted messages, or an error if they cannot be read.
+func (s *Service) DeleteExpired() (int, error) {
+	expired, err := s.store.ExpiredVoiceMessages(s.channelID, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, msg := range expired {
+		err := s.delete(msg)
+		if err != nil {
+			s.log.Warn("Failed to delete expired voice message: " + err.Error())
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// Run periodically deletes the expired messages until the stop channel is closed.
+func (s *Service) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			deleted, err := s.DeleteExpired()
+			if err != nil {
+				s.log.Error("Expired voice messages cleanup failed: " + err.Error())
+			}
+			if deleted > 0 {
+				s.log.Info(fmt.Sprintf("Deleted %d expired voice message(s)", deleted))
+			}
+		}
+	}
+}
+
+// air puts the message on air. The returned airing holds the message even if airing fails.
+func (s *Service) air(msg *Message, mode Mode) (*Airing, error) {
+	clip := playback.Clip{
+		Name:     historyPrefix + msg.Name,
+		SegName:  segmentsPrefix + msg.ID,
+		Path:     msg.Path,
+		Duration: msg.Duration,
+	}
+
+	airing := &Airing{Message: msg, Mode: ModeNext}
+
+	var err error
+	if mode == ModeNow {
+		var over playback.Airing
+		over, err = s.state.AirOver(clip)
+		if over.Over {
+			airing.Mode = ModeNow
+			airing.Delay = over.Delay
+		}
+	} else {
+		err = s.state.AirNext(clip)
+	}
+	if err != nil {
+		return airing, err
+	}
+
+	return airing, s.store.MarkVoiceMessageAired(msg.ID, time.Now().Unix())
+}
+
+func (s *Service) message(id string) (*Message, error) {
+	msg, err := s.store.VoiceMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg == nil || msg.ChannelID != s.channelID {
+		return nil, errors.New("voice message not found")
+	}
+
+	return msg, nil
+}
+
+func (s *Service) delete(msg *Message) error {
+	err := s.store.DeleteVoiceMessage(msg.ID)
+	if err != nil {
+		return err
+	}
+
+	err = fs.DeleteFile(msg.Path)
+	if err != nil {
+		s.log.Warn("Failed to delete voice message file: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package voice
+
+// Mode defines when a voice message airs.
+type Mode string
+
+const (
+	ModeNext Mode = "next" // Right after the current track
+	ModeNow  Mode = "now"  // Over the current track, ducked while the message plays, once the live window has passed
+)
+
+var modes = []Mode{ModeNext, ModeNow}
+
+// Message is a short clip recorded in the studio that airs between or over the tracks.
+// Messages are kept apart from the music library and deleted once they expire.
+type Message struct {
+	ID        string  `json:"id"`
+	ChannelID string  `json:"channelId"`
+	Name      string  `json:"name"`
+	Path      string  `json:"-"`
+	Duration  float64 `json:"duration"`  // Duration of the normalized clip in seconds
+	CreatedAt int64   `json:"createdAt"` // Unix timestamp of the upload
+	ExpiresAt int64   `json:"expiresAt"` // Unix timestamp after which the message is deleted
+	AiredAt   int64   `json:"airedAt"`   // Unix timestamp of the last time the message was put on air, 0 if never
+}
+
+// Airing is a message that was put on air.
+type Airing struct {
+	*Message
+	Mode  Mode    `json:"mode"`  // The mode the message airs in, "now" falls back to "next" if the message doesn't fit over the current track
+	Delay float64 `json:"delay"` // Seconds from the current playback position until the message starts over the track, 0 in the "next" mode
+}
+
+// BodyWithMode is a request body to put a message on air.
+type BodyWithMode struct {
+	Mode Mode `json:"mode"`
+}
+
+type Store interface {
+	VoiceMessages(channelID string) ([]*Message, error)
+	VoiceMessage(id string) (*Message, error)
+	AddVoiceMessage(msg *Message) error
+	MarkVoiceMessageAired(id string, airedAt int64) error
+	DeleteVoiceMessage(id string) error
+	ExpiredVoiceMessages(channelID string, now int64) ([]*Message, error)
+}
@@ -0,0 +1,33 @@
+package voice
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	if len(name) > maxNameLength {
+		return fmt.Errorf("name cannot be longer than %d characters", maxNameLength)
+	}
+	return nil
+}
+
+func validateMode(mode Mode) error {
+	if !slices.Contains(modes, mode) {
+		return fmt.Errorf("unsupported mode %q", mode)
+	}
+	return nil
+}
+
+func validateTTL(ttl time.Duration) error {
+	if ttl < minTTL || ttl > maxTTL {
+		return fmt.Errorf("expiry must be between 1 hour and %d days", int(maxTTL.Hours()/24))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package voice
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateName(t *testing.T) {
+	t.Run("rejects blank name", func(t *testing.T) {
+		if err := validateName("   "); err == nil {
+			t.Error("expected error for blank name, got nil")
+		}
+	})
+
+	t.Run("rejects too long name", func(t *testing.T) {
+		if err := validateName(strings.Repeat("a", maxNameLength+1)); err == nil {
+			t.Error("expected error for long name, got nil")
+		}
+	})
+
+	t.Run("accepts valid name", func(t *testing.T) {
+		if err := validateName("Shout-out to the night shift"); err != nil {
+			t.Errorf("expected nil for valid name, got: %v", err)
+		}
+	})
+}
+
+func TestValidateMode(t *testing.T) {
+	for _, mode := range modes {
+		if err := validateMode(mode); err != nil {
+			t.Errorf("expected mode %q to be valid, got: %v", mode, err)
+		}
+	}
+
+	if err := validateMode("later"); err == nil {
+		t.Error("expected error for unsupported mode, got nil")
+	}
+}
+
+func TestValidateTTL(t *testing.T) {
+	cases := []struct {
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{ttl: 0, wantErr: true},
+		{ttl: -time.Hour, wantErr: true},
+		{ttl: time.Minute, wantErr: true},
+		{ttl: minTTL - time.Second, wantErr: true},
+		{ttl: minTTL, wantErr: false},
+		{ttl: DefaultTTL, wantErr: false},
+		{ttl: maxTTL, wantErr: false},
+		{ttl: maxTTL + time.Hour, wantErr: true},
+	}
+
+	for _, c := range cases {
+		err := validateTTL(c.ttl)
+		if (err != nil) != c.wantErr {
+			t.Errorf("validateTTL(%v) error = %v, wantErr %v", c.ttl, err, c.wantErr)
+		}
+	}
+}